@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
+)
+
+// defaultAccountName names the account configured by the API Token and API Base URL settings.
+// Objects of the default account are stored with an empty account name, as they were before
+// the plugin supported several accounts.
+const defaultAccountName = "default"
+
+var accountNameRegexp = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// pagerDutyAccount is a PagerDuty account the plugin connects to, such as the account of a
+// subsidiary in the EU service region.
+type pagerDutyAccount struct {
+	Name             string `json:"name"`
+	APIToken         string `json:"api_token"`
+	APIBaseURL       string `json:"api_base_url,omitempty"`
+	EventsAPIBaseURL string `json:"events_api_base_url,omitempty"`
+	WebhookSecret    string `json:"webhook_secret,omitempty"`
+}
+
+// parseAccounts parses the additional accounts, given as a JSON array of objects with a name,
+// api_token and optional api_base_url, events_api_base_url and webhook_secret. Invalid accounts
+// are left out and reported in the error, along with the valid accounts.
+func parseAccounts(value string) ([]*pagerDutyAccount, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	var parsed []*pagerDutyAccount
+	if err := json.Unmarshal([]byte(value), &parsed); err != nil {
+		return nil, errors.Wrap(err, "accounts must be a JSON array of objects")
+	}
+
+	var accounts []*pagerDutyAccount
+	var invalid []string
+	names := map[string]bool{defaultAccountName: true}
+	for _, account := range parsed {
+		if err := validateAccount(account, names); err != nil {
+			invalid = append(invalid, err.Error())
+			continue
+		}
+		names[account.Name] = true
+		accounts = append(accounts, account)
+	}
+
+	if len(invalid) > 0 {
+		return accounts, fmt.Errorf("ignored invalid accounts: %s", strings.Join(invalid, "; "))
+	}
+	return accounts, nil
+}
+
+// validateAccount returns an error if an account is invalid or its name is among the names
+// already taken.
+func validateAccount(account *pagerDutyAccount, names map[string]bool) error {
+	if !accountNameRegexp.MatchString(account.Name) {
+		return fmt.Errorf("invalid account name %q, use up to 32 lowercase letters, numbers, dashes and underscores", account.Name)
+	}
+	if names[account.Name] {
+		return fmt.Errorf("account name %q is used more than once or is reserved", account.Name)
+	}
+	if account.APIToken == "" {
+		return fmt.Errorf("account %q has no api_token", account.Name)
+	}
+	if err := validateAPIBaseURL(account.APIBaseURL); err != nil {
+		return errors.Wrapf(err, "account %q has an invalid api_base_url", account.Name)
+	}
+	if err := validateAPIBaseURL(account.EventsAPIBaseURL); err != nil {
+		return errors.Wrapf(err, "account %q has an invalid events_api_base_url", account.Name)
+	}
+	return nil
+}
+
+// normalizeAccountName maps the name of the default account to the empty name it is stored
+// with.
+func normalizeAccountName(name string) string {
+	if name == defaultAccountName {
+		return ""
+	}
+	return name
+}
+
+// displayAccountName is the name of an account as shown to users.
+func displayAccountName(name string) string {
+	if name == "" {
+		return defaultAccountName
+	}
+	return name
+}
+
+// account returns the account with the given name, the default account for an empty name, or
+// nil if there is no such account.
+func (c *configuration) account(name string) *pagerDutyAccount {
+	name = normalizeAccountName(name)
+	if name == "" {
+		return &pagerDutyAccount{
+			APIToken:         c.APIToken,
+			APIBaseURL:       c.APIBaseURL,
+			EventsAPIBaseURL: c.EventsAPIBaseURL,
+			WebhookSecret:    c.WebhookSecret,
+		}
+	}
+
+	for _, account := range c.accounts {
+		if account.Name == name {
+			return account
+		}
+	}
+	return nil
+}
+
+// allAccounts returns the default account followed by the additional accounts.
+func (c *configuration) allAccounts() []*pagerDutyAccount {
+	return append([]*pagerDutyAccount{c.account("")}, c.accounts...)
+}
+
+// accountNames returns the names of all accounts, for use in messages.
+func (c *configuration) accountNames() []string {
+	names := []string{defaultAccountName}
+	for _, account := range c.accounts {
+		names = append(names, account.Name)
+	}
+	return names
+}
+
+// unknownAccountError is returned for requests naming an account that is not configured.
+func (p *Plugin) unknownAccountError(name string) *APIError {
+	return &APIError{
+		ID:         "api.pagerduty.account.unknown",
+		Message:    fmt.Sprintf("Unknown PagerDuty account %q. Configured accounts: %s", name, strings.Join(p.getConfiguration().accountNames(), ", ")),
+		StatusCode: http.StatusBadRequest,
+	}
+}
+
+// checkAccount returns an error if the named account is not configured.
+func (p *Plugin) checkAccount(name string) *APIError {
+	if p.getConfiguration().account(name) == nil {
+		return p.unknownAccountError(name)
+	}
+	return nil
+}
+
+// accountClient returns a client for the REST API of an account.
+func (p *Plugin) accountClient(name string) (*pagerduty.Client, *APIError) {
+	account := p.getConfiguration().account(name)
+	if account == nil {
+		return nil, p.unknownAccountError(name)
+	}
+	return p.createPagerDutyClient(account.APIToken, account.APIBaseURL), nil
+}
+
+// accountEventsClient returns a client for the Events API of an account.
+func (p *Plugin) accountEventsClient(name string) (*pagerduty.EventsClient, *APIError) {
+	account := p.getConfiguration().account(name)
+	if account == nil {
+		return nil, p.unknownAccountError(name)
+	}
+	return p.createEventsClient(account.EventsAPIBaseURL), nil
+}
+
+// requestAccount returns the normalized name of the account a request is for, given by its
+// account query parameter.
+func requestAccount(r *http.Request) string {
+	return normalizeAccountName(r.URL.Query().Get("account"))
+}
+
+// requestAccounts returns the accounts a request is for: the one it names, or every account for
+// views that are merged across accounts.
+func (p *Plugin) requestAccounts(r *http.Request) ([]*pagerDutyAccount, *APIError) {
+	config := p.getConfiguration()
+	if r.URL.Query().Get("account") == "" {
+		return config.allAccounts(), nil
+	}
+
+	account := config.account(r.URL.Query().Get("account"))
+	if account == nil {
+		return nil, p.unknownAccountError(r.URL.Query().Get("account"))
+	}
+	return []*pagerDutyAccount{account}, nil
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
+	"github.com/svelle/mattermost-pagerduty-plugin/server/store/kvstore"
+)
+
+func TestParseAccounts(t *testing.T) {
+	t.Run("valid accounts", func(t *testing.T) {
+		accounts, err := parseAccounts(`[
+			{"name": "eu", "api_token": "token1", "api_base_url": "https://api.eu.pagerduty.com", "events_api_base_url": "https://events.eu.pagerduty.com"},
+			{"name": "subsidiary_2", "api_token": "token2", "webhook_secret": "secret"}
+		]`)
+		require.NoError(t, err)
+		require.Len(t, accounts, 2)
+		assert.Equal(t, &pagerDutyAccount{
+			Name:             "eu",
+			APIToken:         "token1",
+			APIBaseURL:       "https://api.eu.pagerduty.com",
+			EventsAPIBaseURL: "https://events.eu.pagerduty.com",
+		}, accounts[0])
+		assert.Equal(t, "secret", accounts[1].WebhookSecret)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		accounts, err := parseAccounts("  ")
+		require.NoError(t, err)
+		assert.Empty(t, accounts)
+	})
+
+	invalid := []struct {
+		name  string
+		value string
+		err   string
+	}{
+		{name: "not an array", value: `{"name": "eu"}`, err: "JSON array"},
+		{name: "invalid name", value: `[{"name": "EU Region", "api_token": "token"}]`, err: "invalid account name"},
+		{name: "reserved name", value: `[{"name": "default", "api_token": "token"}]`, err: "reserved"},
+		{name: "duplicate name", value: `[{"name": "eu", "api_token": "a"}, {"name": "eu", "api_token": "b"}]`, err: "more than once"},
+		{name: "missing token", value: `[{"name": "eu"}]`, err: "no api_token"},
+		{name: "invalid URL", value: `[{"name": "eu", "api_token": "token", "api_base_url": "api.eu.pagerduty.com"}]`, err: "invalid api_base_url"},
+		{name: "invalid events URL", value: `[{"name": "eu", "api_token": "token", "events_api_base_url": "http://events.eu.pagerduty.com"}]`, err: "invalid events_api_base_url"},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseAccounts(tt.value)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+
+	t.Run("valid accounts are kept", func(t *testing.T) {
+		accounts, err := parseAccounts(`[
+			{"name": "eu", "api_token": "token1"},
+			{"name": "us"},
+			{"name": "eu", "api_token": "token2"},
+			{"name": "apac", "api_token": "token3"}
+		]`)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `account "us" has no api_token`)
+		assert.Contains(t, err.Error(), `account name "eu" is used more than once`)
+		require.Len(t, accounts, 2)
+		assert.Equal(t, "eu", accounts[0].Name)
+		assert.Equal(t, "token1", accounts[0].APIToken)
+		assert.Equal(t, "apac", accounts[1].Name)
+	})
+}
+
+func TestConfigurationAccount(t *testing.T) {
+	config := &configuration{
+		APIToken:      "default-token",
+		APIBaseURL:    "https://api.pagerduty.com",
+		WebhookSecret: "default-secret",
+		accounts:      []*pagerDutyAccount{{Name: "eu", APIToken: "eu-token", APIBaseURL: "https://api.eu.pagerduty.com"}},
+	}
+
+	for _, name := range []string{"", defaultAccountName} {
+		account := config.account(name)
+		require.NotNil(t, account)
+		assert.Equal(t, "", account.Name)
+		assert.Equal(t, "default-token", account.APIToken)
+		assert.Equal(t, "default-secret", account.WebhookSecret)
+	}
+
+	account := config.account("eu")
+	require.NotNil(t, account)
+	assert.Equal(t, "eu-token", account.APIToken)
+
+	assert.Nil(t, config.account("us"))
+	assert.Equal(t, []string{"default", "eu"}, config.accountNames())
+	assert.Len(t, config.allAccounts(), 2)
+}
+
+func TestPlugin_requestAccounts(t *testing.T) {
+	plugin := &Plugin{}
+	plugin.setConfiguration(&configuration{
+		accounts: []*pagerDutyAccount{{Name: "eu", APIToken: "eu-token"}},
+	})
+
+	t.Run("all accounts without a parameter", func(t *testing.T) {
+		accounts, apiErr := plugin.requestAccounts(httptest.NewRequest("GET", "/api/v1/oncalls", nil))
+		require.Nil(t, apiErr)
+		require.Len(t, accounts, 2)
+		assert.Equal(t, "", accounts[0].Name)
+		assert.Equal(t, "eu", accounts[1].Name)
+	})
+
+	t.Run("named account", func(t *testing.T) {
+		accounts, apiErr := plugin.requestAccounts(httptest.NewRequest("GET", "/api/v1/oncalls?account=default", nil))
+		require.Nil(t, apiErr)
+		require.Len(t, accounts, 1)
+		assert.Equal(t, "", accounts[0].Name)
+	})
+
+	t.Run("unknown account", func(t *testing.T) {
+		_, apiErr := plugin.requestAccounts(httptest.NewRequest("GET", "/api/v1/oncalls?account=us", nil))
+		require.NotNil(t, apiErr)
+		assert.Equal(t, "api.pagerduty.account.unknown", apiErr.ID)
+		assert.Equal(t, 400, apiErr.StatusCode)
+	})
+}
+
+func TestParseCommandAccount(t *testing.T) {
+	tests := []struct {
+		name            string
+		command         string
+		expectedFields  []string
+		expectedAccount string
+		expectedOK      bool
+	}{
+		{
+			name:           "no account",
+			command:        "/pagerduty maintenance list",
+			expectedFields: []string{"/pagerduty", "maintenance", "list"},
+			expectedOK:     true,
+		},
+		{
+			name:            "account after the command",
+			command:         "/pagerduty incident alerts PINC1 --account eu",
+			expectedFields:  []string{"/pagerduty", "incident", "alerts", "PINC1"},
+			expectedAccount: "eu",
+			expectedOK:      true,
+		},
+		{
+			name:            "account before the command",
+			command:         "/pagerduty --account eu maintenance start PSVC1 2h Deploying v2.1",
+			expectedFields:  []string{"/pagerduty", "maintenance", "start", "PSVC1", "2h", "Deploying", "v2.1"},
+			expectedAccount: "eu",
+			expectedOK:      true,
+		},
+		{
+			name:           "default account",
+			command:        "/pagerduty maintenance list --account default",
+			expectedFields: []string{"/pagerduty", "maintenance", "list"},
+			expectedOK:     true,
+		},
+		{
+			name:    "missing name",
+			command: "/pagerduty maintenance list --account",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, account, ok := parseCommandAccount(strings.Fields(tt.command))
+			assert.Equal(t, tt.expectedOK, ok)
+			if !tt.expectedOK {
+				return
+			}
+			assert.Equal(t, tt.expectedFields, fields)
+			assert.Equal(t, tt.expectedAccount, account)
+		})
+	}
+}
+
+func TestPlugin_ExecuteCommand_account(t *testing.T) {
+	plugin, _, _ := setupHandlerTestPlugin(t)
+	plugin.setConfiguration(&configuration{
+		APIToken: "token",
+		accounts: []*pagerDutyAccount{{Name: "eu", APIToken: "eu-token"}},
+	})
+
+	var authorizations []string
+	usePagerDutyServer(t, plugin, func(w http.ResponseWriter, r *http.Request) {
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+		fmt.Fprint(w, `{"maintenance_windows": []}`)
+	})
+
+	execute := func(command string) string {
+		response, appErr := plugin.ExecuteCommand(nil, &model.CommandArgs{UserId: "admin-user-id", Command: command})
+		require.Nil(t, appErr)
+		return response.Text
+	}
+
+	t.Run("default account", func(t *testing.T) {
+		authorizations = nil
+		assert.Equal(t, "There are no ongoing or upcoming maintenance windows.", execute("/pagerduty maintenance list"))
+		assert.Equal(t, []string{"Token token=token"}, authorizations)
+	})
+
+	t.Run("selected account", func(t *testing.T) {
+		authorizations = nil
+		assert.Equal(t, "There are no ongoing or upcoming maintenance windows.", execute("/pagerduty maintenance list --account eu"))
+		assert.Equal(t, []string{"Token token=eu-token"}, authorizations)
+	})
+
+	t.Run("unknown account", func(t *testing.T) {
+		authorizations = nil
+		assert.Contains(t, execute("/pagerduty maintenance list --account us"), `Unknown PagerDuty account "us"`)
+		assert.Empty(t, authorizations)
+	})
+}
+
+func TestPlugin_mergedAccountViews(t *testing.T) {
+	tests := []struct {
+		name             string
+		failingTokens    map[string]bool
+		expectedStatus   int
+		expectedAccounts []string
+		expectedErrors   []pagerduty.AccountError
+	}{
+		{
+			name:             "every account",
+			expectedStatus:   http.StatusOK,
+			expectedAccounts: []string{"default", "eu"},
+		},
+		{
+			name:             "one account failing",
+			failingTokens:    map[string]bool{"Token token=eu-token": true},
+			expectedStatus:   http.StatusOK,
+			expectedAccounts: []string{"default"},
+			expectedErrors:   []pagerduty.AccountError{{Account: "eu", Message: "Failed to retrieve schedules"}},
+		},
+		{
+			name:           "every account failing",
+			failingTokens:  map[string]bool{"Token token=token": true, "Token token=eu-token": true},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin, _, _ := setupHandlerTestPlugin(t)
+			plugin.setConfiguration(&configuration{
+				APIToken: "token",
+				accounts: []*pagerDutyAccount{{Name: "eu", APIToken: "eu-token"}},
+			})
+			usePagerDutyServer(t, plugin, func(w http.ResponseWriter, r *http.Request) {
+				if tt.failingTokens[r.Header.Get("Authorization")] {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				fmt.Fprint(w, `{"schedules": [{"id": "PSCHED1", "name": "Primary"}]}`)
+			})
+
+			w := serveTestRequest(plugin, http.MethodGet, "/api/v1/schedules", "test-user-id", "")
+			require.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response pagerduty.SchedulesResponse
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+			accounts := make([]string, 0, len(response.Schedules))
+			for _, schedule := range response.Schedules {
+				accounts = append(accounts, schedule.Account)
+			}
+			assert.Equal(t, tt.expectedAccounts, accounts)
+			assert.Equal(t, tt.expectedErrors, response.AccountErrors)
+		})
+	}
+}
+
+func TestPlugin_handleCreateIncident_account(t *testing.T) {
+	plugin, _, _ := setupHandlerTestPlugin(t)
+	plugin.setConfiguration(&configuration{
+		APIToken: "token",
+		accounts: []*pagerDutyAccount{{Name: "eu", APIToken: "eu-token"}},
+	})
+	require.NoError(t, plugin.kvstore.SavePagingPolicy(&kvstore.PagingPolicy{ServiceID: "PSVC1", Account: "eu", RequireConfirmation: true}))
+
+	var authorizations []string
+	usePagerDutyServer(t, plugin, func(w http.ResponseWriter, r *http.Request) {
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"incident": {"id": "PINC1", "title": "Database down"}}`)
+	})
+
+	t.Run("policy of the account applied", func(t *testing.T) {
+		authorizations = nil
+		w := serveTestRequest(plugin, http.MethodPost, "/api/v1/incidents?account=eu", "test-user-id", `{"title": "Database down", "service_id": "PSVC1"}`)
+		assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+		assert.Empty(t, authorizations)
+	})
+
+	t.Run("created in the account", func(t *testing.T) {
+		authorizations = nil
+		w := serveTestRequest(plugin, http.MethodPost, "/api/v1/incidents?account=eu", "test-user-id", `{"title": "Database down", "service_id": "PSVC1", "confirmed": true}`)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		assert.Equal(t, []string{"Token token=eu-token"}, authorizations)
+	})
+
+	t.Run("policy of another account not applied", func(t *testing.T) {
+		authorizations = nil
+		w := serveTestRequest(plugin, http.MethodPost, "/api/v1/incidents", "test-user-id", `{"title": "Database down", "service_id": "PSVC1"}`)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		assert.Equal(t, []string{"Token token=token"}, authorizations)
+	})
+}
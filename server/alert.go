@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
+)
+
+// maxListedGroupAlerts is how many triggered alerts of a group are listed by ID, so that they
+// can be resolved or moved.
+const maxListedGroupAlerts = 5
+
+// alertSeverityOrder ranks the severities of Events API v2 alerts, most severe last.
+var alertSeverityOrder = map[string]int{
+	"info":     1,
+	"warning":  2,
+	"error":    3,
+	"critical": 4,
+}
+
+// AlertGroup is a set of alerts of an incident that share a summary, typically the same check
+// failing repeatedly or on several hosts.
+type AlertGroup struct {
+	Summary   string            `json:"summary"`
+	Severity  string            `json:"severity,omitempty"`
+	Triggered int               `json:"triggered"`
+	Resolved  int               `json:"resolved"`
+	Alerts    []pagerduty.Alert `json:"alerts"`
+}
+
+// IncidentAlertGroups are the alerts of an incident, grouped by summary.
+type IncidentAlertGroups struct {
+	IncidentID string       `json:"incident_id"`
+	Total      int          `json:"total"`
+	Triggered  int          `json:"triggered"`
+	Groups     []AlertGroup `json:"groups"`
+}
+
+// UpdateAlertRequest represents the request body for resolving an alert, or for moving it to
+// another incident
+type UpdateAlertRequest struct {
+	Status     string `json:"status,omitempty"`
+	IncidentID string `json:"incident_id,omitempty"`
+}
+
+// groupAlerts groups alerts by summary. Groups with the most triggered alerts come first, then
+// the largest.
+func groupAlerts(incidentID string, alerts []pagerduty.Alert) *IncidentAlertGroups {
+	result := &IncidentAlertGroups{IncidentID: incidentID, Total: len(alerts), Groups: []AlertGroup{}}
+
+	index := map[string]int{}
+	for _, alert := range alerts {
+		summary := alert.Summary
+		if summary == "" {
+			summary = alert.ID
+		}
+
+		i, ok := index[summary]
+		if !ok {
+			i = len(result.Groups)
+			index[summary] = i
+			result.Groups = append(result.Groups, AlertGroup{Summary: summary})
+		}
+
+		group := &result.Groups[i]
+		group.Alerts = append(group.Alerts, alert)
+		if alertSeverityOrder[alert.Severity] > alertSeverityOrder[group.Severity] {
+			group.Severity = alert.Severity
+		}
+		if alert.Status == pagerduty.AlertStatusResolved {
+			group.Resolved++
+		} else {
+			group.Triggered++
+			result.Triggered++
+		}
+	}
+
+	sort.SliceStable(result.Groups, func(i, j int) bool {
+		gi, gj := result.Groups[i], result.Groups[j]
+		if gi.Triggered != gj.Triggered {
+			return gi.Triggered > gj.Triggered
+		}
+		if len(gi.Alerts) != len(gj.Alerts) {
+			return len(gi.Alerts) > len(gj.Alerts)
+		}
+		return gi.Summary < gj.Summary
+	})
+
+	return result
+}
+
+// getIncidentAlertGroups returns the alerts of an incident of an account, grouped by summary.
+func (p *Plugin) getIncidentAlertGroups(account, incidentID string) (*IncidentAlertGroups, *APIError) {
+	client, apiErr := p.accountClient(account)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	alerts, err := client.GetAllIncidentAlerts(incidentID)
+	if err != nil {
+		p.client.Log.Error("Failed to get alerts from PagerDuty", "error", err.Error(), "incident_id", incidentID)
+		return nil, &APIError{
+			ID:         "api.pagerduty.alerts.error",
+			Message:    "Failed to retrieve alerts",
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+
+	return groupAlerts(incidentID, alerts.Alerts), nil
+}
+
+// updateAlert resolves an alert of an incident, or moves it to another incident, on behalf of
+// the given user, and notes it in the threads of the channels following the incident.
+func (p *Plugin) updateAlert(userID, account, incidentID, alertID string, req *UpdateAlertRequest) (_ *pagerduty.Alert, apiErr *APIError) {
+	defer func() { p.recordAudit(userID, account, auditActionUpdateAlert, alertID, apiErr) }()
+
+	if (req.Status == "") == (req.IncidentID == "") {
+		return nil, &APIError{
+			ID:         "api.pagerduty.alert.update.invalid",
+			Message:    "Either a status or an incident_id to move the alert to is required",
+			StatusCode: http.StatusBadRequest,
+		}
+	}
+	if req.Status != "" && req.Status != pagerduty.AlertStatusResolved {
+		return nil, &APIError{
+			ID:         "api.pagerduty.alert.status.invalid",
+			Message:    "Alerts can only be resolved",
+			StatusCode: http.StatusBadRequest,
+		}
+	}
+	if req.IncidentID == incidentID {
+		return nil, &APIError{
+			ID:         "api.pagerduty.alert.incident.invalid",
+			Message:    "The alert already belongs to this incident",
+			StatusCode: http.StatusBadRequest,
+		}
+	}
+
+	user, err := p.client.User.Get(userID)
+	if err != nil {
+		p.client.Log.Error("Failed to get user", "error", err.Error(), "user_id", userID)
+		return nil, &APIError{
+			ID:         "api.pagerduty.alert.user.error",
+			Message:    "Failed to retrieve user",
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+
+	client, apiErr := p.accountClient(account)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	var response *pagerduty.AlertResponse
+	if req.Status == pagerduty.AlertStatusResolved {
+		response, err = client.ResolveAlert(incidentID, alertID, user.Email)
+	} else {
+		response, err = client.MoveAlert(incidentID, alertID, req.IncidentID, user.Email)
+	}
+	if err != nil {
+		p.client.Log.Error("Failed to update alert in PagerDuty", "error", err.Error(), "incident_id", incidentID, "alert_id", alertID)
+		return nil, &APIError{
+			ID:         "api.pagerduty.alert.update.error",
+			Message:    "Failed to update the alert",
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+
+	alert := &response.Alert
+	if alert.ID == "" {
+		alert.ID = alertID
+	}
+	p.postIncidentReply(account, incidentID, formatAlertUpdate(user.Username, alert, req.IncidentID))
+
+	return alert, nil
+}
+
+// formatAlertUpdate formats a user resolving an alert, or moving it to another incident.
+func formatAlertUpdate(username string, alert *pagerduty.Alert, toIncidentID string) string {
+	name := fmt.Sprintf("`%s`", alert.ID)
+	if alert.Summary != "" {
+		name += " " + alert.Summary
+	}
+
+	if toIncidentID != "" {
+		return fmt.Sprintf(":arrow_right: @%s moved alert %s to incident `%s`.", username, name, toIncidentID)
+	}
+	return fmt.Sprintf(":white_check_mark: @%s resolved alert %s.", username, name)
+}
+
+// formatAlertGroups formats the grouped alerts of an incident, listing the triggered alerts of
+// each group so that they can be resolved or moved.
+func formatAlertGroups(groups *IncidentAlertGroups) string {
+	if groups.Total == 0 {
+		return fmt.Sprintf("Incident `%s` has no alerts.", groups.IncidentID)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "###### Alerts of incident `%s`\n", groups.IncidentID)
+	fmt.Fprintf(&sb, "%d alerts in %d groups, %d triggered\n", groups.Total, len(groups.Groups), groups.Triggered)
+
+	for _, group := range groups.Groups {
+		icon := ":large_green_circle:"
+		if group.Triggered > 0 {
+			icon = ":red_circle:"
+		}
+		fmt.Fprintf(&sb, "* %s **%s** ×%d", icon, group.Summary, len(group.Alerts))
+		if group.Severity != "" {
+			fmt.Fprintf(&sb, " (%s)", group.Severity)
+		}
+		fmt.Fprintf(&sb, " - %d triggered, %d resolved\n", group.Triggered, group.Resolved)
+
+		listed := 0
+		for _, alert := range group.Alerts {
+			if alert.Status == pagerduty.AlertStatusResolved {
+				continue
+			}
+			if listed == maxListedGroupAlerts {
+				fmt.Fprintf(&sb, "  * _and %d more_\n", group.Triggered-listed)
+				break
+			}
+			fmt.Fprintf(&sb, "  * `%s`%s\n", alert.ID, formatAlertDetails(alert.Body))
+			listed++
+		}
+	}
+
+	sb.WriteString("\nResolve an alert with `/pagerduty incident alert resolve <incident-id> <alert-id>`, or move it with `/pagerduty incident alert move <incident-id> <alert-id> <to-incident-id>`.")
+	return sb.String()
+}
+
+// formatAlertDetails formats the custom details of an alert as sorted key=value pairs, skipping
+// nested values.
+func formatAlertDetails(body *pagerduty.AlertBody) string {
+	if body == nil || len(body.Details) == 0 {
+		return ""
+	}
+
+	var details []string
+	for key, value := range body.Details {
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			continue
+		}
+		details = append(details, fmt.Sprintf("%s=%v", key, value))
+	}
+	sort.Strings(details)
+
+	if len(details) == 0 {
+		return ""
+	}
+	return " " + strings.Join(details, " ")
+}
@@ -0,0 +1,554 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
+	"github.com/svelle/mattermost-pagerduty-plugin/server/store/kvstore"
+)
+
+const (
+	// alertRuleRateWindow is the window the events of an alert rule are counted in.
+	alertRuleRateWindow = time.Minute
+
+	// defaultAlertRuleMaxEventsPerMinute limits rules that do not set their own limit, so that a
+	// noisy channel cannot flood PagerDuty.
+	defaultAlertRuleMaxEventsPerMinute = 10
+
+	// alertRuleSeverityGroup is the name of the capture group of a rule's pattern mapped to the
+	// severity of its alerts.
+	alertRuleSeverityGroup = "severity"
+)
+
+var dedupKeyPlaceholderRegexp = regexp.MustCompile(`\{\{\s*(\w+)\s*\}\}`)
+
+var alertSeverities = []string{pagerduty.SeverityCritical, pagerduty.SeverityError, pagerduty.SeverityWarning, pagerduty.SeverityInfo}
+
+// SaveAlertRuleRequest represents the request body for creating or replacing an alert rule
+type SaveAlertRuleRequest struct {
+	Name               string            `json:"name"`
+	ChannelID          string            `json:"channel_id"`
+	ServiceID          string            `json:"service_id"`
+	AuthorIDs          []string          `json:"author_ids,omitempty"`
+	BotsOnly           bool              `json:"bots_only,omitempty"`
+	TriggerPattern     string            `json:"trigger_pattern"`
+	ResolvePattern     string            `json:"resolve_pattern,omitempty"`
+	SeverityMap        map[string]string `json:"severity_map,omitempty"`
+	DefaultSeverity    string            `json:"default_severity,omitempty"`
+	DedupKeyTemplate   string            `json:"dedup_key_template,omitempty"`
+	MaxEventsPerMinute int               `json:"max_events_per_minute,omitempty"`
+	DryRun             bool              `json:"dry_run,omitempty"`
+}
+
+// alertRuleMatch is the event a message matching an alert rule turns into.
+type alertRuleMatch struct {
+	Action   string
+	Summary  string
+	Severity string
+	DedupKey string
+	Fields   map[string]string
+}
+
+// canManageAlertRules reports whether the user may manage alert rules. Rules page whoever is on
+// call for a service, so this is restricted to plugin admins.
+func (p *Plugin) canManageAlertRules(userID string) bool {
+	return p.hasPermission(userID, permissionAdmin)
+}
+
+// getAlertRules returns the alert rules for a system admin.
+func (p *Plugin) getAlertRules(userID string) ([]*kvstore.AlertRule, *APIError) {
+	if !p.canManageAlertRules(userID) {
+		return nil, alertRulePermissionError()
+	}
+
+	rules, err := p.kvstore.GetAlertRules()
+	if err != nil {
+		p.client.Log.Error("Failed to get alert rules", "error", err.Error())
+		return nil, &APIError{
+			ID:         "api.pagerduty.alert_rule.get.error",
+			Message:    "Failed to retrieve alert rules",
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+	if rules == nil {
+		rules = []*kvstore.AlertRule{}
+	}
+	return rules, nil
+}
+
+// createAlertRule validates and stores a new alert rule for a service of an account on behalf of
+// the given user.
+func (p *Plugin) createAlertRule(userID, account string, req *SaveAlertRuleRequest) (_ *kvstore.AlertRule, apiErr *APIError) {
+	defer func() { p.recordAudit(userID, account, auditActionCreateAlertRule, req.Name, apiErr) }()
+
+	rule := &kvstore.AlertRule{
+		ID:        model.NewId(),
+		Account:   account,
+		CreatorID: userID,
+		CreateAt:  model.GetMillis(),
+	}
+	return p.saveAlertRule(userID, rule, req)
+}
+
+// updateAlertRule replaces the settings of an alert rule on behalf of the given user.
+func (p *Plugin) updateAlertRule(userID, ruleID string, req *SaveAlertRuleRequest) (_ *kvstore.AlertRule, apiErr *APIError) {
+	var account string
+	defer func() { p.recordAudit(userID, account, auditActionUpdateAlertRule, ruleID, apiErr) }()
+
+	rules, apiErr := p.getAlertRules(userID)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	i := slices.IndexFunc(rules, func(rule *kvstore.AlertRule) bool { return rule.ID == ruleID })
+	if i < 0 {
+		return nil, alertRuleNotFoundError(ruleID)
+	}
+
+	rule := *rules[i]
+	account = rule.Account
+	rule.UpdateAt = model.GetMillis()
+	return p.saveAlertRule(userID, &rule, req)
+}
+
+// setAlertRuleDryRun turns the dry-run mode of an alert rule on or off on behalf of the given
+// user. In dry-run mode, the events a rule would send are only logged.
+func (p *Plugin) setAlertRuleDryRun(userID, ruleID string, dryRun bool) (_ *kvstore.AlertRule, apiErr *APIError) {
+	var account string
+	defer func() { p.recordAudit(userID, account, auditActionUpdateAlertRule, ruleID, apiErr) }()
+
+	if !p.canManageAlertRules(userID) {
+		return nil, alertRulePermissionError()
+	}
+
+	var updated *kvstore.AlertRule
+	apiErr = p.updateAlertRules(func(rules []*kvstore.AlertRule) ([]*kvstore.AlertRule, *APIError) {
+		i := slices.IndexFunc(rules, func(rule *kvstore.AlertRule) bool { return rule.ID == ruleID })
+		if i < 0 {
+			return nil, alertRuleNotFoundError(ruleID)
+		}
+
+		account = rules[i].Account
+		rules[i].DryRun = dryRun
+		rules[i].UpdateAt = model.GetMillis()
+		updated = rules[i]
+		return rules, nil
+	})
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	return updated, nil
+}
+
+// deleteAlertRule removes an alert rule on behalf of the given user.
+func (p *Plugin) deleteAlertRule(userID, ruleID string) (apiErr *APIError) {
+	var account string
+	defer func() { p.recordAudit(userID, account, auditActionDeleteAlertRule, ruleID, apiErr) }()
+
+	if !p.canManageAlertRules(userID) {
+		return alertRulePermissionError()
+	}
+
+	return p.updateAlertRules(func(rules []*kvstore.AlertRule) ([]*kvstore.AlertRule, *APIError) {
+		i := slices.IndexFunc(rules, func(rule *kvstore.AlertRule) bool { return rule.ID == ruleID })
+		if i < 0 {
+			return nil, alertRuleNotFoundError(ruleID)
+		}
+		account = rules[i].Account
+		return slices.Delete(slices.Clone(rules), i, i+1), nil
+	})
+}
+
+// saveAlertRule applies a request to a rule, validates it and stores it, replacing the rule
+// with the same ID if there is one. Rules with an UpdateAt are replacements, which fail if the
+// rule was deleted meanwhile.
+func (p *Plugin) saveAlertRule(userID string, rule *kvstore.AlertRule, req *SaveAlertRuleRequest) (*kvstore.AlertRule, *APIError) {
+	if !p.canManageAlertRules(userID) {
+		return nil, alertRulePermissionError()
+	}
+
+	rule.Name = strings.TrimSpace(req.Name)
+	rule.ChannelID = req.ChannelID
+	rule.ServiceID = req.ServiceID
+	rule.AuthorIDs = req.AuthorIDs
+	rule.BotsOnly = req.BotsOnly
+	rule.TriggerPattern = req.TriggerPattern
+	rule.ResolvePattern = req.ResolvePattern
+	rule.DedupKeyTemplate = req.DedupKeyTemplate
+	rule.DefaultSeverity = strings.ToLower(req.DefaultSeverity)
+	rule.MaxEventsPerMinute = req.MaxEventsPerMinute
+	rule.DryRun = req.DryRun
+
+	rule.SeverityMap = nil
+	if len(req.SeverityMap) > 0 {
+		rule.SeverityMap = make(map[string]string, len(req.SeverityMap))
+		for value, severity := range req.SeverityMap {
+			rule.SeverityMap[strings.ToLower(value)] = strings.ToLower(severity)
+		}
+	}
+
+	if apiErr := validateAlertRule(rule); apiErr != nil {
+		return nil, apiErr
+	}
+
+	if _, err := p.client.Channel.Get(rule.ChannelID); err != nil {
+		return nil, &APIError{
+			ID:         "api.pagerduty.alert_rule.channel.not_found",
+			Message:    fmt.Sprintf("Channel %s was not found", rule.ChannelID),
+			StatusCode: http.StatusBadRequest,
+		}
+	}
+
+	// The service must have an Events API v2 integration for the rule to send events to.
+	if _, _, apiErr := p.getServiceRoutingKey(rule.Account, rule.ServiceID); apiErr != nil {
+		return nil, apiErr
+	}
+
+	apiErr := p.updateAlertRules(func(rules []*kvstore.AlertRule) ([]*kvstore.AlertRule, *APIError) {
+		i := slices.IndexFunc(rules, func(existing *kvstore.AlertRule) bool { return existing.ID == rule.ID })
+		switch {
+		case i >= 0:
+			rules[i] = rule
+		case rule.UpdateAt != 0:
+			return nil, alertRuleNotFoundError(rule.ID)
+		default:
+			rules = append(rules, rule)
+		}
+		return rules, nil
+	})
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	return rule, nil
+}
+
+// updateAlertRules applies a change to the stored alert rules without overwriting changes made
+// at the same time by other requests or nodes. The change is applied again to the latest rules
+// if they changed meanwhile, and may fail with an error to leave the rules unchanged.
+func (p *Plugin) updateAlertRules(change func(rules []*kvstore.AlertRule) ([]*kvstore.AlertRule, *APIError)) *APIError {
+	var changeErr *APIError
+	_, err := p.kvstore.UpdateAlertRules(func(rules []*kvstore.AlertRule) ([]*kvstore.AlertRule, error) {
+		rules, changeErr = change(rules)
+		if changeErr != nil {
+			return nil, errors.New(changeErr.Message)
+		}
+		return rules, nil
+	})
+	if changeErr != nil {
+		return changeErr
+	}
+	if err != nil {
+		p.client.Log.Error("Failed to save alert rules", "error", err.Error())
+		return &APIError{
+			ID:         "api.pagerduty.alert_rule.save.error",
+			Message:    "Failed to save alert rules",
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+	return nil
+}
+
+// validateAlertRule checks that an alert rule has the required fields, that its patterns
+// compile and that its dedup key template and severities can be resolved.
+func validateAlertRule(rule *kvstore.AlertRule) *APIError {
+	invalid := func(id, message string) *APIError {
+		return &APIError{ID: "api.pagerduty.alert_rule." + id, Message: message, StatusCode: http.StatusBadRequest}
+	}
+
+	if rule.ChannelID == "" || rule.ServiceID == "" || rule.TriggerPattern == "" {
+		return invalid("fields.missing", "channel_id, service_id and trigger_pattern are required")
+	}
+
+	patterns := []string{rule.TriggerPattern}
+	if rule.ResolvePattern != "" {
+		if rule.DedupKeyTemplate == "" {
+			return invalid("dedup_key_template.missing", "A dedup_key_template is required to resolve alerts")
+		}
+		patterns = append(patterns, rule.ResolvePattern)
+	}
+
+	for _, pattern := range patterns {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return invalid("pattern.invalid", fmt.Sprintf("Invalid pattern %s: %s", pattern, err.Error()))
+		}
+
+		// Both patterns need to capture every field of the dedup key, so that the alert a
+		// resolving message refers to can be found.
+		for _, placeholder := range dedupKeyPlaceholderRegexp.FindAllStringSubmatch(rule.DedupKeyTemplate, -1) {
+			if compiled.SubexpIndex(placeholder[1]) < 0 {
+				return invalid("dedup_key_template.invalid", fmt.Sprintf("Pattern %s has no capture group named %s", pattern, placeholder[1]))
+			}
+		}
+	}
+
+	if rule.DefaultSeverity != "" && !slices.Contains(alertSeverities, rule.DefaultSeverity) {
+		return invalid("severity.invalid", fmt.Sprintf("Invalid default_severity %s. Use one of %s", rule.DefaultSeverity, strings.Join(alertSeverities, ", ")))
+	}
+	for value, severity := range rule.SeverityMap {
+		if !slices.Contains(alertSeverities, severity) {
+			return invalid("severity.invalid", fmt.Sprintf("Invalid severity %s for %s. Use one of %s", severity, value, strings.Join(alertSeverities, ", ")))
+		}
+	}
+
+	if rule.MaxEventsPerMinute < 0 {
+		return invalid("rate_limit.invalid", "max_events_per_minute cannot be negative")
+	}
+
+	return nil
+}
+
+// sendMatchingAlertEvents sends an alert event for every alert rule of the post's channel that
+// the post matches, or logs it for rules in dry-run mode.
+func (p *Plugin) sendMatchingAlertEvents(post *model.Post) {
+	if post.UserId == p.botUserID || post.IsSystemMessage() || post.Message == "" {
+		return
+	}
+
+	rules, err := p.kvstore.GetAlertRules()
+	if err != nil {
+		p.client.Log.Error("Failed to get alert rules", "error", err.Error())
+		return
+	}
+
+	var author *model.User
+	for _, rule := range rules {
+		if rule.ChannelID != post.ChannelId {
+			continue
+		}
+		if len(rule.AuthorIDs) > 0 && !slices.Contains(rule.AuthorIDs, post.UserId) {
+			continue
+		}
+
+		if author == nil {
+			if author, err = p.client.User.Get(post.UserId); err != nil {
+				p.client.Log.Warn("Failed to get post author", "error", err.Error(), "user_id", post.UserId)
+				return
+			}
+		}
+		if rule.BotsOnly && !isBotPost(post, author) {
+			continue
+		}
+
+		match, err := matchAlertRule(rule, post.Message)
+		if err != nil {
+			p.client.Log.Warn("Skipping alert rule with invalid pattern", "rule_id", rule.ID, "error", err.Error())
+			continue
+		}
+		if match == nil {
+			continue
+		}
+
+		p.sendAlertRuleEvent(rule, match, post, author)
+	}
+}
+
+// sendAlertRuleEvent sends the event of a message matching an alert rule, unless the rule has
+// reached its rate limit.
+func (p *Plugin) sendAlertRuleEvent(rule *kvstore.AlertRule, match *alertRuleMatch, post *model.Post, author *model.User) {
+	limit := rule.MaxEventsPerMinute
+	if limit == 0 {
+		limit = defaultAlertRuleMaxEventsPerMinute
+	}
+
+	count, err := p.kvstore.IncrementAlertRuleEventCount(rule.ID, alertRuleRateWindow, time.Now())
+	if err != nil {
+		p.client.Log.Error("Failed to count alert rule event", "error", err.Error(), "rule_id", rule.ID)
+		return
+	}
+	if count > limit {
+		p.client.Log.Warn("Alert rule rate limit reached, dropping event", "rule_id", rule.ID, "limit", limit, "post_id", post.Id)
+		return
+	}
+
+	event := &pagerduty.Event{
+		EventAction: match.Action,
+		DedupKey:    match.DedupKey,
+		Client:      changeEventSource,
+	}
+	if permalink := p.getPostPermalink(post.Id); permalink != "" {
+		event.ClientURL = permalink
+		event.Links = []pagerduty.Link{{Href: permalink, Text: "View in Mattermost"}}
+	}
+	if match.Action == pagerduty.EventActionTrigger {
+		customDetails := map[string]interface{}{
+			"message": post.Message,
+			"author":  "@" + author.Username,
+		}
+		for name, value := range match.Fields {
+			customDetails[name] = value
+		}
+
+		event.Payload = &pagerduty.EventPayload{
+			Summary:       truncateChangeEventSummary(match.Summary),
+			Source:        author.Username,
+			Severity:      match.Severity,
+			Timestamp:     time.UnixMilli(post.CreateAt).UTC().Format(time.RFC3339),
+			CustomDetails: customDetails,
+		}
+	}
+
+	if rule.DryRun {
+		p.client.Log.Info("Alert rule dry run, not sending event", "rule_id", rule.ID, "post_id", post.Id,
+			"event_action", match.Action, "dedup_key", match.DedupKey, "severity", match.Severity, "summary", match.Summary)
+		return
+	}
+
+	routingKey, _, apiErr := p.getCachedServiceRoutingKey(rule.Account, rule.ServiceID)
+	if apiErr != nil {
+		p.client.Log.Warn("Skipping alert rule without a routing key", "rule_id", rule.ID, "error", apiErr.Message)
+		return
+	}
+	event.RoutingKey = routingKey
+
+	client, apiErr := p.accountEventsClient(rule.Account)
+	if apiErr != nil {
+		p.client.Log.Warn("Skipping alert rule of an unknown account", "rule_id", rule.ID, "error", apiErr.Message)
+		return
+	}
+	if _, err := client.SendEvent(event); err != nil {
+		p.client.Log.Error("Failed to send alert event to PagerDuty", "error", err.Error(), "rule_id", rule.ID, "post_id", post.Id)
+	}
+}
+
+// isBotPost reports whether a post was made by a bot or an incoming webhook.
+func isBotPost(post *model.Post, author *model.User) bool {
+	return author.IsBot || post.GetProp(model.PostPropsFromWebhook) == "true" || post.GetProp(model.PostPropsFromBot) == "true"
+}
+
+// matchAlertRule returns the event a message turns into under an alert rule, or nil if it
+// matches neither of the rule's patterns. Resolving takes precedence, so that recovery messages
+// that also match the trigger pattern resolve their alert.
+func matchAlertRule(rule *kvstore.AlertRule, message string) (*alertRuleMatch, error) {
+	if rule.ResolvePattern != "" {
+		pattern, err := regexp.Compile(rule.ResolvePattern)
+		if err != nil {
+			return nil, err
+		}
+		if fields, ok := matchNamedGroups(pattern, message); ok {
+			dedupKey := expandDedupKeyTemplate(rule.DedupKeyTemplate, fields)
+			if dedupKey == "" {
+				return nil, nil
+			}
+			return &alertRuleMatch{Action: pagerduty.EventActionResolve, DedupKey: dedupKey, Fields: fields}, nil
+		}
+	}
+
+	pattern, err := regexp.Compile(rule.TriggerPattern)
+	if err != nil {
+		return nil, err
+	}
+	fields, ok := matchNamedGroups(pattern, message)
+	if !ok {
+		return nil, nil
+	}
+
+	summary, _ := changeEventSummary(pattern, message)
+	return &alertRuleMatch{
+		Action:   pagerduty.EventActionTrigger,
+		Summary:  summary,
+		Severity: alertRuleSeverity(rule, fields[alertRuleSeverityGroup]),
+		DedupKey: expandDedupKeyTemplate(rule.DedupKeyTemplate, fields),
+		Fields:   fields,
+	}, nil
+}
+
+// matchNamedGroups returns the values of the named capture groups of a pattern in a message,
+// and whether the message matches at all.
+func matchNamedGroups(pattern *regexp.Regexp, message string) (map[string]string, bool) {
+	match := pattern.FindStringSubmatch(message)
+	if match == nil {
+		return nil, false
+	}
+
+	fields := map[string]string{}
+	for i, name := range pattern.SubexpNames() {
+		if name != "" && match[i] != "" {
+			fields[name] = strings.TrimSpace(match[i])
+		}
+	}
+	return fields, true
+}
+
+// alertRuleSeverity maps the severity captured from a message to a PagerDuty severity, using
+// the captured value itself if it is one, then the rule's default, then error.
+func alertRuleSeverity(rule *kvstore.AlertRule, captured string) string {
+	captured = strings.ToLower(captured)
+	if severity, ok := rule.SeverityMap[captured]; ok {
+		return severity
+	}
+	if slices.Contains(alertSeverities, captured) {
+		return captured
+	}
+	if rule.DefaultSeverity != "" {
+		return rule.DefaultSeverity
+	}
+	return pagerduty.SeverityError
+}
+
+// expandDedupKeyTemplate replaces the {{name}} placeholders of a template with the named
+// capture groups of a match. It returns an empty string if any of them is missing, as a
+// partial key could merge unrelated alerts.
+func expandDedupKeyTemplate(template string, fields map[string]string) string {
+	complete := true
+	key := dedupKeyPlaceholderRegexp.ReplaceAllStringFunc(template, func(placeholder string) string {
+		value := fields[dedupKeyPlaceholderRegexp.FindStringSubmatch(placeholder)[1]]
+		if value == "" {
+			complete = false
+		}
+		return value
+	})
+	if !complete {
+		return ""
+	}
+	return key
+}
+
+// formatAlertRules lists the alert rules for the alertrule command.
+func formatAlertRules(rules []*kvstore.AlertRule) string {
+	if len(rules) == 0 {
+		return "There are no alert rules. Add one with `/pagerduty alertrule add <service-id> <pattern>`."
+	}
+
+	var sb strings.Builder
+	sb.WriteString("###### Alert rules\n")
+	for _, rule := range rules {
+		fmt.Fprintf(&sb, "* `%s`", rule.ID)
+		if rule.Name != "" {
+			fmt.Fprintf(&sb, " **%s**", rule.Name)
+		}
+		fmt.Fprintf(&sb, " - `%s` in channel `%s` → service `%s`", rule.TriggerPattern, rule.ChannelID, rule.ServiceID)
+		if rule.ResolvePattern != "" {
+			fmt.Fprintf(&sb, ", resolved by `%s`", rule.ResolvePattern)
+		}
+		if rule.DryRun {
+			sb.WriteString(" _(dry run)_")
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+func alertRulePermissionError() *APIError {
+	return &APIError{
+		ID:         "api.pagerduty.alert_rule.permission",
+		Message:    "Only plugin admins can manage alert rules",
+		StatusCode: http.StatusForbidden,
+	}
+}
+
+func alertRuleNotFoundError(ruleID string) *APIError {
+	return &APIError{
+		ID:         "api.pagerduty.alert_rule.not_found",
+		Message:    fmt.Sprintf("Alert rule %s was not found", ruleID),
+		StatusCode: http.StatusNotFound,
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
+	"github.com/svelle/mattermost-pagerduty-plugin/server/store/kvstore"
+)
+
+func TestValidateAlertRule(t *testing.T) {
+	valid := kvstore.AlertRule{
+		ChannelID:        "channel1",
+		ServiceID:        "PSVC123",
+		TriggerPattern:   `^(?P<severity>CRITICAL|WARNING) (?P<host>\S+): (?P<summary>.+)`,
+		ResolvePattern:   `^RECOVERY (?P<host>\S+)`,
+		DedupKeyTemplate: "nagios-{{host}}",
+		SeverityMap:      map[string]string{"warning": "warning"},
+	}
+
+	tests := []struct {
+		name       string
+		modify     func(rule *kvstore.AlertRule)
+		expectedID string
+	}{
+		{
+			name:   "valid",
+			modify: func(rule *kvstore.AlertRule) {},
+		},
+		{
+			name:       "missing trigger pattern",
+			modify:     func(rule *kvstore.AlertRule) { rule.TriggerPattern = "" },
+			expectedID: "api.pagerduty.alert_rule.fields.missing",
+		},
+		{
+			name:       "invalid pattern",
+			modify:     func(rule *kvstore.AlertRule) { rule.ResolvePattern = "(" },
+			expectedID: "api.pagerduty.alert_rule.pattern.invalid",
+		},
+		{
+			name:       "resolve pattern without dedup key",
+			modify:     func(rule *kvstore.AlertRule) { rule.DedupKeyTemplate = "" },
+			expectedID: "api.pagerduty.alert_rule.dedup_key_template.missing",
+		},
+		{
+			name:       "dedup key field not captured by the resolve pattern",
+			modify:     func(rule *kvstore.AlertRule) { rule.ResolvePattern = `^RECOVERY` },
+			expectedID: "api.pagerduty.alert_rule.dedup_key_template.invalid",
+		},
+		{
+			name:       "invalid mapped severity",
+			modify:     func(rule *kvstore.AlertRule) { rule.SeverityMap = map[string]string{"warning": "minor"} },
+			expectedID: "api.pagerduty.alert_rule.severity.invalid",
+		},
+		{
+			name:       "negative rate limit",
+			modify:     func(rule *kvstore.AlertRule) { rule.MaxEventsPerMinute = -1 },
+			expectedID: "api.pagerduty.alert_rule.rate_limit.invalid",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := valid
+			tt.modify(&rule)
+
+			apiErr := validateAlertRule(&rule)
+			if tt.expectedID == "" {
+				assert.Nil(t, apiErr)
+				return
+			}
+			require.NotNil(t, apiErr)
+			assert.Equal(t, tt.expectedID, apiErr.ID)
+		})
+	}
+}
+
+func TestMatchAlertRule(t *testing.T) {
+	rule := &kvstore.AlertRule{
+		TriggerPattern:   `^(?P<severity>CRITICAL|WARNING|UNKNOWN) (?P<host>\S+): (?P<summary>.+)`,
+		ResolvePattern:   `^RECOVERY (?P<host>\S+)`,
+		DedupKeyTemplate: "nagios-{{ host }}",
+		SeverityMap:      map[string]string{"unknown": "info"},
+		DefaultSeverity:  "error",
+	}
+
+	tests := []struct {
+		name     string
+		message  string
+		expected *alertRuleMatch
+	}{
+		{
+			name:    "trigger",
+			message: "CRITICAL db-1: disk full",
+			expected: &alertRuleMatch{
+				Action:   pagerduty.EventActionTrigger,
+				Summary:  "disk full",
+				Severity: "critical",
+				DedupKey: "nagios-db-1",
+				Fields:   map[string]string{"severity": "CRITICAL", "host": "db-1", "summary": "disk full"},
+			},
+		},
+		{
+			name:    "mapped severity",
+			message: "UNKNOWN db-1: check timed out",
+			expected: &alertRuleMatch{
+				Action:   pagerduty.EventActionTrigger,
+				Summary:  "check timed out",
+				Severity: "info",
+				DedupKey: "nagios-db-1",
+				Fields:   map[string]string{"severity": "UNKNOWN", "host": "db-1", "summary": "check timed out"},
+			},
+		},
+		{
+			name:    "resolve",
+			message: "RECOVERY db-1 disk ok",
+			expected: &alertRuleMatch{
+				Action:   pagerduty.EventActionResolve,
+				DedupKey: "nagios-db-1",
+				Fields:   map[string]string{"host": "db-1"},
+			},
+		},
+		{
+			name:     "no match",
+			message:  "deploying db-1",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, err := matchAlertRule(rule, tt.message)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, match)
+		})
+	}
+}
+
+func TestAlertRuleSeverity(t *testing.T) {
+	rule := &kvstore.AlertRule{SeverityMap: map[string]string{"p1": "critical"}}
+	assert.Equal(t, "critical", alertRuleSeverity(rule, "P1"))
+	assert.Equal(t, "warning", alertRuleSeverity(rule, "Warning"))
+	assert.Equal(t, "error", alertRuleSeverity(rule, "p5"))
+
+	rule.DefaultSeverity = "info"
+	assert.Equal(t, "info", alertRuleSeverity(rule, ""))
+}
+
+func TestExpandDedupKeyTemplate(t *testing.T) {
+	fields := map[string]string{"host": "db-1", "check": "disk"}
+	assert.Equal(t, "db-1/disk", expandDedupKeyTemplate("{{host}}/{{check}}", fields))
+	assert.Equal(t, "", expandDedupKeyTemplate("{{host}}/{{service}}", fields))
+	assert.Equal(t, "", expandDedupKeyTemplate("", fields))
+}
+
+func TestPlugin_updateAlertRules(t *testing.T) {
+	storedRules := func(t *testing.T, values map[string][]byte) []string {
+		var rules []*kvstore.AlertRule
+		require.NoError(t, json.Unmarshal(values["alert_rules"], &rules))
+		ids := make([]string, 0, len(rules))
+		for _, rule := range rules {
+			ids = append(ids, rule.ID)
+		}
+		return ids
+	}
+
+	t.Run("changes made meanwhile are kept", func(t *testing.T) {
+		plugin, _, values := setupHandlerTestPlugin(t)
+		values["alert_rules"] = []byte(`[{"id":"rule1"}]`)
+
+		attempts := 0
+		apiErr := plugin.updateAlertRules(func(rules []*kvstore.AlertRule) ([]*kvstore.AlertRule, *APIError) {
+			attempts++
+			if attempts == 1 {
+				// Another node adds a rule before this change is saved.
+				values["alert_rules"] = []byte(`[{"id":"rule1"},{"id":"rule2"}]`)
+			}
+			return append(rules, &kvstore.AlertRule{ID: "rule3"}), nil
+		})
+		require.Nil(t, apiErr)
+		assert.Equal(t, 2, attempts)
+		assert.Equal(t, []string{"rule1", "rule2", "rule3"}, storedRules(t, values))
+	})
+
+	t.Run("failed changes are not saved", func(t *testing.T) {
+		plugin, _, values := setupHandlerTestPlugin(t)
+		values["alert_rules"] = []byte(`[{"id":"rule1"}]`)
+
+		apiErr := plugin.updateAlertRules(func(rules []*kvstore.AlertRule) ([]*kvstore.AlertRule, *APIError) {
+			return nil, alertRuleNotFoundError("rule2")
+		})
+		require.NotNil(t, apiErr)
+		assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+		assert.Equal(t, `[{"id":"rule1"}]`, string(values["alert_rules"]))
+	})
+
+	t.Run("the first rule is saved", func(t *testing.T) {
+		plugin, _, values := setupHandlerTestPlugin(t)
+
+		apiErr := plugin.updateAlertRules(func(rules []*kvstore.AlertRule) ([]*kvstore.AlertRule, *APIError) {
+			assert.Empty(t, rules)
+			return append(rules, &kvstore.AlertRule{ID: "rule1"}), nil
+		})
+		require.Nil(t, apiErr)
+		assert.Equal(t, []string{"rule1"}, storedRules(t, values))
+	})
+
+	t.Run("dry run and deletion of rules", func(t *testing.T) {
+		plugin, _, values := setupHandlerTestPlugin(t)
+		values["alert_rules"] = []byte(`[{"id":"rule1"},{"id":"rule2"}]`)
+
+		_, apiErr := plugin.setAlertRuleDryRun("test-user-id", "rule1", true)
+		require.NotNil(t, apiErr)
+		assert.Equal(t, http.StatusForbidden, apiErr.StatusCode)
+
+		rule, apiErr := plugin.setAlertRuleDryRun("admin-user-id", "rule1", true)
+		require.Nil(t, apiErr)
+		assert.True(t, rule.DryRun)
+
+		_, apiErr = plugin.setAlertRuleDryRun("admin-user-id", "rule3", true)
+		require.NotNil(t, apiErr)
+		assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+
+		require.Nil(t, plugin.deleteAlertRule("admin-user-id", "rule2"))
+		apiErr = plugin.deleteAlertRule("admin-user-id", "rule2")
+		require.NotNil(t, apiErr)
+		assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+
+		rules, err := plugin.kvstore.GetAlertRules()
+		require.NoError(t, err)
+		require.Len(t, rules, 1)
+		assert.Equal(t, "rule1", rules[0].ID)
+		assert.True(t, rules[0].DryRun)
+	})
+}
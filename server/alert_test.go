@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
+)
+
+func TestGroupAlerts(t *testing.T) {
+	alerts := []pagerduty.Alert{
+		{ID: "A1", Summary: "Disk full", Status: pagerduty.AlertStatusResolved, Severity: "warning"},
+		{ID: "A2", Summary: "CPU high", Status: pagerduty.AlertStatusTriggered, Severity: "warning"},
+		{ID: "A3", Summary: "CPU high", Status: pagerduty.AlertStatusTriggered, Severity: "critical"},
+		{ID: "A4", Summary: "CPU high", Status: pagerduty.AlertStatusResolved, Severity: "info"},
+		{ID: "A5", Summary: "Disk full", Status: pagerduty.AlertStatusResolved},
+		{ID: "A6", Status: pagerduty.AlertStatusTriggered},
+	}
+
+	groups := groupAlerts("INC1", alerts)
+	assert.Equal(t, 6, groups.Total)
+	assert.Equal(t, 3, groups.Triggered)
+	require.Len(t, groups.Groups, 3)
+
+	assert.Equal(t, "CPU high", groups.Groups[0].Summary)
+	assert.Equal(t, "critical", groups.Groups[0].Severity)
+	assert.Equal(t, 2, groups.Groups[0].Triggered)
+	assert.Equal(t, 1, groups.Groups[0].Resolved)
+
+	assert.Equal(t, "A6", groups.Groups[1].Summary)
+	assert.Equal(t, "Disk full", groups.Groups[2].Summary)
+	assert.Equal(t, 2, groups.Groups[2].Resolved)
+}
+
+func TestFormatAlertGroups(t *testing.T) {
+	t.Run("no alerts", func(t *testing.T) {
+		assert.Equal(t, "Incident `INC1` has no alerts.", formatAlertGroups(groupAlerts("INC1", nil)))
+	})
+
+	t.Run("grouped", func(t *testing.T) {
+		alerts := []pagerduty.Alert{
+			{ID: "A1", Summary: "CPU high", Status: pagerduty.AlertStatusTriggered, Severity: "critical", Body: &pagerduty.AlertBody{
+				Details: map[string]interface{}{"host": "db-1", "load": 12.5, "tags": []interface{}{"prod"}},
+			}},
+			{ID: "A2", Summary: "Disk full", Status: pagerduty.AlertStatusResolved},
+		}
+
+		assert.Equal(t, "###### Alerts of incident `INC1`\n"+
+			"2 alerts in 2 groups, 1 triggered\n"+
+			"* :red_circle: **CPU high** ×1 (critical) - 1 triggered, 0 resolved\n"+
+			"  * `A1` host=db-1 load=12.5\n"+
+			"* :large_green_circle: **Disk full** ×1 - 0 triggered, 1 resolved\n"+
+			"\nResolve an alert with `/pagerduty incident alert resolve <incident-id> <alert-id>`, or move it with `/pagerduty incident alert move <incident-id> <alert-id> <to-incident-id>`.",
+			formatAlertGroups(groupAlerts("INC1", alerts)))
+	})
+}
+
+func TestFormatAlertUpdate(t *testing.T) {
+	alert := &pagerduty.Alert{ID: "A1", Summary: "CPU high"}
+	assert.Equal(t, ":white_check_mark: @alice resolved alert `A1` CPU high.", formatAlertUpdate("alice", alert, ""))
+	assert.Equal(t, ":arrow_right: @alice moved alert `A1` CPU high to incident `INC2`.", formatAlertUpdate("alice", alert, "INC2"))
+}
@@ -12,17 +12,93 @@ import (
 func (p *Plugin) ServeHTTP(c *plugin.Context, w http.ResponseWriter, r *http.Request) {
 	router := mux.NewRouter()
 
-	// Middleware to require that the user is logged in
-	router.Use(p.MattermostAuthorizationRequired)
+	// PagerDuty webhooks are authenticated by their signature
+	router.HandleFunc("/webhook", p.handleWebhook).Methods(http.MethodPost)
 
 	apiRouter := router.PathPrefix("/api/v1").Subrouter()
 
+	// Middleware to require that the user is logged in and to limit how often they call each
+	// endpoint. Each route further requires permission for the operation it performs.
+	apiRouter.Use(p.MattermostAuthorizationRequired)
+	apiRouter.Use(p.RateLimitRequests)
+
 	// PagerDuty endpoints
-	apiRouter.HandleFunc("/schedules", p.handleGetSchedules).Methods(http.MethodGet)
-	apiRouter.HandleFunc("/oncalls", p.handleGetOnCalls).Methods(http.MethodGet)
-	apiRouter.HandleFunc("/schedule", p.handleGetScheduleDetails).Methods(http.MethodGet)
-	apiRouter.HandleFunc("/services", p.handleGetServices).Methods(http.MethodGet)
-	apiRouter.HandleFunc("/incidents", p.handleCreateIncident).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/schedules", p.requirePermission(permissionRead, p.handleGetSchedules)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/oncalls", p.requirePermission(permissionRead, p.handleGetOnCalls)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/schedule", p.requirePermission(permissionRead, p.handleGetScheduleDetails)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/services", p.requirePermission(permissionRead, p.handleGetServices)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/services/{id}", p.requirePermission(permissionRead, p.handleGetService)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/services/{id}/paging_policy", p.requirePermission(permissionRead, p.handleGetPagingPolicy)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/services/{id}/paging_policy", p.requirePermission(permissionAdmin, p.handleSavePagingPolicy)).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/services/{id}/paging_policy", p.requirePermission(permissionAdmin, p.handleDeletePagingPolicy)).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/paging_policies", p.requirePermission(permissionAdmin, p.handleGetPagingPolicies)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/escalation_policies", p.requirePermission(permissionRead, p.handleGetEscalationPolicies)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/escalation_policies/{id}", p.requirePermission(permissionRead, p.handleGetEscalationPolicy)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/incidents", p.requirePermission(permissionRead, p.handleGetIncidents)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/incidents", p.requirePermission(permissionPage, p.handleCreateIncident)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/incidents/{id}/responder_requests", p.requirePermission(permissionPage, p.handleAddResponders)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/incidents/{id}/workflows", p.requirePermission(permissionRespond, p.handleStartIncidentWorkflow)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/incidents/{id}/alerts", p.requirePermission(permissionRead, p.handleGetIncidentAlerts)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/incidents/{id}/alerts/{alert_id}", p.requirePermission(permissionRespond, p.handleUpdateAlert)).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/incidents/{id}/merge", p.requirePermission(permissionRespond, p.handleMergeIncidents)).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/incidents/{id}/related", p.requirePermission(permissionRead, p.handleGetRelatedIncidents)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/incidents/{id}/status_updates", p.requirePermission(permissionRead, p.handleGetStatusUpdates)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/incidents/{id}/status_updates", p.requirePermission(permissionRespond, p.handleCreateStatusUpdate)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/incidents/{id}/subscriptions", p.requirePermission(permissionRespond, p.handleSubscribeIncident)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/incidents/{id}/subscriptions/{channel_id}", p.requirePermission(permissionRespond, p.handleUnsubscribeIncident)).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/incident_workflows", p.requirePermission(permissionRead, p.handleGetIncidentWorkflows)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/maintenance_windows", p.requirePermission(permissionRead, p.handleGetMaintenanceWindows)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/maintenance_windows", p.requirePermission(permissionRespond, p.handleCreateMaintenanceWindow)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/maintenance_windows/{id}", p.requirePermission(permissionRespond, p.handleDeleteMaintenanceWindow)).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/teams", p.requirePermission(permissionRead, p.handleGetTeams)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/teams/{id}/members", p.requirePermission(permissionRead, p.handleGetTeamMembers)).Methods(http.MethodGet)
+
+	// Interactive dialog submissions
+	apiRouter.HandleFunc("/dialogs/status_update", p.requirePermission(permissionRespond, p.handleStatusUpdateDialog)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/dialogs/merge", p.requirePermission(permissionRespond, p.handleMergeDialog)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/dialogs/workflow", p.requirePermission(permissionRespond, p.handleIncidentWorkflowDialog)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/dialogs/responders", p.requirePermission(permissionPage, p.handleResponderDialog)).Methods(http.MethodPost)
+
+	// Interactive message button clicks
+	apiRouter.HandleFunc("/actions/workflow", p.requirePermission(permissionRespond, p.handleIncidentWorkflowAction)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/actions/responders", p.requirePermission(permissionPage, p.handleResponderAction)).Methods(http.MethodPost)
+
+	// Scheduled roster endpoints
+	apiRouter.HandleFunc("/rosters", p.requirePermission(permissionRead, p.handleGetRosters)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/rosters", p.requirePermission(permissionRespond, p.handleCreateRoster)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/rosters/{id}", p.requirePermission(permissionRespond, p.handleDeleteRoster)).Methods(http.MethodDelete)
+
+	// On-call group sync endpoints
+	apiRouter.HandleFunc("/group_syncs", p.requirePermission(permissionAdmin, p.handleGetGroupSyncs)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/group_syncs", p.requirePermission(permissionAdmin, p.handleCreateGroupSync)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/group_syncs/{id}", p.requirePermission(permissionAdmin, p.handleDeleteGroupSync)).Methods(http.MethodDelete)
+
+	// Change event endpoints
+	apiRouter.HandleFunc("/change_events", p.requirePermission(permissionRespond, p.handleSendChangeEvent)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/change_event_rules", p.requirePermission(permissionRead, p.handleGetChangeEventRules)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/change_event_rules", p.requirePermission(permissionRespond, p.handleCreateChangeEventRule)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/change_event_rules/{id}", p.requirePermission(permissionRespond, p.handleDeleteChangeEventRule)).Methods(http.MethodDelete)
+
+	// Alert rule endpoints
+	apiRouter.HandleFunc("/alert_rules", p.requirePermission(permissionAdmin, p.handleGetAlertRules)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/alert_rules", p.requirePermission(permissionAdmin, p.handleCreateAlertRule)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/alert_rules/{id}", p.requirePermission(permissionAdmin, p.handleUpdateAlertRule)).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/alert_rules/{id}", p.requirePermission(permissionAdmin, p.handleDeleteAlertRule)).Methods(http.MethodDelete)
+
+	// Business service impact endpoints
+	apiRouter.HandleFunc("/business_services", p.requirePermission(permissionRead, p.handleGetBusinessServices)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/status_dashboards", p.requirePermission(permissionRead, p.handleGetStatusDashboards)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/impacts", p.requirePermission(permissionRead, p.handleGetImpacts)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/impacts", p.requirePermission(permissionRespond, p.handlePostImpact)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/impact_posts", p.requirePermission(permissionRead, p.handleGetImpactPosts)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/impact_posts", p.requirePermission(permissionRespond, p.handleCreateImpactPost)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/impact_posts/{id}", p.requirePermission(permissionRespond, p.handleDeleteImpactPost)).Methods(http.MethodDelete)
+
+	// Audit trail endpoints
+	apiRouter.HandleFunc("/audit", p.requirePermission(permissionAdmin, p.handleGetAuditRecords)).Methods(http.MethodGet)
+
+	// Admin endpoints
+	apiRouter.HandleFunc("/admin/status", p.requirePermission(permissionAdmin, p.handleGetPluginStatus)).Methods(http.MethodGet)
 
 	router.ServeHTTP(w, r)
 }
@@ -0,0 +1,15 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+func (p *Plugin) handleGetPluginStatus(w http.ResponseWriter, r *http.Request) {
+	p.client.Log.Debug("handleGetPluginStatus called", "user_id", r.Header.Get("Mattermost-User-ID"))
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(p.getPluginStatus()); err != nil {
+		p.client.Log.Error("Failed to encode plugin status response", "error", err.Error())
+	}
+}
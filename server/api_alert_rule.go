@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func (p *Plugin) handleGetAlertRules(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	p.client.Log.Debug("handleGetAlertRules called", "user_id", userID)
+
+	rules, apiErr := p.getAlertRules(userID)
+	if apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(rules); err != nil {
+		p.client.Log.Error("Failed to encode alert rules response", "error", err.Error())
+	}
+}
+
+func (p *Plugin) handleCreateAlertRule(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	p.client.Log.Debug("handleCreateAlertRule called", "user_id", userID)
+
+	config := p.getConfiguration()
+	if err := config.IsValid(); err != nil {
+		p.client.Log.Warn("Plugin configuration invalid", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.config.invalid",
+			Message:    "Plugin not configured",
+			StatusCode: http.StatusNotImplemented,
+		})
+		return
+	}
+
+	var req SaveAlertRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		p.client.Log.Warn("Failed to decode create alert rule request", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.alert_rule.decode.error",
+			Message:    "Invalid request body",
+			StatusCode: http.StatusBadRequest,
+		})
+		return
+	}
+
+	rule, apiErr := p.createAlertRule(userID, requestAccount(r), &req)
+	if apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	p.client.Log.Info("Successfully created alert rule", "rule_id", rule.ID, "channel_id", rule.ChannelID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(rule); err != nil {
+		p.client.Log.Error("Failed to encode create alert rule response", "error", err.Error())
+	}
+}
+
+func (p *Plugin) handleUpdateAlertRule(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	ruleID := mux.Vars(r)["id"]
+	p.client.Log.Debug("handleUpdateAlertRule called", "user_id", userID, "rule_id", ruleID)
+
+	config := p.getConfiguration()
+	if err := config.IsValid(); err != nil {
+		p.client.Log.Warn("Plugin configuration invalid", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.config.invalid",
+			Message:    "Plugin not configured",
+			StatusCode: http.StatusNotImplemented,
+		})
+		return
+	}
+
+	var req SaveAlertRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		p.client.Log.Warn("Failed to decode update alert rule request", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.alert_rule.decode.error",
+			Message:    "Invalid request body",
+			StatusCode: http.StatusBadRequest,
+		})
+		return
+	}
+
+	rule, apiErr := p.updateAlertRule(userID, ruleID, &req)
+	if apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	p.client.Log.Info("Successfully updated alert rule", "rule_id", rule.ID)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(rule); err != nil {
+		p.client.Log.Error("Failed to encode update alert rule response", "error", err.Error())
+	}
+}
+
+func (p *Plugin) handleDeleteAlertRule(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	ruleID := mux.Vars(r)["id"]
+	p.client.Log.Debug("handleDeleteAlertRule called", "user_id", userID, "rule_id", ruleID)
+
+	if apiErr := p.deleteAlertRule(userID, ruleID); apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	p.client.Log.Info("Successfully deleted alert rule", "rule_id", ruleID)
+	w.WriteHeader(http.StatusNoContent)
+}
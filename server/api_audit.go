@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+func (p *Plugin) handleGetAuditRecords(w http.ResponseWriter, r *http.Request) {
+	p.client.Log.Debug("handleGetAuditRecords called", "user_id", r.Header.Get("Mattermost-User-ID"))
+
+	query, apiErr := parseAuditQuery(r.URL.Query())
+	if apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.audit.format.invalid",
+			Message:    "format must be json or csv",
+			StatusCode: http.StatusBadRequest,
+		})
+		return
+	}
+
+	records, apiErr := p.getAuditRecords(query)
+	if apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="pagerduty-audit.csv"`)
+		if err := writeAuditCSV(w, records); err != nil {
+			p.client.Log.Error("Failed to write audit records CSV", "error", err.Error())
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(records); err != nil {
+		p.client.Log.Error("Failed to encode audit records response", "error", err.Error())
+	}
+}
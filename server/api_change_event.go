@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/store/kvstore"
+)
+
+func (p *Plugin) handleSendChangeEvent(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	p.client.Log.Debug("handleSendChangeEvent called", "user_id", userID)
+
+	config := p.getConfiguration()
+	if err := config.IsValid(); err != nil {
+		p.client.Log.Warn("Plugin configuration invalid", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.config.invalid",
+			Message:    "Plugin not configured",
+			StatusCode: http.StatusNotImplemented,
+		})
+		return
+	}
+
+	var req SendChangeEventRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		p.client.Log.Warn("Failed to decode change event request", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.change_event.decode.error",
+			Message:    "Invalid request body",
+			StatusCode: http.StatusBadRequest,
+		})
+		return
+	}
+
+	response, apiErr := p.sendChangeEvent(userID, requestAccount(r), &req)
+	if apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	p.client.Log.Info("Successfully sent change event", "service_id", req.ServiceID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		p.client.Log.Error("Failed to encode change event response", "error", err.Error())
+	}
+}
+
+func (p *Plugin) handleGetChangeEventRules(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	p.client.Log.Debug("handleGetChangeEventRules called", "user_id", userID)
+
+	channelID := r.URL.Query().Get("channel_id")
+	if channelID == "" {
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.change_event_rule.channel.missing",
+			Message:    "Channel ID is required",
+			StatusCode: http.StatusBadRequest,
+		})
+		return
+	}
+
+	if !p.client.User.HasPermissionToChannel(userID, channelID, model.PermissionCreatePost) {
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.change_event_rule.permission",
+			Message:    "You do not have permission to post in this channel",
+			StatusCode: http.StatusForbidden,
+		})
+		return
+	}
+
+	rules, err := p.kvstore.GetChangeEventRules(channelID)
+	if err != nil {
+		p.client.Log.Error("Failed to get change event rules", "error", err.Error(), "channel_id", channelID)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.change_event_rule.get.error",
+			Message:    "Failed to retrieve change event rules",
+			StatusCode: http.StatusInternalServerError,
+		})
+		return
+	}
+	if rules == nil {
+		rules = []*kvstore.ChangeEventRule{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(rules); err != nil {
+		p.client.Log.Error("Failed to encode change event rules response", "error", err.Error())
+	}
+}
+
+func (p *Plugin) handleCreateChangeEventRule(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	p.client.Log.Debug("handleCreateChangeEventRule called", "user_id", userID)
+
+	config := p.getConfiguration()
+	if err := config.IsValid(); err != nil {
+		p.client.Log.Warn("Plugin configuration invalid", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.config.invalid",
+			Message:    "Plugin not configured",
+			StatusCode: http.StatusNotImplemented,
+		})
+		return
+	}
+
+	var req CreateChangeEventRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		p.client.Log.Warn("Failed to decode create change event rule request", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.change_event_rule.decode.error",
+			Message:    "Invalid request body",
+			StatusCode: http.StatusBadRequest,
+		})
+		return
+	}
+
+	rule, apiErr := p.createChangeEventRule(userID, requestAccount(r), &req)
+	if apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	p.client.Log.Info("Successfully created change event rule", "rule_id", rule.ID, "channel_id", rule.ChannelID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(rule); err != nil {
+		p.client.Log.Error("Failed to encode create change event rule response", "error", err.Error())
+	}
+}
+
+func (p *Plugin) handleDeleteChangeEventRule(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	ruleID := mux.Vars(r)["id"]
+	p.client.Log.Debug("handleDeleteChangeEventRule called", "user_id", userID, "rule_id", ruleID)
+
+	channelID := r.URL.Query().Get("channel_id")
+	if channelID == "" {
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.change_event_rule.channel.missing",
+			Message:    "Channel ID is required",
+			StatusCode: http.StatusBadRequest,
+		})
+		return
+	}
+
+	if apiErr := p.deleteChangeEventRule(userID, channelID, ruleID); apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	p.client.Log.Info("Successfully deleted change event rule", "rule_id", ruleID, "channel_id", channelID)
+	w.WriteHeader(http.StatusNoContent)
+}
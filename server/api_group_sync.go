@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func (p *Plugin) handleGetGroupSyncs(w http.ResponseWriter, r *http.Request) {
+	p.client.Log.Debug("handleGetGroupSyncs called", "user_id", r.Header.Get("Mattermost-User-ID"))
+
+	groupSyncs, err := p.kvstore.ListGroupSyncs()
+	if err != nil {
+		p.client.Log.Error("Failed to list group syncs", "error", err.Error())
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.group_sync.list.error",
+			Message:    "Failed to retrieve on-call groups",
+			StatusCode: http.StatusInternalServerError,
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(groupSyncs); err != nil {
+		p.client.Log.Error("Failed to encode group syncs response", "error", err.Error())
+	}
+}
+
+func (p *Plugin) handleCreateGroupSync(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	p.client.Log.Debug("handleCreateGroupSync called", "user_id", userID)
+
+	var req CreateGroupSyncRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		p.client.Log.Warn("Failed to decode create group sync request", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.group_sync.decode.error",
+			Message:    "Invalid request body",
+			StatusCode: http.StatusBadRequest,
+		})
+		return
+	}
+
+	groupSync, apiErr := p.createGroupSync(userID, requestAccount(r), &req)
+	if apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	p.client.Log.Info("Successfully created group sync", "group_sync_id", groupSync.ID, "group", groupSync.GroupName)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(groupSync); err != nil {
+		p.client.Log.Error("Failed to encode create group sync response", "error", err.Error())
+	}
+}
+
+func (p *Plugin) handleDeleteGroupSync(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	groupSyncID := mux.Vars(r)["id"]
+	p.client.Log.Debug("handleDeleteGroupSync called", "user_id", userID, "group_sync_id", groupSyncID)
+
+	if _, apiErr := p.deleteGroupSync(userID, groupSyncID); apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
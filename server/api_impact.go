@@ -0,0 +1,285 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/mux"
+)
+
+func (p *Plugin) handleGetBusinessServices(w http.ResponseWriter, r *http.Request) {
+	p.client.Log.Debug("handleGetBusinessServices called", "user_id", r.Header.Get("Mattermost-User-ID"))
+
+	config := p.getConfiguration()
+	if err := config.IsValid(); err != nil {
+		p.client.Log.Warn("Plugin configuration invalid", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.config.invalid",
+			Message:    "Plugin not configured",
+			StatusCode: http.StatusNotImplemented,
+		})
+		return
+	}
+
+	client, apiErr := p.accountClient(requestAccount(r))
+	if apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	params := url.Values{}
+	params.Set("limit", "100")
+	businessServices, err := client.ListBusinessServices(params)
+	if err != nil {
+		p.client.Log.Error("Failed to get business services from PagerDuty", "error", err.Error())
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.business_services.error",
+			Message:    "Failed to retrieve business services",
+			StatusCode: http.StatusInternalServerError,
+		})
+		return
+	}
+
+	p.client.Log.Info("Successfully retrieved business services", "count", len(businessServices.BusinessServices))
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(businessServices); err != nil {
+		p.client.Log.Error("Failed to encode business services response", "error", err.Error())
+	}
+}
+
+func (p *Plugin) handleGetStatusDashboards(w http.ResponseWriter, r *http.Request) {
+	p.client.Log.Debug("handleGetStatusDashboards called", "user_id", r.Header.Get("Mattermost-User-ID"))
+
+	config := p.getConfiguration()
+	if err := config.IsValid(); err != nil {
+		p.client.Log.Warn("Plugin configuration invalid", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.config.invalid",
+			Message:    "Plugin not configured",
+			StatusCode: http.StatusNotImplemented,
+		})
+		return
+	}
+
+	client, apiErr := p.accountClient(requestAccount(r))
+	if apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	dashboards, err := client.ListStatusDashboards()
+	if err != nil {
+		p.client.Log.Error("Failed to get status dashboards from PagerDuty", "error", err.Error())
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.status_dashboards.error",
+			Message:    "Failed to retrieve status dashboards",
+			StatusCode: http.StatusInternalServerError,
+		})
+		return
+	}
+
+	p.client.Log.Info("Successfully retrieved status dashboards", "count", len(dashboards.StatusDashboards))
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(dashboards); err != nil {
+		p.client.Log.Error("Failed to encode status dashboards response", "error", err.Error())
+	}
+}
+
+func (p *Plugin) handleGetImpacts(w http.ResponseWriter, r *http.Request) {
+	p.client.Log.Debug("handleGetImpacts called", "user_id", r.Header.Get("Mattermost-User-ID"))
+
+	config := p.getConfiguration()
+	if err := config.IsValid(); err != nil {
+		p.client.Log.Warn("Plugin configuration invalid", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.config.invalid",
+			Message:    "Plugin not configured",
+			StatusCode: http.StatusNotImplemented,
+		})
+		return
+	}
+
+	targets := ImpactTargets{
+		BusinessServiceIDs: parseIDList(r.URL.Query()["business_service_ids"]),
+		StatusDashboardID:  r.URL.Query().Get("status_dashboard_id"),
+	}
+	if apiErr := validateImpactTargets(targets); apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	client, apiErr := p.accountClient(requestAccount(r))
+	if apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	report, err := p.getImpactReport(client, targets)
+	if err != nil {
+		p.client.Log.Error("Failed to get impacts from PagerDuty", "error", err.Error())
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.impact.error",
+			Message:    "Failed to retrieve the service status",
+			StatusCode: http.StatusInternalServerError,
+		})
+		return
+	}
+
+	p.client.Log.Info("Successfully retrieved impacts", "count", len(report.Impacts), "impacted", report.Impacted)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		p.client.Log.Error("Failed to encode impacts response", "error", err.Error())
+	}
+}
+
+func (p *Plugin) handlePostImpact(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	p.client.Log.Debug("handlePostImpact called", "user_id", userID)
+
+	config := p.getConfiguration()
+	if err := config.IsValid(); err != nil {
+		p.client.Log.Warn("Plugin configuration invalid", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.config.invalid",
+			Message:    "Plugin not configured",
+			StatusCode: http.StatusNotImplemented,
+		})
+		return
+	}
+
+	var req PostImpactRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		p.client.Log.Warn("Failed to decode post impact request", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.impact.decode.error",
+			Message:    "Invalid request body",
+			StatusCode: http.StatusBadRequest,
+		})
+		return
+	}
+
+	if apiErr := p.postImpact(userID, requestAccount(r), &req); apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	p.client.Log.Info("Successfully posted impact report", "channel_id", req.ChannelID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (p *Plugin) handleGetImpactPosts(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	p.client.Log.Debug("handleGetImpactPosts called", "user_id", userID)
+
+	channelID := r.URL.Query().Get("channel_id")
+	if channelID == "" {
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.impact.channel.missing",
+			Message:    "Channel ID is required",
+			StatusCode: http.StatusBadRequest,
+		})
+		return
+	}
+
+	if !p.canManageRosters(userID, channelID) {
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.impact.permission",
+			Message:    "You do not have permission to post in this channel",
+			StatusCode: http.StatusForbidden,
+		})
+		return
+	}
+
+	posts, err := p.getChannelImpactPosts(channelID)
+	if err != nil {
+		p.client.Log.Error("Failed to list impact posts", "error", err.Error())
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.impact.list.error",
+			Message:    "Failed to retrieve scheduled service status posts",
+			StatusCode: http.StatusInternalServerError,
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(posts); err != nil {
+		p.client.Log.Error("Failed to encode impact posts response", "error", err.Error())
+	}
+}
+
+func (p *Plugin) handleCreateImpactPost(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	p.client.Log.Debug("handleCreateImpactPost called", "user_id", userID)
+
+	var req CreateImpactPostRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		p.client.Log.Warn("Failed to decode create impact post request", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.impact.decode.error",
+			Message:    "Invalid request body",
+			StatusCode: http.StatusBadRequest,
+		})
+		return
+	}
+
+	post, apiErr := p.createImpactPost(userID, requestAccount(r), &req)
+	if apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	p.client.Log.Info("Successfully created impact post", "impact_post_id", post.ID, "channel_id", post.ChannelID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(post); err != nil {
+		p.client.Log.Error("Failed to encode create impact post response", "error", err.Error())
+	}
+}
+
+func (p *Plugin) handleDeleteImpactPost(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	postID := mux.Vars(r)["id"]
+	p.client.Log.Debug("handleDeleteImpactPost called", "user_id", userID, "impact_post_id", postID)
+
+	post, err := p.kvstore.GetImpactPost(postID)
+	if err != nil {
+		p.client.Log.Error("Failed to get impact post", "error", err.Error(), "impact_post_id", postID)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.impact.get.error",
+			Message:    "Failed to retrieve scheduled service status post",
+			StatusCode: http.StatusInternalServerError,
+		})
+		return
+	}
+	if post == nil {
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.impact.not_found",
+			Message:    "Scheduled service status post not found",
+			StatusCode: http.StatusNotFound,
+		})
+		return
+	}
+
+	if !p.canManageRosters(userID, post.ChannelID) {
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.impact.permission",
+			Message:    "You do not have permission to post in this channel",
+			StatusCode: http.StatusForbidden,
+		})
+		return
+	}
+
+	if err := p.kvstore.DeleteImpactPost(postID); err != nil {
+		p.client.Log.Error("Failed to delete impact post", "error", err.Error(), "impact_post_id", postID)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.impact.delete.error",
+			Message:    "Failed to delete scheduled service status post",
+			StatusCode: http.StatusInternalServerError,
+		})
+		return
+	}
+
+	p.client.Log.Info("Successfully deleted impact post", "impact_post_id", postID)
+	w.WriteHeader(http.StatusNoContent)
+}
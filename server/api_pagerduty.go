@@ -2,9 +2,14 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
+	"github.com/gorilla/mux"
+
 	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
 )
 
@@ -22,12 +27,39 @@ func (p *Plugin) handleGetSchedules(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	client := p.createPagerDutyClient(config.APIToken, config.APIBaseURL)
-	p.client.Log.Debug("Fetching schedules from PagerDuty API", "base_url", config.APIBaseURL)
+	accounts, apiErr := p.requestAccounts(r)
+	if apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
 
-	schedules, err := client.GetSchedules(100, 0)
-	if err != nil {
-		p.client.Log.Error("Failed to get schedules from PagerDuty", "error", err.Error())
+	// Without an account parameter, the schedules of every account are merged. An account that
+	// fails is reported along with the schedules of the others, unless every account fails.
+	schedules := &pagerduty.SchedulesResponse{Schedules: []pagerduty.Schedule{}}
+	for _, account := range accounts {
+		client := p.createPagerDutyClient(account.APIToken, account.APIBaseURL)
+		teamIDs := p.getRequestTeamIDs(r, account.Name)
+		p.client.Log.Debug("Fetching schedules from PagerDuty API", "account", displayAccountName(account.Name), "base_url", account.APIBaseURL, "team_ids", teamIDs)
+
+		accountSchedules, err := client.GetSchedulesForTeams(100, 0, teamIDs)
+		if err != nil {
+			p.client.Log.Error("Failed to get schedules from PagerDuty", "error", err.Error(), "account", displayAccountName(account.Name))
+			schedules.AccountErrors = append(schedules.AccountErrors, pagerduty.AccountError{
+				Account: displayAccountName(account.Name),
+				Message: "Failed to retrieve schedules",
+			})
+			continue
+		}
+
+		for i := range accountSchedules.Schedules {
+			accountSchedules.Schedules[i].Account = displayAccountName(account.Name)
+		}
+		schedules.Schedules = append(schedules.Schedules, accountSchedules.Schedules...)
+		schedules.Limit = accountSchedules.Limit
+		schedules.Total += accountSchedules.Total
+		schedules.More = schedules.More || accountSchedules.More
+	}
+	if len(schedules.AccountErrors) == len(accounts) {
 		p.handleError(w, r, &APIError{
 			ID:         "api.pagerduty.schedules.error",
 			Message:    "Failed to retrieve schedules",
@@ -57,22 +89,56 @@ func (p *Plugin) handleGetOnCalls(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	client := p.createPagerDutyClient(config.APIToken, config.APIBaseURL)
+	accounts, apiErr := p.requestAccounts(r)
+	if apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
 
+	// A schedule belongs to a single account, so it is looked up in the default account unless
+	// another one is named.
 	scheduleID := r.URL.Query().Get("schedule_id")
-	var oncalls *pagerduty.OnCallsResponse
-	var err error
-
 	if scheduleID != "" {
-		p.client.Log.Debug("Fetching on-calls for specific schedule", "schedule_id", scheduleID)
-		oncalls, err = client.GetOnCallsForSchedule(scheduleID)
-	} else {
-		p.client.Log.Debug("Fetching current on-calls for all schedules")
-		oncalls, err = client.GetCurrentOnCalls()
+		accounts = accounts[:1]
 	}
 
-	if err != nil {
-		p.client.Log.Error("Failed to get on-calls from PagerDuty", "error", err.Error(), "schedule_id", scheduleID)
+	// Without an account parameter, the on-calls of every account are merged. An account that
+	// fails is reported along with the on-calls of the others, unless every account fails.
+	oncalls := &pagerduty.OnCallsResponse{OnCalls: []pagerduty.OnCall{}}
+	for _, account := range accounts {
+		client := p.createPagerDutyClient(account.APIToken, account.APIBaseURL)
+
+		var accountOnCalls *pagerduty.OnCallsResponse
+		var err error
+		if scheduleID != "" {
+			p.client.Log.Debug("Fetching on-calls for specific schedule", "schedule_id", scheduleID, "account", displayAccountName(account.Name))
+			accountOnCalls, err = client.GetOnCallsForSchedule(scheduleID)
+		} else if teamIDs := p.getRequestTeamIDs(r, account.Name); len(teamIDs) > 0 {
+			p.client.Log.Debug("Fetching current on-calls for teams", "team_ids", teamIDs, "account", displayAccountName(account.Name))
+			accountOnCalls, err = p.getOnCallsForTeams(client, teamIDs)
+		} else {
+			p.client.Log.Debug("Fetching current on-calls for all schedules", "account", displayAccountName(account.Name))
+			accountOnCalls, err = client.GetCurrentOnCalls()
+		}
+
+		if err != nil {
+			p.client.Log.Error("Failed to get on-calls from PagerDuty", "error", err.Error(), "schedule_id", scheduleID, "account", displayAccountName(account.Name))
+			oncalls.AccountErrors = append(oncalls.AccountErrors, pagerduty.AccountError{
+				Account: displayAccountName(account.Name),
+				Message: "Failed to retrieve on-call users",
+			})
+			continue
+		}
+
+		for i := range accountOnCalls.OnCalls {
+			accountOnCalls.OnCalls[i].Account = displayAccountName(account.Name)
+		}
+		oncalls.OnCalls = append(oncalls.OnCalls, accountOnCalls.OnCalls...)
+		oncalls.Limit = accountOnCalls.Limit
+		oncalls.Total += accountOnCalls.Total
+		oncalls.More = oncalls.More || accountOnCalls.More
+	}
+	if len(oncalls.AccountErrors) == len(accounts) {
 		p.handleError(w, r, &APIError{
 			ID:         "api.pagerduty.oncalls.error",
 			Message:    "Failed to retrieve on-call users",
@@ -113,7 +179,12 @@ func (p *Plugin) handleGetScheduleDetails(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	client := p.createPagerDutyClient(config.APIToken, config.APIBaseURL)
+	account := requestAccount(r)
+	client, apiErr := p.accountClient(account)
+	if apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
 
 	// Get schedule with the next 48 hours of coverage
 	now := time.Now()
@@ -152,10 +223,16 @@ func (p *Plugin) handleGetServices(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	client := p.createPagerDutyClient(config.APIToken, config.APIBaseURL)
-	p.client.Log.Debug("Fetching services from PagerDuty API", "base_url", config.APIBaseURL)
+	account := requestAccount(r)
+	client, apiErr := p.accountClient(account)
+	if apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+	teamIDs := p.getRequestTeamIDs(r, account)
+	p.client.Log.Debug("Fetching services from PagerDuty API", "account", displayAccountName(account), "team_ids", teamIDs)
 
-	services, err := client.GetServices(100, 0)
+	services, err := client.GetServicesForTeams(100, 0, teamIDs)
 	if err != nil {
 		p.client.Log.Error("Failed to get services from PagerDuty", "error", err.Error())
 		p.handleError(w, r, &APIError{
@@ -173,16 +250,321 @@ func (p *Plugin) handleGetServices(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (p *Plugin) handleGetService(w http.ResponseWriter, r *http.Request) {
+	p.client.Log.Debug("handleGetService called", "user_id", r.Header.Get("Mattermost-User-ID"))
+
+	config := p.getConfiguration()
+	if err := config.IsValid(); err != nil {
+		p.client.Log.Warn("Plugin configuration invalid", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.config.invalid",
+			Message:    "Plugin not configured",
+			StatusCode: http.StatusNotImplemented,
+		})
+		return
+	}
+
+	serviceID := mux.Vars(r)["id"]
+	account := requestAccount(r)
+	client, apiErr := p.accountClient(account)
+	if apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	detail, err := p.getServiceDetail(client, serviceID)
+	if err != nil {
+		p.client.Log.Error("Failed to get service details from PagerDuty", "error", err.Error(), "service_id", serviceID)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.service.error",
+			Message:    "Failed to retrieve service details",
+			StatusCode: http.StatusInternalServerError,
+		})
+		return
+	}
+
+	p.client.Log.Info("Successfully retrieved service details", "service_id", serviceID, "name", detail.Service.Name)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(detail); err != nil {
+		p.client.Log.Error("Failed to encode service response", "error", err.Error())
+	}
+}
+
+func (p *Plugin) handleGetEscalationPolicies(w http.ResponseWriter, r *http.Request) {
+	p.client.Log.Debug("handleGetEscalationPolicies called", "user_id", r.Header.Get("Mattermost-User-ID"))
+
+	config := p.getConfiguration()
+	if err := config.IsValid(); err != nil {
+		p.client.Log.Warn("Plugin configuration invalid", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.config.invalid",
+			Message:    "Plugin not configured",
+			StatusCode: http.StatusNotImplemented,
+		})
+		return
+	}
+
+	account := requestAccount(r)
+	client, apiErr := p.accountClient(account)
+	if apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	params := url.Values{}
+	params.Set("limit", "100")
+	if query := r.URL.Query().Get("query"); query != "" {
+		params.Set("query", query)
+	}
+	for _, teamID := range p.getRequestTeamIDs(r, account) {
+		params.Add("team_ids[]", teamID)
+	}
+
+	policies, err := client.ListEscalationPolicies(params)
+	if err != nil {
+		p.client.Log.Error("Failed to get escalation policies from PagerDuty", "error", err.Error())
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.escalation_policies.error",
+			Message:    "Failed to retrieve escalation policies",
+			StatusCode: http.StatusInternalServerError,
+		})
+		return
+	}
+
+	chains, err := p.resolveEscalationChains(client, policies.EscalationPolicies)
+	if err != nil {
+		p.client.Log.Error("Failed to resolve escalation policy on-calls", "error", err.Error())
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.escalation_policies.oncalls.error",
+			Message:    "Failed to retrieve on-call users",
+			StatusCode: http.StatusInternalServerError,
+		})
+		return
+	}
+
+	response := EscalationChainsResponse{
+		ListResponse:       policies.ListResponse,
+		EscalationPolicies: chains,
+	}
+
+	p.client.Log.Info("Successfully retrieved escalation policies", "count", len(chains))
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		p.client.Log.Error("Failed to encode escalation policies response", "error", err.Error())
+	}
+}
+
+func (p *Plugin) handleGetEscalationPolicy(w http.ResponseWriter, r *http.Request) {
+	p.client.Log.Debug("handleGetEscalationPolicy called", "user_id", r.Header.Get("Mattermost-User-ID"))
+
+	config := p.getConfiguration()
+	if err := config.IsValid(); err != nil {
+		p.client.Log.Warn("Plugin configuration invalid", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.config.invalid",
+			Message:    "Plugin not configured",
+			StatusCode: http.StatusNotImplemented,
+		})
+		return
+	}
+
+	policyID := mux.Vars(r)["id"]
+	account := requestAccount(r)
+	client, apiErr := p.accountClient(account)
+	if apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	policy, err := client.GetEscalationPolicy(policyID)
+	if err != nil {
+		p.client.Log.Error("Failed to get escalation policy from PagerDuty", "error", err.Error(), "escalation_policy_id", policyID)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.escalation_policy.error",
+			Message:    "Failed to retrieve escalation policy",
+			StatusCode: http.StatusInternalServerError,
+		})
+		return
+	}
+
+	chains, err := p.resolveEscalationChains(client, []pagerduty.EscalationPolicy{policy.EscalationPolicy})
+	if err != nil {
+		p.client.Log.Error("Failed to resolve escalation policy on-calls", "error", err.Error(), "escalation_policy_id", policyID)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.escalation_policy.oncalls.error",
+			Message:    "Failed to retrieve on-call users",
+			StatusCode: http.StatusInternalServerError,
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(chains[0]); err != nil {
+		p.client.Log.Error("Failed to encode escalation policy response", "error", err.Error())
+	}
+}
+
+func (p *Plugin) handleGetIncidents(w http.ResponseWriter, r *http.Request) {
+	p.client.Log.Debug("handleGetIncidents called", "user_id", r.Header.Get("Mattermost-User-ID"))
+
+	config := p.getConfiguration()
+	if err := config.IsValid(); err != nil {
+		p.client.Log.Warn("Plugin configuration invalid", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.config.invalid",
+			Message:    "Plugin not configured",
+			StatusCode: http.StatusNotImplemented,
+		})
+		return
+	}
+
+	account := requestAccount(r)
+	client, apiErr := p.accountClient(account)
+	if apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	// Only open incidents are listed unless other statuses are asked for.
+	statuses := parseIDList(r.URL.Query()["statuses"])
+	if len(statuses) == 0 {
+		statuses = []string{"triggered", "acknowledged"}
+	}
+
+	params := url.Values{}
+	params.Set("limit", "100")
+	for _, status := range statuses {
+		params.Add("statuses[]", status)
+	}
+	for _, serviceID := range parseIDList(r.URL.Query()["service_ids"]) {
+		params.Add("service_ids[]", serviceID)
+	}
+	for _, teamID := range p.getRequestTeamIDs(r, account) {
+		params.Add("team_ids[]", teamID)
+	}
+
+	incidents, err := client.GetIncidents(params)
+	if err != nil {
+		p.client.Log.Error("Failed to get incidents from PagerDuty", "error", err.Error())
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.incidents.error",
+			Message:    "Failed to retrieve incidents",
+			StatusCode: http.StatusInternalServerError,
+		})
+		return
+	}
+
+	p.client.Log.Info("Successfully retrieved incidents", "count", len(incidents.Incidents))
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(incidents); err != nil {
+		p.client.Log.Error("Failed to encode incidents response", "error", err.Error())
+	}
+}
+
+func (p *Plugin) handleGetTeams(w http.ResponseWriter, r *http.Request) {
+	p.client.Log.Debug("handleGetTeams called", "user_id", r.Header.Get("Mattermost-User-ID"))
+
+	config := p.getConfiguration()
+	if err := config.IsValid(); err != nil {
+		p.client.Log.Warn("Plugin configuration invalid", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.config.invalid",
+			Message:    "Plugin not configured",
+			StatusCode: http.StatusNotImplemented,
+		})
+		return
+	}
+
+	account := requestAccount(r)
+	client, apiErr := p.accountClient(account)
+	if apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	params := url.Values{}
+	params.Set("limit", "100")
+	if query := r.URL.Query().Get("query"); query != "" {
+		params.Set("query", query)
+	}
+
+	teams, err := client.ListTeams(params)
+	if err != nil {
+		p.client.Log.Error("Failed to get teams from PagerDuty", "error", err.Error())
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.teams.error",
+			Message:    "Failed to retrieve teams",
+			StatusCode: http.StatusInternalServerError,
+		})
+		return
+	}
+
+	p.client.Log.Info("Successfully retrieved teams", "count", len(teams.Teams))
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(teams); err != nil {
+		p.client.Log.Error("Failed to encode teams response", "error", err.Error())
+	}
+}
+
+func (p *Plugin) handleGetTeamMembers(w http.ResponseWriter, r *http.Request) {
+	p.client.Log.Debug("handleGetTeamMembers called", "user_id", r.Header.Get("Mattermost-User-ID"))
+
+	config := p.getConfiguration()
+	if err := config.IsValid(); err != nil {
+		p.client.Log.Warn("Plugin configuration invalid", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.config.invalid",
+			Message:    "Plugin not configured",
+			StatusCode: http.StatusNotImplemented,
+		})
+		return
+	}
+
+	teamID := mux.Vars(r)["id"]
+	account := requestAccount(r)
+	client, apiErr := p.accountClient(account)
+	if apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	members, err := client.ListTeamMembers(teamID, 100, 0)
+	if err != nil {
+		p.client.Log.Error("Failed to get team members from PagerDuty", "error", err.Error(), "team_id", teamID)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.team.members.error",
+			Message:    "Failed to retrieve team members",
+			StatusCode: http.StatusInternalServerError,
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(members); err != nil {
+		p.client.Log.Error("Failed to encode team members response", "error", err.Error())
+	}
+}
+
 // CreateIncidentRequest represents the request body for creating an incident
 type CreateIncidentRequest struct {
 	Title       string   `json:"title"`
 	Description string   `json:"description,omitempty"`
 	ServiceID   string   `json:"service_id"`
 	AssigneeIDs []string `json:"assignee_ids,omitempty"`
+	Urgency     string   `json:"urgency,omitempty"`
+
+	// Justification and Confirmed satisfy the paging policy of the service, if it asks for them.
+	Justification string `json:"justification,omitempty"`
+	Confirmed     bool   `json:"confirmed,omitempty"`
+
+	// IdempotencyKey identifies the request, so that repeating it returns the incident it
+	// created instead of creating another one. It may also be sent as an Idempotency-Key header.
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
 }
 
 func (p *Plugin) handleCreateIncident(w http.ResponseWriter, r *http.Request) {
-	p.client.Log.Debug("handleCreateIncident called", "user_id", r.Header.Get("Mattermost-User-ID"))
+	userID := r.Header.Get("Mattermost-User-ID")
+	p.client.Log.Debug("handleCreateIncident called", "user_id", userID)
 
 	if r.Method != http.MethodPost {
 		p.handleError(w, r, &APIError{
@@ -225,19 +607,87 @@ func (p *Plugin) handleCreateIncident(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	client := p.createPagerDutyClient(config.APIToken, config.APIBaseURL)
-	p.client.Log.Debug("Creating incident in PagerDuty", "title", req.Title, "service_id", req.ServiceID, "assignees", len(req.AssigneeIDs))
+	if req.IdempotencyKey == "" {
+		req.IdempotencyKey = r.Header.Get("Idempotency-Key")
+	}
+	if len(req.IdempotencyKey) > maxIdempotencyKeyLength {
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.incident.idempotency_key.invalid",
+			Message:    fmt.Sprintf("The idempotency key may be at most %d characters long", maxIdempotencyKeyLength),
+			StatusCode: http.StatusBadRequest,
+		})
+		return
+	}
+
+	if req.Urgency != "" && req.Urgency != urgencyHigh && req.Urgency != urgencyLow {
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.incident.urgency.invalid",
+			Message:    "Urgency must be high or low",
+			StatusCode: http.StatusBadRequest,
+		})
+		return
+	}
+
+	account := requestAccount(r)
+	client, apiErr := p.accountClient(account)
+	if apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	if apiErr := p.checkPagingPolicy(userID, account, &req, time.Now()); apiErr != nil {
+		p.client.Log.Info("Paging policy rejected incident", "service_id", req.ServiceID, "user_id", userID, "reason", apiErr.ID)
+		p.recordAudit(userID, account, auditActionCreateIncident, req.ServiceID, apiErr)
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	idempotencyKey, idempotencyTTL := incidentIdempotencyKey(userID, account, &req)
+	existing, apiErr := p.beginIncidentRequest(idempotencyKey)
+	if apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+	if existing != nil {
+		p.client.Log.Info("Returning incident of a repeated request", "incident_id", existing.Incident.ID, "user_id", userID)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Idempotent-Replayed", "true")
+		if err := json.NewEncoder(w).Encode(existing); err != nil {
+			p.client.Log.Error("Failed to encode create incident response", "error", err.Error())
+		}
+		return
+	}
+
+	// PagerDuty rejects another incident with the same incident key while the first is open,
+	// which guards against repeats that reach it some other way. Only keys given by the client
+	// are passed on, as a derived key would block paging again with the same title.
+	incidentKey := ""
+	if req.IdempotencyKey != "" {
+		incidentKey = idempotencyKey
+	}
+
+	description := req.Description
+	if justification := strings.TrimSpace(req.Justification); justification != "" {
+		description = strings.TrimSpace(description + "\n\nJustification: " + justification)
+	}
+
+	p.client.Log.Debug("Creating incident in PagerDuty", "title", req.Title, "service_id", req.ServiceID, "assignees", len(req.AssigneeIDs), "account", displayAccountName(account))
 
-	incident, err := client.CreateIncident(req.Title, req.Description, req.ServiceID, req.AssigneeIDs)
+	incident, err := client.CreateIncident(req.Title, description, req.ServiceID, req.Urgency, incidentKey, req.AssigneeIDs)
 	if err != nil {
 		p.client.Log.Error("Failed to create incident in PagerDuty", "error", err.Error())
-		p.handleError(w, r, &APIError{
+		p.abandonIncidentRequest(idempotencyKey)
+		apiErr := &APIError{
 			ID:         "api.pagerduty.incident.create.error",
 			Message:    "Failed to create incident",
 			StatusCode: http.StatusInternalServerError,
-		})
+		}
+		p.recordAudit(userID, account, auditActionCreateIncident, req.ServiceID, apiErr)
+		p.handleError(w, r, apiErr)
 		return
 	}
+	p.finishIncidentRequest(idempotencyKey, incident, idempotencyTTL)
+	p.recordAudit(userID, account, auditActionCreateIncident, incident.Incident.ID, nil)
 
 	p.client.Log.Info("Successfully created incident", "incident_id", incident.Incident.ID, "title", incident.Incident.Title)
 	w.Header().Set("Content-Type", "application/json")
@@ -246,3 +696,529 @@ func (p *Plugin) handleCreateIncident(w http.ResponseWriter, r *http.Request) {
 		p.client.Log.Error("Failed to encode create incident response", "error", err.Error())
 	}
 }
+
+func (p *Plugin) handleGetMaintenanceWindows(w http.ResponseWriter, r *http.Request) {
+	p.client.Log.Debug("handleGetMaintenanceWindows called", "user_id", r.Header.Get("Mattermost-User-ID"))
+
+	config := p.getConfiguration()
+	if err := config.IsValid(); err != nil {
+		p.client.Log.Warn("Plugin configuration invalid", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.config.invalid",
+			Message:    "Plugin not configured",
+			StatusCode: http.StatusNotImplemented,
+		})
+		return
+	}
+
+	account := requestAccount(r)
+	client, apiErr := p.accountClient(account)
+	if apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	// Only ongoing and future windows are listed unless another filter is asked for.
+	filter := r.URL.Query().Get("filter")
+	if filter == "" {
+		filter = "open"
+	}
+
+	params := url.Values{}
+	params.Set("limit", "100")
+	params.Set("filter", filter)
+	for _, serviceID := range parseIDList(r.URL.Query()["service_ids"]) {
+		params.Add("service_ids[]", serviceID)
+	}
+	for _, teamID := range p.getRequestTeamIDs(r, account) {
+		params.Add("team_ids[]", teamID)
+	}
+
+	windows, err := client.ListMaintenanceWindows(params)
+	if err != nil {
+		p.client.Log.Error("Failed to get maintenance windows from PagerDuty", "error", err.Error())
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.maintenance.list.error",
+			Message:    "Failed to retrieve maintenance windows",
+			StatusCode: http.StatusInternalServerError,
+		})
+		return
+	}
+
+	p.client.Log.Info("Successfully retrieved maintenance windows", "count", len(windows.MaintenanceWindows))
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(windows); err != nil {
+		p.client.Log.Error("Failed to encode maintenance windows response", "error", err.Error())
+	}
+}
+
+func (p *Plugin) handleCreateMaintenanceWindow(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	p.client.Log.Debug("handleCreateMaintenanceWindow called", "user_id", userID)
+
+	config := p.getConfiguration()
+	if err := config.IsValid(); err != nil {
+		p.client.Log.Warn("Plugin configuration invalid", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.config.invalid",
+			Message:    "Plugin not configured",
+			StatusCode: http.StatusNotImplemented,
+		})
+		return
+	}
+
+	var req CreateMaintenanceWindowRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		p.client.Log.Warn("Failed to decode create maintenance window request", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.maintenance.decode.error",
+			Message:    "Invalid request body",
+			StatusCode: http.StatusBadRequest,
+		})
+		return
+	}
+
+	window, apiErr := p.createMaintenanceWindow(userID, requestAccount(r), &req)
+	if apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	p.client.Log.Info("Successfully created maintenance window", "maintenance_window_id", window.ID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(window); err != nil {
+		p.client.Log.Error("Failed to encode create maintenance window response", "error", err.Error())
+	}
+}
+
+func (p *Plugin) handleDeleteMaintenanceWindow(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	windowID := mux.Vars(r)["id"]
+	p.client.Log.Debug("handleDeleteMaintenanceWindow called", "user_id", userID, "maintenance_window_id", windowID)
+
+	config := p.getConfiguration()
+	if err := config.IsValid(); err != nil {
+		p.client.Log.Warn("Plugin configuration invalid", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.config.invalid",
+			Message:    "Plugin not configured",
+			StatusCode: http.StatusNotImplemented,
+		})
+		return
+	}
+
+	if apiErr := p.deleteMaintenanceWindow(userID, requestAccount(r), windowID); apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	p.client.Log.Info("Successfully deleted maintenance window", "maintenance_window_id", windowID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (p *Plugin) handleGetStatusUpdates(w http.ResponseWriter, r *http.Request) {
+	incidentID := mux.Vars(r)["id"]
+	p.client.Log.Debug("handleGetStatusUpdates called", "user_id", r.Header.Get("Mattermost-User-ID"), "incident_id", incidentID)
+
+	config := p.getConfiguration()
+	if err := config.IsValid(); err != nil {
+		p.client.Log.Warn("Plugin configuration invalid", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.config.invalid",
+			Message:    "Plugin not configured",
+			StatusCode: http.StatusNotImplemented,
+		})
+		return
+	}
+
+	account := requestAccount(r)
+	client, apiErr := p.accountClient(account)
+	if apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	updates, err := client.ListStatusUpdates(incidentID)
+	if err != nil {
+		p.client.Log.Error("Failed to get status updates from PagerDuty", "error", err.Error(), "incident_id", incidentID)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.status_updates.error",
+			Message:    "Failed to retrieve status updates",
+			StatusCode: http.StatusInternalServerError,
+		})
+		return
+	}
+
+	p.client.Log.Info("Successfully retrieved status updates", "incident_id", incidentID, "count", len(updates.StatusUpdates))
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(updates); err != nil {
+		p.client.Log.Error("Failed to encode status updates response", "error", err.Error())
+	}
+}
+
+func (p *Plugin) handleCreateStatusUpdate(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	incidentID := mux.Vars(r)["id"]
+	p.client.Log.Debug("handleCreateStatusUpdate called", "user_id", userID, "incident_id", incidentID)
+
+	config := p.getConfiguration()
+	if err := config.IsValid(); err != nil {
+		p.client.Log.Warn("Plugin configuration invalid", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.config.invalid",
+			Message:    "Plugin not configured",
+			StatusCode: http.StatusNotImplemented,
+		})
+		return
+	}
+
+	var req CreateStatusUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		p.client.Log.Warn("Failed to decode create status update request", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.status_update.decode.error",
+			Message:    "Invalid request body",
+			StatusCode: http.StatusBadRequest,
+		})
+		return
+	}
+
+	update, apiErr := p.createStatusUpdate(userID, requestAccount(r), incidentID, &req)
+	if apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	p.client.Log.Info("Successfully created status update", "incident_id", incidentID, "status_update_id", update.ID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(update); err != nil {
+		p.client.Log.Error("Failed to encode create status update response", "error", err.Error())
+	}
+}
+
+func (p *Plugin) handleSubscribeIncident(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	incidentID := mux.Vars(r)["id"]
+	p.client.Log.Debug("handleSubscribeIncident called", "user_id", userID, "incident_id", incidentID)
+
+	config := p.getConfiguration()
+	if err := config.IsValid(); err != nil {
+		p.client.Log.Warn("Plugin configuration invalid", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.config.invalid",
+			Message:    "Plugin not configured",
+			StatusCode: http.StatusNotImplemented,
+		})
+		return
+	}
+
+	var req SubscribeIncidentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		p.client.Log.Warn("Failed to decode subscribe incident request", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.subscription.decode.error",
+			Message:    "Invalid request body",
+			StatusCode: http.StatusBadRequest,
+		})
+		return
+	}
+
+	subscription, apiErr := p.subscribeChannelToIncident(userID, requestAccount(r), req.ChannelID, incidentID)
+	if apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	p.client.Log.Info("Successfully subscribed channel to incident", "incident_id", incidentID, "channel_id", req.ChannelID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(subscription); err != nil {
+		p.client.Log.Error("Failed to encode incident subscription response", "error", err.Error())
+	}
+}
+
+func (p *Plugin) handleUnsubscribeIncident(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	incidentID := mux.Vars(r)["id"]
+	channelID := mux.Vars(r)["channel_id"]
+	p.client.Log.Debug("handleUnsubscribeIncident called", "user_id", userID, "incident_id", incidentID, "channel_id", channelID)
+
+	if apiErr := p.unsubscribeChannelFromIncident(userID, requestAccount(r), channelID, incidentID); apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	p.client.Log.Info("Successfully unsubscribed channel from incident", "incident_id", incidentID, "channel_id", channelID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (p *Plugin) handleMergeIncidents(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	incidentID := mux.Vars(r)["id"]
+	p.client.Log.Debug("handleMergeIncidents called", "user_id", userID, "incident_id", incidentID)
+
+	config := p.getConfiguration()
+	if err := config.IsValid(); err != nil {
+		p.client.Log.Warn("Plugin configuration invalid", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.config.invalid",
+			Message:    "Plugin not configured",
+			StatusCode: http.StatusNotImplemented,
+		})
+		return
+	}
+
+	var req MergeIncidentsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		p.client.Log.Warn("Failed to decode merge incidents request", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.merge.decode.error",
+			Message:    "Invalid request body",
+			StatusCode: http.StatusBadRequest,
+		})
+		return
+	}
+
+	incident, apiErr := p.mergeIncidents(userID, requestAccount(r), incidentID, req.SourceIncidentIDs)
+	if apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	p.client.Log.Info("Successfully merged incidents", "incident_id", incidentID, "count", len(req.SourceIncidentIDs))
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(incident); err != nil {
+		p.client.Log.Error("Failed to encode merge incidents response", "error", err.Error())
+	}
+}
+
+func (p *Plugin) handleGetRelatedIncidents(w http.ResponseWriter, r *http.Request) {
+	incidentID := mux.Vars(r)["id"]
+	p.client.Log.Debug("handleGetRelatedIncidents called", "user_id", r.Header.Get("Mattermost-User-ID"), "incident_id", incidentID)
+
+	config := p.getConfiguration()
+	if err := config.IsValid(); err != nil {
+		p.client.Log.Warn("Plugin configuration invalid", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.config.invalid",
+			Message:    "Plugin not configured",
+			StatusCode: http.StatusNotImplemented,
+		})
+		return
+	}
+
+	account := requestAccount(r)
+	client, apiErr := p.accountClient(account)
+	if apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	related, err := client.ListRelatedIncidents(incidentID)
+	if err != nil {
+		p.client.Log.Error("Failed to get related incidents from PagerDuty", "error", err.Error(), "incident_id", incidentID)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.related_incidents.error",
+			Message:    "Failed to retrieve related incidents",
+			StatusCode: http.StatusInternalServerError,
+		})
+		return
+	}
+
+	p.client.Log.Info("Successfully retrieved related incidents", "incident_id", incidentID, "count", len(related.RelatedIncidents))
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(related); err != nil {
+		p.client.Log.Error("Failed to encode related incidents response", "error", err.Error())
+	}
+}
+
+func (p *Plugin) handleGetIncidentWorkflows(w http.ResponseWriter, r *http.Request) {
+	p.client.Log.Debug("handleGetIncidentWorkflows called", "user_id", r.Header.Get("Mattermost-User-ID"))
+
+	config := p.getConfiguration()
+	if err := config.IsValid(); err != nil {
+		p.client.Log.Warn("Plugin configuration invalid", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.config.invalid",
+			Message:    "Plugin not configured",
+			StatusCode: http.StatusNotImplemented,
+		})
+		return
+	}
+
+	account := requestAccount(r)
+	client, apiErr := p.accountClient(account)
+	if apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	workflows, err := p.getIncidentWorkflows(client, r.URL.Query().Get("query"))
+	if err != nil {
+		p.client.Log.Error("Failed to get incident workflows from PagerDuty", "error", err.Error())
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.incident_workflows.error",
+			Message:    "Failed to retrieve incident workflows",
+			StatusCode: http.StatusInternalServerError,
+		})
+		return
+	}
+
+	p.client.Log.Info("Successfully retrieved incident workflows", "count", len(workflows))
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(workflows); err != nil {
+		p.client.Log.Error("Failed to encode incident workflows response", "error", err.Error())
+	}
+}
+
+func (p *Plugin) handleStartIncidentWorkflow(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	incidentID := mux.Vars(r)["id"]
+	p.client.Log.Debug("handleStartIncidentWorkflow called", "user_id", userID, "incident_id", incidentID)
+
+	config := p.getConfiguration()
+	if err := config.IsValid(); err != nil {
+		p.client.Log.Warn("Plugin configuration invalid", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.config.invalid",
+			Message:    "Plugin not configured",
+			StatusCode: http.StatusNotImplemented,
+		})
+		return
+	}
+
+	var req StartIncidentWorkflowRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		p.client.Log.Warn("Failed to decode start incident workflow request", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.incident_workflow.decode.error",
+			Message:    "Invalid request body",
+			StatusCode: http.StatusBadRequest,
+		})
+		return
+	}
+
+	instance, apiErr := p.startIncidentWorkflow(userID, requestAccount(r), incidentID, req.IncidentWorkflowID)
+	if apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	p.client.Log.Info("Successfully started incident workflow", "incident_id", incidentID, "incident_workflow_id", req.IncidentWorkflowID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(instance); err != nil {
+		p.client.Log.Error("Failed to encode incident workflow instance response", "error", err.Error())
+	}
+}
+
+func (p *Plugin) handleAddResponders(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	incidentID := mux.Vars(r)["id"]
+	p.client.Log.Debug("handleAddResponders called", "user_id", userID, "incident_id", incidentID)
+
+	config := p.getConfiguration()
+	if err := config.IsValid(); err != nil {
+		p.client.Log.Warn("Plugin configuration invalid", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.config.invalid",
+			Message:    "Plugin not configured",
+			StatusCode: http.StatusNotImplemented,
+		})
+		return
+	}
+
+	var req AddRespondersRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		p.client.Log.Warn("Failed to decode add responders request", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.responders.decode.error",
+			Message:    "Invalid request body",
+			StatusCode: http.StatusBadRequest,
+		})
+		return
+	}
+
+	request, apiErr := p.addResponders(userID, requestAccount(r), incidentID, &req)
+	if apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	p.client.Log.Info("Successfully requested responders", "incident_id", incidentID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(request); err != nil {
+		p.client.Log.Error("Failed to encode responder request response", "error", err.Error())
+	}
+}
+
+func (p *Plugin) handleGetIncidentAlerts(w http.ResponseWriter, r *http.Request) {
+	incidentID := mux.Vars(r)["id"]
+	p.client.Log.Debug("handleGetIncidentAlerts called", "user_id", r.Header.Get("Mattermost-User-ID"), "incident_id", incidentID)
+
+	config := p.getConfiguration()
+	if err := config.IsValid(); err != nil {
+		p.client.Log.Warn("Plugin configuration invalid", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.config.invalid",
+			Message:    "Plugin not configured",
+			StatusCode: http.StatusNotImplemented,
+		})
+		return
+	}
+
+	groups, apiErr := p.getIncidentAlertGroups(requestAccount(r), incidentID)
+	if apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	p.client.Log.Info("Successfully retrieved alerts", "incident_id", incidentID, "count", groups.Total)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(groups); err != nil {
+		p.client.Log.Error("Failed to encode alerts response", "error", err.Error())
+	}
+}
+
+func (p *Plugin) handleUpdateAlert(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	vars := mux.Vars(r)
+	p.client.Log.Debug("handleUpdateAlert called", "user_id", userID, "incident_id", vars["id"], "alert_id", vars["alert_id"])
+
+	config := p.getConfiguration()
+	if err := config.IsValid(); err != nil {
+		p.client.Log.Warn("Plugin configuration invalid", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.config.invalid",
+			Message:    "Plugin not configured",
+			StatusCode: http.StatusNotImplemented,
+		})
+		return
+	}
+
+	var req UpdateAlertRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		p.client.Log.Warn("Failed to decode update alert request", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.alert.decode.error",
+			Message:    "Invalid request body",
+			StatusCode: http.StatusBadRequest,
+		})
+		return
+	}
+
+	alert, apiErr := p.updateAlert(userID, requestAccount(r), vars["id"], vars["alert_id"], &req)
+	if apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	p.client.Log.Info("Successfully updated alert", "incident_id", vars["id"], "alert_id", vars["alert_id"])
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(alert); err != nil {
+		p.client.Log.Error("Failed to encode alert response", "error", err.Error())
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func (p *Plugin) handleGetPagingPolicies(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	p.client.Log.Debug("handleGetPagingPolicies called", "user_id", userID)
+
+	policies, apiErr := p.getPagingPolicies(userID)
+	if apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(policies); err != nil {
+		p.client.Log.Error("Failed to encode paging policies response", "error", err.Error())
+	}
+}
+
+func (p *Plugin) handleGetPagingPolicy(w http.ResponseWriter, r *http.Request) {
+	serviceID := mux.Vars(r)["id"]
+	p.client.Log.Debug("handleGetPagingPolicy called", "user_id", r.Header.Get("Mattermost-User-ID"), "service_id", serviceID)
+
+	policy, apiErr := p.getPagingPolicy(requestAccount(r), serviceID)
+	if apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(policy); err != nil {
+		p.client.Log.Error("Failed to encode paging policy response", "error", err.Error())
+	}
+}
+
+func (p *Plugin) handleSavePagingPolicy(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	serviceID := mux.Vars(r)["id"]
+	p.client.Log.Debug("handleSavePagingPolicy called", "user_id", userID, "service_id", serviceID)
+
+	config := p.getConfiguration()
+	if err := config.IsValid(); err != nil {
+		p.client.Log.Warn("Plugin configuration invalid", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.config.invalid",
+			Message:    "Plugin not configured",
+			StatusCode: http.StatusNotImplemented,
+		})
+		return
+	}
+
+	var req SavePagingPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		p.client.Log.Warn("Failed to decode paging policy request", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.paging_policy.decode.error",
+			Message:    "Invalid request body",
+			StatusCode: http.StatusBadRequest,
+		})
+		return
+	}
+
+	policy, apiErr := p.savePagingPolicy(userID, requestAccount(r), serviceID, &req)
+	if apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	p.client.Log.Info("Successfully saved paging policy", "service_id", serviceID)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(policy); err != nil {
+		p.client.Log.Error("Failed to encode paging policy response", "error", err.Error())
+	}
+}
+
+func (p *Plugin) handleDeletePagingPolicy(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	serviceID := mux.Vars(r)["id"]
+	p.client.Log.Debug("handleDeletePagingPolicy called", "user_id", userID, "service_id", serviceID)
+
+	if apiErr := p.deletePagingPolicy(userID, requestAccount(r), serviceID); apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	p.client.Log.Info("Successfully deleted paging policy", "service_id", serviceID)
+	w.WriteHeader(http.StatusNoContent)
+}
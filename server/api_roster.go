@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func (p *Plugin) handleGetRosters(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	p.client.Log.Debug("handleGetRosters called", "user_id", userID)
+
+	channelID := r.URL.Query().Get("channel_id")
+	if channelID == "" {
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.roster.channel.missing",
+			Message:    "Channel ID is required",
+			StatusCode: http.StatusBadRequest,
+		})
+		return
+	}
+
+	if !p.canManageRosters(userID, channelID) {
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.roster.permission",
+			Message:    "You do not have permission to post in this channel",
+			StatusCode: http.StatusForbidden,
+		})
+		return
+	}
+
+	rosters, err := p.getChannelRosters(channelID)
+	if err != nil {
+		p.client.Log.Error("Failed to list rosters", "error", err.Error())
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.roster.list.error",
+			Message:    "Failed to retrieve rosters",
+			StatusCode: http.StatusInternalServerError,
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(rosters); err != nil {
+		p.client.Log.Error("Failed to encode rosters response", "error", err.Error())
+	}
+}
+
+func (p *Plugin) handleCreateRoster(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	p.client.Log.Debug("handleCreateRoster called", "user_id", userID)
+
+	var req CreateRosterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		p.client.Log.Warn("Failed to decode create roster request", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.roster.decode.error",
+			Message:    "Invalid request body",
+			StatusCode: http.StatusBadRequest,
+		})
+		return
+	}
+
+	roster, apiErr := p.createRoster(userID, requestAccount(r), &req)
+	if apiErr != nil {
+		p.handleError(w, r, apiErr)
+		return
+	}
+
+	p.client.Log.Info("Successfully created roster", "roster_id", roster.ID, "channel_id", roster.ChannelID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(roster); err != nil {
+		p.client.Log.Error("Failed to encode create roster response", "error", err.Error())
+	}
+}
+
+func (p *Plugin) handleDeleteRoster(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	rosterID := mux.Vars(r)["id"]
+	p.client.Log.Debug("handleDeleteRoster called", "user_id", userID, "roster_id", rosterID)
+
+	roster, err := p.kvstore.GetRoster(rosterID)
+	if err != nil {
+		p.client.Log.Error("Failed to get roster", "error", err.Error(), "roster_id", rosterID)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.roster.get.error",
+			Message:    "Failed to retrieve roster",
+			StatusCode: http.StatusInternalServerError,
+		})
+		return
+	}
+	if roster == nil {
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.roster.not_found",
+			Message:    "Roster not found",
+			StatusCode: http.StatusNotFound,
+		})
+		return
+	}
+
+	if !p.canManageRosters(userID, roster.ChannelID) {
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.roster.permission",
+			Message:    "You do not have permission to post in this channel",
+			StatusCode: http.StatusForbidden,
+		})
+		return
+	}
+
+	if err := p.kvstore.DeleteRoster(rosterID); err != nil {
+		p.client.Log.Error("Failed to delete roster", "error", err.Error(), "roster_id", rosterID)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.roster.delete.error",
+			Message:    "Failed to delete roster",
+			StatusCode: http.StatusInternalServerError,
+		})
+		return
+	}
+
+	p.client.Log.Info("Successfully deleted roster", "roster_id", rosterID)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -157,3 +157,34 @@ func TestPlugin_getConfiguration(t *testing.T) {
 		}
 	})
 }
+
+func TestPlugin_ServeHTTP_writeRoutesRequireRespond(t *testing.T) {
+	routes := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodPost, "/api/v1/incidents/PINC1/subscriptions"},
+		{http.MethodDelete, "/api/v1/incidents/PINC1/subscriptions/channel1"},
+		{http.MethodPost, "/api/v1/rosters"},
+		{http.MethodDelete, "/api/v1/rosters/roster1"},
+		{http.MethodPost, "/api/v1/impacts"},
+		{http.MethodPost, "/api/v1/impact_posts"},
+		{http.MethodDelete, "/api/v1/impact_posts/post1"},
+	}
+
+	for _, route := range routes {
+		t.Run(route.method+" "+route.path, func(t *testing.T) {
+			plugin, _, _ := setupHandlerTestPlugin(t)
+			plugin.setConfiguration(&configuration{APIToken: "token", allowlists: map[string][]allowlistEntry{
+				permissionRespond: {{Kind: allowlistRole, Value: "responder"}},
+			}})
+
+			w := serveTestRequest(plugin, route.method, route.path, "test-user-id", "{}")
+			assert.Equal(t, http.StatusForbidden, w.Code)
+
+			var apiErr APIError
+			assert.NoError(t, json.NewDecoder(w.Body).Decode(&apiErr))
+			assert.Equal(t, "api.pagerduty.permission."+permissionRespond, apiErr.ID)
+		})
+	}
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/store/kvstore"
+)
+
+// Actions recorded in the audit trail.
+const (
+	auditActionCreateIncident        = "incident.create"
+	auditActionMergeIncidents        = "incident.merge"
+	auditActionCreateStatusUpdate    = "incident.status_update"
+	auditActionAddResponders         = "incident.responders"
+	auditActionStartIncidentWorkflow = "incident.workflow"
+	auditActionUpdateAlert           = "alert.update"
+	auditActionCreateMaintenance     = "maintenance.create"
+	auditActionDeleteMaintenance     = "maintenance.delete"
+	auditActionSendChangeEvent       = "change_event.send"
+	auditActionCreateChangeEventRule = "change_event_rule.create"
+	auditActionDeleteChangeEventRule = "change_event_rule.delete"
+	auditActionCreateAlertRule       = "alert_rule.create"
+	auditActionUpdateAlertRule       = "alert_rule.update"
+	auditActionDeleteAlertRule       = "alert_rule.delete"
+	auditActionSavePagingPolicy      = "paging_policy.save"
+	auditActionDeletePagingPolicy    = "paging_policy.delete"
+	auditActionCreateGroupSync       = "group_sync.create"
+	auditActionDeleteGroupSync       = "group_sync.delete"
+	auditActionUpdateConfiguration   = "configuration.update"
+)
+
+// Results of the actions recorded in the audit trail.
+const (
+	auditResultSuccess = "success"
+	auditResultDenied  = "denied"
+	auditResultFailure = "failure"
+)
+
+const (
+	auditTargetConfiguration = "configuration"
+
+	// configurationAuditDedupWindow is how long a configuration change is remembered once
+	// recorded. Every node of the cluster is notified of a change within moments, and only the
+	// first records it; the same change made again later is recorded again.
+	configurationAuditDedupWindow = 10 * time.Minute
+
+	// defaultAuditRetentionDays is how many days records of the audit trail are kept unless
+	// configured otherwise.
+	defaultAuditRetentionDays = 90
+
+	defaultAuditQueryLimit = 100
+	maxAuditQueryLimit     = 10000
+)
+
+// AuditQuery filters the records of the audit trail. Empty fields match every record.
+type AuditQuery struct {
+	UserID string
+	Action string
+	Target string
+	Result string
+	Since  int64
+	Until  int64
+	Limit  int
+}
+
+// auditResult classifies the outcome of an action by the error it returned, if any.
+func auditResult(apiErr *APIError) string {
+	switch {
+	case apiErr == nil:
+		return auditResultSuccess
+	case apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden:
+		return auditResultDenied
+	default:
+		return auditResultFailure
+	}
+}
+
+// requestAuditAction names the action of a request to the plugin API by its method and route,
+// such as "POST /incidents/{id}/merge", for requests denied before their handler names it.
+func requestAuditAction(r *http.Request) string {
+	path := r.URL.Path
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			path = template
+		}
+	}
+	return r.Method + " " + strings.TrimPrefix(path, "/api/v1")
+}
+
+// recordAudit appends a write action of a user on an account to the audit trail, along with the
+// PagerDuty user the user is mapped to in that account. Failing to record an action is logged,
+// but does not fail the action.
+func (p *Plugin) recordAudit(userID, account, action, target string, apiErr *APIError) {
+	if p.kvstore == nil {
+		return
+	}
+
+	record := &kvstore.AuditRecord{
+		ID:       model.NewId(),
+		UserID:   userID,
+		Account:  account,
+		Action:   action,
+		Target:   target,
+		Result:   auditResult(apiErr),
+		CreateAt: model.GetMillis(),
+	}
+	if apiErr != nil {
+		record.Error = apiErr.Message
+	}
+
+	if userID != "" {
+		if user, err := p.client.User.Get(userID); err != nil {
+			p.client.Log.Warn("Failed to get user for audit record", "error", err.Error(), "user_id", userID)
+		} else {
+			record.Username = user.Username
+			if pdUser, err := p.getCachedPagerDutyUserForMattermostUser(account, user); err != nil {
+				p.client.Log.Debug("Failed to map user for audit record", "error", err.Error(), "user_id", userID)
+			} else if pdUser != nil {
+				record.PagerDutyUserID = pdUser.ID
+				record.PagerDutyUserEmail = pdUser.Email
+			}
+		}
+	}
+
+	p.appendAuditRecord(record)
+}
+
+// recordConfigurationAudit appends a change of the plugin configuration to the audit trail,
+// naming the settings that changed. Changes are made in the System Console, so they are not
+// attributed to a user. Each change is recorded once, by the first node of the cluster to be
+// notified of it.
+func (p *Plugin) recordConfigurationAudit(oldConfiguration, newConfiguration *configuration) {
+	changed := changedConfigurationSettings(oldConfiguration, newConfiguration)
+	if len(changed) == 0 || p.kvstore == nil {
+		return
+	}
+
+	first, err := p.kvstore.MarkConfigurationChangeAudited(configurationChangeHash(oldConfiguration, newConfiguration), configurationAuditDedupWindow)
+	if err != nil {
+		p.client.Log.Error("Failed to mark configuration change audited", "error", err.Error())
+		return
+	}
+	if !first {
+		return
+	}
+
+	p.appendAuditRecord(&kvstore.AuditRecord{
+		ID:       model.NewId(),
+		Action:   auditActionUpdateConfiguration,
+		Target:   auditTargetConfiguration,
+		Result:   auditResultSuccess,
+		Details:  "Changed " + strings.Join(changed, ", "),
+		CreateAt: model.GetMillis(),
+	})
+}
+
+func (p *Plugin) appendAuditRecord(record *kvstore.AuditRecord) {
+	if err := p.kvstore.AppendAuditRecord(record, p.getConfiguration().auditRetention()); err != nil {
+		p.client.Log.Error("Failed to record audit record", "error", err.Error(), "action", record.Action, "target", record.Target)
+	}
+}
+
+// changedConfigurationSettings returns the names of the settings that differ between two
+// configurations. Only names are returned, so that secrets such as the API token never end up
+// in the audit trail.
+func changedConfigurationSettings(oldConfiguration, newConfiguration *configuration) []string {
+	oldValue, newValue := reflect.ValueOf(*oldConfiguration), reflect.ValueOf(*newConfiguration)
+
+	var changed []string
+	for i := 0; i < oldValue.NumField(); i++ {
+		field := oldValue.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if !reflect.DeepEqual(oldValue.Field(i).Interface(), newValue.Field(i).Interface()) {
+			changed = append(changed, field.Name)
+		}
+	}
+	return changed
+}
+
+// configurationChangeHash identifies a change between two configurations without revealing
+// their settings.
+func configurationChangeHash(oldConfiguration, newConfiguration *configuration) string {
+	hash := sha256.New()
+	for _, c := range []*configuration{oldConfiguration, newConfiguration} {
+		// Only the exported settings are encoded, which cannot fail.
+		encoded, _ := json.Marshal(c)
+		hash.Write(encoded)
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// getAuditRecords returns the audit records matching a query, newest first. Records older than
+// the retention have expired, so the query never reaches further back.
+func (p *Plugin) getAuditRecords(query AuditQuery) ([]*kvstore.AuditRecord, *APIError) {
+	now := model.GetMillis()
+	since, until := query.Since, query.Until
+	if oldest := now - p.getConfiguration().auditRetention().Milliseconds(); since < oldest {
+		since = oldest
+	}
+	if until == 0 || until > now {
+		until = now
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultAuditQueryLimit
+	}
+
+	records, err := p.kvstore.ListAuditRecords(since, until, limit, query.matches)
+	if err != nil {
+		p.client.Log.Error("Failed to list audit records", "error", err.Error())
+		return nil, &APIError{
+			ID:         "api.pagerduty.audit.list.error",
+			Message:    "Failed to retrieve audit records",
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+
+	return records, nil
+}
+
+// matches reports whether a record matches the filters of a query other than its time range,
+// which the records are listed by.
+func (q AuditQuery) matches(record *kvstore.AuditRecord) bool {
+	return (q.UserID == "" || record.UserID == q.UserID) &&
+		(q.Action == "" || record.Action == q.Action) &&
+		(q.Target == "" || record.Target == q.Target) &&
+		(q.Result == "" || record.Result == q.Result)
+}
+
+// writeAuditCSV writes audit records as CSV, with a header row and RFC 3339 timestamps.
+func writeAuditCSV(w io.Writer, records []*kvstore.AuditRecord) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"id", "timestamp", "user_id", "username", "pagerduty_user_id", "pagerduty_user_email", "account", "action", "target", "result", "error", "details"}); err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		if err := writer.Write([]string{
+			record.ID,
+			time.UnixMilli(record.CreateAt).UTC().Format(time.RFC3339),
+			record.UserID,
+			record.Username,
+			record.PagerDutyUserID,
+			record.PagerDutyUserEmail,
+			record.Account,
+			record.Action,
+			record.Target,
+			record.Result,
+			record.Error,
+			record.Details,
+		}); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// parseAuditQuery reads the filters of an audit query from the query string of a request.
+// since and until are RFC 3339 timestamps.
+func parseAuditQuery(values url.Values) (AuditQuery, *APIError) {
+	query := AuditQuery{
+		UserID: values.Get("user_id"),
+		Action: values.Get("action"),
+		Target: values.Get("target"),
+		Result: values.Get("result"),
+	}
+
+	for key, bound := range map[string]*int64{"since": &query.Since, "until": &query.Until} {
+		value := values.Get(key)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return query, &APIError{
+				ID:         "api.pagerduty.audit." + key + ".invalid",
+				Message:    key + " must be an RFC 3339 timestamp",
+				StatusCode: http.StatusBadRequest,
+			}
+		}
+		*bound = t.UnixMilli()
+	}
+
+	if value := values.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 || limit > maxAuditQueryLimit {
+			return query, &APIError{
+				ID:         "api.pagerduty.audit.limit.invalid",
+				Message:    "limit must be a number between 1 and " + strconv.Itoa(maxAuditQueryLimit),
+				StatusCode: http.StatusBadRequest,
+			}
+		}
+		query.Limit = limit
+	}
+
+	return query, nil
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/store/kvstore"
+)
+
+func TestAuditResult(t *testing.T) {
+	assert.Equal(t, auditResultSuccess, auditResult(nil))
+	assert.Equal(t, auditResultDenied, auditResult(&APIError{StatusCode: http.StatusForbidden}))
+	assert.Equal(t, auditResultFailure, auditResult(&APIError{StatusCode: http.StatusInternalServerError}))
+}
+
+func TestPlugin_getAuditRecords(t *testing.T) {
+	plugin, _, _ := setupHandlerTestPlugin(t)
+
+	// The records are spread over three days.
+	now := time.Now()
+	at := func(hoursAgo int) int64 { return now.Add(-time.Duration(hoursAgo) * time.Hour).UnixMilli() }
+	for _, record := range []*kvstore.AuditRecord{
+		{ID: "1", UserID: "user1", Action: auditActionCreateIncident, Target: "SVC1", Result: auditResultSuccess, CreateAt: at(48)},
+		{ID: "2", UserID: "user2", Action: auditActionCreateIncident, Target: "SVC2", Result: auditResultDenied, CreateAt: at(36)},
+		{ID: "3", UserID: "user1", Action: auditActionCreateMaintenance, Target: "SVC1", Result: auditResultSuccess, CreateAt: at(24)},
+		{ID: "4", Action: auditActionUpdateConfiguration, Target: auditTargetConfiguration, Result: auditResultSuccess, CreateAt: at(1)},
+	} {
+		plugin.appendAuditRecord(record)
+	}
+
+	ids := func(records []*kvstore.AuditRecord) []string {
+		var ids []string
+		for _, record := range records {
+			ids = append(ids, record.ID)
+		}
+		return ids
+	}
+
+	tests := []struct {
+		name        string
+		query       AuditQuery
+		expectedIDs []string
+	}{
+		{
+			name:        "no filters returns newest first",
+			query:       AuditQuery{},
+			expectedIDs: []string{"4", "3", "2", "1"},
+		},
+		{
+			name:        "by user",
+			query:       AuditQuery{UserID: "user1"},
+			expectedIDs: []string{"3", "1"},
+		},
+		{
+			name:        "by action and result",
+			query:       AuditQuery{Action: auditActionCreateIncident, Result: auditResultDenied},
+			expectedIDs: []string{"2"},
+		},
+		{
+			name:        "by target",
+			query:       AuditQuery{Target: "SVC1"},
+			expectedIDs: []string{"3", "1"},
+		},
+		{
+			name:        "by time",
+			query:       AuditQuery{Since: at(36), Until: at(24)},
+			expectedIDs: []string{"3", "2"},
+		},
+		{
+			name:        "limited",
+			query:       AuditQuery{Limit: 2},
+			expectedIDs: []string{"4", "3"},
+		},
+		{
+			name:        "limited after filtering",
+			query:       AuditQuery{UserID: "user1", Limit: 1},
+			expectedIDs: []string{"3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, apiErr := plugin.getAuditRecords(tt.query)
+			require.Nil(t, apiErr)
+			assert.Equal(t, tt.expectedIDs, ids(records))
+		})
+	}
+}
+
+func TestParseAuditQuery(t *testing.T) {
+	query, apiErr := parseAuditQuery(url.Values{
+		"user_id": {"user1"},
+		"action":  {auditActionCreateIncident},
+		"since":   {"2024-01-15T00:00:00Z"},
+		"limit":   {"50"},
+	})
+	require.Nil(t, apiErr)
+	assert.Equal(t, "user1", query.UserID)
+	assert.Equal(t, auditActionCreateIncident, query.Action)
+	assert.Equal(t, time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC).UnixMilli(), query.Since)
+	assert.Zero(t, query.Until)
+	assert.Equal(t, 50, query.Limit)
+
+	_, apiErr = parseAuditQuery(url.Values{"until": {"yesterday"}})
+	require.NotNil(t, apiErr)
+	assert.Equal(t, "api.pagerduty.audit.until.invalid", apiErr.ID)
+
+	_, apiErr = parseAuditQuery(url.Values{"limit": {"0"}})
+	require.NotNil(t, apiErr)
+	assert.Equal(t, "api.pagerduty.audit.limit.invalid", apiErr.ID)
+}
+
+func TestWriteAuditCSV(t *testing.T) {
+	var buf bytes.Buffer
+	err := writeAuditCSV(&buf, []*kvstore.AuditRecord{{
+		ID:              "record1",
+		UserID:          "user1",
+		Username:        "alice",
+		PagerDutyUserID: "PUSER1",
+		Account:         "eu",
+		Action:          auditActionCreateIncident,
+		Target:          "PINC1",
+		Result:          auditResultFailure,
+		Error:           "Failed to create incident, try again",
+		CreateAt:        time.Date(2024, 1, 15, 9, 30, 0, 0, time.UTC).UnixMilli(),
+	}})
+	require.NoError(t, err)
+
+	assert.Equal(t, "id,timestamp,user_id,username,pagerduty_user_id,pagerduty_user_email,account,action,target,result,error,details\n"+
+		"record1,2024-01-15T09:30:00Z,user1,alice,PUSER1,,eu,incident.create,PINC1,failure,\"Failed to create incident, try again\",\n", buf.String())
+}
+
+func TestChangedConfigurationSettings(t *testing.T) {
+	oldConfiguration := &configuration{APIToken: "old-token", EnableOnCallStatus: true}
+	newConfiguration := &configuration{
+		APIToken:           "new-token",
+		EnableOnCallStatus: true,
+		AdminAllowlist:     "role:system_user",
+		allowlists:         map[string][]allowlistEntry{permissionAdmin: defaultAllowlist},
+	}
+
+	assert.Equal(t, []string{"APIToken", "AdminAllowlist"}, changedConfigurationSettings(oldConfiguration, newConfiguration))
+	assert.Empty(t, changedConfigurationSettings(oldConfiguration, oldConfiguration.Clone()))
+}
+
+func TestPlugin_recordAudit(t *testing.T) {
+	plugin, api, _ := setupHandlerTestPlugin(t)
+	plugin.setConfiguration(&configuration{APIToken: "token", accounts: []*pagerDutyAccount{{Name: "eu", APIToken: "eu-token"}}})
+	api.On("GetUser", "alice-user-id").Return(&model.User{Id: "alice-user-id", Username: "alice", Email: "alice@example.com"}, nil)
+
+	lookups := map[string]int{}
+	usePagerDutyServer(t, plugin, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/users", r.URL.Path)
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Token token=")
+		lookups[token]++
+
+		id := "PALICE"
+		if token == "eu-token" {
+			id = "PEUALICE"
+		}
+		fmt.Fprintf(w, `{"users": [{"id": %q, "email": "alice@example.com"}]}`, id)
+	})
+
+	plugin.recordAudit("alice-user-id", "", auditActionCreateIncident, "PSVC1", nil)
+	plugin.recordAudit("alice-user-id", "", auditActionMergeIncidents, "PINC1", nil)
+	plugin.recordAudit("alice-user-id", "eu", auditActionMergeIncidents, "PINC2", &APIError{Message: "Failed", StatusCode: http.StatusInternalServerError})
+
+	// The PagerDuty user is looked up once per account.
+	assert.Equal(t, map[string]int{"token": 1, "eu-token": 1}, lookups)
+
+	records, apiErr := plugin.getAuditRecords(AuditQuery{})
+	require.Nil(t, apiErr)
+	require.Len(t, records, 3)
+	pagerDutyUsers := map[string]string{}
+	for _, record := range records {
+		assert.Equal(t, "alice", record.Username)
+		assert.Equal(t, "alice@example.com", record.PagerDutyUserEmail)
+		pagerDutyUsers[record.Target] = record.Account + ":" + record.PagerDutyUserID
+	}
+	assert.Equal(t, map[string]string{"PSVC1": ":PALICE", "PINC1": ":PALICE", "PINC2": "eu:PEUALICE"}, pagerDutyUsers)
+}
+
+func TestPlugin_recordConfigurationAudit(t *testing.T) {
+	plugin, _, _ := setupHandlerTestPlugin(t)
+	oldConfiguration := &configuration{APIToken: "old-token"}
+	newConfiguration := &configuration{APIToken: "new-token"}
+
+	// Every node of the cluster is notified of the change.
+	plugin.recordConfigurationAudit(oldConfiguration, newConfiguration)
+	plugin.recordConfigurationAudit(oldConfiguration, newConfiguration.Clone())
+
+	records, apiErr := plugin.getAuditRecords(AuditQuery{})
+	require.Nil(t, apiErr)
+	require.Len(t, records, 1)
+	assert.Equal(t, auditActionUpdateConfiguration, records[0].Action)
+	assert.Equal(t, "Changed APIToken", records[0].Details)
+
+	// A later change is recorded too.
+	laterConfiguration := &configuration{APIToken: "new-token", EnableOnCallStatus: true}
+	plugin.recordConfigurationAudit(newConfiguration, laterConfiguration)
+	plugin.recordConfigurationAudit(newConfiguration, laterConfiguration.Clone())
+
+	records, apiErr = plugin.getAuditRecords(AuditQuery{})
+	require.Nil(t, apiErr)
+	require.Len(t, records, 2)
+	details := []string{records[0].Details, records[1].Details}
+	assert.ElementsMatch(t, []string{"Changed APIToken", "Changed EnableOnCallStatus"}, details)
+}
+
+func TestConfigurationChangeHash(t *testing.T) {
+	oldConfiguration := &configuration{APIToken: "old-token"}
+	newConfiguration := &configuration{APIToken: "new-token"}
+
+	hash := configurationChangeHash(oldConfiguration, newConfiguration)
+	assert.Equal(t, hash, configurationChangeHash(oldConfiguration.Clone(), newConfiguration.Clone()))
+	assert.NotEqual(t, hash, configurationChangeHash(newConfiguration, oldConfiguration))
+}
+
+func TestPlugin_requirePermission_recordsDenial(t *testing.T) {
+	plugin, _, _ := setupHandlerTestPlugin(t)
+	plugin.setConfiguration(&configuration{APIToken: "token", allowlists: map[string][]allowlistEntry{
+		permissionRead:    {{Kind: allowlistRole, Value: "reader"}},
+		permissionRespond: {{Kind: allowlistRole, Value: "responder"}},
+	}})
+
+	w := serveTestRequest(plugin, http.MethodPut, "/api/v1/incidents/PINC1/merge?account=eu", "test-user-id", "{}")
+	require.Equal(t, http.StatusForbidden, w.Code)
+	w = serveTestRequest(plugin, http.MethodGet, "/api/v1/incidents", "test-user-id", "")
+	require.Equal(t, http.StatusForbidden, w.Code)
+
+	// Only the denied write is recorded.
+	records, apiErr := plugin.getAuditRecords(AuditQuery{})
+	require.Nil(t, apiErr)
+	require.Len(t, records, 1)
+	assert.Equal(t, "test-user-id", records[0].UserID)
+	assert.Equal(t, "eu", records[0].Account)
+	assert.Equal(t, "PUT /incidents/{id}/merge", records[0].Action)
+	assert.Equal(t, "PINC1", records[0].Target)
+	assert.Equal(t, auditResultDenied, records[0].Result)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/store/kvstore"
+)
+
+// getBotAPIClient returns a Mattermost REST API client authenticated as the plugin's bot. It is
+// used for operations the plugin API does not offer, such as managing custom group members.
+// The bot's access token is created on first use and kept in the KV store.
+func (p *Plugin) getBotAPIClient() (*model.Client4, error) {
+	token, err := p.kvstore.GetBotAccessToken()
+	if errors.Is(err, kvstore.ErrUnknownEncryptionKey) {
+		// The token was encrypted with a key that was replaced while the plugin was not running.
+		p.client.Log.Warn("Bot access token cannot be decrypted, a new one will be created")
+		token = nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if token == nil {
+		accessToken, err := p.client.User.CreateAccessToken(p.botUserID, "Used by the PagerDuty plugin")
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create bot access token")
+		}
+
+		token = &kvstore.BotAccessToken{ID: accessToken.Id, Token: accessToken.Token}
+		if err := p.kvstore.SetBotAccessToken(token); err != nil {
+			return nil, err
+		}
+	}
+
+	siteURL := p.client.Configuration.GetConfig().ServiceSettings.SiteURL
+	if siteURL == nil || *siteURL == "" {
+		return nil, errors.New("site URL is not configured")
+	}
+
+	client := model.NewAPIv4Client(*siteURL)
+	client.SetToken(token.Token)
+	return client, nil
+}
+
+// checkBotAPIResponse discards the stored bot access token if the server rejected it, so that
+// a new one is created on the next use.
+func (p *Plugin) checkBotAPIResponse(resp *model.Response) {
+	if resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		return
+	}
+
+	p.client.Log.Warn("Bot access token was rejected, a new one will be created")
+	if err := p.kvstore.DeleteBotAccessToken(); err != nil {
+		p.client.Log.Error("Failed to delete bot access token", "error", err.Error())
+	}
+}
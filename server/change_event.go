@@ -0,0 +1,431 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
+	"github.com/svelle/mattermost-pagerduty-plugin/server/store/kvstore"
+)
+
+const (
+	// maxChangeEventSummaryLength is the longest summary accepted by the Events API.
+	maxChangeEventSummaryLength = 1024
+
+	// changeEventSource is the source of change events sent from Mattermost.
+	changeEventSource = "Mattermost"
+
+	// changeEventSummaryGroup is the name of the capture group of a rule's pattern used as the
+	// summary of its change events. Without it, the first line of the message is used.
+	changeEventSummaryGroup = "summary"
+
+	// routingKeyCacheTTL is how long the routing key of a service is reused by the rules
+	// matching posted messages before it is looked up again.
+	routingKeyCacheTTL = 10 * time.Minute
+
+	// routingKeyErrorCacheTTL is how long a failed lookup is reused, so that a rule whose service
+	// has no integration does not fetch the service for every matching message.
+	routingKeyErrorCacheTTL = time.Minute
+)
+
+// SendChangeEventRequest represents the request body for sending a change event, either to a
+// service or directly to the routing key of an integration
+type SendChangeEventRequest struct {
+	ServiceID     string                 `json:"service_id,omitempty"`
+	RoutingKey    string                 `json:"routing_key,omitempty"`
+	Summary       string                 `json:"summary"`
+	Source        string                 `json:"source,omitempty"`
+	CustomDetails map[string]interface{} `json:"custom_details,omitempty"`
+	Links         []pagerduty.Link       `json:"links,omitempty"`
+}
+
+// CreateChangeEventRuleRequest represents the request body for creating a change event rule
+type CreateChangeEventRuleRequest struct {
+	ChannelID string `json:"channel_id"`
+	Pattern   string `json:"pattern"`
+	ServiceID string `json:"service_id"`
+}
+
+// cachedRoutingKey is the outcome of looking up the routing key of a service under a
+// configuration.
+type cachedRoutingKey struct {
+	config      *configuration
+	routingKey  string
+	serviceName string
+	apiErr      *APIError
+	expireAt    time.Time
+}
+
+// getCachedServiceRoutingKey returns the routing key of a service like getServiceRoutingKey,
+// reusing the outcome of a recent lookup under the same configuration. It is used when posted
+// messages match rules, so that each of them does not wait for PagerDuty.
+func (p *Plugin) getCachedServiceRoutingKey(account, serviceID string) (string, string, *APIError) {
+	config := p.getConfiguration()
+	key := account + "/" + serviceID
+
+	p.routingKeyLock.Lock()
+	cached, ok := p.routingKeys[key]
+	p.routingKeyLock.Unlock()
+	if ok && cached.config == config && time.Now().Before(cached.expireAt) {
+		return cached.routingKey, cached.serviceName, cached.apiErr
+	}
+
+	return p.getServiceRoutingKey(account, serviceID)
+}
+
+// getServiceRoutingKey returns the routing key of the Events API v2 integration of a service
+// of an account, which change events are sent to, and the name of the service. It always asks
+// PagerDuty, and caches the outcome for getCachedServiceRoutingKey.
+func (p *Plugin) getServiceRoutingKey(account, serviceID string) (string, string, *APIError) {
+	config := p.getConfiguration()
+	routingKey, serviceName, apiErr := p.lookUpServiceRoutingKey(account, serviceID)
+
+	ttl := routingKeyCacheTTL
+	if apiErr != nil {
+		ttl = routingKeyErrorCacheTTL
+	}
+
+	p.routingKeyLock.Lock()
+	defer p.routingKeyLock.Unlock()
+	if p.routingKeys == nil {
+		p.routingKeys = map[string]*cachedRoutingKey{}
+	}
+	p.routingKeys[account+"/"+serviceID] = &cachedRoutingKey{
+		config:      config,
+		routingKey:  routingKey,
+		serviceName: serviceName,
+		apiErr:      apiErr,
+		expireAt:    time.Now().Add(ttl),
+	}
+	return routingKey, serviceName, apiErr
+}
+
+// lookUpServiceRoutingKey fetches a service from PagerDuty to find its routing key.
+func (p *Plugin) lookUpServiceRoutingKey(account, serviceID string) (string, string, *APIError) {
+	client, apiErr := p.accountClient(account)
+	if apiErr != nil {
+		return "", "", apiErr
+	}
+
+	service, err := client.GetService(serviceID)
+	if err != nil {
+		p.client.Log.Warn("Failed to get service from PagerDuty", "error", err.Error(), "service_id", serviceID)
+		return "", "", &APIError{
+			ID:         "api.pagerduty.change_event.service.not_found",
+			Message:    fmt.Sprintf("Service %s was not found", serviceID),
+			StatusCode: http.StatusNotFound,
+		}
+	}
+
+	for _, integration := range service.Service.Integrations {
+		if strings.HasPrefix(integration.Type, "events_api_v2_inbound_integration") && integration.IntegrationKey != "" {
+			return integration.IntegrationKey, service.Service.Name, nil
+		}
+	}
+
+	return "", "", &APIError{
+		ID:         "api.pagerduty.change_event.integration.not_found",
+		Message:    fmt.Sprintf("Service %s has no Events API v2 integration to send change events to", service.Service.Name),
+		StatusCode: http.StatusBadRequest,
+	}
+}
+
+// sendChangeEvent sends a change event to an account on behalf of the given user.
+func (p *Plugin) sendChangeEvent(userID, account string, req *SendChangeEventRequest) (_ *pagerduty.EventResponse, apiErr *APIError) {
+	defer func() { p.recordAudit(userID, account, auditActionSendChangeEvent, req.ServiceID, apiErr) }()
+
+	if strings.TrimSpace(req.Summary) == "" {
+		return nil, &APIError{
+			ID:         "api.pagerduty.change_event.summary.missing",
+			Message:    "A summary is required",
+			StatusCode: http.StatusBadRequest,
+		}
+	}
+	if (req.ServiceID == "") == (req.RoutingKey == "") {
+		return nil, &APIError{
+			ID:         "api.pagerduty.change_event.target.invalid",
+			Message:    "Either service_id or routing_key is required",
+			StatusCode: http.StatusBadRequest,
+		}
+	}
+
+	client, apiErr := p.accountEventsClient(account)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	routingKey := req.RoutingKey
+	if req.ServiceID != "" {
+		var apiErr *APIError
+		if routingKey, _, apiErr = p.getServiceRoutingKey(account, req.ServiceID); apiErr != nil {
+			return nil, apiErr
+		}
+	}
+
+	source := req.Source
+	if source == "" {
+		source = changeEventSource
+	}
+
+	customDetails := map[string]interface{}{}
+	for key, value := range req.CustomDetails {
+		customDetails[key] = value
+	}
+	if user, err := p.client.User.Get(userID); err == nil {
+		customDetails["sent_by"] = "@" + user.Username
+	}
+
+	event := &pagerduty.ChangeEvent{
+		RoutingKey: routingKey,
+		Payload: pagerduty.ChangeEventPayload{
+			Summary:       truncateChangeEventSummary(req.Summary),
+			Timestamp:     time.Now().UTC().Format(time.RFC3339),
+			Source:        source,
+			CustomDetails: customDetails,
+		},
+		Links: req.Links,
+	}
+
+	response, err := client.SendChangeEvent(event)
+	if err != nil {
+		p.client.Log.Error("Failed to send change event to PagerDuty", "error", err.Error(), "service_id", req.ServiceID)
+		return nil, &APIError{
+			ID:         "api.pagerduty.change_event.send.error",
+			Message:    "Failed to send the change event",
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+
+	return response, nil
+}
+
+// createChangeEventRule validates and stores a new change event rule for a service of an account
+// on behalf of the given user.
+func (p *Plugin) createChangeEventRule(userID, account string, req *CreateChangeEventRuleRequest) (_ *kvstore.ChangeEventRule, apiErr *APIError) {
+	defer func() { p.recordAudit(userID, account, auditActionCreateChangeEventRule, req.ChannelID, apiErr) }()
+
+	if req.ChannelID == "" || req.Pattern == "" || req.ServiceID == "" {
+		return nil, &APIError{
+			ID:         "api.pagerduty.change_event_rule.fields.missing",
+			Message:    "channel_id, pattern and service_id are required",
+			StatusCode: http.StatusBadRequest,
+		}
+	}
+
+	if !p.client.User.HasPermissionToChannel(userID, req.ChannelID, model.PermissionCreatePost) {
+		return nil, &APIError{
+			ID:         "api.pagerduty.change_event_rule.permission",
+			Message:    "You do not have permission to post in this channel",
+			StatusCode: http.StatusForbidden,
+		}
+	}
+
+	if _, err := regexp.Compile(req.Pattern); err != nil {
+		return nil, &APIError{
+			ID:         "api.pagerduty.change_event_rule.pattern.invalid",
+			Message:    fmt.Sprintf("Invalid pattern: %s", err.Error()),
+			StatusCode: http.StatusBadRequest,
+		}
+	}
+
+	_, serviceName, apiErr := p.getServiceRoutingKey(account, req.ServiceID)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	rules, err := p.kvstore.GetChangeEventRules(req.ChannelID)
+	if err != nil {
+		p.client.Log.Error("Failed to get change event rules", "error", err.Error(), "channel_id", req.ChannelID)
+		return nil, &APIError{
+			ID:         "api.pagerduty.change_event_rule.get.error",
+			Message:    "Failed to retrieve change event rules",
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+
+	rule := &kvstore.ChangeEventRule{
+		ID:          model.NewId(),
+		ChannelID:   req.ChannelID,
+		Pattern:     req.Pattern,
+		ServiceID:   req.ServiceID,
+		ServiceName: serviceName,
+		Account:     account,
+		CreatorID:   userID,
+		CreateAt:    model.GetMillis(),
+	}
+
+	if err := p.kvstore.SaveChangeEventRules(req.ChannelID, append(rules, rule)); err != nil {
+		p.client.Log.Error("Failed to save change event rules", "error", err.Error(), "channel_id", req.ChannelID)
+		return nil, &APIError{
+			ID:         "api.pagerduty.change_event_rule.save.error",
+			Message:    "Failed to save change event rule",
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+
+	return rule, nil
+}
+
+// deleteChangeEventRule removes a change event rule of a channel on behalf of the given user.
+func (p *Plugin) deleteChangeEventRule(userID, channelID, ruleID string) (apiErr *APIError) {
+	var account string
+	defer func() { p.recordAudit(userID, account, auditActionDeleteChangeEventRule, ruleID, apiErr) }()
+
+	if !p.client.User.HasPermissionToChannel(userID, channelID, model.PermissionCreatePost) {
+		return &APIError{
+			ID:         "api.pagerduty.change_event_rule.permission",
+			Message:    "You do not have permission to post in this channel",
+			StatusCode: http.StatusForbidden,
+		}
+	}
+
+	rules, err := p.kvstore.GetChangeEventRules(channelID)
+	if err != nil {
+		p.client.Log.Error("Failed to get change event rules", "error", err.Error(), "channel_id", channelID)
+		return &APIError{
+			ID:         "api.pagerduty.change_event_rule.get.error",
+			Message:    "Failed to retrieve change event rules",
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+
+	remaining := make([]*kvstore.ChangeEventRule, 0, len(rules))
+	for _, rule := range rules {
+		if rule.ID == ruleID {
+			account = rule.Account
+		} else {
+			remaining = append(remaining, rule)
+		}
+	}
+	if len(remaining) == len(rules) {
+		return &APIError{
+			ID:         "api.pagerduty.change_event_rule.not_found",
+			Message:    fmt.Sprintf("Change event rule %s was not found in this channel", ruleID),
+			StatusCode: http.StatusNotFound,
+		}
+	}
+
+	if err := p.kvstore.SaveChangeEventRules(channelID, remaining); err != nil {
+		p.client.Log.Error("Failed to save change event rules", "error", err.Error(), "channel_id", channelID)
+		return &APIError{
+			ID:         "api.pagerduty.change_event_rule.delete.error",
+			Message:    "Failed to delete change event rule",
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+	return nil
+}
+
+// sendMatchingChangeEvents sends a change event for every change event rule of the post's
+// channel whose pattern matches the message.
+func (p *Plugin) sendMatchingChangeEvents(post *model.Post) {
+	if post.UserId == p.botUserID || post.IsSystemMessage() || post.Message == "" {
+		return
+	}
+
+	rules, err := p.kvstore.GetChangeEventRules(post.ChannelId)
+	if err != nil {
+		p.client.Log.Error("Failed to get change event rules", "error", err.Error(), "channel_id", post.ChannelId)
+		return
+	}
+	if len(rules) == 0 {
+		return
+	}
+
+	author := ""
+	if user, err := p.client.User.Get(post.UserId); err == nil {
+		author = "@" + user.Username
+	}
+
+	for _, rule := range rules {
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			p.client.Log.Warn("Skipping change event rule with invalid pattern", "rule_id", rule.ID, "error", err.Error())
+			continue
+		}
+
+		summary, ok := changeEventSummary(pattern, post.Message)
+		if !ok {
+			continue
+		}
+
+		routingKey, _, apiErr := p.getCachedServiceRoutingKey(rule.Account, rule.ServiceID)
+		if apiErr != nil {
+			p.client.Log.Warn("Skipping change event rule without a routing key", "rule_id", rule.ID, "error", apiErr.Message)
+			continue
+		}
+
+		client, apiErr := p.accountEventsClient(rule.Account)
+		if apiErr != nil {
+			p.client.Log.Warn("Skipping change event rule of an unknown account", "rule_id", rule.ID, "error", apiErr.Message)
+			continue
+		}
+
+		event := &pagerduty.ChangeEvent{
+			RoutingKey: routingKey,
+			Payload: pagerduty.ChangeEventPayload{
+				Summary:   truncateChangeEventSummary(summary),
+				Timestamp: time.UnixMilli(post.CreateAt).UTC().Format(time.RFC3339),
+				Source:    changeEventSource,
+				CustomDetails: map[string]interface{}{
+					"message": post.Message,
+					"author":  author,
+				},
+			},
+		}
+		if permalink := p.getPostPermalink(post.Id); permalink != "" {
+			event.Links = []pagerduty.Link{{Href: permalink, Text: "View in Mattermost"}}
+		}
+
+		if _, err := client.SendChangeEvent(event); err != nil {
+			p.client.Log.Error("Failed to send change event to PagerDuty", "error", err.Error(), "rule_id", rule.ID, "post_id", post.Id)
+		}
+	}
+}
+
+// getPostPermalink returns the absolute permalink of a post, or an empty string if the site URL
+// is not configured.
+func (p *Plugin) getPostPermalink(postID string) string {
+	siteURL := p.client.Configuration.GetConfig().ServiceSettings.SiteURL
+	if siteURL == nil || *siteURL == "" {
+		return ""
+	}
+	return strings.TrimSuffix(*siteURL, "/") + "/_redirect/pl/" + postID
+}
+
+// changeEventSummary returns the summary of the change event for a message matching a pattern:
+// the capture group named summary if the pattern has one, or else the first line of the
+// message. It returns false if the message does not match.
+func changeEventSummary(pattern *regexp.Regexp, message string) (string, bool) {
+	match := pattern.FindStringSubmatch(message)
+	if match == nil {
+		return "", false
+	}
+
+	if i := pattern.SubexpIndex(changeEventSummaryGroup); i >= 0 && match[i] != "" {
+		return strings.TrimSpace(match[i]), true
+	}
+
+	firstLine, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	return firstLine, true
+}
+
+func truncateChangeEventSummary(summary string) string {
+	summary = strings.TrimSpace(summary)
+	if len(summary) <= maxChangeEventSummaryLength {
+		return summary
+	}
+
+	// Cut at a rune boundary so that the summary stays valid UTF-8.
+	cut := maxChangeEventSummaryLength - len("…")
+	for cut > 0 && !utf8.RuneStart(summary[cut]) {
+		cut--
+	}
+	return summary[:cut] + "…"
+}
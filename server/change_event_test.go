@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
+)
+
+func TestChangeEventSummary(t *testing.T) {
+	tests := []struct {
+		name     string
+		pattern  string
+		message  string
+		expected string
+		matched  bool
+	}{
+		{
+			name:     "no match",
+			pattern:  `^Deployed`,
+			message:  "Rolled back v2.1",
+			expected: "",
+			matched:  false,
+		},
+		{
+			name:     "first line without a summary group",
+			pattern:  `(?i)deployed`,
+			message:  "  Deployed v2.1 to production\nChangelog: ...",
+			expected: "Deployed v2.1 to production",
+			matched:  true,
+		},
+		{
+			name:     "summary group",
+			pattern:  `^Deployed (?P<summary>\S+)`,
+			message:  "Deployed v2.1 to production",
+			expected: "v2.1",
+			matched:  true,
+		},
+		{
+			name:     "empty summary group falls back to the first line",
+			pattern:  `^Deployed(?P<summary>\s*v\d*)?`,
+			message:  "Deployed",
+			expected: "Deployed",
+			matched:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary, matched := changeEventSummary(regexp.MustCompile(tt.pattern), tt.message)
+			assert.Equal(t, tt.matched, matched)
+			assert.Equal(t, tt.expected, summary)
+		})
+	}
+}
+
+func TestTruncateChangeEventSummary(t *testing.T) {
+	assert.Equal(t, "Deployed v2.1", truncateChangeEventSummary(" Deployed v2.1 "))
+
+	long := strings.Repeat("é", maxChangeEventSummaryLength)
+	truncated := truncateChangeEventSummary(long)
+	assert.LessOrEqual(t, len(truncated), maxChangeEventSummaryLength)
+	assert.True(t, utf8.ValidString(truncated))
+	assert.True(t, strings.HasSuffix(truncated, "…"))
+}
+
+func TestPlugin_getCachedServiceRoutingKey(t *testing.T) {
+	setup := func(t *testing.T) (*Plugin, *int) {
+		plugin, _, _ := setupHandlerTestPlugin(t)
+		requests := 0
+		usePagerDutyServer(t, plugin, func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			switch r.URL.Path {
+			case "/services/PSVC1":
+				_ = json.NewEncoder(w).Encode(pagerduty.ServiceResponse{Service: pagerduty.Service{
+					ID:           "PSVC1",
+					Name:         "Checkout",
+					Integrations: []pagerduty.Integration{{ID: "PINT1", Type: "events_api_v2_inbound_integration", IntegrationKey: "routing-key"}},
+				}})
+			case "/services/PSVC2":
+				_ = json.NewEncoder(w).Encode(pagerduty.ServiceResponse{Service: pagerduty.Service{ID: "PSVC2", Name: "Search"}})
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		})
+		return plugin, &requests
+	}
+
+	t.Run("routing keys are reused", func(t *testing.T) {
+		plugin, requests := setup(t)
+
+		for i := 0; i < 3; i++ {
+			routingKey, serviceName, apiErr := plugin.getCachedServiceRoutingKey("", "PSVC1")
+			require.Nil(t, apiErr)
+			assert.Equal(t, "routing-key", routingKey)
+			assert.Equal(t, "Checkout", serviceName)
+		}
+		assert.Equal(t, 1, *requests)
+	})
+
+	t.Run("failed lookups are reused", func(t *testing.T) {
+		plugin, requests := setup(t)
+
+		for i := 0; i < 3; i++ {
+			_, _, apiErr := plugin.getCachedServiceRoutingKey("", "PSVC2")
+			require.NotNil(t, apiErr)
+			assert.Equal(t, "api.pagerduty.change_event.integration.not_found", apiErr.ID)
+		}
+		assert.Equal(t, 1, *requests)
+	})
+
+	t.Run("expired routing keys are looked up again", func(t *testing.T) {
+		plugin, requests := setup(t)
+
+		_, _, apiErr := plugin.getCachedServiceRoutingKey("", "PSVC1")
+		require.Nil(t, apiErr)
+		plugin.routingKeys["/PSVC1"].expireAt = time.Now().Add(-time.Second)
+
+		_, _, apiErr = plugin.getCachedServiceRoutingKey("", "PSVC1")
+		require.Nil(t, apiErr)
+		assert.Equal(t, 2, *requests)
+	})
+
+	t.Run("routing keys are looked up again when the configuration changes", func(t *testing.T) {
+		plugin, requests := setup(t)
+
+		_, _, apiErr := plugin.getCachedServiceRoutingKey("", "PSVC1")
+		require.Nil(t, apiErr)
+		plugin.setConfiguration(&configuration{APIToken: "new-token"})
+
+		_, _, apiErr = plugin.getCachedServiceRoutingKey("", "PSVC1")
+		require.Nil(t, apiErr)
+		assert.Equal(t, 2, *requests)
+	})
+
+	t.Run("accounts are cached separately", func(t *testing.T) {
+		plugin, requests := setup(t)
+		plugin.setConfiguration(&configuration{APIToken: "token", accounts: []*pagerDutyAccount{{Name: "eu", APIToken: "eu-token"}}})
+
+		_, _, apiErr := plugin.getCachedServiceRoutingKey("", "PSVC1")
+		require.Nil(t, apiErr)
+		_, _, apiErr = plugin.getCachedServiceRoutingKey("eu", "PSVC1")
+		require.Nil(t, apiErr)
+		assert.Equal(t, 2, *requests)
+	})
+
+	t.Run("uncached lookups refresh the cache", func(t *testing.T) {
+		plugin, requests := setup(t)
+
+		_, _, apiErr := plugin.getServiceRoutingKey("", "PSVC1")
+		require.Nil(t, apiErr)
+		_, _, apiErr = plugin.getServiceRoutingKey("", "PSVC1")
+		require.Nil(t, apiErr)
+		_, _, apiErr = plugin.getCachedServiceRoutingKey("", "PSVC1")
+		require.Nil(t, apiErr)
+		assert.Equal(t, 2, *requests)
+	})
+}
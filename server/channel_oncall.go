@@ -0,0 +1,390 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
+	"github.com/svelle/mattermost-pagerduty-plugin/server/store/kvstore"
+)
+
+const (
+	// channelOnCallMaxInterval is the longest a channel's on-call display goes without being
+	// refreshed, so that changes PagerDuty does not announce, such as overrides, are picked up.
+	channelOnCallMaxInterval = time.Hour
+
+	// channelHeaderSeparator separates the on-call fragment from the rest of the header.
+	channelHeaderSeparator = " | "
+)
+
+// SetChannelOnCallRequest represents the request to show the current on-calls in a channel
+type SetChannelOnCallRequest struct {
+	ChannelID           string   `json:"channel_id"`
+	Mode                string   `json:"mode"`
+	ScheduleIDs         []string `json:"schedule_ids,omitempty"`
+	EscalationPolicyIDs []string `json:"escalation_policy_ids,omitempty"`
+}
+
+// canManageChannelOnCall reports whether the user may change the on-call display of a
+// channel, which requires being allowed to manage the channel's properties.
+func (p *Plugin) canManageChannelOnCall(userID string, channel *model.Channel) bool {
+	switch channel.Type {
+	case model.ChannelTypeOpen:
+		return p.client.User.HasPermissionToChannel(userID, channel.Id, model.PermissionManagePublicChannelProperties)
+	case model.ChannelTypePrivate:
+		return p.client.User.HasPermissionToChannel(userID, channel.Id, model.PermissionManagePrivateChannelProperties)
+	default:
+		return p.client.User.HasPermissionToChannel(userID, channel.Id, model.PermissionCreatePost)
+	}
+}
+
+// setChannelOnCall validates and stores the on-call display of a channel for targets of an
+// account, replacing any existing one, and shows it right away.
+func (p *Plugin) setChannelOnCall(userID, account string, req *SetChannelOnCallRequest) (*kvstore.ChannelOnCall, *APIError) {
+	if apiErr := p.checkAccount(account); apiErr != nil {
+		return nil, apiErr
+	}
+
+	if req.Mode != kvstore.ChannelOnCallModeHeader && req.Mode != kvstore.ChannelOnCallModePost {
+		return nil, &APIError{
+			ID:         "api.pagerduty.channel_oncall.mode.invalid",
+			Message:    "The mode must be header or post",
+			StatusCode: http.StatusBadRequest,
+		}
+	}
+
+	if len(req.ScheduleIDs) == 0 && len(req.EscalationPolicyIDs) == 0 {
+		return nil, &APIError{
+			ID:         "api.pagerduty.channel_oncall.targets.missing",
+			Message:    "At least one schedule or escalation policy is required",
+			StatusCode: http.StatusBadRequest,
+		}
+	}
+
+	channel, err := p.client.Channel.Get(req.ChannelID)
+	if err != nil || !p.canManageChannelOnCall(userID, channel) {
+		return nil, &APIError{
+			ID:         "api.pagerduty.channel_oncall.permission",
+			Message:    "You do not have permission to manage this channel",
+			StatusCode: http.StatusForbidden,
+		}
+	}
+
+	existing, err := p.kvstore.GetChannelOnCall(req.ChannelID)
+	if err != nil {
+		p.client.Log.Error("Failed to get channel on-call", "error", err.Error(), "channel_id", req.ChannelID)
+		return nil, &APIError{
+			ID:         "api.pagerduty.channel_oncall.get.error",
+			Message:    "Failed to retrieve the on-call display of this channel",
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+
+	channelOnCall := &kvstore.ChannelOnCall{
+		Account:             account,
+		ChannelID:           req.ChannelID,
+		Mode:                req.Mode,
+		ScheduleIDs:         req.ScheduleIDs,
+		EscalationPolicyIDs: req.EscalationPolicyIDs,
+		CreatorID:           userID,
+		CreateAt:            model.GetMillis(),
+	}
+
+	if existing != nil {
+		if existing.Mode == channelOnCall.Mode {
+			// Keep track of what is shown so that it is replaced rather than duplicated.
+			channelOnCall.Fragment = existing.Fragment
+			channelOnCall.PostID = existing.PostID
+		} else if err := p.clearChannelOnCall(existing); err != nil {
+			p.client.Log.Warn("Failed to clear previous channel on-call display", "error", err.Error(), "channel_id", req.ChannelID)
+		}
+	}
+
+	if err := p.refreshChannelOnCall(channelOnCall, time.Now()); err != nil {
+		p.client.Log.Error("Failed to show channel on-calls", "error", err.Error(), "channel_id", req.ChannelID)
+		return nil, &APIError{
+			ID:         "api.pagerduty.channel_oncall.refresh.error",
+			Message:    "Failed to show the current on-calls in this channel",
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+
+	if err := p.kvstore.SaveChannelOnCall(channelOnCall); err != nil {
+		p.client.Log.Error("Failed to save channel on-call", "error", err.Error(), "channel_id", req.ChannelID)
+		return nil, &APIError{
+			ID:         "api.pagerduty.channel_oncall.save.error",
+			Message:    "Failed to save the on-call display of this channel",
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+
+	return channelOnCall, nil
+}
+
+// removeChannelOnCall removes the on-call display of a channel, including the header fragment
+// or pinned post.
+func (p *Plugin) removeChannelOnCall(channelOnCall *kvstore.ChannelOnCall) error {
+	if err := p.clearChannelOnCall(channelOnCall); err != nil {
+		return err
+	}
+	return p.kvstore.DeleteChannelOnCall(channelOnCall.ChannelID)
+}
+
+// requestChannelOnCallRefresh marks every channel on-call display as due, so that they are
+// refreshed on the next run of the background job.
+func (p *Plugin) requestChannelOnCallRefresh() {
+	channelOnCalls, err := p.kvstore.ListChannelOnCalls()
+	if err != nil {
+		p.client.Log.Error("Failed to list channel on-calls", "error", err.Error())
+		return
+	}
+
+	for _, channelOnCall := range channelOnCalls {
+		if channelOnCall.NextUpdateAt == 0 {
+			continue
+		}
+		channelOnCall.NextUpdateAt = 0
+		if err := p.kvstore.SaveChannelOnCall(channelOnCall); err != nil {
+			p.client.Log.Error("Failed to update channel on-call", "error", err.Error(), "channel_id", channelOnCall.ChannelID)
+		}
+	}
+}
+
+// runChannelOnCalls refreshes every channel on-call display that is due, either because a
+// shift boundary has passed or because a refresh was requested.
+func (p *Plugin) runChannelOnCalls(now time.Time) error {
+	channelOnCalls, err := p.kvstore.ListChannelOnCalls()
+	if err != nil {
+		return errors.Wrap(err, "failed to list channel on-calls")
+	}
+
+	for _, channelOnCall := range channelOnCalls {
+		if channelOnCall.NextUpdateAt > now.UnixMilli() {
+			continue
+		}
+
+		if err := p.refreshChannelOnCall(channelOnCall, now); err != nil {
+			p.client.Log.Error("Failed to refresh channel on-calls", "error", err.Error(), "channel_id", channelOnCall.ChannelID)
+			continue
+		}
+
+		if err := p.kvstore.SaveChannelOnCall(channelOnCall); err != nil {
+			p.client.Log.Error("Failed to update channel on-call", "error", err.Error(), "channel_id", channelOnCall.ChannelID)
+		}
+	}
+
+	return nil
+}
+
+// refreshChannelOnCall shows the current on-calls in the channel's header or pinned post and
+// schedules the next refresh for the next shift boundary. The channel is only modified if
+// the on-calls changed.
+func (p *Plugin) refreshChannelOnCall(channelOnCall *kvstore.ChannelOnCall, now time.Time) error {
+	scheduleOnCalls, policyOnCalls, err := p.getOnCallsForTargets(channelOnCall.Account, channelOnCall.ScheduleIDs, channelOnCall.EscalationPolicyIDs)
+	if err != nil {
+		return err
+	}
+
+	entries := p.formatChannelOnCallEntries(channelOnCall, scheduleOnCalls, policyOnCalls)
+
+	switch channelOnCall.Mode {
+	case kvstore.ChannelOnCallModeHeader:
+		err = p.updateChannelHeaderOnCall(channelOnCall, ":pager: "+strings.Join(entries, " · "))
+	case kvstore.ChannelOnCallModePost:
+		err = p.updatePinnedOnCallPost(channelOnCall, "#### :pager: Currently on call\n- "+strings.Join(entries, "\n- "))
+	default:
+		err = errors.Errorf("unknown mode %s", channelOnCall.Mode)
+	}
+	if err != nil {
+		return err
+	}
+
+	channelOnCall.LastUpdateAt = now.UnixMilli()
+	channelOnCall.NextUpdateAt = nextShiftBoundary(append(scheduleOnCalls, policyOnCalls...), now, channelOnCallMaxInterval).UnixMilli()
+	return nil
+}
+
+func (p *Plugin) updateChannelHeaderOnCall(channelOnCall *kvstore.ChannelOnCall, fragment string) error {
+	channel, err := p.client.Channel.Get(channelOnCall.ChannelID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get channel")
+	}
+
+	header := replaceHeaderFragment(channel.Header, channelOnCall.Fragment, fragment)
+	if header == channel.Header {
+		channelOnCall.Fragment = fragment
+		return nil
+	}
+	if utf8.RuneCountInString(header) > model.ChannelHeaderMaxRunes {
+		return errors.New("the on-calls do not fit in the channel header")
+	}
+
+	channel.Header = header
+	if err := p.client.Channel.Update(channel); err != nil {
+		return errors.Wrap(err, "failed to update channel header")
+	}
+
+	channelOnCall.Fragment = fragment
+	return nil
+}
+
+func (p *Plugin) updatePinnedOnCallPost(channelOnCall *kvstore.ChannelOnCall, message string) error {
+	if channelOnCall.PostID != "" {
+		post, err := p.client.Post.GetPost(channelOnCall.PostID)
+		if err == nil && post.DeleteAt == 0 {
+			if post.Message == message {
+				return nil
+			}
+
+			post.Message = message
+			if err := p.client.Post.UpdatePost(post); err != nil {
+				return errors.Wrap(err, "failed to update on-call post")
+			}
+			channelOnCall.Fragment = message
+			return nil
+		}
+	}
+
+	// The post does not exist yet or was deleted by a user.
+	post := &model.Post{
+		UserId:    p.botUserID,
+		ChannelId: channelOnCall.ChannelID,
+		Message:   message,
+		IsPinned:  true,
+	}
+	if err := p.client.Post.CreatePost(post); err != nil {
+		return errors.Wrap(err, "failed to create on-call post")
+	}
+
+	channelOnCall.PostID = post.Id
+	channelOnCall.Fragment = message
+	return nil
+}
+
+// clearChannelOnCall removes the on-calls from the channel, leaving the stored display intact.
+func (p *Plugin) clearChannelOnCall(channelOnCall *kvstore.ChannelOnCall) error {
+	switch channelOnCall.Mode {
+	case kvstore.ChannelOnCallModeHeader:
+		if channelOnCall.Fragment == "" {
+			return nil
+		}
+
+		channel, err := p.client.Channel.Get(channelOnCall.ChannelID)
+		if err != nil {
+			return errors.Wrap(err, "failed to get channel")
+		}
+
+		header := replaceHeaderFragment(channel.Header, channelOnCall.Fragment, "")
+		if header == channel.Header {
+			return nil
+		}
+
+		channel.Header = header
+		if err := p.client.Channel.Update(channel); err != nil {
+			return errors.Wrap(err, "failed to update channel header")
+		}
+
+	case kvstore.ChannelOnCallModePost:
+		if channelOnCall.PostID == "" {
+			return nil
+		}
+
+		if err := p.client.Post.DeletePost(channelOnCall.PostID); err != nil {
+			return errors.Wrap(err, "failed to delete on-call post")
+		}
+	}
+
+	return nil
+}
+
+// formatChannelOnCallEntries returns one entry per target: the users on call for each
+// schedule, and the first two levels of each escalation policy as on-call and secondary.
+func (p *Plugin) formatChannelOnCallEntries(channelOnCall *kvstore.ChannelOnCall, scheduleOnCalls, policyOnCalls []pagerduty.OnCall) []string {
+	var entries []string
+
+	for _, scheduleID := range channelOnCall.ScheduleIDs {
+		var users []string
+		name := scheduleID
+		for _, oncall := range scheduleOnCalls {
+			if oncall.Schedule.ID != scheduleID {
+				continue
+			}
+			name = oncall.Schedule.Name
+			if user := p.formatPagerDutyUser(oncall.User); !slices.Contains(users, user) {
+				users = append(users, user)
+			}
+		}
+
+		if len(users) == 0 {
+			users = []string{"_nobody_"}
+		}
+		entries = append(entries, fmt.Sprintf("**%s:** %s", name, strings.Join(users, ", ")))
+	}
+
+	for _, policyID := range channelOnCall.EscalationPolicyIDs {
+		var primary, secondary []string
+		name := policyID
+		for _, oncall := range policyOnCalls {
+			if oncall.EscalationPolicy == nil || oncall.EscalationPolicy.ID != policyID {
+				continue
+			}
+			name = oncall.EscalationPolicy.Name
+
+			user := p.formatPagerDutyUser(oncall.User)
+			switch oncall.EscalationLevel {
+			case 1:
+				if !slices.Contains(primary, user) {
+					primary = append(primary, user)
+				}
+			case 2:
+				if !slices.Contains(secondary, user) {
+					secondary = append(secondary, user)
+				}
+			}
+		}
+
+		if len(primary) == 0 {
+			primary = []string{"_nobody_"}
+		}
+		entry := fmt.Sprintf("**%s:** %s", name, strings.Join(primary, ", "))
+		if len(secondary) > 0 {
+			entry += fmt.Sprintf(" (secondary: %s)", strings.Join(secondary, ", "))
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+// replaceHeaderFragment replaces the previous on-call fragment of a channel header with a new
+// one, leaving the rest of the header untouched. If the previous fragment is no longer found,
+// e.g. because a user edited the header, the new fragment is appended. An empty fragment
+// removes the previous one along with its separator.
+func replaceHeaderFragment(header, oldFragment, newFragment string) string {
+	if oldFragment != "" && strings.Contains(header, oldFragment) {
+		if newFragment != "" {
+			return strings.Replace(header, oldFragment, newFragment, 1)
+		}
+
+		for _, old := range []string{channelHeaderSeparator + oldFragment, oldFragment + channelHeaderSeparator, oldFragment} {
+			if strings.Contains(header, old) {
+				return strings.Replace(header, old, "", 1)
+			}
+		}
+	}
+
+	if newFragment == "" {
+		return header
+	}
+	if strings.TrimSpace(header) == "" {
+		return newFragment
+	}
+	return header + channelHeaderSeparator + newFragment
+}
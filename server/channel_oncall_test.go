@@ -0,0 +1,112 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
+	"github.com/svelle/mattermost-pagerduty-plugin/server/store/kvstore"
+)
+
+func TestReplaceHeaderFragment(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      string
+		oldFragment string
+		newFragment string
+		want        string
+	}{
+		{
+			name:        "empty header",
+			header:      "",
+			newFragment: "On call: Alice",
+			want:        "On call: Alice",
+		},
+		{
+			name:        "appended to existing header",
+			header:      "Team channel",
+			newFragment: "On call: Alice",
+			want:        "Team channel | On call: Alice",
+		},
+		{
+			name:        "replaced in place",
+			header:      "Team channel | On call: Alice | [Runbook](https://example.com)",
+			oldFragment: "On call: Alice",
+			newFragment: "On call: Bob",
+			want:        "Team channel | On call: Bob | [Runbook](https://example.com)",
+		},
+		{
+			name:        "appended if the previous fragment was edited away",
+			header:      "Edited header",
+			oldFragment: "On call: Alice",
+			newFragment: "On call: Bob",
+			want:        "Edited header | On call: Bob",
+		},
+		{
+			name:        "removed with its separator",
+			header:      "Team channel | On call: Alice",
+			oldFragment: "On call: Alice",
+			want:        "Team channel",
+		},
+		{
+			name:        "removed from the start",
+			header:      "On call: Alice | Team channel",
+			oldFragment: "On call: Alice",
+			want:        "Team channel",
+		},
+		{
+			name:        "removed when alone",
+			header:      "On call: Alice",
+			oldFragment: "On call: Alice",
+			want:        "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, replaceHeaderFragment(tt.header, tt.oldFragment, tt.newFragment))
+		})
+	}
+}
+
+func TestPlugin_formatChannelOnCallEntries(t *testing.T) {
+	plugin := &Plugin{}
+
+	channelOnCall := &kvstore.ChannelOnCall{
+		ScheduleIDs:         []string{"SCHED1", "SCHED2"},
+		EscalationPolicyIDs: []string{"EP1", "EP2"},
+	}
+
+	scheduleOnCalls := []pagerduty.OnCall{
+		{User: pagerduty.User{ID: "USER1", Name: "Alice"}, Schedule: pagerduty.Schedule{ID: "SCHED1", Name: "Primary"}},
+		{User: pagerduty.User{ID: "USER1", Name: "Alice"}, Schedule: pagerduty.Schedule{ID: "SCHED1", Name: "Primary"}},
+	}
+
+	policyOnCalls := []pagerduty.OnCall{
+		{
+			User:             pagerduty.User{ID: "USER3", Name: "Carol"},
+			EscalationPolicy: &pagerduty.EscalationPolicy{ID: "EP1", Name: "Payments"},
+			EscalationLevel:  2,
+		},
+		{
+			User:             pagerduty.User{ID: "USER2", Name: "Bob"},
+			EscalationPolicy: &pagerduty.EscalationPolicy{ID: "EP1", Name: "Payments"},
+			EscalationLevel:  1,
+		},
+		{
+			User:             pagerduty.User{ID: "USER4", Name: "Dave"},
+			EscalationPolicy: &pagerduty.EscalationPolicy{ID: "EP2", Name: "Search"},
+			EscalationLevel:  1,
+		},
+	}
+
+	entries := plugin.formatChannelOnCallEntries(channelOnCall, scheduleOnCalls, policyOnCalls)
+
+	assert.Equal(t, []string{
+		"**Primary:** Alice",
+		"**SCHED2:** _nobody_",
+		"**Payments:** Bob (secondary: Carol)",
+		"**Search:** Dave",
+	}, entries)
+}
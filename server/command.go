@@ -0,0 +1,1333 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/pkg/errors"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
+	"github.com/svelle/mattermost-pagerduty-plugin/server/store/kvstore"
+)
+
+const commandTrigger = "pagerduty"
+
+const commandHelp = "###### PagerDuty Plugin - Slash Command Help\n" +
+	"* `/pagerduty roster add <targets> <time-zone> <cron-expression>` - Post the current on-calls to this channel on a schedule. " +
+	"Targets are a comma-separated list of `schedule:<id>` and `policy:<id>`, e.g. `/pagerduty roster add schedule:PABC123 Europe/Berlin 0 9 * * 1-5`\n" +
+	"* `/pagerduty roster list` - List the roster posts scheduled for this channel\n" +
+	"* `/pagerduty roster remove <id>` - Remove a scheduled roster post\n" +
+	"* `/pagerduty roster post <id>` - Post a roster right away\n" +
+	"* `/pagerduty groupsync add <group-name> <targets>` - Keep a custom user group in sync with who is on call for the targets (plugin admins only)\n" +
+	"* `/pagerduty groupsync list` - List the synced on-call groups\n" +
+	"* `/pagerduty groupsync remove <id>` - Stop syncing an on-call group\n" +
+	"* `/pagerduty groupsync sync <id>` - Sync an on-call group right away\n" +
+	"* `/pagerduty channel header <targets>` - Keep the current on-calls of the targets in this channel's header\n" +
+	"* `/pagerduty channel pin <targets>` - Keep the current on-calls of the targets in a post pinned to this channel\n" +
+	"* `/pagerduty channel refresh` - Refresh the on-calls shown in this channel right away\n" +
+	"* `/pagerduty channel remove` - Stop showing on-calls in this channel\n" +
+	"* `/pagerduty status on|off` - Show in your custom status when you are on call\n" +
+	"* `/pagerduty team set <team-ids>` - Scope requests made from this Mattermost team to a comma-separated list of PagerDuty teams by default (team admins only)\n" +
+	"* `/pagerduty team show` - Show the default PagerDuty teams of this Mattermost team\n" +
+	"* `/pagerduty team clear` - Stop scoping requests made from this Mattermost team\n" +
+	"* `/pagerduty incident subscribe <id>` - Follow an incident in this channel. Its status updates are posted in a thread\n" +
+	"* `/pagerduty incident unsubscribe <id>` - Stop following an incident in this channel\n" +
+	"* `/pagerduty incident list` - List the incidents this channel follows\n" +
+	"* `/pagerduty incident update <id>` - Post a status update on an incident\n" +
+	"* `/pagerduty incident responders <id>` - Ask a user or an escalation policy to help with an incident\n" +
+	"* `/pagerduty incident workflow <id>` - Run an incident workflow on an incident\n" +
+	"* `/pagerduty incident alerts <id>` - List the alerts of an incident, grouped by summary\n" +
+	"* `/pagerduty incident alert resolve <incident-id> <alert-id>` - Resolve a single alert of an incident\n" +
+	"* `/pagerduty incident alert move <incident-id> <alert-id> <to-incident-id>` - Move an alert to another incident\n" +
+	"* `/pagerduty incident merge` - Pick a parent among the open incidents this channel follows and merge others into it\n" +
+	"* `/pagerduty incident related <id>` - List the incidents related to an incident\n" +
+	"* `/pagerduty impact post <targets>` - Post the current impact status to this channel. " +
+	"Targets are a comma-separated list of business services `service:<id>`, or a single status dashboard `dashboard:<id>`\n" +
+	"* `/pagerduty impact schedule <targets> <time-zone> <cron-expression>` - Post the impact status to this channel on a schedule\n" +
+	"* `/pagerduty impact list` - List the impact status posts scheduled for this channel\n" +
+	"* `/pagerduty impact remove <id>` - Remove a scheduled impact status post\n" +
+	"* `/pagerduty impact services` - List the business services and status dashboards\n" +
+	"* `/pagerduty maintenance start <service-ids> <duration> [reason]` - Put a comma-separated list of services into maintenance, e.g. `/pagerduty maintenance start PSVC123 2h Deploying v2.1`. " +
+	"The start and end are announced in this channel\n" +
+	"* `/pagerduty maintenance list` - List the ongoing and upcoming maintenance windows\n" +
+	"* `/pagerduty maintenance end <id>` - End a maintenance window early\n" +
+	"* `/pagerduty change send <service-id> <summary>` - Send a change event, such as a deploy, to a PagerDuty service\n" +
+	"* `/pagerduty change add <service-id> <pattern>` - Send a change event to a service for every message in this channel matching a regular expression. " +
+	"A capture group named `summary` is used as its summary, or else the first line of the message\n" +
+	"* `/pagerduty change list` - List the change event rules of this channel\n" +
+	"* `/pagerduty change remove <id>` - Remove a change event rule\n" +
+	"* `/pagerduty alertrule add <service-id> <pattern>` - Trigger an alert on a service for every message in this channel matching a regular expression (plugin admins only). " +
+	"Use the REST API for author filters, resolve patterns, severities, dedup keys and rate limits\n" +
+	"* `/pagerduty alertrule list` - List the alert rules\n" +
+	"* `/pagerduty alertrule dryrun <id> <on|off>` - Only log the events an alert rule would send, or start sending them\n" +
+	"* `/pagerduty alertrule remove <id>` - Remove an alert rule\n" +
+	"* `/pagerduty pagingpolicy show <service-id>` - Show what it takes to page a service\n" +
+	"* `/pagerduty pagingpolicy list` - List the paging policies of all services (plugin admins only). Policies are set through the REST API\n" +
+	"* `/pagerduty pagingpolicy remove <service-id>` - Remove the paging policy of a service (plugin admins only)\n" +
+	"* `/pagerduty help` - Show this help text\n\n" +
+	"Add `--account <name>` to a command to use another PagerDuty account than the default one, e.g. `/pagerduty incident alerts PINC123 --account eu`"
+
+func getCommand() *model.Command {
+	return &model.Command{
+		Trigger:          commandTrigger,
+		DisplayName:      "PagerDuty",
+		Description:      "Interact with PagerDuty from Mattermost.",
+		AutoComplete:     true,
+		AutoCompleteDesc: "Available commands: roster, groupsync, channel, status, team, incident, impact, maintenance, change, alertrule, pagingpolicy, help",
+		AutoCompleteHint: "[command]",
+		AutocompleteData: getAutocompleteData(),
+	}
+}
+
+func getAutocompleteData() *model.AutocompleteData {
+	pagerduty := model.NewAutocompleteData(commandTrigger, "[command]", "Available commands: roster, groupsync, channel, status, team, incident, impact, maintenance, change, alertrule, pagingpolicy, help")
+
+	roster := model.NewAutocompleteData("roster", "[subcommand]", "Manage scheduled on-call roster posts for this channel")
+
+	rosterAdd := model.NewAutocompleteData("add", "<targets> <time-zone> <cron-expression>", "Schedule a roster post")
+	rosterAdd.AddTextArgument("Comma-separated schedule:<id> and policy:<id> targets", "[targets]", "")
+	rosterAdd.AddTextArgument("IANA time zone, e.g. Europe/Berlin", "[time-zone]", "")
+	rosterAdd.AddTextArgument("Five-field cron expression, e.g. 0 9 * * 1-5", "[cron-expression]", "")
+	roster.AddCommand(rosterAdd)
+
+	roster.AddCommand(model.NewAutocompleteData("list", "", "List the roster posts scheduled for this channel"))
+
+	rosterRemove := model.NewAutocompleteData("remove", "<id>", "Remove a scheduled roster post")
+	rosterRemove.AddTextArgument("Roster ID", "[id]", "")
+	roster.AddCommand(rosterRemove)
+
+	rosterPost := model.NewAutocompleteData("post", "<id>", "Post a roster right away")
+	rosterPost.AddTextArgument("Roster ID", "[id]", "")
+	roster.AddCommand(rosterPost)
+
+	pagerduty.AddCommand(roster)
+
+	groupSync := model.NewAutocompleteData("groupsync", "[subcommand]", "Manage user groups synced with on-call schedules")
+
+	groupSyncAdd := model.NewAutocompleteData("add", "<group-name> <targets>", "Sync a custom user group with the on-calls of the targets")
+	groupSyncAdd.AddTextArgument("Name of the group, e.g. oncall-payments", "[group-name]", "")
+	groupSyncAdd.AddTextArgument("Comma-separated schedule:<id> and policy:<id> targets", "[targets]", "")
+	groupSync.AddCommand(groupSyncAdd)
+
+	groupSync.AddCommand(model.NewAutocompleteData("list", "", "List the synced on-call groups"))
+
+	groupSyncRemove := model.NewAutocompleteData("remove", "<id>", "Stop syncing an on-call group")
+	groupSyncRemove.AddTextArgument("Group sync ID", "[id]", "")
+	groupSync.AddCommand(groupSyncRemove)
+
+	groupSyncSync := model.NewAutocompleteData("sync", "<id>", "Sync an on-call group right away")
+	groupSyncSync.AddTextArgument("Group sync ID", "[id]", "")
+	groupSync.AddCommand(groupSyncSync)
+
+	pagerduty.AddCommand(groupSync)
+
+	channel := model.NewAutocompleteData("channel", "[subcommand]", "Show the current on-calls in this channel")
+
+	channelHeader := model.NewAutocompleteData("header", "<targets>", "Keep the current on-calls in the channel header")
+	channelHeader.AddTextArgument("Comma-separated schedule:<id> and policy:<id> targets", "[targets]", "")
+	channel.AddCommand(channelHeader)
+
+	channelPin := model.NewAutocompleteData("pin", "<targets>", "Keep the current on-calls in a pinned post")
+	channelPin.AddTextArgument("Comma-separated schedule:<id> and policy:<id> targets", "[targets]", "")
+	channel.AddCommand(channelPin)
+
+	channel.AddCommand(model.NewAutocompleteData("refresh", "", "Refresh the on-calls shown in this channel"))
+	channel.AddCommand(model.NewAutocompleteData("remove", "", "Stop showing on-calls in this channel"))
+
+	pagerduty.AddCommand(channel)
+
+	status := model.NewAutocompleteData("status", "[on|off]", "Show in your custom status when you are on call")
+	status.AddStaticListArgument("", true, []model.AutocompleteListItem{
+		{Item: "on", HelpText: "Set your custom status while you are on call"},
+		{Item: "off", HelpText: "Stop setting your custom status"},
+	})
+	pagerduty.AddCommand(status)
+
+	team := model.NewAutocompleteData("team", "[subcommand]", "Manage the default PagerDuty teams of this Mattermost team")
+
+	teamSet := model.NewAutocompleteData("set", "<team-ids>", "Scope requests made from this team to PagerDuty teams")
+	teamSet.AddTextArgument("Comma-separated PagerDuty team IDs", "[team-ids]", "")
+	team.AddCommand(teamSet)
+
+	team.AddCommand(model.NewAutocompleteData("show", "", "Show the default PagerDuty teams"))
+	team.AddCommand(model.NewAutocompleteData("clear", "", "Stop scoping requests made from this team"))
+
+	pagerduty.AddCommand(team)
+
+	incident := model.NewAutocompleteData("incident", "[subcommand]", "Follow incidents and post status updates")
+
+	incidentSubscribe := model.NewAutocompleteData("subscribe", "<id>", "Follow an incident in this channel")
+	incidentSubscribe.AddTextArgument("PagerDuty incident ID", "[id]", "")
+	incident.AddCommand(incidentSubscribe)
+
+	incidentUnsubscribe := model.NewAutocompleteData("unsubscribe", "<id>", "Stop following an incident in this channel")
+	incidentUnsubscribe.AddTextArgument("PagerDuty incident ID", "[id]", "")
+	incident.AddCommand(incidentUnsubscribe)
+
+	incident.AddCommand(model.NewAutocompleteData("list", "", "List the incidents this channel follows"))
+
+	incidentUpdate := model.NewAutocompleteData("update", "<id>", "Post a status update on an incident")
+	incidentUpdate.AddTextArgument("PagerDuty incident ID", "[id]", "")
+	incident.AddCommand(incidentUpdate)
+
+	incidentResponders := model.NewAutocompleteData("responders", "<id>", "Ask a user or an escalation policy to help with an incident")
+	incidentResponders.AddTextArgument("PagerDuty incident ID", "[id]", "")
+	incident.AddCommand(incidentResponders)
+
+	incidentWorkflow := model.NewAutocompleteData("workflow", "<id>", "Run an incident workflow on an incident")
+	incidentWorkflow.AddTextArgument("PagerDuty incident ID", "[id]", "")
+	incident.AddCommand(incidentWorkflow)
+
+	incidentAlerts := model.NewAutocompleteData("alerts", "<id>", "List the alerts of an incident, grouped by summary")
+	incidentAlerts.AddTextArgument("PagerDuty incident ID", "[id]", "")
+	incident.AddCommand(incidentAlerts)
+
+	incidentAlert := model.NewAutocompleteData("alert", "[command]", "Resolve or move a single alert")
+	incidentAlertResolve := model.NewAutocompleteData("resolve", "<incident-id> <alert-id>", "Resolve a single alert of an incident")
+	incidentAlertResolve.AddTextArgument("PagerDuty incident and alert IDs", "[incident-id] [alert-id]", "")
+	incidentAlert.AddCommand(incidentAlertResolve)
+	incidentAlertMove := model.NewAutocompleteData("move", "<incident-id> <alert-id> <to-incident-id>", "Move an alert to another incident")
+	incidentAlertMove.AddTextArgument("PagerDuty incident and alert IDs", "[incident-id] [alert-id] [to-incident-id]", "")
+	incidentAlert.AddCommand(incidentAlertMove)
+	incident.AddCommand(incidentAlert)
+
+	incident.AddCommand(model.NewAutocompleteData("merge", "", "Merge incidents this channel follows into a parent incident"))
+
+	incidentRelated := model.NewAutocompleteData("related", "<id>", "List the incidents related to an incident")
+	incidentRelated.AddTextArgument("PagerDuty incident ID", "[id]", "")
+	incident.AddCommand(incidentRelated)
+
+	pagerduty.AddCommand(incident)
+
+	impact := model.NewAutocompleteData("impact", "[subcommand]", "Show the impact status of business services")
+
+	impactPost := model.NewAutocompleteData("post", "<targets>", "Post the current impact status to this channel")
+	impactPost.AddTextArgument("Comma-separated service:<id> business services, or dashboard:<id>", "[targets]", "")
+	impact.AddCommand(impactPost)
+
+	impactSchedule := model.NewAutocompleteData("schedule", "<targets> <time-zone> <cron-expression>", "Post the impact status on a schedule")
+	impactSchedule.AddTextArgument("Comma-separated service:<id> business services, or dashboard:<id>", "[targets]", "")
+	impactSchedule.AddTextArgument("IANA time zone, e.g. Europe/Berlin", "[time-zone]", "")
+	impactSchedule.AddTextArgument("Five-field cron expression, e.g. 0 9 * * 1-5", "[cron-expression]", "")
+	impact.AddCommand(impactSchedule)
+
+	impact.AddCommand(model.NewAutocompleteData("list", "", "List the impact status posts scheduled for this channel"))
+
+	impactRemove := model.NewAutocompleteData("remove", "<id>", "Remove a scheduled impact status post")
+	impactRemove.AddTextArgument("Impact post ID", "[id]", "")
+	impact.AddCommand(impactRemove)
+
+	impact.AddCommand(model.NewAutocompleteData("services", "", "List the business services and status dashboards"))
+
+	pagerduty.AddCommand(impact)
+
+	maintenance := model.NewAutocompleteData("maintenance", "[subcommand]", "Manage PagerDuty maintenance windows")
+
+	maintenanceStart := model.NewAutocompleteData("start", "<service-ids> <duration> [reason]", "Put services into maintenance")
+	maintenanceStart.AddTextArgument("Comma-separated PagerDuty service IDs", "[service-ids]", "")
+	maintenanceStart.AddTextArgument("Duration, e.g. 30m or 2h", "[duration]", "")
+	maintenanceStart.AddTextArgument("Reason for the maintenance", "[reason]", "")
+	maintenance.AddCommand(maintenanceStart)
+
+	maintenance.AddCommand(model.NewAutocompleteData("list", "", "List the ongoing and upcoming maintenance windows"))
+
+	maintenanceEnd := model.NewAutocompleteData("end", "<id>", "End a maintenance window early")
+	maintenanceEnd.AddTextArgument("Maintenance window ID", "[id]", "")
+	maintenance.AddCommand(maintenanceEnd)
+
+	pagerduty.AddCommand(maintenance)
+
+	change := model.NewAutocompleteData("change", "[subcommand]", "Send PagerDuty change events")
+
+	changeSend := model.NewAutocompleteData("send", "<service-id> <summary>", "Send a change event to a service")
+	changeSend.AddTextArgument("PagerDuty service ID", "[service-id]", "")
+	changeSend.AddTextArgument("Summary of the change, e.g. Deployed v2.1", "[summary]", "")
+	change.AddCommand(changeSend)
+
+	changeAdd := model.NewAutocompleteData("add", "<service-id> <pattern>", "Send a change event for messages in this channel matching a pattern")
+	changeAdd.AddTextArgument("PagerDuty service ID", "[service-id]", "")
+	changeAdd.AddTextArgument("Regular expression, e.g. ^Deployed (?P<summary>.+)", "[pattern]", "")
+	change.AddCommand(changeAdd)
+
+	change.AddCommand(model.NewAutocompleteData("list", "", "List the change event rules of this channel"))
+
+	changeRemove := model.NewAutocompleteData("remove", "<id>", "Remove a change event rule")
+	changeRemove.AddTextArgument("Change event rule ID", "[id]", "")
+	change.AddCommand(changeRemove)
+
+	pagerduty.AddCommand(change)
+
+	alertRule := model.NewAutocompleteData("alertrule", "[subcommand]", "Trigger PagerDuty alerts from channel messages")
+
+	alertRuleAdd := model.NewAutocompleteData("add", "<service-id> <pattern>", "Trigger an alert for messages in this channel matching a pattern")
+	alertRuleAdd.AddTextArgument("PagerDuty service ID", "[service-id]", "")
+	alertRuleAdd.AddTextArgument("Regular expression, e.g. ^CRITICAL (?P<summary>.+)", "[pattern]", "")
+	alertRule.AddCommand(alertRuleAdd)
+
+	alertRule.AddCommand(model.NewAutocompleteData("list", "", "List the alert rules"))
+
+	alertRuleDryRun := model.NewAutocompleteData("dryrun", "<id> <on|off>", "Only log the events an alert rule would send")
+	alertRuleDryRun.AddTextArgument("Alert rule ID", "[id]", "")
+	alertRuleDryRun.AddStaticListArgument("Dry run", true, []model.AutocompleteListItem{
+		{Item: "on", HelpText: "Only log events"},
+		{Item: "off", HelpText: "Send events to PagerDuty"},
+	})
+	alertRule.AddCommand(alertRuleDryRun)
+
+	alertRuleRemove := model.NewAutocompleteData("remove", "<id>", "Remove an alert rule")
+	alertRuleRemove.AddTextArgument("Alert rule ID", "[id]", "")
+	alertRule.AddCommand(alertRuleRemove)
+
+	pagerduty.AddCommand(alertRule)
+
+	pagingPolicy := model.NewAutocompleteData("pagingpolicy", "[subcommand]", "View and manage the paging policies of services")
+
+	pagingPolicyShow := model.NewAutocompleteData("show", "<service-id>", "Show what it takes to page a service")
+	pagingPolicyShow.AddTextArgument("PagerDuty service ID", "[service-id]", "")
+	pagingPolicy.AddCommand(pagingPolicyShow)
+
+	pagingPolicy.AddCommand(model.NewAutocompleteData("list", "", "List the paging policies of all services"))
+
+	pagingPolicyRemove := model.NewAutocompleteData("remove", "<service-id>", "Remove the paging policy of a service")
+	pagingPolicyRemove.AddTextArgument("PagerDuty service ID", "[service-id]", "")
+	pagingPolicy.AddCommand(pagingPolicyRemove)
+
+	pagerduty.AddCommand(pagingPolicy)
+	pagerduty.AddCommand(model.NewAutocompleteData("help", "", "Show help"))
+
+	return pagerduty
+}
+
+// registerCommands registers the plugin's slash commands with the server.
+func (p *Plugin) registerCommands() error {
+	if err := p.client.SlashCommand.Register(getCommand()); err != nil {
+		return errors.Wrap(err, "failed to register command")
+	}
+	return nil
+}
+
+// ExecuteCommand executes a command that has been previously registered via the RegisterCommand API.
+func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
+	fields, account, ok := parseCommandAccount(strings.Fields(args.Command))
+	if !ok {
+		return commandResponse("Usage: `--account <name>`"), nil
+	}
+	if len(fields) < 2 {
+		return commandResponse(commandHelp), nil
+	}
+	if apiErr := p.checkAccount(account); apiErr != nil {
+		return commandResponse(apiErr.Message + "."), nil
+	}
+
+	if permission := commandPermission(fields[1:]); permission != "" {
+		if apiErr := p.checkPermission(args.UserId, permission); apiErr != nil {
+			return commandResponse(apiErr.Message + "."), nil
+		}
+	}
+
+	switch fields[1] {
+	case "roster":
+		return p.executeRosterCommand(args, account, fields[2:]), nil
+	case "groupsync":
+		return p.executeGroupSyncCommand(args, account, fields[2:]), nil
+	case "channel":
+		return p.executeChannelCommand(args, account, fields[2:]), nil
+	case "status":
+		return p.executeStatusCommand(args, account, fields[2:]), nil
+	case "team":
+		return p.executeTeamCommand(args, account, fields[2:]), nil
+	case "incident":
+		return p.executeIncidentCommand(args, account, fields[2:]), nil
+	case "impact":
+		return p.executeImpactCommand(args, account, fields[2:]), nil
+	case "maintenance":
+		return p.executeMaintenanceCommand(args, account, fields[2:]), nil
+	case "change":
+		return p.executeChangeCommand(args, account, fields[2:]), nil
+	case "alertrule":
+		return p.executeAlertRuleCommand(args, account, fields[2:]), nil
+	case "pagingpolicy":
+		return p.executePagingPolicyCommand(args, account, fields[2:]), nil
+	case "help":
+		return commandResponse(commandHelp), nil
+	default:
+		return commandResponse(fmt.Sprintf("Unknown command `%s`.\n%s", fields[1], commandHelp)), nil
+	}
+}
+
+// parseCommandAccount removes the `--account <name>` option from the fields of a command and
+// returns the normalized name of the account it selects, the default account if it is omitted.
+// It returns false if the option has no name.
+func parseCommandAccount(fields []string) ([]string, string, bool) {
+	for i, field := range fields {
+		if field != "--account" {
+			continue
+		}
+		if i+1 == len(fields) {
+			return nil, "", false
+		}
+		account := normalizeAccountName(fields[i+1])
+		return append(fields[:i:i], fields[i+2:]...), account, true
+	}
+	return fields, "", true
+}
+
+func (p *Plugin) executeRosterCommand(args *model.CommandArgs, account string, fields []string) *model.CommandResponse {
+	if len(fields) == 0 {
+		return commandResponse(commandHelp)
+	}
+
+	if err := p.getConfiguration().IsValid(); err != nil {
+		return commandResponse("The PagerDuty plugin is not configured. Please contact your system administrator.")
+	}
+
+	switch fields[0] {
+	case "add":
+		if len(fields) < 4 {
+			return commandResponse("Usage: `/pagerduty roster add <targets> <time-zone> <cron-expression>`")
+		}
+
+		scheduleIDs, policyIDs, err := parseTargets(fields[1])
+		if err != nil {
+			return commandResponse(fmt.Sprintf("Failed to parse targets: %s. Use `schedule:<id>` or `policy:<id>`.", err.Error()))
+		}
+
+		req := &CreateRosterRequest{
+			ChannelID:           args.ChannelId,
+			TimeZone:            fields[2],
+			CronExpression:      strings.Join(fields[3:], " "),
+			ScheduleIDs:         scheduleIDs,
+			EscalationPolicyIDs: policyIDs,
+		}
+
+		roster, apiErr := p.createRoster(args.UserId, account, req)
+		if apiErr != nil {
+			return commandResponse(apiErr.Message)
+		}
+
+		next, _ := nextRosterPost(roster)
+		return commandResponse(fmt.Sprintf("Scheduled roster `%s`. The next post is on %s.", roster.ID, next.Format("Mon Jan 2, 15:04 MST")))
+
+	case "list":
+		rosters, err := p.getChannelRosters(args.ChannelId)
+		if err != nil {
+			p.client.Log.Error("Failed to list rosters", "error", err.Error())
+			return commandResponse("Failed to list the rosters of this channel.")
+		}
+		if len(rosters) == 0 {
+			return commandResponse("No roster posts are scheduled for this channel.")
+		}
+
+		var sb strings.Builder
+		sb.WriteString("| ID | Schedule | Time zone | Schedules | Escalation policies |\n|---|---|---|---|---|\n")
+		for _, roster := range rosters {
+			fmt.Fprintf(&sb, "| `%s` | `%s` | %s | %s | %s |\n", roster.ID, roster.CronExpression, roster.TimeZone,
+				strings.Join(roster.ScheduleIDs, ", "), strings.Join(roster.EscalationPolicyIDs, ", "))
+		}
+		return commandResponse(sb.String())
+
+	case "remove", "post":
+		if len(fields) != 2 {
+			return commandResponse(fmt.Sprintf("Usage: `/pagerduty roster %s <id>`", fields[0]))
+		}
+
+		roster, err := p.kvstore.GetRoster(fields[1])
+		if err != nil {
+			p.client.Log.Error("Failed to get roster", "error", err.Error(), "roster_id", fields[1])
+			return commandResponse("Failed to retrieve the roster.")
+		}
+		if roster == nil || !p.canManageRosters(args.UserId, roster.ChannelID) {
+			return commandResponse(fmt.Sprintf("Roster `%s` not found.", fields[1]))
+		}
+
+		if fields[0] == "post" {
+			if err := p.postRoster(roster); err != nil {
+				p.client.Log.Error("Failed to post roster", "error", err.Error(), "roster_id", roster.ID)
+				return commandResponse("Failed to post the roster.")
+			}
+			return commandResponse(fmt.Sprintf("Posted roster `%s`.", roster.ID))
+		}
+
+		if err := p.kvstore.DeleteRoster(roster.ID); err != nil {
+			p.client.Log.Error("Failed to delete roster", "error", err.Error(), "roster_id", roster.ID)
+			return commandResponse("Failed to remove the roster.")
+		}
+		return commandResponse(fmt.Sprintf("Removed roster `%s`.", roster.ID))
+
+	default:
+		return commandResponse(fmt.Sprintf("Unknown roster command `%s`.\n%s", fields[0], commandHelp))
+	}
+}
+
+func (p *Plugin) executeGroupSyncCommand(args *model.CommandArgs, account string, fields []string) *model.CommandResponse {
+	if len(fields) == 0 {
+		return commandResponse(commandHelp)
+	}
+
+	if err := p.getConfiguration().IsValid(); err != nil {
+		return commandResponse("The PagerDuty plugin is not configured. Please contact your system administrator.")
+	}
+
+	if !p.canManageGroupSyncs(args.UserId) {
+		return commandResponse("Only plugin admins can manage on-call groups.")
+	}
+
+	switch fields[0] {
+	case "add":
+		if len(fields) != 3 {
+			return commandResponse("Usage: `/pagerduty groupsync add <group-name> <targets>`")
+		}
+
+		scheduleIDs, policyIDs, err := parseTargets(fields[2])
+		if err != nil {
+			return commandResponse(fmt.Sprintf("Failed to parse targets: %s. Use `schedule:<id>` or `policy:<id>`.", err.Error()))
+		}
+
+		groupSync, apiErr := p.createGroupSync(args.UserId, account, &CreateGroupSyncRequest{
+			GroupName:           fields[1],
+			ScheduleIDs:         scheduleIDs,
+			EscalationPolicyIDs: policyIDs,
+		})
+		if apiErr != nil {
+			return commandResponse(apiErr.Message)
+		}
+
+		return commandResponse(fmt.Sprintf("Syncing @%s with the on-calls of its targets as `%s`. Members are updated within a minute.", groupSync.GroupName, groupSync.ID))
+
+	case "list":
+		groupSyncs, err := p.kvstore.ListGroupSyncs()
+		if err != nil {
+			p.client.Log.Error("Failed to list group syncs", "error", err.Error())
+			return commandResponse("Failed to list the on-call groups.")
+		}
+		if len(groupSyncs) == 0 {
+			return commandResponse("No on-call groups are synced.")
+		}
+
+		var sb strings.Builder
+		sb.WriteString("| ID | Group | Schedules | Escalation policies | Last synced |\n|---|---|---|---|---|\n")
+		for _, groupSync := range groupSyncs {
+			lastSync := "Never"
+			if groupSync.LastSyncAt > 0 {
+				lastSync = time.UnixMilli(groupSync.LastSyncAt).UTC().Format("Mon Jan 2, 15:04 MST")
+			}
+			fmt.Fprintf(&sb, "| `%s` | @%s | %s | %s | %s |\n", groupSync.ID, groupSync.GroupName,
+				strings.Join(groupSync.ScheduleIDs, ", "), strings.Join(groupSync.EscalationPolicyIDs, ", "), lastSync)
+		}
+		return commandResponse(sb.String())
+
+	case "remove":
+		if len(fields) != 2 {
+			return commandResponse("Usage: `/pagerduty groupsync remove <id>`")
+		}
+
+		groupSync, apiErr := p.deleteGroupSync(args.UserId, fields[1])
+		if apiErr != nil {
+			return commandResponse(apiErr.Message + ".")
+		}
+		return commandResponse(fmt.Sprintf("Stopped syncing @%s. The group and its current members were kept.", groupSync.GroupName))
+
+	case "sync":
+		if len(fields) != 2 {
+			return commandResponse("Usage: `/pagerduty groupsync sync <id>`")
+		}
+
+		groupSync, err := p.kvstore.GetGroupSync(fields[1])
+		if err != nil {
+			p.client.Log.Error("Failed to get group sync", "error", err.Error(), "group_sync_id", fields[1])
+			return commandResponse("Failed to retrieve the on-call group.")
+		}
+		if groupSync == nil {
+			return commandResponse(fmt.Sprintf("On-call group `%s` not found.", fields[1]))
+		}
+
+		old := *groupSync
+		if err := p.reconcileGroupSync(groupSync, time.Now()); err != nil {
+			p.client.Log.Error("Failed to sync on-call group", "error", err.Error(), "group", groupSync.GroupName)
+			return commandResponse("Failed to sync the on-call group.")
+		}
+		p.saveReconciledGroupSync(&old, groupSync)
+		return commandResponse(fmt.Sprintf("Synced @%s.", groupSync.GroupName))
+
+	default:
+		return commandResponse(fmt.Sprintf("Unknown groupsync command `%s`.\n%s", fields[0], commandHelp))
+	}
+}
+
+func (p *Plugin) executeChannelCommand(args *model.CommandArgs, account string, fields []string) *model.CommandResponse {
+	if len(fields) == 0 {
+		return commandResponse(commandHelp)
+	}
+
+	if err := p.getConfiguration().IsValid(); err != nil {
+		return commandResponse("The PagerDuty plugin is not configured. Please contact your system administrator.")
+	}
+
+	switch fields[0] {
+	case "header", "pin":
+		if len(fields) != 2 {
+			return commandResponse(fmt.Sprintf("Usage: `/pagerduty channel %s <targets>`", fields[0]))
+		}
+
+		scheduleIDs, policyIDs, err := parseTargets(fields[1])
+		if err != nil {
+			return commandResponse(fmt.Sprintf("Failed to parse targets: %s. Use `schedule:<id>` or `policy:<id>`.", err.Error()))
+		}
+
+		mode := kvstore.ChannelOnCallModeHeader
+		if fields[0] == "pin" {
+			mode = kvstore.ChannelOnCallModePost
+		}
+
+		_, apiErr := p.setChannelOnCall(args.UserId, account, &SetChannelOnCallRequest{
+			ChannelID:           args.ChannelId,
+			Mode:                mode,
+			ScheduleIDs:         scheduleIDs,
+			EscalationPolicyIDs: policyIDs,
+		})
+		if apiErr != nil {
+			return commandResponse(apiErr.Message)
+		}
+
+		return commandResponse("The current on-calls are now shown in this channel and refreshed at every handoff.")
+
+	case "refresh", "remove":
+		if len(fields) != 1 {
+			return commandResponse(fmt.Sprintf("Usage: `/pagerduty channel %s`", fields[0]))
+		}
+
+		channel, err := p.client.Channel.Get(args.ChannelId)
+		if err != nil || !p.canManageChannelOnCall(args.UserId, channel) {
+			return commandResponse("You do not have permission to manage this channel.")
+		}
+
+		channelOnCall, err := p.kvstore.GetChannelOnCall(args.ChannelId)
+		if err != nil {
+			p.client.Log.Error("Failed to get channel on-call", "error", err.Error(), "channel_id", args.ChannelId)
+			return commandResponse("Failed to retrieve the on-call display of this channel.")
+		}
+		if channelOnCall == nil {
+			return commandResponse("This channel does not show any on-calls.")
+		}
+
+		if fields[0] == "refresh" {
+			if err := p.refreshChannelOnCall(channelOnCall, time.Now()); err != nil {
+				p.client.Log.Error("Failed to refresh channel on-calls", "error", err.Error(), "channel_id", args.ChannelId)
+				return commandResponse("Failed to refresh the on-calls of this channel.")
+			}
+			if err := p.kvstore.SaveChannelOnCall(channelOnCall); err != nil {
+				p.client.Log.Error("Failed to update channel on-call", "error", err.Error(), "channel_id", args.ChannelId)
+			}
+			return commandResponse("Refreshed the on-calls of this channel.")
+		}
+
+		if err := p.removeChannelOnCall(channelOnCall); err != nil {
+			p.client.Log.Error("Failed to remove channel on-call", "error", err.Error(), "channel_id", args.ChannelId)
+			return commandResponse("Failed to stop showing on-calls in this channel.")
+		}
+		return commandResponse("This channel no longer shows on-calls.")
+
+	default:
+		return commandResponse(fmt.Sprintf("Unknown channel command `%s`.\n%s", fields[0], commandHelp))
+	}
+}
+
+func (p *Plugin) executeStatusCommand(args *model.CommandArgs, account string, fields []string) *model.CommandResponse {
+	if len(fields) != 1 || (fields[0] != "on" && fields[0] != "off") {
+		return commandResponse("Usage: `/pagerduty status on|off`")
+	}
+	if account != "" {
+		return commandResponse("On-call custom statuses use the default PagerDuty account.")
+	}
+
+	config := p.getConfiguration()
+	if err := config.IsValid(); err != nil {
+		return commandResponse("The PagerDuty plugin is not configured. Please contact your system administrator.")
+	}
+
+	if fields[0] == "off" {
+		if err := p.disableOnCallStatus(args.UserId); err != nil {
+			p.client.Log.Error("Failed to disable on-call status", "error", err.Error(), "user_id", args.UserId)
+			return commandResponse("Failed to turn off your on-call status.")
+		}
+		return commandResponse("Your custom status will no longer be set while you are on call.")
+	}
+
+	if !config.EnableOnCallStatus {
+		return commandResponse("On-call custom statuses are not enabled. Please contact your system administrator.")
+	}
+
+	user, err := p.client.User.Get(args.UserId)
+	if err != nil {
+		p.client.Log.Error("Failed to get user", "error", err.Error(), "user_id", args.UserId)
+		return commandResponse("Failed to turn on your on-call status.")
+	}
+
+	status, err := p.enableOnCallStatus(user)
+	if err != nil {
+		p.client.Log.Error("Failed to enable on-call status", "error", err.Error(), "user_id", args.UserId)
+		return commandResponse("Failed to turn on your on-call status.")
+	}
+	if status == nil {
+		return commandResponse(fmt.Sprintf("No PagerDuty user was found with your email address %s.", user.Email))
+	}
+
+	return commandResponse("Your custom status will be set while you are on call. It is updated within a minute of every shift change.")
+}
+
+func (p *Plugin) executeTeamCommand(args *model.CommandArgs, account string, fields []string) *model.CommandResponse {
+	if len(fields) == 0 {
+		return commandResponse(commandHelp)
+	}
+
+	config := p.getConfiguration()
+	if err := config.IsValid(); err != nil {
+		return commandResponse("The PagerDuty plugin is not configured. Please contact your system administrator.")
+	}
+
+	switch fields[0] {
+	case "show":
+		mapping, err := p.kvstore.GetTeamMapping(account, args.TeamId)
+		if err != nil {
+			p.client.Log.Error("Failed to get team mapping", "error", err.Error(), "team_id", args.TeamId)
+			return commandResponse("Failed to retrieve the PagerDuty teams of this team.")
+		}
+		if mapping == nil || len(mapping.PagerDutyTeamIDs) == 0 {
+			return commandResponse("Requests made from this team are not scoped to any PagerDuty teams.")
+		}
+		return commandResponse(fmt.Sprintf("Requests made from this team are scoped to the PagerDuty teams %s.", p.formatPagerDutyTeams(account, mapping.PagerDutyTeamIDs)))
+
+	case "set", "clear":
+		if !p.canManageTeamMapping(args.UserId, args.TeamId) {
+			return commandResponse("Only team admins can change the PagerDuty teams of this team.")
+		}
+
+		if fields[0] == "clear" {
+			if err := p.kvstore.DeleteTeamMapping(account, args.TeamId); err != nil {
+				p.client.Log.Error("Failed to delete team mapping", "error", err.Error(), "team_id", args.TeamId)
+				return commandResponse("Failed to clear the PagerDuty teams of this team.")
+			}
+			return commandResponse("Requests made from this team are no longer scoped to PagerDuty teams.")
+		}
+
+		if len(fields) != 2 {
+			return commandResponse("Usage: `/pagerduty team set <team-ids>`")
+		}
+
+		teamIDs := parseIDList(fields[1:])
+		client, apiErr := p.accountClient(account)
+		if apiErr != nil {
+			return commandResponse(apiErr.Message)
+		}
+		for _, teamID := range teamIDs {
+			if _, err := client.GetTeam(teamID); err != nil {
+				p.client.Log.Warn("Failed to get PagerDuty team", "error", err.Error(), "pagerduty_team_id", teamID)
+				return commandResponse(fmt.Sprintf("PagerDuty team `%s` was not found.", teamID))
+			}
+		}
+
+		mapping := &kvstore.TeamMapping{
+			Account:          account,
+			TeamID:           args.TeamId,
+			PagerDutyTeamIDs: teamIDs,
+			UpdatedBy:        args.UserId,
+			UpdateAt:         model.GetMillis(),
+		}
+		if err := p.kvstore.SaveTeamMapping(mapping); err != nil {
+			p.client.Log.Error("Failed to save team mapping", "error", err.Error(), "team_id", args.TeamId)
+			return commandResponse("Failed to save the PagerDuty teams of this team.")
+		}
+		return commandResponse(fmt.Sprintf("Requests made from this team are now scoped to the PagerDuty teams %s.", p.formatPagerDutyTeams(account, teamIDs)))
+
+	default:
+		return commandResponse(fmt.Sprintf("Unknown team command `%s`.\n%s", fields[0], commandHelp))
+	}
+}
+
+func (p *Plugin) executeIncidentCommand(args *model.CommandArgs, account string, fields []string) *model.CommandResponse {
+	if len(fields) == 0 {
+		return commandResponse(commandHelp)
+	}
+
+	if err := p.getConfiguration().IsValid(); err != nil {
+		return commandResponse("The PagerDuty plugin is not configured. Please contact your system administrator.")
+	}
+
+	switch fields[0] {
+	case "list":
+		subscriptions, err := p.getChannelIncidentSubscriptions(args.ChannelId)
+		if err != nil {
+			p.client.Log.Error("Failed to list incident subscriptions", "error", err.Error())
+			return commandResponse("Failed to list the incidents of this channel.")
+		}
+		if len(subscriptions) == 0 {
+			return commandResponse("This channel does not follow any incidents.")
+		}
+
+		var sb strings.Builder
+		sb.WriteString("###### Incidents followed in this channel\n")
+		for _, subscription := range subscriptions {
+			fmt.Fprintf(&sb, "* `%s` - [summary](/_redirect/pl/%s)\n", subscription.IncidentID, subscription.PostID)
+		}
+		return commandResponse(sb.String())
+
+	case "merge":
+		message, err := p.openMergeDialog(args.TriggerId, account, args.ChannelId)
+		if err != nil {
+			p.client.Log.Error("Failed to open merge dialog", "error", err.Error(), "channel_id", args.ChannelId)
+			return commandResponse("Failed to open the merge dialog.")
+		}
+		if message != "" {
+			return commandResponse(message)
+		}
+		return &model.CommandResponse{}
+
+	case "related":
+		if len(fields) != 2 {
+			return commandResponse("Usage: `/pagerduty incident related <id>`")
+		}
+
+		client, apiErr := p.accountClient(account)
+		if apiErr != nil {
+			return commandResponse(apiErr.Message)
+		}
+
+		related, err := client.ListRelatedIncidents(fields[1])
+		if err != nil {
+			p.client.Log.Error("Failed to get related incidents from PagerDuty", "error", err.Error(), "incident_id", fields[1])
+			return commandResponse("Failed to retrieve related incidents.")
+		}
+		return commandResponse(formatRelatedIncidents(fields[1], related.RelatedIncidents))
+
+	case "alerts":
+		if len(fields) != 2 {
+			return commandResponse("Usage: `/pagerduty incident alerts <id>`")
+		}
+
+		groups, apiErr := p.getIncidentAlertGroups(account, fields[1])
+		if apiErr != nil {
+			return commandResponse(apiErr.Message)
+		}
+		return commandResponse(formatAlertGroups(groups))
+
+	case "alert":
+		req := &UpdateAlertRequest{}
+		switch {
+		case len(fields) == 4 && fields[1] == "resolve":
+			req.Status = pagerduty.AlertStatusResolved
+		case len(fields) == 5 && fields[1] == "move":
+			req.IncidentID = fields[4]
+		default:
+			return commandResponse("Usage: `/pagerduty incident alert resolve <incident-id> <alert-id>` or `/pagerduty incident alert move <incident-id> <alert-id> <to-incident-id>`")
+		}
+
+		if _, apiErr := p.updateAlert(args.UserId, account, fields[2], fields[3], req); apiErr != nil {
+			return commandResponse(apiErr.Message)
+		}
+		if req.IncidentID != "" {
+			return commandResponse(fmt.Sprintf("Moved alert `%s` to incident `%s`.", fields[3], req.IncidentID))
+		}
+		return commandResponse(fmt.Sprintf("Resolved alert `%s`.", fields[3]))
+
+	case "subscribe", "unsubscribe", "update", "workflow", "responders":
+		if len(fields) != 2 {
+			return commandResponse(fmt.Sprintf("Usage: `/pagerduty incident %s <id>`", fields[0]))
+		}
+		incidentID := fields[1]
+
+		switch fields[0] {
+		case "subscribe":
+			if _, apiErr := p.subscribeChannelToIncident(args.UserId, account, args.ChannelId, incidentID); apiErr != nil {
+				return commandResponse(apiErr.Message)
+			}
+			return commandResponse(fmt.Sprintf("This channel now follows incident `%s`.", incidentID))
+
+		case "unsubscribe":
+			if apiErr := p.unsubscribeChannelFromIncident(args.UserId, account, args.ChannelId, incidentID); apiErr != nil {
+				return commandResponse(apiErr.Message)
+			}
+			return commandResponse(fmt.Sprintf("This channel no longer follows incident `%s`.", incidentID))
+
+		case "responders":
+			if err := p.openResponderDialog(args.TriggerId, account, incidentID); err != nil {
+				p.client.Log.Error("Failed to open responder dialog", "error", err.Error(), "incident_id", incidentID)
+				return commandResponse("Failed to open the responder dialog.")
+			}
+			return &model.CommandResponse{}
+
+		case "workflow":
+			message, err := p.openIncidentWorkflowDialog(args.TriggerId, account, incidentID)
+			if err != nil {
+				p.client.Log.Error("Failed to open incident workflow dialog", "error", err.Error(), "incident_id", incidentID)
+				return commandResponse("Failed to open the workflow dialog.")
+			}
+			if message != "" {
+				return commandResponse(message)
+			}
+			return &model.CommandResponse{}
+
+		default:
+			if err := p.openStatusUpdateDialog(args.TriggerId, account, incidentID); err != nil {
+				p.client.Log.Error("Failed to open status update dialog", "error", err.Error(), "incident_id", incidentID)
+				return commandResponse("Failed to open the status update dialog.")
+			}
+			return &model.CommandResponse{}
+		}
+
+	default:
+		return commandResponse(fmt.Sprintf("Unknown incident command `%s`.\n%s", fields[0], commandHelp))
+	}
+}
+
+func (p *Plugin) executeImpactCommand(args *model.CommandArgs, account string, fields []string) *model.CommandResponse {
+	if len(fields) == 0 {
+		return commandResponse(commandHelp)
+	}
+
+	config := p.getConfiguration()
+	if err := config.IsValid(); err != nil {
+		return commandResponse("The PagerDuty plugin is not configured. Please contact your system administrator.")
+	}
+
+	switch fields[0] {
+	case "post":
+		if len(fields) != 2 {
+			return commandResponse("Usage: `/pagerduty impact post <targets>`")
+		}
+
+		targets, err := parseImpactTargets(fields[1])
+		if err != nil {
+			return commandResponse(fmt.Sprintf("Failed to parse targets: %s. Use `service:<id>` or `dashboard:<id>`.", err.Error()))
+		}
+
+		if apiErr := p.postImpact(args.UserId, account, &PostImpactRequest{ImpactTargets: targets, ChannelID: args.ChannelId}); apiErr != nil {
+			return commandResponse(apiErr.Message)
+		}
+		return &model.CommandResponse{}
+
+	case "schedule":
+		if len(fields) < 4 {
+			return commandResponse("Usage: `/pagerduty impact schedule <targets> <time-zone> <cron-expression>`")
+		}
+
+		targets, err := parseImpactTargets(fields[1])
+		if err != nil {
+			return commandResponse(fmt.Sprintf("Failed to parse targets: %s. Use `service:<id>` or `dashboard:<id>`.", err.Error()))
+		}
+
+		req := &CreateImpactPostRequest{
+			ImpactTargets:  targets,
+			ChannelID:      args.ChannelId,
+			TimeZone:       fields[2],
+			CronExpression: strings.Join(fields[3:], " "),
+		}
+
+		post, apiErr := p.createImpactPost(args.UserId, account, req)
+		if apiErr != nil {
+			return commandResponse(apiErr.Message)
+		}
+
+		next, _ := nextScheduledPost(post.CronExpression, post.TimeZone, post.CreateAt)
+		return commandResponse(fmt.Sprintf("Scheduled impact status post `%s`. The next post is on %s.", post.ID, next.Format("Mon Jan 2, 15:04 MST")))
+
+	case "list":
+		posts, err := p.getChannelImpactPosts(args.ChannelId)
+		if err != nil {
+			p.client.Log.Error("Failed to list impact posts", "error", err.Error())
+			return commandResponse("Failed to list the impact status posts of this channel.")
+		}
+		if len(posts) == 0 {
+			return commandResponse("No impact status posts are scheduled for this channel.")
+		}
+
+		var sb strings.Builder
+		sb.WriteString("| ID | Schedule | Time zone | Business services | Status dashboard |\n|---|---|---|---|---|\n")
+		for _, post := range posts {
+			fmt.Fprintf(&sb, "| `%s` | `%s` | %s | %s | %s |\n", post.ID, post.CronExpression, post.TimeZone,
+				strings.Join(post.BusinessServiceIDs, ", "), post.StatusDashboardID)
+		}
+		return commandResponse(sb.String())
+
+	case "remove":
+		if len(fields) != 2 {
+			return commandResponse("Usage: `/pagerduty impact remove <id>`")
+		}
+
+		post, err := p.kvstore.GetImpactPost(fields[1])
+		if err != nil {
+			p.client.Log.Error("Failed to get impact post", "error", err.Error(), "impact_post_id", fields[1])
+			return commandResponse("Failed to retrieve the impact status post.")
+		}
+		if post == nil || !p.canManageRosters(args.UserId, post.ChannelID) {
+			return commandResponse(fmt.Sprintf("Impact status post `%s` not found.", fields[1]))
+		}
+
+		if err := p.kvstore.DeleteImpactPost(post.ID); err != nil {
+			p.client.Log.Error("Failed to delete impact post", "error", err.Error(), "impact_post_id", post.ID)
+			return commandResponse("Failed to remove the impact status post.")
+		}
+		return commandResponse(fmt.Sprintf("Removed impact status post `%s`.", post.ID))
+
+	case "services":
+		client, apiErr := p.accountClient(account)
+		if apiErr != nil {
+			return commandResponse(apiErr.Message)
+		}
+
+		params := url.Values{}
+		params.Set("limit", "100")
+		businessServices, err := client.ListBusinessServices(params)
+		if err != nil {
+			p.client.Log.Error("Failed to get business services from PagerDuty", "error", err.Error())
+			return commandResponse("Failed to retrieve business services.")
+		}
+
+		var sb strings.Builder
+		sb.WriteString("###### Business services\n")
+		if len(businessServices.BusinessServices) == 0 {
+			sb.WriteString("_None_\n")
+		}
+		for _, businessService := range businessServices.BusinessServices {
+			fmt.Fprintf(&sb, "* `service:%s` - %s\n", businessService.ID, businessService.Name)
+		}
+
+		// Status dashboards are not available on every plan, so failing to list them is not fatal.
+		if dashboards, err := client.ListStatusDashboards(); err != nil {
+			p.client.Log.Warn("Failed to get status dashboards from PagerDuty", "error", err.Error())
+		} else if len(dashboards.StatusDashboards) > 0 {
+			sb.WriteString("###### Status dashboards\n")
+			for _, dashboard := range dashboards.StatusDashboards {
+				fmt.Fprintf(&sb, "* `dashboard:%s` - %s\n", dashboard.ID, dashboard.Name)
+			}
+		}
+		return commandResponse(sb.String())
+
+	default:
+		return commandResponse(fmt.Sprintf("Unknown impact command `%s`.\n%s", fields[0], commandHelp))
+	}
+}
+
+func (p *Plugin) executeMaintenanceCommand(args *model.CommandArgs, account string, fields []string) *model.CommandResponse {
+	if len(fields) == 0 {
+		return commandResponse(commandHelp)
+	}
+
+	config := p.getConfiguration()
+	if err := config.IsValid(); err != nil {
+		return commandResponse("The PagerDuty plugin is not configured. Please contact your system administrator.")
+	}
+
+	switch fields[0] {
+	case "start":
+		if len(fields) < 3 {
+			return commandResponse("Usage: `/pagerduty maintenance start <service-ids> <duration> [reason]`")
+		}
+
+		req := &CreateMaintenanceWindowRequest{
+			ServiceIDs:  parseIDList(fields[1:2]),
+			Duration:    fields[2],
+			Description: strings.Join(fields[3:], " "),
+			ChannelID:   args.ChannelId,
+		}
+
+		window, apiErr := p.createMaintenanceWindow(args.UserId, account, req)
+		if apiErr != nil {
+			return commandResponse(apiErr.Message)
+		}
+		return commandResponse(fmt.Sprintf("Started maintenance window `%s`. End it early with `/pagerduty maintenance end %s`.", window.ID, window.ID))
+
+	case "list":
+		client, apiErr := p.accountClient(account)
+		if apiErr != nil {
+			return commandResponse(apiErr.Message)
+		}
+
+		params := url.Values{}
+		params.Set("filter", "open")
+		params.Set("limit", "100")
+		response, err := client.ListMaintenanceWindows(params)
+		if err != nil {
+			p.client.Log.Error("Failed to get maintenance windows from PagerDuty", "error", err.Error())
+			return commandResponse("Failed to retrieve maintenance windows.")
+		}
+		if len(response.MaintenanceWindows) == 0 {
+			return commandResponse("There are no ongoing or upcoming maintenance windows.")
+		}
+
+		var sb strings.Builder
+		sb.WriteString("###### Maintenance windows\n")
+		for _, window := range response.MaintenanceWindows {
+			names := make([]string, 0, len(window.Services))
+			for _, service := range window.Services {
+				names = append(names, service.Summary)
+			}
+			sb.WriteString(fmt.Sprintf("* `%s` - **%s** from %s to %s", window.ID, strings.Join(names, ", "), formatMaintenanceTime(window.StartTime), formatMaintenanceTime(window.EndTime)))
+			if window.Description != "" {
+				sb.WriteString(": " + window.Description)
+			}
+			sb.WriteString("\n")
+		}
+		return commandResponse(sb.String())
+
+	case "end":
+		if len(fields) != 2 {
+			return commandResponse("Usage: `/pagerduty maintenance end <id>`")
+		}
+
+		if apiErr := p.deleteMaintenanceWindow(args.UserId, account, fields[1]); apiErr != nil {
+			return commandResponse(apiErr.Message)
+		}
+		return commandResponse(fmt.Sprintf("Ended maintenance window `%s`.", fields[1]))
+
+	default:
+		return commandResponse(fmt.Sprintf("Unknown maintenance command `%s`.\n%s", fields[0], commandHelp))
+	}
+}
+
+// formatMaintenanceTime formats a maintenance window boundary returned by PagerDuty in UTC.
+func formatMaintenanceTime(value string) string {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return value
+	}
+	return t.UTC().Format("Mon Jan 2, 15:04 MST")
+}
+
+// formatPagerDutyTeams lists PagerDuty teams of an account by name, falling back to their IDs.
+func (p *Plugin) formatPagerDutyTeams(account string, teamIDs []string) string {
+	client, apiErr := p.accountClient(account)
+
+	names := make([]string, 0, len(teamIDs))
+	for _, teamID := range teamIDs {
+		if apiErr == nil {
+			if team, err := client.GetTeam(teamID); err == nil {
+				names = append(names, fmt.Sprintf("**%s** (`%s`)", team.Team.Name, teamID))
+				continue
+			}
+		}
+		names = append(names, fmt.Sprintf("`%s`", teamID))
+	}
+	return strings.Join(names, ", ")
+}
+
+// parseTargets parses a comma-separated list of `schedule:<id>` and `policy:<id>` targets.
+func parseTargets(targets string) (scheduleIDs, policyIDs []string, err error) {
+	for _, target := range strings.Split(targets, ",") {
+		kind, id, _ := strings.Cut(target, ":")
+		if id == "" {
+			kind = ""
+		}
+		switch kind {
+		case "schedule":
+			scheduleIDs = append(scheduleIDs, id)
+		case "policy":
+			policyIDs = append(policyIDs, id)
+		default:
+			return nil, nil, errors.Errorf("invalid target `%s`", target)
+		}
+	}
+	return scheduleIDs, policyIDs, nil
+}
+
+func commandResponse(text string) *model.CommandResponse {
+	return &model.CommandResponse{
+		ResponseType: model.CommandResponseTypeEphemeral,
+		Text:         text,
+	}
+}
+
+func (p *Plugin) executeChangeCommand(args *model.CommandArgs, account string, fields []string) *model.CommandResponse {
+	if len(fields) == 0 {
+		return commandResponse(commandHelp)
+	}
+
+	if err := p.getConfiguration().IsValid(); err != nil {
+		return commandResponse("The PagerDuty plugin is not configured. Please contact your system administrator.")
+	}
+
+	switch fields[0] {
+	case "send":
+		if len(fields) < 3 {
+			return commandResponse("Usage: `/pagerduty change send <service-id> <summary>`")
+		}
+
+		req := &SendChangeEventRequest{
+			ServiceID: fields[1],
+			Summary:   strings.Join(fields[2:], " "),
+		}
+		if _, apiErr := p.sendChangeEvent(args.UserId, account, req); apiErr != nil {
+			return commandResponse(apiErr.Message)
+		}
+		return commandResponse(fmt.Sprintf("Sent change event to service `%s`.", req.ServiceID))
+
+	case "add":
+		if len(fields) < 3 {
+			return commandResponse("Usage: `/pagerduty change add <service-id> <pattern>`")
+		}
+
+		req := &CreateChangeEventRuleRequest{
+			ChannelID: args.ChannelId,
+			ServiceID: fields[1],
+			Pattern:   strings.Join(fields[2:], " "),
+		}
+		rule, apiErr := p.createChangeEventRule(args.UserId, account, req)
+		if apiErr != nil {
+			return commandResponse(apiErr.Message)
+		}
+		return commandResponse(fmt.Sprintf("Created change event rule `%s`. Messages in this channel matching `%s` are sent to %s as change events.", rule.ID, rule.Pattern, rule.ServiceName))
+
+	case "list":
+		if !p.client.User.HasPermissionToChannel(args.UserId, args.ChannelId, model.PermissionCreatePost) {
+			return commandResponse("You do not have permission to post in this channel.")
+		}
+
+		rules, err := p.kvstore.GetChangeEventRules(args.ChannelId)
+		if err != nil {
+			p.client.Log.Error("Failed to get change event rules", "error", err.Error(), "channel_id", args.ChannelId)
+			return commandResponse("Failed to retrieve change event rules.")
+		}
+		if len(rules) == 0 {
+			return commandResponse("This channel has no change event rules. Add one with `/pagerduty change add <service-id> <pattern>`.")
+		}
+
+		var sb strings.Builder
+		sb.WriteString("###### Change event rules\n")
+		for _, rule := range rules {
+			fmt.Fprintf(&sb, "* `%s` - `%s` → %s\n", rule.ID, rule.Pattern, rule.ServiceName)
+		}
+		return commandResponse(sb.String())
+
+	case "remove":
+		if len(fields) != 2 {
+			return commandResponse("Usage: `/pagerduty change remove <id>`")
+		}
+
+		if apiErr := p.deleteChangeEventRule(args.UserId, args.ChannelId, fields[1]); apiErr != nil {
+			return commandResponse(apiErr.Message)
+		}
+		return commandResponse(fmt.Sprintf("Removed change event rule `%s`.", fields[1]))
+
+	default:
+		return commandResponse(fmt.Sprintf("Unknown change command `%s`.\n%s", fields[0], commandHelp))
+	}
+}
+
+func (p *Plugin) executeAlertRuleCommand(args *model.CommandArgs, account string, fields []string) *model.CommandResponse {
+	if len(fields) == 0 {
+		return commandResponse(commandHelp)
+	}
+
+	if err := p.getConfiguration().IsValid(); err != nil {
+		return commandResponse("The PagerDuty plugin is not configured. Please contact your system administrator.")
+	}
+
+	if !p.canManageAlertRules(args.UserId) {
+		return commandResponse("Only plugin admins can manage alert rules.")
+	}
+
+	switch fields[0] {
+	case "add":
+		if len(fields) < 3 {
+			return commandResponse("Usage: `/pagerduty alertrule add <service-id> <pattern>`")
+		}
+
+		rule, apiErr := p.createAlertRule(args.UserId, account, &SaveAlertRuleRequest{
+			ChannelID:      args.ChannelId,
+			ServiceID:      fields[1],
+			TriggerPattern: strings.Join(fields[2:], " "),
+		})
+		if apiErr != nil {
+			return commandResponse(apiErr.Message)
+		}
+		return commandResponse(fmt.Sprintf("Created alert rule `%s`. Messages in this channel matching `%s` trigger an alert on service `%s`.", rule.ID, rule.TriggerPattern, rule.ServiceID))
+
+	case "list":
+		rules, apiErr := p.getAlertRules(args.UserId)
+		if apiErr != nil {
+			return commandResponse(apiErr.Message)
+		}
+		return commandResponse(formatAlertRules(rules))
+
+	case "dryrun":
+		if len(fields) != 3 || (fields[2] != "on" && fields[2] != "off") {
+			return commandResponse("Usage: `/pagerduty alertrule dryrun <id> <on|off>`")
+		}
+
+		rule, apiErr := p.setAlertRuleDryRun(args.UserId, fields[1], fields[2] == "on")
+		if apiErr != nil {
+			return commandResponse(apiErr.Message)
+		}
+		if rule.DryRun {
+			return commandResponse(fmt.Sprintf("Alert rule `%s` now only logs the events it would send.", rule.ID))
+		}
+		return commandResponse(fmt.Sprintf("Alert rule `%s` now sends its events to PagerDuty.", rule.ID))
+
+	case "remove":
+		if len(fields) != 2 {
+			return commandResponse("Usage: `/pagerduty alertrule remove <id>`")
+		}
+
+		if apiErr := p.deleteAlertRule(args.UserId, fields[1]); apiErr != nil {
+			return commandResponse(apiErr.Message)
+		}
+		return commandResponse(fmt.Sprintf("Removed alert rule `%s`.", fields[1]))
+
+	default:
+		return commandResponse(fmt.Sprintf("Unknown alertrule command `%s`.\n%s", fields[0], commandHelp))
+	}
+}
+
+func (p *Plugin) executePagingPolicyCommand(args *model.CommandArgs, account string, fields []string) *model.CommandResponse {
+	if len(fields) == 0 {
+		return commandResponse(commandHelp)
+	}
+
+	if err := p.getConfiguration().IsValid(); err != nil {
+		return commandResponse("The PagerDuty plugin is not configured. Please contact your system administrator.")
+	}
+
+	switch fields[0] {
+	case "show":
+		if len(fields) != 2 {
+			return commandResponse("Usage: `/pagerduty pagingpolicy show <service-id>`")
+		}
+
+		policy, apiErr := p.getPagingPolicy(account, fields[1])
+		if apiErr != nil {
+			return commandResponse(apiErr.Message)
+		}
+		return commandResponse(formatPagingPolicy(policy))
+
+	case "list":
+		policies, apiErr := p.getPagingPolicies(args.UserId)
+		if apiErr != nil {
+			return commandResponse(apiErr.Message)
+		}
+		if len(policies) == 0 {
+			return commandResponse("No service has a paging policy.")
+		}
+
+		formatted := make([]string, 0, len(policies))
+		for _, policy := range policies {
+			formatted = append(formatted, formatPagingPolicy(policy))
+		}
+		return commandResponse("###### Paging policies\n" + strings.Join(formatted, "\n\n"))
+
+	case "remove":
+		if len(fields) != 2 {
+			return commandResponse("Usage: `/pagerduty pagingpolicy remove <service-id>`")
+		}
+
+		if apiErr := p.deletePagingPolicy(args.UserId, account, fields[1]); apiErr != nil {
+			return commandResponse(apiErr.Message)
+		}
+		return commandResponse(fmt.Sprintf("Removed the paging policy of service `%s`.", fields[1]))
+
+	default:
+		return commandResponse(fmt.Sprintf("Unknown pagingpolicy command `%s`.\n%s", fields[0], commandHelp))
+	}
+}
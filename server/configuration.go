@@ -2,6 +2,7 @@ package main
 
 import (
 	"reflect"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -18,8 +19,47 @@ import (
 // If you add non-reference types to your configuration struct, be sure to rewrite Clone as a deep
 // copy appropriate for your types.
 type configuration struct {
-	APIToken   string `json:"APIToken"`
-	APIBaseURL string `json:"APIBaseURL"`
+	APIToken         string `json:"APIToken"`
+	APIBaseURL       string `json:"APIBaseURL"`
+	EventsAPIBaseURL string `json:"EventsAPIBaseURL"`
+
+	// Accounts are the PagerDuty accounts in addition to the default one, as JSON.
+	Accounts string `json:"Accounts"`
+
+	EnableHandoffReminders  bool   `json:"EnableHandoffReminders"`
+	HandoffReminderMinutes  int    `json:"HandoffReminderMinutes"`
+	HandoffSummaryChannelID string `json:"HandoffSummaryChannelID"`
+
+	WebhookSecret string `json:"WebhookSecret"`
+
+	EnableOnCallStatus bool `json:"EnableOnCallStatus"`
+
+	ReadAllowlist    string `json:"ReadAllowlist"`
+	PageAllowlist    string `json:"PageAllowlist"`
+	RespondAllowlist string `json:"RespondAllowlist"`
+	AdminAllowlist   string `json:"AdminAllowlist"`
+
+	ReadRateLimitPerMinute   int `json:"ReadRateLimitPerMinute"`
+	WriteRateLimitPerMinute  int `json:"WriteRateLimitPerMinute"`
+	GlobalRateLimitPerMinute int `json:"GlobalRateLimitPerMinute"`
+
+	// AuditRetentionDays is how many days records of the audit trail are kept. The default
+	// retention applies if it is not positive.
+	AuditRetentionDays int `json:"AuditRetentionDays"`
+
+	// EncryptionKey is the secret the key that encrypts secrets at rest is derived from. A key
+	// is generated and saved in the plugin configuration if it is empty.
+	EncryptionKey string `json:"EncryptionKey"`
+
+	// allowlists are the parsed allowlists of each operation, computed when the configuration
+	// changes.
+	allowlists map[string][]allowlistEntry
+
+	// accounts are the parsed additional accounts, computed when the configuration changes.
+	accounts []*pagerDutyAccount
+
+	// accountsError describes the additional accounts that were left out as invalid, if any.
+	accountsError string
 }
 
 // Clone shallow copies the configuration. Your implementation may require a deep copy if
@@ -79,15 +119,83 @@ func (p *Plugin) OnConfigurationChange() error {
 		return errors.Wrap(err, "failed to load plugin configuration")
 	}
 
+	configuration.allowlists = map[string][]allowlistEntry{}
+	for permission, value := range map[string]string{
+		permissionRead:    configuration.ReadAllowlist,
+		permissionPage:    configuration.PageAllowlist,
+		permissionRespond: configuration.RespondAllowlist,
+		permissionAdmin:   configuration.AdminAllowlist,
+	} {
+		entries, err := parseAllowlist(value)
+		if err != nil {
+			p.MattermostPlugin.API.LogWarn("Ignoring invalid allowlist entries", "permission", permission, "error", err.Error())
+		}
+		configuration.allowlists[permission] = entries
+	}
+
+	accounts, err := parseAccounts(configuration.Accounts)
+	if err != nil {
+		p.MattermostPlugin.API.LogWarn("Ignoring invalid PagerDuty accounts", "error", err.Error())
+		configuration.accountsError = err.Error()
+	}
+	configuration.accounts = accounts
+
+	// The first configuration is loaded when the plugin starts, which is not a change.
+	p.configurationLock.RLock()
+	oldConfiguration := p.configuration
+	p.configurationLock.RUnlock()
+	if oldConfiguration != nil {
+		p.recordConfigurationAudit(oldConfiguration, configuration)
+	}
+
 	p.setConfiguration(configuration)
 
+	// The encryption key is first set when the plugin is activated.
+	if oldConfiguration != nil && p.kvstore != nil && oldConfiguration.EncryptionKey != configuration.EncryptionKey {
+		if err := p.setupEncryption(configuration); err != nil {
+			p.MattermostPlugin.API.LogError("Failed to change the encryption key", "error", err.Error())
+		}
+	}
+
+	// Connections are checked once the plugin is active, and in the background so that saving
+	// the configuration does not wait for PagerDuty.
+	if p.createPagerDutyClient != nil && p.kvstore != nil {
+		go p.checkConnections(configuration)
+	}
+
 	return nil
 }
 
+// allowlist returns the allowlist of an operation. Operations other than admin are allowed to
+// every member of the system, but not to guests, if their allowlist is empty.
+func (c *configuration) allowlist(permission string) []allowlistEntry {
+	if entries := c.allowlists[permission]; len(entries) > 0 || permission == permissionAdmin {
+		return entries
+	}
+	return defaultAllowlist
+}
+
+// auditRetention returns how long records of the audit trail are kept.
+func (c *configuration) auditRetention() time.Duration {
+	days := c.AuditRetentionDays
+	if days <= 0 {
+		days = defaultAuditRetentionDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
 func (c *configuration) IsValid() error {
 	if c.APIToken == "" {
 		return errors.New("PagerDuty API Token is required")
 	}
 
+	if err := validateAPIBaseURL(c.APIBaseURL); err != nil {
+		return errors.Wrap(err, "PagerDuty API Base URL is invalid")
+	}
+
+	if err := validateAPIBaseURL(c.EventsAPIBaseURL); err != nil {
+		return errors.Wrap(err, "PagerDuty Events API Base URL is invalid")
+	}
+
 	return nil
 }
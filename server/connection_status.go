@@ -0,0 +1,145 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
+	"github.com/svelle/mattermost-pagerduty-plugin/server/store/kvstore"
+)
+
+const (
+	// accessReadOnly is the access of a user-level token of a read-only user, which may view
+	// but not page.
+	accessReadOnly = "read_only"
+
+	// accessFull is the access of a user-level token of a user who may also create and change
+	// incidents.
+	accessFull = "full"
+
+	// accessUnknown is the access of an account-level API key. PagerDuty does not tell whether
+	// such a key is read-only without trying to change something.
+	accessUnknown = "unknown"
+)
+
+// readOnlyRoles are the PagerDuty user roles that may not make changes.
+var readOnlyRoles = map[string]bool{
+	"read_only_user":         true,
+	"read_only_limited_user": true,
+	"observer":               true,
+}
+
+// pluginStatus is the status of the plugin shown to admins in the System Console.
+type pluginStatus struct {
+	ConfigurationValid bool                        `json:"configuration_valid"`
+	ConfigurationError string                      `json:"configuration_error,omitempty"`
+	AccountsError      string                      `json:"accounts_error,omitempty"`
+	Accounts           []*kvstore.ConnectionStatus `json:"accounts"`
+}
+
+// validateAPIBaseURL returns an error unless the value is empty, meaning the default URL, or an
+// absolute https URL.
+func validateAPIBaseURL(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	u, err := url.Parse(value)
+	if err != nil {
+		return errors.Wrapf(err, "invalid URL %q", value)
+	}
+	if u.Scheme != "https" || u.Host == "" {
+		return errors.Errorf("URL %q must be an absolute https URL", value)
+	}
+	return nil
+}
+
+// checkConnections checks the connection to every account of a configuration in the
+// background, and stores the results for the whole cluster unless the configuration changed
+// again meanwhile.
+func (p *Plugin) checkConnections(config *configuration) {
+	statuses := make([]*kvstore.ConnectionStatus, 0, len(config.accounts)+1)
+	for _, account := range config.allAccounts() {
+		status := p.checkConnection(account, time.Now())
+		if !status.Connected && account.APIToken != "" {
+			p.client.Log.Warn("Failed to connect to PagerDuty", "account", status.Account, "error", status.Error)
+		}
+		statuses = append(statuses, status)
+	}
+
+	if p.getConfiguration() != config {
+		return
+	}
+
+	if err := p.kvstore.SaveConnectionStatuses(statuses); err != nil {
+		p.client.Log.Error("Failed to save connection statuses", "error", err.Error())
+	}
+}
+
+// checkConnection verifies the URL and token of an account by fetching its abilities, and finds
+// out whether the token may make changes from the role of the user it acts as. Nothing is
+// changed in PagerDuty to find out.
+func (p *Plugin) checkConnection(account *pagerDutyAccount, now time.Time) *kvstore.ConnectionStatus {
+	status := &kvstore.ConnectionStatus{
+		Account:    displayAccountName(account.Name),
+		APIBaseURL: account.APIBaseURL,
+		CheckedAt:  now,
+	}
+
+	if account.APIToken == "" {
+		status.Error = "no API token is configured"
+		return status
+	}
+	if err := validateAPIBaseURL(account.APIBaseURL); err != nil {
+		status.Error = err.Error()
+		return status
+	}
+
+	client := p.createPagerDutyClient(account.APIToken, account.APIBaseURL)
+	abilities, err := client.GetAbilities()
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	status.Connected = true
+	status.Abilities = abilities.Abilities
+
+	user, err := client.GetCurrentUser()
+	if err != nil {
+		var apiErr *pagerduty.APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+			status.Error = errors.Wrap(err, "failed to check access").Error()
+		}
+		status.Access = accessUnknown
+		return status
+	}
+	status.Access = accessFull
+	if readOnlyRoles[user.Role] {
+		status.Access = accessReadOnly
+	}
+	return status
+}
+
+// getPluginStatus returns the validity of the configuration, the accounts left out as invalid
+// and the connection status of each account as last checked.
+func (p *Plugin) getPluginStatus() *pluginStatus {
+	config := p.getConfiguration()
+	status := &pluginStatus{ConfigurationValid: true, AccountsError: config.accountsError}
+	if err := config.IsValid(); err != nil {
+		status.ConfigurationValid = false
+		status.ConfigurationError = err.Error()
+	}
+
+	accounts, err := p.kvstore.GetConnectionStatuses()
+	if err != nil {
+		p.client.Log.Error("Failed to get connection statuses", "error", err.Error())
+	}
+	status.Accounts = accounts
+	if status.Accounts == nil {
+		status.Accounts = []*kvstore.ConnectionStatus{}
+	}
+	return status
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
+	"github.com/svelle/mattermost-pagerduty-plugin/server/store/kvstore"
+)
+
+func TestValidateAPIBaseURL(t *testing.T) {
+	for _, value := range []string{"", "https://api.pagerduty.com", "https://api.eu.pagerduty.com/"} {
+		assert.NoError(t, validateAPIBaseURL(value), value)
+	}
+	for _, value := range []string{"http://api.pagerduty.com", "api.pagerduty.com", "https://", "https://api.pagerduty.com/%zz"} {
+		assert.Error(t, validateAPIBaseURL(value), value)
+	}
+}
+
+func TestPlugin_checkConnections(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get("Authorization")
+		switch {
+		case token == "Token token=invalid":
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error": {"message": "Unauthorized", "code": 2006}}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/abilities":
+			_, _ = w.Write([]byte(`{"abilities": ["teams"]}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/users/me" && token == "Token token=full":
+			_, _ = w.Write([]byte(`{"user": {"id": "PUSER1", "role": "admin"}}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/users/me" && token == "Token token=read-only":
+			_, _ = w.Write([]byte(`{"user": {"id": "PUSER2", "role": "read_only_user"}}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/users/me":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error": {"message": "Invalid Input Provided", "code": 2001}}`))
+		case r.Method != http.MethodGet:
+			t.Errorf("unexpected %s %s while checking the connection", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	api := &plugintest.API{}
+	api.On("LogWarn", "Failed to connect to PagerDuty", "account", "broken", "error", mock.Anything).Return().Once()
+	defer api.AssertExpectations(t)
+
+	mockKVStore(api)
+
+	plugin := &Plugin{}
+	plugin.SetAPI(api)
+	plugin.client = pluginapi.NewClient(api, nil)
+	plugin.kvstore = kvstore.NewKVStore(plugin.client)
+	plugin.createPagerDutyClient = func(apiToken, _ string) *pagerduty.Client {
+		return pagerduty.NewClient(apiToken, server.URL)
+	}
+
+	config := &configuration{
+		APIToken:   "full",
+		APIBaseURL: "https://api.pagerduty.com",
+		accounts: []*pagerDutyAccount{
+			{Name: "eu", APIToken: "read-only", APIBaseURL: "https://api.eu.pagerduty.com"},
+			{Name: "broken", APIToken: "invalid"},
+			{Name: "api_key", APIToken: "api-key"},
+		},
+	}
+	plugin.setConfiguration(config)
+	plugin.checkConnections(config)
+
+	status := plugin.getPluginStatus()
+	assert.True(t, status.ConfigurationValid)
+	assert.Empty(t, status.AccountsError)
+	require.Len(t, status.Accounts, 4)
+
+	assert.Equal(t, "default", status.Accounts[0].Account)
+	assert.True(t, status.Accounts[0].Connected)
+	assert.Equal(t, accessFull, status.Accounts[0].Access)
+	assert.Equal(t, []string{"teams"}, status.Accounts[0].Abilities)
+
+	assert.Equal(t, "eu", status.Accounts[1].Account)
+	assert.True(t, status.Accounts[1].Connected)
+	assert.Equal(t, accessReadOnly, status.Accounts[1].Access)
+
+	assert.Equal(t, "broken", status.Accounts[2].Account)
+	assert.False(t, status.Accounts[2].Connected)
+	assert.Contains(t, status.Accounts[2].Error, "Unauthorized")
+
+	assert.Equal(t, "api_key", status.Accounts[3].Account)
+	assert.True(t, status.Accounts[3].Connected)
+	assert.Equal(t, accessUnknown, status.Accounts[3].Access)
+	assert.Empty(t, status.Accounts[3].Error)
+
+	t.Run("statuses are shared by every node", func(t *testing.T) {
+		node := &Plugin{}
+		node.SetAPI(api)
+		node.client = pluginapi.NewClient(api, nil)
+		node.kvstore = kvstore.NewKVStore(node.client)
+		node.setConfiguration(config)
+		assert.Equal(t, status.Accounts, node.getPluginStatus().Accounts)
+	})
+
+	t.Run("results of a replaced configuration are not recorded", func(t *testing.T) {
+		api.On("LogWarn", "Failed to connect to PagerDuty", "account", "default", "error", mock.Anything).Return().Once()
+		plugin.setConfiguration(&configuration{APIToken: "full"})
+		plugin.checkConnections(&configuration{APIToken: "invalid"})
+		assert.Len(t, plugin.getPluginStatus().Accounts, 4)
+	})
+
+	t.Run("invalid accounts are reported", func(t *testing.T) {
+		accounts, err := parseAccounts(`[{"name": "eu", "api_token": "read-only"}, {"name": "us"}]`)
+		require.Error(t, err)
+		plugin.setConfiguration(&configuration{APIToken: "full", accounts: accounts, accountsError: err.Error()})
+
+		status := plugin.getPluginStatus()
+		assert.True(t, status.ConfigurationValid)
+		assert.Contains(t, status.AccountsError, `account "us" has no api_token`)
+	})
+}
+
+func TestPlugin_checkConnection(t *testing.T) {
+	plugin := &Plugin{}
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("missing token", func(t *testing.T) {
+		status := plugin.checkConnection(&pagerDutyAccount{}, now)
+		assert.False(t, status.Connected)
+		assert.Equal(t, "default", status.Account)
+		assert.Equal(t, "no API token is configured", status.Error)
+		assert.Equal(t, now, status.CheckedAt)
+	})
+
+	t.Run("invalid URL", func(t *testing.T) {
+		status := plugin.checkConnection(&pagerDutyAccount{APIToken: "token", APIBaseURL: "http://api.pagerduty.com"}, now)
+		assert.False(t, status.Connected)
+		assert.Contains(t, status.Error, "https")
+	})
+}
@@ -0,0 +1,154 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		wantErr bool
+	}{
+		{name: "every minute", spec: "* * * * *"},
+		{name: "weekday mornings", spec: "0 9 * * 1-5"},
+		{name: "steps and lists", spec: "*/15 8,12,16 1-15/2 * *"},
+		{name: "names", spec: "30 8 * jan-mar MON,wed"},
+		{name: "sunday as seven", spec: "0 0 * * 7"},
+		{name: "too few fields", spec: "0 9 * *", wantErr: true},
+		{name: "out of range", spec: "60 9 * * *", wantErr: true},
+		{name: "invalid value", spec: "0 nine * * *", wantErr: true},
+		{name: "inverted range", spec: "0 9 * * 5-1", wantErr: true},
+		{name: "zero step", spec: "*/0 9 * * *", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := Parse(tt.spec)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.NotNil(t, schedule)
+			}
+		})
+	}
+}
+
+func TestSchedule_Matches(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		spec string
+		time time.Time
+		want bool
+	}{
+		{
+			name: "weekday morning matches on Monday",
+			spec: "0 9 * * 1-5",
+			time: time.Date(2024, 1, 1, 9, 0, 30, 0, time.UTC),
+			want: true,
+		},
+		{
+			name: "weekday morning does not match on Saturday",
+			spec: "0 9 * * 1-5",
+			time: time.Date(2024, 1, 6, 9, 0, 0, 0, time.UTC),
+			want: false,
+		},
+		{
+			name: "evaluated in the time's location",
+			spec: "0 9 * * *",
+			time: time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC).In(berlin),
+			want: true,
+		},
+		{
+			name: "sunday as seven",
+			spec: "0 0 * * 7",
+			time: time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC),
+			want: true,
+		},
+		{
+			name: "restricted day of month or day of week",
+			spec: "0 0 15 * mon",
+			time: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+			want: true,
+		},
+		{
+			name: "step",
+			spec: "*/20 * * * *",
+			time: time.Date(2024, 1, 1, 3, 40, 0, 0, time.UTC),
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := Parse(tt.spec)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, schedule.Matches(tt.time))
+		})
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{
+			name: "later the same day",
+			spec: "0 9 * * *",
+			from: time.Date(2024, 1, 1, 8, 30, 0, 0, time.UTC),
+			want: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "strictly after the given time",
+			spec: "0 9 * * *",
+			from: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+			want: time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "skips the weekend",
+			spec: "0 9 * * 1-5",
+			from: time.Date(2024, 1, 5, 10, 0, 0, 0, time.UTC),
+			want: time.Date(2024, 1, 8, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "leap day",
+			spec: "0 0 29 2 *",
+			from: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "skips nonexistent time during DST transition",
+			spec: "30 2 * * *",
+			from: time.Date(2024, 3, 31, 0, 0, 0, 0, berlin),
+			want: time.Date(2024, 4, 1, 2, 30, 0, 0, berlin),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := Parse(tt.spec)
+			require.NoError(t, err)
+			assert.True(t, tt.want.Equal(schedule.Next(tt.from)), "got %s", schedule.Next(tt.from))
+		})
+	}
+
+	t.Run("never fires", func(t *testing.T) {
+		schedule, err := Parse("0 0 30 2 *")
+		require.NoError(t, err)
+		assert.True(t, schedule.Next(time.Now()).IsZero())
+	})
+}
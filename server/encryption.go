@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/pluginapi/cluster"
+	"github.com/pkg/errors"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/store/kvstore"
+)
+
+const (
+	// encryptionKeyLockKey guards generating the encryption key, so that the nodes of a cluster
+	// agree on it.
+	encryptionKeyLockKey = "encryption_key_lock"
+
+	// generatedEncryptionKeyLength is the length of generated encryption keys, the same as that
+	// of keys generated in the System Console.
+	generatedEncryptionKeyLength = 32
+)
+
+// ensureEncryptionKey returns the configuration with an encryption key, generating one if the
+// admin did not configure any. The generated key is saved in the plugin configuration, like a
+// key generated in the System Console, rather than next to the secrets it protects in the KV
+// store.
+func (p *Plugin) ensureEncryptionKey(config *configuration) (*configuration, error) {
+	if config.EncryptionKey != "" {
+		return config, nil
+	}
+
+	mutex, err := cluster.NewMutex(p.API, encryptionKeyLockKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create encryption key lock")
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	pluginConfig := p.client.Configuration.GetPluginConfig()
+	if pluginConfig == nil {
+		pluginConfig = map[string]interface{}{}
+	}
+
+	// Another node may have generated the key first.
+	secret, _ := pluginConfig["EncryptionKey"].(string)
+	if secret == "" {
+		secret = model.NewRandomString(generatedEncryptionKeyLength)
+		pluginConfig["EncryptionKey"] = secret
+		if err := p.client.Configuration.SavePluginConfig(pluginConfig); err != nil {
+			return nil, errors.Wrap(err, "failed to save generated encryption key")
+		}
+		p.client.Log.Info("Generated an encryption key for secrets stored by the plugin")
+	}
+
+	// Saving the key changes the configuration again, which should not rotate the key.
+	withKey := config.Clone()
+	withKey.EncryptionKey = secret
+	if p.getConfiguration() == config {
+		p.setConfiguration(withKey)
+	}
+	return withKey, nil
+}
+
+// setupEncryption sets the key the KV store encrypts secrets with, and encrypts the secrets
+// stored before encryption existed or under a previous key with it. Secrets may be stored under
+// the key used until now, or under the keys of a rotation that did not finish. Those keys are
+// kept, encrypted with the new key, until every secret is re-encrypted.
+func (p *Plugin) setupEncryption(config *configuration) error {
+	p.encryptionLock.Lock()
+	defer p.encryptionLock.Unlock()
+
+	config, err := p.ensureEncryptionKey(config)
+	if err != nil {
+		return err
+	}
+	key := kvstore.DeriveEncryptionKey(config.EncryptionKey)
+
+	var previousKeys [][]byte
+	addPreviousKey := func(previousKey []byte) {
+		if len(previousKey) == 0 || bytes.Equal(previousKey, key) {
+			return
+		}
+		for _, k := range previousKeys {
+			if bytes.Equal(k, previousKey) {
+				return
+			}
+		}
+		previousKeys = append(previousKeys, previousKey)
+	}
+
+	addPreviousKey(p.currentEncryptionKey)
+
+	// The keys of an unfinished rotation are encrypted with one of the keys known so far.
+	if err := p.kvstore.SetEncryptionKeys(key, previousKeys...); err != nil {
+		return errors.Wrap(err, "failed to set encryption key")
+	}
+	storedKeys, err := p.kvstore.GetPreviousEncryptionKeys()
+	lostKeys := errors.Is(err, kvstore.ErrUnknownEncryptionKey)
+	if lostKeys {
+		p.client.Log.Warn("The keys of an unfinished key rotation cannot be decrypted, secrets encrypted with them are lost")
+	} else if err != nil {
+		return err
+	}
+	for _, storedKey := range storedKeys {
+		addPreviousKey(storedKey)
+	}
+
+	if err := p.kvstore.SetEncryptionKeys(key, previousKeys...); err != nil {
+		return errors.Wrap(err, "failed to set encryption key")
+	}
+	p.currentEncryptionKey = key
+
+	if len(previousKeys) > 0 {
+		if err := p.kvstore.SavePreviousEncryptionKeys(previousKeys); err != nil {
+			return err
+		}
+	}
+
+	encrypted, err := p.kvstore.EncryptSecrets()
+	if err != nil {
+		return errors.Wrap(err, "failed to encrypt stored secrets")
+	}
+	if encrypted > 0 {
+		p.client.Log.Info("Encrypted stored secrets with the current key", "count", encrypted)
+	}
+
+	if len(previousKeys) > 0 || lostKeys {
+		if err := p.kvstore.DeletePreviousEncryptionKeys(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/store/kvstore"
+)
+
+func TestPlugin_setupEncryption(t *testing.T) {
+	plugin, api, values := setupHandlerTestPlugin(t)
+
+	pluginConfig := map[string]interface{}{"APIToken": "token"}
+	api.On("GetPluginConfig").Return(func() map[string]interface{} { return pluginConfig })
+	api.On("SavePluginConfig", mock.Anything).Run(func(args mock.Arguments) {
+		pluginConfig = args.Get(0).(map[string]interface{})
+	}).Return(nil)
+
+	// Secrets stored before encryption existed.
+	values["bot_access_token"] = []byte(`{"id":"token-id","token":"secret-token"}`)
+
+	t.Run("a key is generated in the plugin configuration and secrets are encrypted", func(t *testing.T) {
+		require.NoError(t, plugin.setupEncryption(plugin.getConfiguration()))
+
+		generated, _ := pluginConfig["EncryptionKey"].(string)
+		assert.Len(t, generated, generatedEncryptionKeyLength)
+		assert.Equal(t, generated, plugin.getConfiguration().EncryptionKey)
+		assert.Equal(t, "token", pluginConfig["APIToken"])
+		assert.NotContains(t, values, "encryption_previous_keys")
+		assert.NotContains(t, string(values["bot_access_token"]), "secret-token")
+
+		token, err := plugin.kvstore.GetBotAccessToken()
+		require.NoError(t, err)
+		assert.Equal(t, &kvstore.BotAccessToken{ID: "token-id", Token: "secret-token"}, token)
+	})
+
+	t.Run("the generated key is kept", func(t *testing.T) {
+		generated := pluginConfig["EncryptionKey"]
+		require.NoError(t, plugin.setupEncryption(plugin.getConfiguration()))
+		assert.Equal(t, generated, pluginConfig["EncryptionKey"])
+		api.AssertNumberOfCalls(t, "SavePluginConfig", 1)
+	})
+
+	t.Run("configuring a key re-encrypts secrets", func(t *testing.T) {
+		sealed := values["bot_access_token"]
+		require.NoError(t, plugin.setupEncryption(&configuration{EncryptionKey: "admin key"}))
+		assert.NotEqual(t, sealed, values["bot_access_token"])
+		assert.NotContains(t, values, "encryption_previous_keys")
+
+		token, err := plugin.kvstore.GetBotAccessToken()
+		require.NoError(t, err)
+		assert.Equal(t, "secret-token", token.Token)
+	})
+
+	t.Run("an interrupted rotation is finished by another node", func(t *testing.T) {
+		// The rotation stopped after the previous key was kept but before secrets were
+		// re-encrypted.
+		previousKey := kvstore.DeriveEncryptionKey("admin key")
+		require.NoError(t, plugin.kvstore.SetEncryptionKeys(kvstore.DeriveEncryptionKey("rotated key"), previousKey))
+		require.NoError(t, plugin.kvstore.SavePreviousEncryptionKeys([][]byte{previousKey}))
+
+		node := &Plugin{}
+		node.SetAPI(api)
+		node.client = plugin.client
+		node.kvstore = kvstore.NewKVStore(node.client)
+		require.NoError(t, node.setupEncryption(&configuration{EncryptionKey: "rotated key"}))
+		assert.NotContains(t, values, "encryption_previous_keys")
+
+		token, err := node.kvstore.GetBotAccessToken()
+		require.NoError(t, err)
+		assert.Equal(t, "secret-token", token.Token)
+
+		// The secrets no longer need the previous key.
+		require.NoError(t, node.kvstore.SetEncryptionKeys(kvstore.DeriveEncryptionKey("rotated key")))
+		token, err = node.kvstore.GetBotAccessToken()
+		require.NoError(t, err)
+		assert.Equal(t, "secret-token", token.Token)
+	})
+
+	t.Run("secrets encrypted with an unknown key", func(t *testing.T) {
+		plugin.currentEncryptionKey = nil
+		require.NoError(t, plugin.setupEncryption(&configuration{EncryptionKey: "lost key"}))
+
+		_, err := plugin.kvstore.GetBotAccessToken()
+		assert.ErrorIs(t, err, kvstore.ErrUnknownEncryptionKey)
+	})
+}
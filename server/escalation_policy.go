@@ -0,0 +1,84 @@
+package main
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
+)
+
+// escalationPolicyOnCallBatchSize bounds the number of escalation policies whose on-calls are
+// requested at once, keeping each response within a single page.
+const escalationPolicyOnCallBatchSize = 10
+
+// EscalationChain is an escalation policy with every level resolved to its current on-calls
+type EscalationChain struct {
+	pagerduty.EscalationPolicy
+	Levels []EscalationLevel `json:"levels"`
+}
+
+// EscalationLevel is a level of an escalation policy and the users currently on call for it
+type EscalationLevel struct {
+	Level                    int                          `json:"level"`
+	EscalationDelayInMinutes int                          `json:"escalation_delay_in_minutes"`
+	Targets                  []pagerduty.EscalationTarget `json:"targets"`
+	OnCalls                  []pagerduty.OnCall           `json:"oncalls"`
+}
+
+// EscalationChainsResponse wraps a list of resolved escalation policies
+type EscalationChainsResponse struct {
+	pagerduty.ListResponse
+	EscalationPolicies []EscalationChain `json:"escalation_policies"`
+}
+
+// resolveEscalationChains looks up the current on-calls of every level of the given escalation
+// policies.
+func (p *Plugin) resolveEscalationChains(client *pagerduty.Client, policies []pagerduty.EscalationPolicy) ([]EscalationChain, error) {
+	var oncalls []pagerduty.OnCall
+	for start := 0; start < len(policies); start += escalationPolicyOnCallBatchSize {
+		batch := policies[start:min(start+escalationPolicyOnCallBatchSize, len(policies))]
+
+		policyIDs := make([]string, 0, len(batch))
+		for _, policy := range batch {
+			policyIDs = append(policyIDs, policy.ID)
+		}
+
+		response, err := client.GetOnCallsForEscalationPolicies(policyIDs)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get on-calls for escalation policies")
+		}
+		oncalls = append(oncalls, response.OnCalls...)
+	}
+
+	chains := make([]EscalationChain, 0, len(policies))
+	for _, policy := range policies {
+		chains = append(chains, buildEscalationChain(policy, oncalls))
+	}
+	return chains, nil
+}
+
+// buildEscalationChain assigns the on-calls of an escalation policy to its levels.
+func buildEscalationChain(policy pagerduty.EscalationPolicy, oncalls []pagerduty.OnCall) EscalationChain {
+	chain := EscalationChain{
+		EscalationPolicy: policy,
+		Levels:           make([]EscalationLevel, 0, len(policy.EscalationRules)),
+	}
+
+	for i, rule := range policy.EscalationRules {
+		level := EscalationLevel{
+			Level:                    i + 1,
+			EscalationDelayInMinutes: rule.EscalationDelayInMinutes,
+			Targets:                  rule.Targets,
+			OnCalls:                  []pagerduty.OnCall{},
+		}
+
+		for _, oncall := range oncalls {
+			if oncall.EscalationPolicy != nil && oncall.EscalationPolicy.ID == policy.ID && oncall.EscalationLevel == level.Level {
+				level.OnCalls = append(level.OnCalls, oncall)
+			}
+		}
+
+		chain.Levels = append(chain.Levels, level)
+	}
+
+	return chain
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
+)
+
+func TestBuildEscalationChain(t *testing.T) {
+	policy := pagerduty.EscalationPolicy{
+		ID:   "EP1",
+		Name: "Ops",
+		EscalationRules: []pagerduty.EscalationRule{
+			{ID: "RULE1", EscalationDelayInMinutes: 15, Targets: []pagerduty.EscalationTarget{{ID: "SCHED1", Type: "schedule_reference"}}},
+			{ID: "RULE2", EscalationDelayInMinutes: 30, Targets: []pagerduty.EscalationTarget{{ID: "USER3", Type: "user_reference"}}},
+			{ID: "RULE3", EscalationDelayInMinutes: 30, Targets: []pagerduty.EscalationTarget{{ID: "SCHED2", Type: "schedule_reference"}}},
+		},
+	}
+
+	oncalls := []pagerduty.OnCall{
+		{User: pagerduty.User{ID: "USER1"}, EscalationPolicy: &pagerduty.EscalationPolicy{ID: "EP1"}, EscalationLevel: 1},
+		{User: pagerduty.User{ID: "USER2"}, EscalationPolicy: &pagerduty.EscalationPolicy{ID: "EP2"}, EscalationLevel: 1},
+		{User: pagerduty.User{ID: "USER3"}, EscalationPolicy: &pagerduty.EscalationPolicy{ID: "EP1"}, EscalationLevel: 2},
+	}
+
+	chain := buildEscalationChain(policy, oncalls)
+
+	assert.Equal(t, "Ops", chain.Name)
+	require.Len(t, chain.Levels, 3)
+
+	assert.Equal(t, 1, chain.Levels[0].Level)
+	assert.Equal(t, 15, chain.Levels[0].EscalationDelayInMinutes)
+	require.Len(t, chain.Levels[0].OnCalls, 1)
+	assert.Equal(t, "USER1", chain.Levels[0].OnCalls[0].User.ID)
+
+	require.Len(t, chain.Levels[1].OnCalls, 1)
+	assert.Equal(t, "USER3", chain.Levels[1].OnCalls[0].User.ID)
+
+	assert.Equal(t, 3, chain.Levels[2].Level)
+	assert.Empty(t, chain.Levels[2].OnCalls)
+	assert.NotNil(t, chain.Levels[2].OnCalls)
+}
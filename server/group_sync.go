@@ -0,0 +1,360 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
+	"github.com/svelle/mattermost-pagerduty-plugin/server/store/kvstore"
+)
+
+const (
+	// groupSyncMaxInterval is the longest a group goes without being reconciled, so that
+	// changes PagerDuty does not announce, such as schedule overrides, are picked up.
+	groupSyncMaxInterval = time.Hour
+
+	// groupMembersPerPage is the page size used when reading group members.
+	groupMembersPerPage = 100
+)
+
+var groupNameRegexp = regexp.MustCompile(`^[a-z0-9._-]+$`)
+
+// CreateGroupSyncRequest represents the request to sync a custom group with the on-calls of
+// schedules or escalation policies
+type CreateGroupSyncRequest struct {
+	GroupName           string   `json:"group_name"`
+	ScheduleIDs         []string `json:"schedule_ids,omitempty"`
+	EscalationPolicyIDs []string `json:"escalation_policy_ids,omitempty"`
+}
+
+// canManageGroupSyncs reports whether the user may manage group syncs. Synced groups can be
+// mentioned across teams, so this is restricted to plugin admins.
+func (p *Plugin) canManageGroupSyncs(userID string) bool {
+	return p.hasPermission(userID, permissionAdmin)
+}
+
+// createGroupSync validates and stores a new group sync. The group itself is created or
+// adopted on the first reconciliation.
+func (p *Plugin) createGroupSync(userID, account string, req *CreateGroupSyncRequest) (_ *kvstore.GroupSync, apiErr *APIError) {
+	defer func() { p.recordAudit(userID, account, auditActionCreateGroupSync, req.GroupName, apiErr) }()
+
+	if !p.canManageGroupSyncs(userID) {
+		return nil, groupSyncPermissionError
+	}
+	if apiErr := p.checkAccount(account); apiErr != nil {
+		return nil, apiErr
+	}
+
+	groupName := strings.TrimPrefix(strings.ToLower(req.GroupName), "@")
+	if !groupNameRegexp.MatchString(groupName) {
+		return nil, &APIError{
+			ID:         "api.pagerduty.group_sync.name.invalid",
+			Message:    "Group names may only contain lowercase letters, numbers, periods, dashes and underscores",
+			StatusCode: http.StatusBadRequest,
+		}
+	}
+
+	if len(req.ScheduleIDs) == 0 && len(req.EscalationPolicyIDs) == 0 {
+		return nil, &APIError{
+			ID:         "api.pagerduty.group_sync.targets.missing",
+			Message:    "At least one schedule or escalation policy is required",
+			StatusCode: http.StatusBadRequest,
+		}
+	}
+
+	groupSyncs, err := p.kvstore.ListGroupSyncs()
+	if err != nil {
+		p.client.Log.Error("Failed to list group syncs", "error", err.Error())
+		return nil, &APIError{
+			ID:         "api.pagerduty.group_sync.list.error",
+			Message:    "Failed to retrieve on-call groups",
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+	for _, existing := range groupSyncs {
+		if existing.GroupName == groupName {
+			return nil, &APIError{
+				ID:         "api.pagerduty.group_sync.exists",
+				Message:    "The group @" + groupName + " is already synced",
+				StatusCode: http.StatusConflict,
+			}
+		}
+	}
+
+	groupSync := &kvstore.GroupSync{
+		ID:                  model.NewId(),
+		Account:             account,
+		GroupName:           groupName,
+		ScheduleIDs:         req.ScheduleIDs,
+		EscalationPolicyIDs: req.EscalationPolicyIDs,
+		CreatorID:           userID,
+		CreateAt:            model.GetMillis(),
+	}
+
+	if err := p.kvstore.SaveGroupSync(groupSync); err != nil {
+		p.client.Log.Error("Failed to save group sync", "error", err.Error())
+		return nil, &APIError{
+			ID:         "api.pagerduty.group_sync.save.error",
+			Message:    "Failed to save on-call group",
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+
+	return groupSync, nil
+}
+
+// groupSyncPermissionError is returned to users who are not allowed to manage group syncs.
+var groupSyncPermissionError = &APIError{
+	ID:         "api.pagerduty.group_sync.permission",
+	Message:    "Only plugin admins can manage on-call groups",
+	StatusCode: http.StatusForbidden,
+}
+
+// deleteGroupSync stops syncing a group. The group itself is left in place, as users may still
+// rely on it.
+func (p *Plugin) deleteGroupSync(userID, id string) (_ *kvstore.GroupSync, apiErr *APIError) {
+	var account, groupName string
+	defer func() { p.recordAudit(userID, account, auditActionDeleteGroupSync, groupName, apiErr) }()
+
+	if !p.canManageGroupSyncs(userID) {
+		return nil, groupSyncPermissionError
+	}
+
+	groupSync, err := p.kvstore.GetGroupSync(id)
+	if err != nil {
+		p.client.Log.Error("Failed to get group sync", "error", err.Error(), "group_sync_id", id)
+		return nil, &APIError{
+			ID:         "api.pagerduty.group_sync.get.error",
+			Message:    "Failed to retrieve the on-call group",
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+	if groupSync == nil {
+		return nil, &APIError{
+			ID:         "api.pagerduty.group_sync.not_found",
+			Message:    fmt.Sprintf("On-call group %s was not found", id),
+			StatusCode: http.StatusNotFound,
+		}
+	}
+	account, groupName = groupSync.Account, groupSync.GroupName
+
+	if err := p.kvstore.DeleteGroupSync(groupSync.ID); err != nil {
+		p.client.Log.Error("Failed to delete group sync", "error", err.Error(), "group_sync_id", groupSync.ID)
+		return nil, &APIError{
+			ID:         "api.pagerduty.group_sync.delete.error",
+			Message:    "Failed to remove the on-call group",
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+	return groupSync, nil
+}
+
+// requestGroupSync asks for every group to be reconciled on the next run of the background job.
+// The request is recorded apart from the group syncs, so that concurrent webhook deliveries and
+// the job never overwrite each other's changes.
+func (p *Plugin) requestGroupSync() {
+	if err := p.kvstore.RequestGroupSync(model.GetMillis()); err != nil {
+		p.client.Log.Error("Failed to request group sync", "error", err.Error())
+	}
+}
+
+// groupSyncDue reports whether a group sync should be reconciled: because a shift boundary has
+// passed, or because a sync was requested after it was last reconciled.
+func groupSyncDue(groupSync *kvstore.GroupSync, requestedAt int64, now time.Time) bool {
+	return groupSync.NextSyncAt <= now.UnixMilli() || groupSync.LastSyncAt < requestedAt
+}
+
+// runGroupSyncs reconciles every group sync that is due.
+func (p *Plugin) runGroupSyncs(now time.Time) error {
+	groupSyncs, err := p.kvstore.ListGroupSyncs()
+	if err != nil {
+		return errors.Wrap(err, "failed to list group syncs")
+	}
+
+	requestedAt, err := p.kvstore.GetGroupSyncRequestedAt()
+	if err != nil {
+		return err
+	}
+
+	for _, groupSync := range groupSyncs {
+		if !groupSyncDue(groupSync, requestedAt, now) {
+			continue
+		}
+
+		old := *groupSync
+		if err := p.reconcileGroupSync(groupSync, now); err != nil {
+			p.client.Log.Error("Failed to sync on-call group", "error", err.Error(), "group", groupSync.GroupName)
+			// Retry on the next run rather than waiting for the next shift boundary.
+			continue
+		}
+
+		p.saveReconciledGroupSync(&old, groupSync)
+	}
+
+	return nil
+}
+
+// saveReconciledGroupSync stores the outcome of reconciling a group sync, unless it was changed
+// or deleted meanwhile, in which case it is reconciled again on the next run.
+func (p *Plugin) saveReconciledGroupSync(old, groupSync *kvstore.GroupSync) {
+	saved, err := p.kvstore.UpdateGroupSync(old, groupSync)
+	if err != nil {
+		p.client.Log.Error("Failed to update group sync", "error", err.Error(), "group_sync_id", groupSync.ID)
+		return
+	}
+	if !saved {
+		p.client.Log.Debug("Group sync changed while it was reconciled", "group_sync_id", groupSync.ID)
+	}
+}
+
+// reconcileGroupSync makes the members of a synced group match the users currently on call and
+// schedules the next reconciliation for the next shift boundary.
+func (p *Plugin) reconcileGroupSync(groupSync *kvstore.GroupSync, now time.Time) error {
+	oncalls, err := p.getGroupSyncOnCalls(groupSync)
+	if err != nil {
+		return err
+	}
+
+	desired := []string{}
+	nextSync := nextShiftBoundary(oncalls, now, groupSyncMaxInterval)
+	for _, oncall := range oncalls {
+		user, err := p.getMattermostUserForPagerDutyUser(oncall.User)
+		if err != nil {
+			p.client.Log.Debug("Skipping on-call user without Mattermost account", "error", err.Error())
+			continue
+		}
+		if !slices.Contains(desired, user.Id) {
+			desired = append(desired, user.Id)
+		}
+	}
+
+	apiClient, err := p.getBotAPIClient()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	if groupSync.GroupID == "" {
+		group, err := p.client.Group.GetByName(groupSync.GroupName)
+		if err != nil || group == nil {
+			if len(desired) == 0 {
+				// Custom groups cannot be created without members; try again at the next shift.
+				groupSync.LastSyncAt = now.UnixMilli()
+				groupSync.NextSyncAt = nextSync.UnixMilli()
+				return nil
+			}
+
+			name := groupSync.GroupName
+			group, resp, err := apiClient.CreateGroup(ctx, &model.Group{
+				Name:           &name,
+				DisplayName:    "On call: " + name,
+				Source:         model.GroupSourceCustom,
+				AllowReference: true,
+				MemberIDs:      desired,
+			})
+			p.checkBotAPIResponse(resp)
+			if err != nil {
+				return errors.Wrap(err, "failed to create group")
+			}
+
+			groupSync.GroupID = group.Id
+			groupSync.LastSyncAt = now.UnixMilli()
+			groupSync.NextSyncAt = nextSync.UnixMilli()
+			return nil
+		}
+
+		if group.Source != model.GroupSourceCustom {
+			return errors.Errorf("group %s is not a custom group", groupSync.GroupName)
+		}
+		groupSync.GroupID = group.Id
+	}
+
+	current, err := p.getGroupMemberIDs(groupSync.GroupID)
+	if err != nil {
+		return err
+	}
+
+	add, remove := diffGroupMembers(current, desired)
+	if len(add) > 0 {
+		_, resp, err := apiClient.UpsertGroupMembers(ctx, groupSync.GroupID, &model.GroupModifyMembers{UserIds: add})
+		p.checkBotAPIResponse(resp)
+		if err != nil {
+			return errors.Wrap(err, "failed to add group members")
+		}
+	}
+	if len(remove) > 0 {
+		_, resp, err := apiClient.DeleteGroupMembers(ctx, groupSync.GroupID, &model.GroupModifyMembers{UserIds: remove})
+		p.checkBotAPIResponse(resp)
+		if err != nil {
+			return errors.Wrap(err, "failed to remove group members")
+		}
+	}
+
+	if len(add) > 0 || len(remove) > 0 {
+		p.client.Log.Info("Synced on-call group", "group", groupSync.GroupName, "added", len(add), "removed", len(remove))
+	}
+
+	groupSync.LastSyncAt = now.UnixMilli()
+	groupSync.NextSyncAt = nextSync.UnixMilli()
+	return nil
+}
+
+// getGroupSyncOnCalls returns the users on call for a group sync: everyone on call for its
+// schedules and the first level of its escalation policies.
+func (p *Plugin) getGroupSyncOnCalls(groupSync *kvstore.GroupSync) ([]pagerduty.OnCall, error) {
+	oncalls, policyOnCalls, err := p.getOnCallsForTargets(groupSync.Account, groupSync.ScheduleIDs, groupSync.EscalationPolicyIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, oncall := range policyOnCalls {
+		if oncall.EscalationLevel == 1 {
+			oncalls = append(oncalls, oncall)
+		}
+	}
+
+	return oncalls, nil
+}
+
+func (p *Plugin) getGroupMemberIDs(groupID string) ([]string, error) {
+	var memberIDs []string
+	for page := 0; ; page++ {
+		users, err := p.client.Group.GetMemberUsers(groupID, page, groupMembersPerPage)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get group members")
+		}
+
+		for _, user := range users {
+			memberIDs = append(memberIDs, user.Id)
+		}
+
+		if len(users) < groupMembersPerPage {
+			return memberIDs, nil
+		}
+	}
+}
+
+// diffGroupMembers returns the users to add and remove to turn the current members into the
+// desired ones.
+func diffGroupMembers(current, desired []string) (add, remove []string) {
+	for _, userID := range desired {
+		if !slices.Contains(current, userID) {
+			add = append(add, userID)
+		}
+	}
+	for _, userID := range current {
+		if !slices.Contains(desired, userID) {
+			remove = append(remove, userID)
+		}
+	}
+	return add, remove
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/store/kvstore"
+)
+
+func TestDiffGroupMembers(t *testing.T) {
+	tests := []struct {
+		name       string
+		current    []string
+		desired    []string
+		wantAdd    []string
+		wantRemove []string
+	}{
+		{
+			name:    "empty group",
+			desired: []string{"user1", "user2"},
+			wantAdd: []string{"user1", "user2"},
+		},
+		{
+			name:    "unchanged",
+			current: []string{"user1", "user2"},
+			desired: []string{"user2", "user1"},
+		},
+		{
+			name:       "shift change",
+			current:    []string{"user1", "user2"},
+			desired:    []string{"user2", "user3"},
+			wantAdd:    []string{"user3"},
+			wantRemove: []string{"user1"},
+		},
+		{
+			name:       "nobody on call",
+			current:    []string{"user1"},
+			desired:    []string{},
+			wantRemove: []string{"user1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			add, remove := diffGroupMembers(tt.current, tt.desired)
+			assert.Equal(t, tt.wantAdd, add)
+			assert.Equal(t, tt.wantRemove, remove)
+		})
+	}
+}
+
+func TestGroupSyncDue(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	later := now.Add(time.Hour).UnixMilli()
+
+	assert.True(t, groupSyncDue(&kvstore.GroupSync{NextSyncAt: now.UnixMilli()}, 0, now))
+	assert.False(t, groupSyncDue(&kvstore.GroupSync{NextSyncAt: later, LastSyncAt: now.UnixMilli()}, 0, now))
+	assert.True(t, groupSyncDue(&kvstore.GroupSync{NextSyncAt: later, LastSyncAt: now.Add(-time.Minute).UnixMilli()}, now.UnixMilli(), now))
+	assert.False(t, groupSyncDue(&kvstore.GroupSync{NextSyncAt: later, LastSyncAt: now.UnixMilli()}, now.Add(-time.Minute).UnixMilli(), now))
+}
+
+// v3IncidentEventPayload is an incident event as delivered by PagerDuty V3 webhooks, with its
+// event type left to fill in.
+const v3IncidentEventPayload = `{
+  "event": {
+    "id": "01DEN1HNLBC1VITMQKZ3IN6NL6",
+    "event_type": "%s",
+    "resource_type": "incident",
+    "occurred_at": "2020-10-02T18:45:22.169Z",
+    "agent": {
+      "html_url": "https://acme.pagerduty.com/users/PLH1HKV",
+      "id": "PLH1HKV",
+      "self": "https://api.pagerduty.com/users/PLH1HKV",
+      "summary": "Tenex Engineer",
+      "type": "user_reference"
+    },
+    "client": null,
+    "data": {
+      "id": "PGR0VU2",
+      "type": "incident",
+      "self": "https://api.pagerduty.com/incidents/PGR0VU2",
+      "html_url": "https://acme.pagerduty.com/incidents/PGR0VU2",
+      "number": 2,
+      "status": "triggered",
+      "incident_key": "d3640fbd41094207a1c11e58e46b1662",
+      "created_at": "2020-04-09T15:16:27Z",
+      "title": "A little bump in the road",
+      "service": {
+        "html_url": "https://acme.pagerduty.com/services/PF9KMXH",
+        "id": "PF9KMXH",
+        "self": "https://api.pagerduty.com/services/PF9KMXH",
+        "summary": "API Service",
+        "type": "service_reference"
+      },
+      "assignees": [
+        {
+          "html_url": "https://acme.pagerduty.com/users/PTUXL6G",
+          "id": "PTUXL6G",
+          "self": "https://api.pagerduty.com/users/PTUXL6G",
+          "summary": "User 123",
+          "type": "user_reference"
+        }
+      ],
+      "escalation_policy": {
+        "html_url": "https://acme.pagerduty.com/escalation_policies/PUS0KTE",
+        "id": "PUS0KTE",
+        "self": "https://api.pagerduty.com/escalation_policies/PUS0KTE",
+        "summary": "Default",
+        "type": "escalation_policy_reference"
+      },
+      "teams": [],
+      "priority": null,
+      "urgency": "high",
+      "conference_bridge": null,
+      "resolve_reason": null
+    }
+  }
+}`
+
+func TestPlugin_handleWebhook_requestsGroupSync(t *testing.T) {
+	tests := []struct {
+		eventType string
+		requested bool
+	}{
+		{eventType: "incident.escalated", requested: true},
+		{eventType: "incident.reassigned", requested: true},
+		{eventType: "incident.delegated", requested: true},
+		{eventType: "incident.acknowledged", requested: true},
+		{eventType: "incident.triggered", requested: false},
+		{eventType: "incident.resolved", requested: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.eventType, func(t *testing.T) {
+			plugin, _, values := setupHandlerTestPlugin(t)
+			plugin.setConfiguration(&configuration{APIToken: "token", WebhookSecret: "secret"})
+			values["group_sync_G1"] = []byte(`{"id":"G1","group_name":"oncall","next_sync_at":1}`)
+
+			deliverWebhookPayload(t, plugin, []byte(fmt.Sprintf(v3IncidentEventPayload, tt.eventType)))
+
+			_, requested := values["requested_group_sync_at"]
+			assert.Equal(t, tt.requested, requested)
+			assert.Equal(t, `{"id":"G1","group_name":"oncall","next_sync_at":1}`, string(values["group_sync_G1"]))
+		})
+	}
+}
+
+func TestPlugin_saveReconciledGroupSync(t *testing.T) {
+	plugin, _, values := setupHandlerTestPlugin(t)
+
+	old := &kvstore.GroupSync{ID: "G1", GroupName: "oncall"}
+	require.NoError(t, plugin.kvstore.SaveGroupSync(old))
+
+	t.Run("saved", func(t *testing.T) {
+		reconciled := *old
+		reconciled.LastSyncAt = 100
+		plugin.saveReconciledGroupSync(old, &reconciled)
+
+		groupSync, err := plugin.kvstore.GetGroupSync("G1")
+		require.NoError(t, err)
+		assert.Equal(t, int64(100), groupSync.LastSyncAt)
+	})
+
+	t.Run("deleted meanwhile", func(t *testing.T) {
+		groupSync, err := plugin.kvstore.GetGroupSync("G1")
+		require.NoError(t, err)
+		require.NoError(t, plugin.kvstore.DeleteGroupSync("G1"))
+
+		reconciled := *groupSync
+		reconciled.LastSyncAt = 200
+		plugin.saveReconciledGroupSync(groupSync, &reconciled)
+
+		_, exists := values["group_sync_G1"]
+		assert.False(t, exists)
+	})
+}
+
+func TestPlugin_groupSyncEndpoints(t *testing.T) {
+	plugin, _, _ := setupHandlerTestPlugin(t)
+
+	w := serveTestRequest(plugin, http.MethodPost, "/api/v1/group_syncs", "test-user-id", `{"group_name": "oncall", "schedule_ids": ["S1"]}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = serveTestRequest(plugin, http.MethodPost, "/api/v1/group_syncs?account=eu", "admin-user-id", `{"group_name": "oncall", "schedule_ids": ["S1"]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serveTestRequest(plugin, http.MethodPost, "/api/v1/group_syncs", "admin-user-id", `{"group_name": "@OnCall", "schedule_ids": ["S1"]}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created kvstore.GroupSync
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&created))
+	assert.Equal(t, "oncall", created.GroupName)
+	assert.Equal(t, "admin-user-id", created.CreatorID)
+
+	w = serveTestRequest(plugin, http.MethodPost, "/api/v1/group_syncs", "admin-user-id", `{"group_name": "oncall", "schedule_ids": ["S2"]}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = serveTestRequest(plugin, http.MethodGet, "/api/v1/group_syncs", "admin-user-id", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var groupSyncs []*kvstore.GroupSync
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&groupSyncs))
+	require.Len(t, groupSyncs, 1)
+	assert.Equal(t, created.ID, groupSyncs[0].ID)
+
+	w = serveTestRequest(plugin, http.MethodDelete, "/api/v1/group_syncs/"+created.ID, "admin-user-id", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = serveTestRequest(plugin, http.MethodDelete, "/api/v1/group_syncs/"+created.ID, "admin-user-id", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
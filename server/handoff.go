@@ -0,0 +1,272 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
+)
+
+const (
+	// defaultHandoffReminderLead is used when no reminder lead time is configured.
+	defaultHandoffReminderLead = 15 * time.Minute
+
+	// handoffLookback is how far back the job looks for handoffs, so that a handoff is still
+	// announced if a run was delayed or skipped.
+	handoffLookback = 5 * time.Minute
+
+	// handoffNotifiedTTL is how long sent handoff notifications are remembered.
+	handoffNotifiedTTL = 24 * time.Hour
+)
+
+// shiftChange describes the handoff from one on-call user to the next on a single schedule.
+type shiftChange struct {
+	// Account is the PagerDuty account of the schedule, empty for the default account.
+	Account             string
+	ScheduleID          string
+	ScheduleName        string
+	EscalationPolicyIDs []string
+	At                  time.Time
+	Outgoing            *pagerduty.OnCall
+	Incoming            *pagerduty.OnCall
+}
+
+// findShiftChanges returns the handoffs taking place in the interval (from, to], ordered by time.
+// A schedule referenced by several escalation policies or levels is reported once per handoff.
+func findShiftChanges(oncalls []pagerduty.OnCall, from, to time.Time) []*shiftChange {
+	changesByKey := map[string]*shiftChange{}
+	changes := []*shiftChange{}
+
+	changeAt := func(oncall *pagerduty.OnCall, at time.Time) *shiftChange {
+		key := fmt.Sprintf("%s_%d", oncall.Schedule.ID, at.Unix())
+		change, ok := changesByKey[key]
+		if !ok {
+			change = &shiftChange{
+				ScheduleID:   oncall.Schedule.ID,
+				ScheduleName: oncall.Schedule.Name,
+				At:           at,
+			}
+			changesByKey[key] = change
+			changes = append(changes, change)
+		}
+
+		if oncall.EscalationPolicy != nil && !slices.Contains(change.EscalationPolicyIDs, oncall.EscalationPolicy.ID) {
+			change.EscalationPolicyIDs = append(change.EscalationPolicyIDs, oncall.EscalationPolicy.ID)
+		}
+		return change
+	}
+
+	inRange := func(t time.Time) bool {
+		return t.After(from) && !t.After(to)
+	}
+
+	for i := range oncalls {
+		oncall := &oncalls[i]
+		if oncall.Schedule.ID == "" {
+			// Escalation policies can target users directly; those on-calls have no shifts.
+			continue
+		}
+
+		if start, err := time.Parse(time.RFC3339, oncall.Start); err == nil && inRange(start) {
+			changeAt(oncall, start).Incoming = oncall
+		}
+		if end, err := time.Parse(time.RFC3339, oncall.End); err == nil && inRange(end) {
+			changeAt(oncall, end).Outgoing = oncall
+		}
+	}
+
+	result := make([]*shiftChange, 0, len(changes))
+	for _, change := range changes {
+		if change.Outgoing != nil && change.Incoming != nil && change.Outgoing.User.ID == change.Incoming.User.ID {
+			// Consecutive shifts of the same user are not a handoff.
+			continue
+		}
+		result = append(result, change)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].At.Before(result[j].At)
+	})
+
+	return result
+}
+
+// runHandoffReminders notifies incoming on-call users ahead of their shift, outgoing users at
+// handoff and, if configured, posts a handoff summary to the handoff channel, for the shifts of
+// every account.
+func (p *Plugin) runHandoffReminders(now time.Time) {
+	for _, account := range p.getConfiguration().allAccounts() {
+		if err := p.runAccountHandoffReminders(account, now); err != nil {
+			p.client.Log.Error("Failed to send handoff reminders", "error", err.Error(), "account", displayAccountName(account.Name))
+		}
+	}
+}
+
+// runAccountHandoffReminders sends the handoff reminders and summaries of an account.
+func (p *Plugin) runAccountHandoffReminders(account *pagerDutyAccount, now time.Time) error {
+	config := p.getConfiguration()
+
+	lead := time.Duration(config.HandoffReminderMinutes) * time.Minute
+	if lead <= 0 {
+		lead = defaultHandoffReminderLead
+	}
+
+	client := p.createPagerDutyClient(account.APIToken, account.APIBaseURL)
+	oncalls, err := client.GetOnCallsBetween(now.Add(-handoffLookback), now.Add(lead))
+	if err != nil {
+		return errors.Wrap(err, "failed to get on-calls")
+	}
+
+	for _, change := range findShiftChanges(oncalls.OnCalls, now, now.Add(lead)) {
+		change.Account = account.Name
+		if change.Incoming != nil {
+			p.sendUpcomingShiftReminder(change, now)
+		}
+	}
+
+	var openIncidents []pagerduty.Incident
+	openIncidentsLoaded := false
+
+	for _, change := range findShiftChanges(oncalls.OnCalls, now.Add(-handoffLookback), now) {
+		change.Account = account.Name
+		if change.Outgoing != nil {
+			p.sendHandoffNotice(change)
+		}
+
+		if config.HandoffSummaryChannelID == "" || !p.markHandoffNotified("summary", change, "") {
+			continue
+		}
+
+		if !openIncidentsLoaded {
+			incidents, err := client.GetOpenIncidents()
+			if err != nil {
+				p.client.Log.Warn("Failed to get open incidents for handoff summary", "error", err.Error())
+			} else {
+				openIncidents = incidents.Incidents
+			}
+			openIncidentsLoaded = true
+		}
+
+		post := &model.Post{
+			UserId:    p.botUserID,
+			ChannelId: config.HandoffSummaryChannelID,
+			Message:   p.formatHandoffSummary(change, openIncidents),
+		}
+		if err := p.client.Post.CreatePost(post); err != nil {
+			p.client.Log.Error("Failed to post handoff summary", "error", err.Error(), "schedule_id", change.ScheduleID)
+		}
+	}
+
+	return nil
+}
+
+func (p *Plugin) sendUpcomingShiftReminder(change *shiftChange, now time.Time) {
+	user, err := p.getMattermostUserForPagerDutyUser(change.Incoming.User)
+	if err != nil {
+		p.client.Log.Debug("Skipping upcoming shift reminder", "error", err.Error())
+		return
+	}
+
+	if !p.markHandoffNotified("upcoming", change, user.Id) {
+		return
+	}
+
+	message := fmt.Sprintf("Heads up! Your on-call shift for **%s** starts in %s (%s).",
+		change.ScheduleName, formatMinutes(change.At.Sub(now)), formatTimeForUser(change.At, user))
+
+	if err := p.client.Post.DM(p.botUserID, user.Id, &model.Post{Message: message}); err != nil {
+		p.client.Log.Error("Failed to send upcoming shift reminder", "error", err.Error(), "user_id", user.Id)
+	}
+}
+
+func (p *Plugin) sendHandoffNotice(change *shiftChange) {
+	user, err := p.getMattermostUserForPagerDutyUser(change.Outgoing.User)
+	if err != nil {
+		p.client.Log.Debug("Skipping handoff notice", "error", err.Error())
+		return
+	}
+
+	if !p.markHandoffNotified("handoff", change, user.Id) {
+		return
+	}
+
+	message := fmt.Sprintf("Your on-call shift for **%s** has ended.", change.ScheduleName)
+	if change.Incoming != nil {
+		message += fmt.Sprintf(" %s is now on call.", p.formatPagerDutyUser(change.Incoming.User))
+	}
+
+	if err := p.client.Post.DM(p.botUserID, user.Id, &model.Post{Message: message}); err != nil {
+		p.client.Log.Error("Failed to send handoff notice", "error", err.Error(), "user_id", user.Id)
+	}
+}
+
+// markHandoffNotified claims a notification so that it is sent once across the cluster. It
+// returns false if the notification was already sent or could not be recorded. Schedule IDs are
+// only unique within an account, so the account is part of the claim.
+func (p *Plugin) markHandoffNotified(kind string, change *shiftChange, userID string) bool {
+	key := fmt.Sprintf("%s_%s_%s_%s_%d", kind, change.Account, change.ScheduleID, userID, change.At.Unix())
+
+	marked, err := p.kvstore.MarkHandoffNotified(key, handoffNotifiedTTL)
+	if err != nil {
+		p.client.Log.Error("Failed to record handoff notification", "error", err.Error(), "key", key)
+		return false
+	}
+	return marked
+}
+
+func (p *Plugin) formatHandoffSummary(change *shiftChange, openIncidents []pagerduty.Incident) string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "#### On-call handoff for %s\n", change.ScheduleName)
+	if change.Outgoing != nil {
+		fmt.Fprintf(&sb, "**Outgoing:** %s\n", p.formatPagerDutyUser(change.Outgoing.User))
+	}
+	if change.Incoming != nil {
+		fmt.Fprintf(&sb, "**Incoming:** %s\n", p.formatPagerDutyUser(change.Incoming.User))
+	}
+
+	var incidents []pagerduty.Incident
+	for _, incident := range openIncidents {
+		if incident.EscalationPolicy != nil && slices.Contains(change.EscalationPolicyIDs, incident.EscalationPolicy.ID) {
+			incidents = append(incidents, incident)
+		}
+	}
+
+	if len(incidents) == 0 {
+		sb.WriteString("\nNo open incidents.")
+		return sb.String()
+	}
+
+	fmt.Fprintf(&sb, "\n**Open incidents (%d):**\n", len(incidents))
+	for _, incident := range incidents {
+		fmt.Fprintf(&sb, "- [#%d %s](%s) on %s (%s, %s urgency)\n",
+			incident.IncidentNumber, incident.Title, incident.HtmlURL, incident.Service.Summary, incident.Status, incident.Urgency)
+	}
+
+	return sb.String()
+}
+
+// formatMinutes renders a duration as a whole number of minutes, rounding up.
+func formatMinutes(d time.Duration) string {
+	minutes := int(math.Ceil(d.Minutes()))
+	if minutes == 1 {
+		return "1 minute"
+	}
+	return fmt.Sprintf("%d minutes", minutes)
+}
+
+// formatTimeForUser renders a time in the user's preferred timezone, falling back to UTC.
+func formatTimeForUser(t time.Time, user *model.User) string {
+	location := time.UTC
+	if loc, err := time.LoadLocation(user.GetPreferredTimezone()); err == nil {
+		location = loc
+	}
+	return t.In(location).Format("Mon Jan 2, 15:04 MST")
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
+)
+
+func TestFindShiftChanges(t *testing.T) {
+	now := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	oncall := func(userID, scheduleID, policyID, start, end string) pagerduty.OnCall {
+		return pagerduty.OnCall{
+			User:             pagerduty.User{ID: userID},
+			Schedule:         pagerduty.Schedule{ID: scheduleID, Name: scheduleID},
+			EscalationPolicy: &pagerduty.EscalationPolicy{ID: policyID},
+			Start:            start,
+			End:              end,
+		}
+	}
+
+	t.Run("detects handoff between two users", func(t *testing.T) {
+		oncalls := []pagerduty.OnCall{
+			oncall("USER1", "SCHED1", "EP1", "2024-01-01T00:00:00Z", "2024-01-01T09:10:00Z"),
+			oncall("USER2", "SCHED1", "EP1", "2024-01-01T09:10:00Z", "2024-01-01T17:00:00Z"),
+		}
+
+		changes := findShiftChanges(oncalls, now, now.Add(15*time.Minute))
+		require.Len(t, changes, 1)
+		assert.Equal(t, "SCHED1", changes[0].ScheduleID)
+		assert.Equal(t, now.Add(10*time.Minute), changes[0].At)
+		assert.Equal(t, "USER1", changes[0].Outgoing.User.ID)
+		assert.Equal(t, "USER2", changes[0].Incoming.User.ID)
+		assert.Equal(t, []string{"EP1"}, changes[0].EscalationPolicyIDs)
+	})
+
+	t.Run("ignores changes outside the interval", func(t *testing.T) {
+		oncalls := []pagerduty.OnCall{
+			oncall("USER1", "SCHED1", "EP1", "2024-01-01T00:00:00Z", "2024-01-01T09:00:00Z"),
+			oncall("USER2", "SCHED1", "EP1", "2024-01-01T09:00:00Z", "2024-01-01T17:00:00Z"),
+		}
+
+		assert.Empty(t, findShiftChanges(oncalls, now, now.Add(15*time.Minute)))
+		assert.Len(t, findShiftChanges(oncalls, now.Add(-5*time.Minute), now), 1)
+	})
+
+	t.Run("collapses schedules shared by several escalation policies", func(t *testing.T) {
+		oncalls := []pagerduty.OnCall{
+			oncall("USER1", "SCHED1", "EP1", "2024-01-01T00:00:00Z", "2024-01-01T09:05:00Z"),
+			oncall("USER1", "SCHED1", "EP2", "2024-01-01T00:00:00Z", "2024-01-01T09:05:00Z"),
+			oncall("USER2", "SCHED1", "EP1", "2024-01-01T09:05:00Z", "2024-01-01T17:00:00Z"),
+			oncall("USER2", "SCHED1", "EP2", "2024-01-01T09:05:00Z", "2024-01-01T17:00:00Z"),
+		}
+
+		changes := findShiftChanges(oncalls, now, now.Add(15*time.Minute))
+		require.Len(t, changes, 1)
+		assert.Equal(t, []string{"EP1", "EP2"}, changes[0].EscalationPolicyIDs)
+	})
+
+	t.Run("skips consecutive shifts of the same user", func(t *testing.T) {
+		oncalls := []pagerduty.OnCall{
+			oncall("USER1", "SCHED1", "EP1", "2024-01-01T00:00:00Z", "2024-01-01T09:05:00Z"),
+			oncall("USER1", "SCHED1", "EP1", "2024-01-01T09:05:00Z", "2024-01-01T17:00:00Z"),
+		}
+
+		assert.Empty(t, findShiftChanges(oncalls, now, now.Add(15*time.Minute)))
+	})
+
+	t.Run("skips on-calls without a schedule", func(t *testing.T) {
+		oncalls := []pagerduty.OnCall{
+			{User: pagerduty.User{ID: "USER1"}},
+		}
+
+		assert.Empty(t, findShiftChanges(oncalls, now, now.Add(15*time.Minute)))
+	})
+
+	t.Run("orders changes by time", func(t *testing.T) {
+		oncalls := []pagerduty.OnCall{
+			oncall("USER2", "SCHED2", "EP2", "2024-01-01T09:12:00Z", "2024-01-01T17:00:00Z"),
+			oncall("USER1", "SCHED1", "EP1", "2024-01-01T09:03:00Z", "2024-01-01T17:00:00Z"),
+		}
+
+		changes := findShiftChanges(oncalls, now, now.Add(15*time.Minute))
+		require.Len(t, changes, 2)
+		assert.Equal(t, "SCHED1", changes[0].ScheduleID)
+		assert.Nil(t, changes[0].Outgoing)
+		assert.Equal(t, "SCHED2", changes[1].ScheduleID)
+	})
+}
+
+func TestFormatMinutes(t *testing.T) {
+	assert.Equal(t, "1 minute", formatMinutes(30*time.Second))
+	assert.Equal(t, "15 minutes", formatMinutes(15*time.Minute))
+	assert.Equal(t, "15 minutes", formatMinutes(14*time.Minute+time.Second))
+}
+
+func TestPlugin_markHandoffNotified(t *testing.T) {
+	plugin, _, _ := setupHandlerTestPlugin(t)
+	at := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+
+	// Schedule IDs are only unique within an account.
+	change := &shiftChange{ScheduleID: "SCHED1", At: at}
+	euChange := &shiftChange{Account: "eu", ScheduleID: "SCHED1", At: at}
+
+	assert.True(t, plugin.markHandoffNotified("handoff", change, "user1"))
+	assert.True(t, plugin.markHandoffNotified("handoff", euChange, "user1"))
+	assert.False(t, plugin.markHandoffNotified("handoff", change, "user1"))
+	assert.False(t, plugin.markHandoffNotified("handoff", euChange, "user1"))
+}
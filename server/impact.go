@@ -0,0 +1,330 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
+	"github.com/svelle/mattermost-pagerduty-plugin/server/store/kvstore"
+)
+
+// impactPostMaxDelay is how late a scheduled impact post may be before it is skipped.
+const impactPostMaxDelay = time.Hour
+
+// ImpactReport is the current impact status of a set of business services, either chosen
+// individually or through a status dashboard.
+type ImpactReport struct {
+	Title    string             `json:"title"`
+	Impacts  []pagerduty.Impact `json:"impacts"`
+	Impacted int                `json:"impacted"`
+}
+
+// ImpactTargets are the business services or the status dashboard an impact report covers.
+type ImpactTargets struct {
+	BusinessServiceIDs []string `json:"business_service_ids,omitempty"`
+	StatusDashboardID  string   `json:"status_dashboard_id,omitempty"`
+}
+
+// PostImpactRequest represents the request body for posting the current impact to a channel
+type PostImpactRequest struct {
+	ImpactTargets
+	ChannelID string `json:"channel_id"`
+}
+
+// CreateImpactPostRequest represents the request body for scheduling impact posts
+type CreateImpactPostRequest struct {
+	ImpactTargets
+	ChannelID      string `json:"channel_id"`
+	CronExpression string `json:"cron_expression"`
+	TimeZone       string `json:"time_zone"`
+}
+
+// validateImpactTargets checks that exactly one kind of target is given.
+func validateImpactTargets(targets ImpactTargets) *APIError {
+	if (len(targets.BusinessServiceIDs) == 0) == (targets.StatusDashboardID == "") {
+		return &APIError{
+			ID:         "api.pagerduty.impact.targets.invalid",
+			Message:    "Either business_service_ids or status_dashboard_id is required",
+			StatusCode: http.StatusBadRequest,
+		}
+	}
+	return nil
+}
+
+// getImpactReport returns the current impact status of the given targets, most severely
+// impacted first.
+func (p *Plugin) getImpactReport(client *pagerduty.Client, targets ImpactTargets) (*ImpactReport, error) {
+	report := &ImpactReport{}
+
+	var response *pagerduty.ImpactsResponse
+	var err error
+	if targets.StatusDashboardID != "" {
+		dashboard, dashboardErr := client.GetStatusDashboard(targets.StatusDashboardID)
+		if dashboardErr != nil {
+			return nil, errors.Wrap(dashboardErr, "failed to get status dashboard")
+		}
+		report.Title = dashboard.StatusDashboard.Name
+
+		response, err = client.GetStatusDashboardImpacts(targets.StatusDashboardID)
+	} else {
+		response, err = client.GetBusinessServiceImpacts(targets.BusinessServiceIDs)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get business service impacts")
+	}
+
+	report.Impacts = response.Services
+	sortImpacts(report.Impacts)
+	for _, impact := range report.Impacts {
+		if impact.Status == pagerduty.ImpactStatusImpacted {
+			report.Impacted++
+		}
+	}
+
+	if report.Title == "" {
+		names := make([]string, 0, len(report.Impacts))
+		for _, impact := range report.Impacts {
+			names = append(names, impact.Name)
+		}
+		report.Title = strings.Join(names, ", ")
+	}
+
+	return report, nil
+}
+
+// sortImpacts orders impacted services first, by the priority of their most severe incident,
+// then by name.
+func sortImpacts(impacts []pagerduty.Impact) {
+	priority := func(impact pagerduty.Impact) int {
+		if impact.Status != pagerduty.ImpactStatusImpacted {
+			return -1
+		}
+		if impact.AdditionalFields == nil || impact.AdditionalFields.HighestImpactingPriority == nil {
+			return 0
+		}
+		return impact.AdditionalFields.HighestImpactingPriority.Order
+	}
+
+	sort.SliceStable(impacts, func(i, j int) bool {
+		if pi, pj := priority(impacts[i]), priority(impacts[j]); pi != pj {
+			return pi > pj
+		}
+		return impacts[i].Name < impacts[j].Name
+	})
+}
+
+func formatImpactReport(report *ImpactReport, now time.Time) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "#### Service status: %s\n", report.Title)
+
+	switch {
+	case len(report.Impacts) == 0:
+		sb.WriteString("_No business services found._\n")
+	case report.Impacted == 0:
+		sb.WriteString(":large_green_circle: All services are operational.\n")
+	default:
+		fmt.Fprintf(&sb, ":red_circle: %d of %d services impacted.\n", report.Impacted, len(report.Impacts))
+	}
+
+	for _, impact := range report.Impacts {
+		if impact.Status != pagerduty.ImpactStatusImpacted {
+			fmt.Fprintf(&sb, "- :large_green_circle: **%s** - Operational\n", impact.Name)
+			continue
+		}
+
+		status := "Impacted"
+		if impact.AdditionalFields != nil && impact.AdditionalFields.HighestImpactingPriority != nil && impact.AdditionalFields.HighestImpactingPriority.Summary != "" {
+			status += fmt.Sprintf(" (%s)", impact.AdditionalFields.HighestImpactingPriority.Summary)
+		}
+		fmt.Fprintf(&sb, "- :red_circle: **%s** - %s\n", impact.Name, status)
+	}
+
+	fmt.Fprintf(&sb, "_As of %s_", now.Format("Mon Jan 2, 15:04 MST"))
+	return sb.String()
+}
+
+// postImpactReport posts the current impact status of the targets of an account to a channel.
+func (p *Plugin) postImpactReport(account, channelID string, targets ImpactTargets, location *time.Location) error {
+	client, apiErr := p.accountClient(account)
+	if apiErr != nil {
+		return errors.New(apiErr.Message)
+	}
+
+	report, err := p.getImpactReport(client, targets)
+	if err != nil {
+		return err
+	}
+
+	post := &model.Post{
+		UserId:    p.botUserID,
+		ChannelId: channelID,
+		Message:   formatImpactReport(report, time.Now().In(location)),
+	}
+	return p.client.Post.CreatePost(post)
+}
+
+// postImpact posts the current impact status of the targets of an account to a channel on
+// behalf of the given user.
+func (p *Plugin) postImpact(userID, account string, req *PostImpactRequest) *APIError {
+	if apiErr := p.checkAccount(account); apiErr != nil {
+		return apiErr
+	}
+	if apiErr := validateImpactTargets(req.ImpactTargets); apiErr != nil {
+		return apiErr
+	}
+
+	if req.ChannelID == "" {
+		return &APIError{
+			ID:         "api.pagerduty.impact.channel.missing",
+			Message:    "channel_id is required",
+			StatusCode: http.StatusBadRequest,
+		}
+	}
+
+	if !p.client.User.HasPermissionToChannel(userID, req.ChannelID, model.PermissionCreatePost) {
+		return &APIError{
+			ID:         "api.pagerduty.impact.permission",
+			Message:    "You do not have permission to post in this channel",
+			StatusCode: http.StatusForbidden,
+		}
+	}
+
+	if err := p.postImpactReport(account, req.ChannelID, req.ImpactTargets, time.UTC); err != nil {
+		p.client.Log.Error("Failed to post impact report", "error", err.Error(), "channel_id", req.ChannelID)
+		return &APIError{
+			ID:         "api.pagerduty.impact.post.error",
+			Message:    "Failed to post the service status",
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+// createImpactPost validates and stores a new scheduled impact post of an account on behalf of
+// the given user.
+func (p *Plugin) createImpactPost(userID, account string, req *CreateImpactPostRequest) (*kvstore.ImpactPost, *APIError) {
+	if apiErr := p.checkAccount(account); apiErr != nil {
+		return nil, apiErr
+	}
+	if apiErr := validateImpactTargets(req.ImpactTargets); apiErr != nil {
+		return nil, apiErr
+	}
+
+	timeZone, apiErr := p.validateScheduledPost("impact", userID, req.ChannelID, req.CronExpression, req.TimeZone)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	post := &kvstore.ImpactPost{
+		ID:                 model.NewId(),
+		Account:            account,
+		ChannelID:          req.ChannelID,
+		CronExpression:     req.CronExpression,
+		TimeZone:           timeZone,
+		BusinessServiceIDs: req.BusinessServiceIDs,
+		StatusDashboardID:  req.StatusDashboardID,
+		CreatorID:          userID,
+		CreateAt:           model.GetMillis(),
+	}
+
+	if err := p.kvstore.SaveImpactPost(post); err != nil {
+		p.client.Log.Error("Failed to save impact post", "error", err.Error())
+		return nil, &APIError{
+			ID:         "api.pagerduty.impact.save.error",
+			Message:    "Failed to save scheduled service status post",
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+
+	return post, nil
+}
+
+// getChannelImpactPosts returns the scheduled impact posts of a channel, oldest first.
+func (p *Plugin) getChannelImpactPosts(channelID string) ([]*kvstore.ImpactPost, error) {
+	posts, err := p.kvstore.ListImpactPosts()
+	if err != nil {
+		return nil, err
+	}
+
+	channelPosts := []*kvstore.ImpactPost{}
+	for _, post := range posts {
+		if post.ChannelID == channelID {
+			channelPosts = append(channelPosts, post)
+		}
+	}
+
+	sort.Slice(channelPosts, func(i, j int) bool {
+		return channelPosts[i].CreateAt < channelPosts[j].CreateAt
+	})
+
+	return channelPosts, nil
+}
+
+// runImpactPosts posts every scheduled impact report that has come due since its last post.
+func (p *Plugin) runImpactPosts(now time.Time) error {
+	posts, err := p.kvstore.ListImpactPosts()
+	if err != nil {
+		return errors.Wrap(err, "failed to list impact posts")
+	}
+
+	for _, post := range posts {
+		next, err := nextScheduledPost(post.CronExpression, post.TimeZone, max(post.CreateAt, post.LastPostAt))
+		if err != nil {
+			p.client.Log.Warn("Skipping invalid impact post", "impact_post_id", post.ID, "error", err.Error())
+			continue
+		}
+		if next.IsZero() || next.After(now) {
+			continue
+		}
+
+		targets := ImpactTargets{BusinessServiceIDs: post.BusinessServiceIDs, StatusDashboardID: post.StatusDashboardID}
+		if now.Sub(next) > impactPostMaxDelay {
+			p.client.Log.Info("Skipping overdue impact post", "impact_post_id", post.ID, "due", next.Format(time.RFC3339))
+		} else if err := p.postImpactReport(post.Account, post.ChannelID, targets, next.Location()); err != nil {
+			p.client.Log.Error("Failed to post impact report", "impact_post_id", post.ID, "error", err.Error())
+			continue
+		}
+
+		post.LastPostAt = now.UnixMilli()
+		if err := p.kvstore.SaveImpactPost(post); err != nil {
+			p.client.Log.Error("Failed to update impact post", "impact_post_id", post.ID, "error", err.Error())
+		}
+	}
+
+	return nil
+}
+
+// parseImpactTargets parses a comma-separated list of `service:<id>` business service targets,
+// or a single `dashboard:<id>` target.
+func parseImpactTargets(targets string) (ImpactTargets, error) {
+	var result ImpactTargets
+	for _, target := range strings.Split(targets, ",") {
+		kind, id, _ := strings.Cut(target, ":")
+		if id == "" {
+			kind = ""
+		}
+		switch kind {
+		case "service":
+			result.BusinessServiceIDs = append(result.BusinessServiceIDs, id)
+		case "dashboard":
+			if result.StatusDashboardID != "" {
+				return ImpactTargets{}, errors.New("only one dashboard can be given")
+			}
+			result.StatusDashboardID = id
+		default:
+			return ImpactTargets{}, errors.Errorf("invalid target `%s`", target)
+		}
+	}
+
+	if len(result.BusinessServiceIDs) > 0 && result.StatusDashboardID != "" {
+		return ImpactTargets{}, errors.New("services and a dashboard cannot be combined")
+	}
+	return result, nil
+}
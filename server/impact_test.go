@@ -0,0 +1,74 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
+)
+
+func TestParseImpactTargets(t *testing.T) {
+	t.Run("business services", func(t *testing.T) {
+		targets, err := parseImpactTargets("service:BS1,service:BS2")
+		require.NoError(t, err)
+		assert.Equal(t, ImpactTargets{BusinessServiceIDs: []string{"BS1", "BS2"}}, targets)
+	})
+
+	t.Run("dashboard", func(t *testing.T) {
+		targets, err := parseImpactTargets("dashboard:SD1")
+		require.NoError(t, err)
+		assert.Equal(t, ImpactTargets{StatusDashboardID: "SD1"}, targets)
+	})
+
+	for _, invalid := range []string{"BS1", "service:", "schedule:S1", "dashboard:SD1,dashboard:SD2", "service:BS1,dashboard:SD1"} {
+		t.Run(invalid, func(t *testing.T) {
+			_, err := parseImpactTargets(invalid)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestFormatImpactReport(t *testing.T) {
+	now := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+
+	impacted := func(name, priority string, order int) pagerduty.Impact {
+		return pagerduty.Impact{
+			Name:   name,
+			Status: pagerduty.ImpactStatusImpacted,
+			AdditionalFields: &pagerduty.ImpactAdditionalFields{
+				HighestImpactingPriority: &pagerduty.PriorityReference{Summary: priority, Order: order},
+			},
+		}
+	}
+
+	t.Run("impacted services first", func(t *testing.T) {
+		impacts := []pagerduty.Impact{
+			{Name: "Search", Status: pagerduty.ImpactStatusNotImpacted},
+			impacted("Checkout", "P2", 128),
+			impacted("Payments", "P1", 256),
+		}
+		sortImpacts(impacts)
+
+		report := &ImpactReport{Title: "Storefront", Impacts: impacts, Impacted: 2}
+		assert.Equal(t, "#### Service status: Storefront\n"+
+			":red_circle: 2 of 3 services impacted.\n"+
+			"- :red_circle: **Payments** - Impacted (P1)\n"+
+			"- :red_circle: **Checkout** - Impacted (P2)\n"+
+			"- :large_green_circle: **Search** - Operational\n"+
+			"_As of Mon Jan 15, 09:00 UTC_", formatImpactReport(report, now))
+	})
+
+	t.Run("all operational", func(t *testing.T) {
+		report := &ImpactReport{
+			Title:   "Checkout",
+			Impacts: []pagerduty.Impact{{Name: "Checkout", Status: pagerduty.ImpactStatusNotImpacted}},
+		}
+		assert.Equal(t, "#### Service status: Checkout\n"+
+			":large_green_circle: All services are operational.\n"+
+			"- :large_green_circle: **Checkout** - Operational\n"+
+			"_As of Mon Jan 15, 09:00 UTC_", formatImpactReport(report, now))
+	})
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
+)
+
+const (
+	mergeDialogPath = "/api/v1/dialogs/merge"
+
+	mergeParentField       = "parent"
+	mergeSourceFieldPrefix = "merge_"
+)
+
+// MergeIncidentsRequest represents the request body for merging incidents into a parent
+type MergeIncidentsRequest struct {
+	SourceIncidentIDs []string `json:"source_incident_ids"`
+}
+
+// mergeIncidents merges the source incidents of an account into the parent on behalf of the
+// given user, then updates the posts of the channels following any of them.
+func (p *Plugin) mergeIncidents(userID, account, parentID string, sourceIDs []string) (_ *pagerduty.Incident, apiErr *APIError) {
+	defer func() { p.recordAudit(userID, account, auditActionMergeIncidents, parentID, apiErr) }()
+
+	if parentID == "" || len(sourceIDs) == 0 {
+		return nil, &APIError{
+			ID:         "api.pagerduty.merge.fields.missing",
+			Message:    "A parent incident and at least one incident to merge are required",
+			StatusCode: http.StatusBadRequest,
+		}
+	}
+	if slices.Contains(sourceIDs, parentID) {
+		return nil, &APIError{
+			ID:         "api.pagerduty.merge.parent.invalid",
+			Message:    "An incident cannot be merged into itself",
+			StatusCode: http.StatusBadRequest,
+		}
+	}
+
+	user, err := p.client.User.Get(userID)
+	if err != nil {
+		p.client.Log.Error("Failed to get user", "error", err.Error(), "user_id", userID)
+		return nil, &APIError{
+			ID:         "api.pagerduty.merge.user.error",
+			Message:    "Failed to retrieve user",
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+
+	client, apiErr := p.accountClient(account)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	// The source incidents are looked up first, as their details are needed to update the posts
+	// of the channels following them.
+	sources := make([]pagerduty.Incident, 0, len(sourceIDs))
+	for _, sourceID := range sourceIDs {
+		source, err := client.GetIncident(sourceID)
+		if err != nil {
+			p.client.Log.Warn("Failed to get incident from PagerDuty", "error", err.Error(), "incident_id", sourceID)
+			sources = append(sources, pagerduty.Incident{ID: sourceID})
+			continue
+		}
+		sources = append(sources, source.Incident)
+	}
+
+	response, err := client.MergeIncidents(parentID, user.Email, sourceIDs)
+	if err != nil {
+		p.client.Log.Error("Failed to merge incidents in PagerDuty", "error", err.Error(), "incident_id", parentID)
+		return nil, &APIError{
+			ID:         "api.pagerduty.merge.error",
+			Message:    "Failed to merge incidents",
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+
+	parent := &response.Incident
+	p.updateMergedIncidentPosts(account, parent, sources, user)
+
+	return parent, nil
+}
+
+// updateMergedIncidentPosts marks the posts of merged incidents as merged and moves the
+// channels following them over to the parent incident, noting the merge in its thread.
+func (p *Plugin) updateMergedIncidentPosts(account string, parent *pagerduty.Incident, sources []pagerduty.Incident, mergedBy *model.User) {
+	mergedByChannel := map[string][]string{}
+	for i := range sources {
+		source := &sources[i]
+
+		subscriptions, err := p.kvstore.ListIncidentSubscriptionsForIncident(account, source.ID)
+		if err != nil {
+			p.client.Log.Error("Failed to list incident subscriptions", "error", err.Error(), "incident_id", source.ID)
+			continue
+		}
+
+		for _, subscription := range subscriptions {
+			if post, err := p.client.Post.GetPost(subscription.PostID); err == nil {
+				post.Message = formatMergedIncidentSummary(source, parent)
+				post.DelProp("attachments")
+				if err := p.client.Post.UpdatePost(post); err != nil {
+					p.client.Log.Warn("Failed to update merged incident post", "error", err.Error(), "post_id", post.Id)
+				}
+			}
+
+			if err := p.kvstore.DeleteIncidentSubscription(account, source.ID, subscription.ChannelID); err != nil {
+				p.client.Log.Error("Failed to delete incident subscription", "error", err.Error(), "incident_id", source.ID)
+			}
+			mergedByChannel[subscription.ChannelID] = append(mergedByChannel[subscription.ChannelID], formatIncidentTitle(source))
+		}
+	}
+
+	for channelID, merged := range mergedByChannel {
+		subscription, err := p.kvstore.GetIncidentSubscription(account, parent.ID, channelID)
+		if err != nil {
+			p.client.Log.Error("Failed to get incident subscription", "error", err.Error(), "incident_id", parent.ID)
+			continue
+		}
+
+		if subscription == nil {
+			if subscription, err = p.followIncident(account, channelID, mergedBy.Id, parent); err != nil {
+				p.client.Log.Error("Failed to follow parent incident", "error", err.Error(), "incident_id", parent.ID)
+				continue
+			}
+		} else if post, err := p.client.Post.GetPost(subscription.PostID); err == nil {
+			post.Message = formatIncidentSummary(parent)
+			if err := p.client.Post.UpdatePost(post); err != nil {
+				p.client.Log.Warn("Failed to update parent incident post", "error", err.Error(), "post_id", post.Id)
+			}
+		}
+
+		post := &model.Post{
+			UserId:    p.botUserID,
+			ChannelId: channelID,
+			RootId:    subscription.PostID,
+			Message:   fmt.Sprintf(":twisted_rightwards_arrows: @%s merged %s into this incident.", mergedBy.Username, strings.Join(merged, ", ")),
+		}
+		if err := p.client.Post.CreatePost(post); err != nil {
+			p.client.Log.Error("Failed to post incident merge", "error", err.Error(), "incident_id", parent.ID)
+		}
+	}
+}
+
+// openMergeDialog opens the dialog for merging the open incidents of an account a channel
+// follows. If there are too few of them to merge, a message explaining why is returned instead.
+func (p *Plugin) openMergeDialog(triggerID, account, channelID string) (string, error) {
+	subscriptions, err := p.getChannelIncidentSubscriptions(channelID)
+	if err != nil {
+		return "", err
+	}
+
+	client, apiErr := p.accountClient(account)
+	if apiErr != nil {
+		return apiErr.Message, nil
+	}
+
+	var incidents []pagerduty.Incident
+	for _, subscription := range subscriptions {
+		if subscription.Account != account {
+			continue
+		}
+
+		incident, err := client.GetIncident(subscription.IncidentID)
+		if err != nil {
+			p.client.Log.Warn("Failed to get incident from PagerDuty", "error", err.Error(), "incident_id", subscription.IncidentID)
+			continue
+		}
+		if incident.Incident.Status != "resolved" {
+			incidents = append(incidents, incident.Incident)
+		}
+	}
+	if len(incidents) < 2 {
+		return "This channel needs to follow at least two open incidents to merge them. Follow more with `/pagerduty incident subscribe <id>`.", nil
+	}
+
+	options := make([]*model.PostActionOptions, 0, len(incidents))
+	elements := []model.DialogElement{{
+		DisplayName: "Parent incident",
+		Name:        mergeParentField,
+		Type:        "select",
+		HelpText:    "The incident the others are merged into. It keeps its alerts and receives theirs.",
+	}}
+	for i := range incidents {
+		label := fmt.Sprintf("#%d %s", incidents[i].IncidentNumber, incidents[i].Title)
+		options = append(options, &model.PostActionOptions{Text: label, Value: incidents[i].ID})
+		elements = append(elements, model.DialogElement{
+			DisplayName: label,
+			Name:        mergeSourceFieldPrefix + incidents[i].ID,
+			Type:        "bool",
+			Placeholder: "Merge into the parent incident",
+			Optional:    true,
+		})
+	}
+	elements[0].Options = options
+
+	return "", p.client.Frontend.OpenInteractiveDialog(model.OpenDialogRequest{
+		TriggerId: triggerID,
+		URL:       fmt.Sprintf("/plugins/%s%s", p.API.GetPluginID(), mergeDialogPath),
+		Dialog: model.Dialog{
+			CallbackId:       channelID,
+			State:            account,
+			Title:            "Merge Incidents",
+			IntroductionText: "Pick a parent incident and the incidents to merge into it. Merged incidents are resolved.",
+			SubmitLabel:      "Merge",
+			Elements:         elements,
+		},
+	})
+}
+
+// handleMergeDialog receives the submission of the merge dialog.
+func (p *Plugin) handleMergeDialog(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	p.client.Log.Debug("handleMergeDialog called", "user_id", userID)
+
+	var submission model.SubmitDialogRequest
+	if err := json.NewDecoder(r.Body).Decode(&submission); err != nil {
+		p.client.Log.Warn("Failed to decode merge dialog submission", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.merge.decode.error",
+			Message:    "Invalid request body",
+			StatusCode: http.StatusBadRequest,
+		})
+		return
+	}
+
+	if submission.Cancelled {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	parentID, _ := submission.Submission[mergeParentField].(string)
+	var sourceIDs []string
+	for name, value := range submission.Submission {
+		incidentID, ok := strings.CutPrefix(name, mergeSourceFieldPrefix)
+		if selected, _ := value.(bool); ok && selected && incidentID != parentID {
+			sourceIDs = append(sourceIDs, incidentID)
+		}
+	}
+	slices.Sort(sourceIDs)
+
+	response := &model.SubmitDialogResponse{}
+	if len(sourceIDs) == 0 {
+		response.Errors = map[string]string{mergeParentField: "Select at least one other incident to merge into the parent."}
+	} else if _, apiErr := p.mergeIncidents(userID, submission.State, parentID, sourceIDs); apiErr != nil {
+		response.Error = apiErr.Message
+	} else {
+		p.client.Log.Info("Successfully merged incidents", "incident_id", parentID, "count", len(sourceIDs))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		p.client.Log.Error("Failed to encode dialog response", "error", err.Error())
+	}
+}
+
+// formatMergedIncidentSummary formats the post of an incident that was merged into another.
+func formatMergedIncidentSummary(source, parent *pagerduty.Incident) string {
+	return fmt.Sprintf("#### :twisted_rightwards_arrows: %s\nMerged into %s. Status updates are posted in the thread of the parent incident.",
+		formatIncidentTitle(source), formatIncidentTitle(parent))
+}
+
+// formatRelatedIncidents lists the incidents related to an incident with the reasons why.
+func formatRelatedIncidents(incidentID string, related []pagerduty.RelatedIncident) string {
+	if len(related) == 0 {
+		return fmt.Sprintf("No incidents related to `%s` were found.", incidentID)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "###### Incidents related to `%s`\n", incidentID)
+	for i := range related {
+		incident := &related[i].Incident
+
+		reasons := make([]string, 0, len(related[i].Relationships))
+		for _, relationship := range related[i].Relationships {
+			reasons = append(reasons, strings.ReplaceAll(relationship.Type, "_", " "))
+		}
+
+		fmt.Fprintf(&sb, "* `%s` %s - %s", incident.ID, formatIncidentTitle(incident), formatIncidentStatus(incident.Status))
+		if len(reasons) > 0 {
+			fmt.Fprintf(&sb, " (%s)", strings.Join(reasons, ", "))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
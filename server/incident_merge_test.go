@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
+)
+
+func TestFormatIncidentTitle(t *testing.T) {
+	t.Run("linked with number", func(t *testing.T) {
+		incident := &pagerduty.Incident{ID: "INC1", Title: "Checkout is down", IncidentNumber: 42, HtmlURL: "https://example.pagerduty.com/incidents/INC1"}
+		assert.Equal(t, "[#42 Checkout is down](https://example.pagerduty.com/incidents/INC1)", formatIncidentTitle(incident))
+	})
+
+	t.Run("falls back to the ID", func(t *testing.T) {
+		assert.Equal(t, "INC1", formatIncidentTitle(&pagerduty.Incident{ID: "INC1"}))
+	})
+}
+
+func TestFormatMergedIncidentSummary(t *testing.T) {
+	source := &pagerduty.Incident{ID: "INC2", Title: "Payments slow", IncidentNumber: 43}
+	parent := &pagerduty.Incident{ID: "INC1", Title: "Checkout is down", IncidentNumber: 42}
+
+	assert.Equal(t, "#### :twisted_rightwards_arrows: #43 Payments slow\n"+
+		"Merged into #42 Checkout is down. Status updates are posted in the thread of the parent incident.", formatMergedIncidentSummary(source, parent))
+}
+
+func TestFormatRelatedIncidents(t *testing.T) {
+	t.Run("none", func(t *testing.T) {
+		assert.Equal(t, "No incidents related to `INC1` were found.", formatRelatedIncidents("INC1", nil))
+	})
+
+	t.Run("with relationships", func(t *testing.T) {
+		related := []pagerduty.RelatedIncident{{
+			Incident: pagerduty.Incident{ID: "INC2", Title: "Payments slow", IncidentNumber: 43, Status: "triggered"},
+			Relationships: []pagerduty.RelatedIncidentRelationship{
+				{Type: "machine_learning_inferred"},
+				{Type: "service_dependency"},
+			},
+		}}
+		assert.Equal(t, "###### Incidents related to `INC1`\n"+
+			"* `INC2` #43 Payments slow - :red_circle: Triggered (machine learning inferred, service dependency)\n", formatRelatedIncidents("INC1", related))
+	})
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
+	"github.com/svelle/mattermost-pagerduty-plugin/server/store/kvstore"
+)
+
+const (
+	// idempotencyKeyTTL is how long the incident created for an idempotency key is remembered.
+	idempotencyKeyTTL = 24 * time.Hour
+
+	// derivedIdempotencyWindow is how long a request without an idempotency key is taken for a
+	// repeat of an earlier one by the same user, for the same service and with the same title,
+	// such as a double-click on the page button.
+	derivedIdempotencyWindow = 2 * time.Minute
+
+	// incidentRequestPendingTTL releases the idempotency key of a request that never finished,
+	// such as one whose server went down while creating the incident.
+	incidentRequestPendingTTL = time.Minute
+
+	maxIdempotencyKeyLength = 255
+)
+
+// incidentIdempotencyKey returns the key identifying a request to create an incident, and for
+// how long the incident it creates is remembered. Keys are scoped to the user and account, so
+// that users cannot replay each other's requests. Without a key given by the client, one is
+// derived from the service and title.
+func incidentIdempotencyKey(userID, account string, req *CreateIncidentRequest) (string, time.Duration) {
+	if req.IdempotencyKey != "" {
+		return hashIdempotencyKey(userID, account, "key", req.IdempotencyKey), idempotencyKeyTTL
+	}
+
+	title := strings.ToLower(strings.Join(strings.Fields(req.Title), " "))
+	return hashIdempotencyKey(userID, account, "derived", req.ServiceID, title), derivedIdempotencyWindow
+}
+
+func hashIdempotencyKey(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// beginIncidentRequest reserves an idempotency key for creating an incident. If the key was
+// used before, it returns the incident created then, or a conflict if that incident is still
+// being created. Failing to reach the KV store lets the request through.
+func (p *Plugin) beginIncidentRequest(key string) (*pagerduty.CreateIncidentResponse, *APIError) {
+	reserved, err := p.kvstore.ReserveIncidentRequest(key, &kvstore.IncidentRequest{CreateAt: model.GetMillis()}, incidentRequestPendingTTL)
+	if err != nil {
+		p.client.Log.Warn("Failed to reserve idempotency key", "error", err.Error())
+		return nil, nil
+	}
+	if reserved {
+		return nil, nil
+	}
+
+	existing, err := p.kvstore.GetIncidentRequest(key)
+	if err != nil {
+		p.client.Log.Warn("Failed to get incident request", "error", err.Error())
+		return nil, nil
+	}
+	if existing == nil {
+		// The earlier request expired in the meantime.
+		return nil, nil
+	}
+
+	if len(existing.Response) == 0 {
+		return nil, &APIError{
+			ID:         "api.pagerduty.incident.in_progress",
+			Message:    "This incident is already being created",
+			StatusCode: http.StatusConflict,
+		}
+	}
+
+	var response pagerduty.CreateIncidentResponse
+	if err := json.Unmarshal(existing.Response, &response); err != nil {
+		p.client.Log.Warn("Failed to decode incident request", "error", err.Error())
+		return nil, nil
+	}
+	return &response, nil
+}
+
+// finishIncidentRequest remembers the incident created for an idempotency key.
+func (p *Plugin) finishIncidentRequest(key string, response *pagerduty.CreateIncidentResponse, ttl time.Duration) {
+	data, err := json.Marshal(response)
+	if err == nil {
+		err = p.kvstore.SaveIncidentRequest(key, &kvstore.IncidentRequest{Response: data, CreateAt: model.GetMillis()}, ttl)
+	}
+	if err != nil {
+		p.client.Log.Warn("Failed to save incident request", "error", err.Error(), "incident_id", response.Incident.ID)
+	}
+}
+
+// abandonIncidentRequest releases the idempotency key of a request that failed, so that it can
+// be retried.
+func (p *Plugin) abandonIncidentRequest(key string) {
+	if err := p.kvstore.DeleteIncidentRequest(key); err != nil {
+		p.client.Log.Warn("Failed to release idempotency key", "error", err.Error())
+	}
+}
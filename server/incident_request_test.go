@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIncidentIdempotencyKey(t *testing.T) {
+	t.Run("client key is scoped to the user and account", func(t *testing.T) {
+		req := &CreateIncidentRequest{Title: "Database down", ServiceID: "SVC1", IdempotencyKey: "abc"}
+
+		key, ttl := incidentIdempotencyKey("user1", "", req)
+		assert.Equal(t, idempotencyKeyTTL, ttl)
+		assert.Len(t, key, 64)
+
+		otherUserKey, _ := incidentIdempotencyKey("user2", "", req)
+		assert.NotEqual(t, key, otherUserKey)
+
+		sameKey, _ := incidentIdempotencyKey("user1", "", &CreateIncidentRequest{Title: "Other title", ServiceID: "SVC2", IdempotencyKey: "abc"})
+		assert.Equal(t, key, sameKey)
+
+		otherAccountKey, _ := incidentIdempotencyKey("user1", "eu", req)
+		assert.NotEqual(t, key, otherAccountKey)
+	})
+
+	t.Run("derived key ignores case and spacing of the title", func(t *testing.T) {
+		key, ttl := incidentIdempotencyKey("user1", "", &CreateIncidentRequest{Title: "Database down", ServiceID: "SVC1"})
+		assert.Equal(t, derivedIdempotencyWindow, ttl)
+
+		sameKey, _ := incidentIdempotencyKey("user1", "", &CreateIncidentRequest{Title: "  database   Down ", ServiceID: "SVC1"})
+		assert.Equal(t, key, sameKey)
+
+		otherServiceKey, _ := incidentIdempotencyKey("user1", "", &CreateIncidentRequest{Title: "Database down", ServiceID: "SVC2"})
+		assert.NotEqual(t, key, otherServiceKey)
+	})
+
+	t.Run("derived and client keys do not collide", func(t *testing.T) {
+		derived, _ := incidentIdempotencyKey("user1", "", &CreateIncidentRequest{Title: "x", ServiceID: "SVC1"})
+		client, _ := incidentIdempotencyKey("user1", "", &CreateIncidentRequest{Title: "x", ServiceID: "SVC1", IdempotencyKey: derived})
+		assert.NotEqual(t, derived, client)
+	})
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
+	"github.com/svelle/mattermost-pagerduty-plugin/server/store/kvstore"
+)
+
+// statusUpdateMirrorTTL is how long mirrored status updates are remembered, well beyond the
+// time PagerDuty takes to deliver a webhook.
+const statusUpdateMirrorTTL = 7 * 24 * time.Hour
+
+// SubscribeIncidentRequest represents the request body for subscribing a channel to an incident
+type SubscribeIncidentRequest struct {
+	ChannelID string `json:"channel_id"`
+}
+
+// subscribeChannelToIncident has a channel follow an incident, posting a summary of it under
+// which its status updates are mirrored.
+func (p *Plugin) subscribeChannelToIncident(userID, account, channelID, incidentID string) (*kvstore.IncidentSubscription, *APIError) {
+	if channelID == "" || incidentID == "" {
+		return nil, &APIError{
+			ID:         "api.pagerduty.subscription.fields.missing",
+			Message:    "channel_id and incident ID are required",
+			StatusCode: http.StatusBadRequest,
+		}
+	}
+
+	if !p.client.User.HasPermissionToChannel(userID, channelID, model.PermissionCreatePost) {
+		return nil, &APIError{
+			ID:         "api.pagerduty.subscription.permission",
+			Message:    "You do not have permission to post in this channel",
+			StatusCode: http.StatusForbidden,
+		}
+	}
+
+	existing, err := p.kvstore.GetIncidentSubscription(account, incidentID, channelID)
+	if err != nil {
+		p.client.Log.Error("Failed to get incident subscription", "error", err.Error(), "incident_id", incidentID)
+		return nil, &APIError{
+			ID:         "api.pagerduty.subscription.get.error",
+			Message:    "Failed to retrieve incident subscription",
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+	if existing != nil {
+		return existing, nil
+	}
+
+	client, apiErr := p.accountClient(account)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	incident, err := client.GetIncident(incidentID)
+	if err != nil {
+		p.client.Log.Warn("Failed to get incident from PagerDuty", "error", err.Error(), "incident_id", incidentID)
+		return nil, &APIError{
+			ID:         "api.pagerduty.subscription.incident.not_found",
+			Message:    fmt.Sprintf("Incident %s was not found", incidentID),
+			StatusCode: http.StatusNotFound,
+		}
+	}
+
+	subscription, err := p.followIncident(account, channelID, userID, &incident.Incident)
+	if err != nil {
+		p.client.Log.Error("Failed to follow incident", "error", err.Error(), "incident_id", incidentID)
+		return nil, &APIError{
+			ID:         "api.pagerduty.subscription.save.error",
+			Message:    "Failed to post the incident to the channel",
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+
+	return subscription, nil
+}
+
+// followIncident posts the summary of an incident of an account to a channel and subscribes the
+// channel to it.
+func (p *Plugin) followIncident(account, channelID, creatorID string, incident *pagerduty.Incident) (*kvstore.IncidentSubscription, error) {
+	post := &model.Post{
+		UserId:    p.botUserID,
+		ChannelId: channelID,
+		Message:   formatIncidentSummary(incident),
+	}
+	model.ParseSlackAttachment(post, p.incidentSummaryAttachments(account, incident.ID))
+	if err := p.client.Post.CreatePost(post); err != nil {
+		return nil, errors.Wrap(err, "failed to post incident summary")
+	}
+
+	subscription := &kvstore.IncidentSubscription{
+		IncidentID: incident.ID,
+		Account:    account,
+		ChannelID:  channelID,
+		PostID:     post.Id,
+		CreatorID:  creatorID,
+		CreateAt:   model.GetMillis(),
+	}
+	if err := p.kvstore.SaveIncidentSubscription(subscription); err != nil {
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+// unsubscribeChannelFromIncident stops mirroring an incident's status updates to a channel.
+func (p *Plugin) unsubscribeChannelFromIncident(userID, account, channelID, incidentID string) *APIError {
+	if !p.client.User.HasPermissionToChannel(userID, channelID, model.PermissionCreatePost) {
+		return &APIError{
+			ID:         "api.pagerduty.subscription.permission",
+			Message:    "You do not have permission to post in this channel",
+			StatusCode: http.StatusForbidden,
+		}
+	}
+
+	subscription, err := p.kvstore.GetIncidentSubscription(account, incidentID, channelID)
+	if err != nil {
+		p.client.Log.Error("Failed to get incident subscription", "error", err.Error(), "incident_id", incidentID)
+		return &APIError{
+			ID:         "api.pagerduty.subscription.get.error",
+			Message:    "Failed to retrieve incident subscription",
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+	if subscription == nil {
+		return &APIError{
+			ID:         "api.pagerduty.subscription.not_found",
+			Message:    fmt.Sprintf("This channel is not subscribed to incident %s", incidentID),
+			StatusCode: http.StatusNotFound,
+		}
+	}
+
+	if err := p.kvstore.DeleteIncidentSubscription(account, incidentID, channelID); err != nil {
+		p.client.Log.Error("Failed to delete incident subscription", "error", err.Error(), "incident_id", incidentID)
+		return &APIError{
+			ID:         "api.pagerduty.subscription.delete.error",
+			Message:    "Failed to delete incident subscription",
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+	return nil
+}
+
+// getChannelIncidentSubscriptions returns the incidents a channel follows, oldest first.
+func (p *Plugin) getChannelIncidentSubscriptions(channelID string) ([]*kvstore.IncidentSubscription, error) {
+	subscriptions, err := p.kvstore.ListIncidentSubscriptions()
+	if err != nil {
+		return nil, err
+	}
+
+	channelSubscriptions := []*kvstore.IncidentSubscription{}
+	for _, subscription := range subscriptions {
+		if subscription.ChannelID == channelID {
+			channelSubscriptions = append(channelSubscriptions, subscription)
+		}
+	}
+
+	sort.Slice(channelSubscriptions, func(i, j int) bool {
+		return channelSubscriptions[i].CreateAt < channelSubscriptions[j].CreateAt
+	})
+
+	return channelSubscriptions, nil
+}
+
+// mirrorStatusUpdate posts a status update as a reply to the incident summary of every channel
+// following the incident. Each status update is mirrored once, however often it is delivered.
+func (p *Plugin) mirrorStatusUpdate(account, incidentID string, update *pagerduty.StatusUpdate) {
+	if update.ID != "" {
+		first, err := p.kvstore.MarkStatusUpdateMirrored(update.ID, statusUpdateMirrorTTL)
+		if err != nil {
+			p.client.Log.Error("Failed to mark status update mirrored", "error", err.Error(), "status_update_id", update.ID)
+			return
+		}
+		if !first {
+			return
+		}
+	}
+
+	p.postIncidentReply(account, incidentID, formatStatusUpdate(update))
+}
+
+// postIncidentReply posts a message as a reply to the incident summary of every channel
+// following the incident.
+func (p *Plugin) postIncidentReply(account, incidentID, message string) {
+	subscriptions, err := p.kvstore.ListIncidentSubscriptionsForIncident(account, incidentID)
+	if err != nil {
+		p.client.Log.Error("Failed to list incident subscriptions", "error", err.Error(), "incident_id", incidentID)
+		return
+	}
+
+	for _, subscription := range subscriptions {
+		post := &model.Post{
+			UserId:    p.botUserID,
+			ChannelId: subscription.ChannelID,
+			RootId:    subscription.PostID,
+			Message:   message,
+		}
+		if err := p.client.Post.CreatePost(post); err != nil {
+			p.client.Log.Error("Failed to post incident reply", "error", err.Error(), "incident_id", incidentID, "channel_id", subscription.ChannelID)
+		}
+	}
+}
+
+// refreshIncidentPosts updates the incident summary of every channel following the incident.
+func (p *Plugin) refreshIncidentPosts(account string, incident *pagerduty.Incident) {
+	subscriptions, err := p.kvstore.ListIncidentSubscriptionsForIncident(account, incident.ID)
+	if err != nil {
+		p.client.Log.Error("Failed to list incident subscriptions", "error", err.Error(), "incident_id", incident.ID)
+		return
+	}
+
+	message := formatIncidentSummary(incident)
+	for _, subscription := range subscriptions {
+		post, err := p.client.Post.GetPost(subscription.PostID)
+		if err != nil {
+			p.client.Log.Warn("Failed to get incident post", "error", err.Error(), "post_id", subscription.PostID)
+			continue
+		}
+		if post.Message == message {
+			continue
+		}
+
+		post.Message = message
+		if err := p.client.Post.UpdatePost(post); err != nil {
+			p.client.Log.Warn("Failed to update incident post", "error", err.Error(), "post_id", post.Id)
+		}
+	}
+}
+
+// incidentSummaryAttachments returns the actions offered on the summary post of an incident.
+func (p *Plugin) incidentSummaryAttachments(account, incidentID string) []*model.SlackAttachment {
+	context := map[string]any{"incident_id": incidentID, "account": account}
+	return []*model.SlackAttachment{{
+		Actions: []*model.PostAction{
+			{
+				Id:   "runworkflow",
+				Name: "Run workflow",
+				Type: model.PostActionTypeButton,
+				Integration: &model.PostActionIntegration{
+					URL:     fmt.Sprintf("/plugins/%s%s", p.API.GetPluginID(), incidentWorkflowActionPath),
+					Context: context,
+				},
+			},
+			{
+				Id:   "addresponders",
+				Name: "Add responders",
+				Type: model.PostActionTypeButton,
+				Integration: &model.PostActionIntegration{
+					URL:     fmt.Sprintf("/plugins/%s%s", p.API.GetPluginID(), responderActionPath),
+					Context: context,
+				},
+			},
+		},
+	}}
+}
+
+// formatIncidentSummary formats the post a channel following an incident is shown.
+func formatIncidentSummary(incident *pagerduty.Incident) string {
+	details := []string{"**Status:** " + formatIncidentStatus(incident.Status)}
+	if incident.Urgency != "" {
+		details = append(details, "**Urgency:** "+incident.Urgency)
+	}
+	if incident.Service.Summary != "" {
+		details = append(details, "**Service:** "+incident.Service.Summary)
+	}
+
+	summary := strings.Join(details, " · ")
+	if len(incident.IncidentsResponders) > 0 {
+		summary += "\n**Responders:** " + formatIncidentResponders(incident.IncidentsResponders)
+	}
+
+	return fmt.Sprintf("#### :rotating_light: %s\n%s\n_Status updates for this incident are posted in this thread._", formatIncidentTitle(incident), summary)
+}
+
+// formatIncidentTitle formats an incident as its number and title, linking to it in PagerDuty.
+func formatIncidentTitle(incident *pagerduty.Incident) string {
+	title := incident.Title
+	if title == "" {
+		title = incident.ID
+	}
+	if incident.IncidentNumber != 0 {
+		title = fmt.Sprintf("#%d %s", incident.IncidentNumber, title)
+	}
+	if incident.HtmlURL != "" {
+		title = fmt.Sprintf("[%s](%s)", title, incident.HtmlURL)
+	}
+	return title
+}
+
+func formatIncidentStatus(status string) string {
+	switch status {
+	case "triggered":
+		return ":red_circle: Triggered"
+	case "acknowledged":
+		return ":large_orange_circle: Acknowledged"
+	case "resolved":
+		return ":large_green_circle: Resolved"
+	default:
+		return status
+	}
+}
+
+func formatStatusUpdate(update *pagerduty.StatusUpdate) string {
+	var sb strings.Builder
+	sb.WriteString("**Status update**")
+	if update.Sender != nil && update.Sender.Summary != "" {
+		sb.WriteString(" from " + update.Sender.Summary)
+	}
+	if update.Subject != "" {
+		sb.WriteString(": " + update.Subject)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(update.Message), "\n") {
+		sb.WriteString("\n> " + line)
+	}
+	return sb.String()
+}
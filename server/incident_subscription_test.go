@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
+)
+
+func TestFormatIncidentSummary(t *testing.T) {
+	incident := &pagerduty.Incident{
+		Title:          "Checkout is down",
+		IncidentNumber: 42,
+		Status:         "acknowledged",
+		Urgency:        "high",
+		Service:        pagerduty.ServiceReference{ID: "SVC1", Summary: "Checkout"},
+		HtmlURL:        "https://example.pagerduty.com/incidents/INC1",
+	}
+
+	assert.Equal(t, "#### :rotating_light: [#42 Checkout is down](https://example.pagerduty.com/incidents/INC1)\n"+
+		"**Status:** :large_orange_circle: Acknowledged · **Urgency:** high · **Service:** Checkout\n"+
+		"_Status updates for this incident are posted in this thread._", formatIncidentSummary(incident))
+
+	t.Run("with responders", func(t *testing.T) {
+		incident := &pagerduty.Incident{
+			ID:     "INC1",
+			Status: "triggered",
+			IncidentsResponders: []pagerduty.IncidentsResponder{
+				{State: pagerduty.ResponderStateJoined, User: pagerduty.UserReference{ID: "PUSER1", Summary: "Bob"}},
+				{State: pagerduty.ResponderStatePending, User: pagerduty.UserReference{ID: "PUSER2", Summary: "Carol"}},
+			},
+		}
+
+		assert.Equal(t, "#### :rotating_light: INC1\n"+
+			"**Status:** :red_circle: Triggered\n"+
+			"**Responders:** Bob (joined), Carol (pending)\n"+
+			"_Status updates for this incident are posted in this thread._", formatIncidentSummary(incident))
+	})
+}
+
+func TestFormatStatusUpdate(t *testing.T) {
+	t.Run("with sender and subject", func(t *testing.T) {
+		update := &pagerduty.StatusUpdate{
+			Message: "Rolled back the deploy.\nMonitoring error rates.",
+			Subject: "Mitigated",
+			Sender:  &pagerduty.UserReference{ID: "USER1", Summary: "Alice"},
+		}
+		assert.Equal(t, "**Status update** from Alice: Mitigated\n> Rolled back the deploy.\n> Monitoring error rates.", formatStatusUpdate(update))
+	})
+
+	t.Run("message only", func(t *testing.T) {
+		update := &pagerduty.StatusUpdate{Message: "Investigating\n"}
+		assert.Equal(t, "**Status update**\n> Investigating", formatStatusUpdate(update))
+	})
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
+)
+
+const (
+	incidentWorkflowActionPath = "/api/v1/actions/workflow"
+	incidentWorkflowDialogPath = "/api/v1/dialogs/workflow"
+
+	incidentWorkflowField = "workflow"
+
+	incidentWorkflowStarted   = "started"
+	incidentWorkflowCompleted = "completed"
+)
+
+// StartIncidentWorkflowRequest represents the request body for starting an incident workflow
+type StartIncidentWorkflowRequest struct {
+	IncidentWorkflowID string `json:"incident_workflow_id"`
+}
+
+// getIncidentWorkflows returns the incident workflows that can be started, sorted by name.
+func (p *Plugin) getIncidentWorkflows(client *pagerduty.Client, query string) ([]pagerduty.IncidentWorkflow, error) {
+	params := url.Values{}
+	params.Set("limit", "100")
+	if query != "" {
+		params.Set("query", query)
+	}
+
+	response, err := client.ListIncidentWorkflows(params)
+	if err != nil {
+		return nil, err
+	}
+
+	workflows := response.IncidentWorkflows
+	sort.Slice(workflows, func(i, j int) bool {
+		return strings.ToLower(workflows[i].Name) < strings.ToLower(workflows[j].Name)
+	})
+	return workflows, nil
+}
+
+// startIncidentWorkflow starts an incident workflow on an incident of an account on behalf of
+// the given user, and announces it to the channels following the incident.
+func (p *Plugin) startIncidentWorkflow(userID, account, incidentID, workflowID string) (_ *pagerduty.IncidentWorkflowInstance, apiErr *APIError) {
+	defer func() { p.recordAudit(userID, account, auditActionStartIncidentWorkflow, incidentID, apiErr) }()
+
+	if incidentID == "" || workflowID == "" {
+		return nil, &APIError{
+			ID:         "api.pagerduty.incident_workflow.fields.missing",
+			Message:    "An incident and an incident_workflow_id are required",
+			StatusCode: http.StatusBadRequest,
+		}
+	}
+
+	user, err := p.client.User.Get(userID)
+	if err != nil {
+		p.client.Log.Error("Failed to get user", "error", err.Error(), "user_id", userID)
+		return nil, &APIError{
+			ID:         "api.pagerduty.incident_workflow.user.error",
+			Message:    "Failed to retrieve user",
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+
+	client, apiErr := p.accountClient(account)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	workflow, err := client.GetIncidentWorkflow(workflowID)
+	if err != nil {
+		p.client.Log.Warn("Failed to get incident workflow from PagerDuty", "error", err.Error(), "incident_workflow_id", workflowID)
+		return nil, &APIError{
+			ID:         "api.pagerduty.incident_workflow.not_found",
+			Message:    fmt.Sprintf("Incident workflow %s was not found", workflowID),
+			StatusCode: http.StatusNotFound,
+		}
+	}
+
+	response, err := client.StartIncidentWorkflow(workflowID, incidentID)
+	if err != nil {
+		p.client.Log.Error("Failed to start incident workflow in PagerDuty", "error", err.Error(), "incident_workflow_id", workflowID, "incident_id", incidentID)
+		return nil, &APIError{
+			ID:         "api.pagerduty.incident_workflow.start.error",
+			Message:    "Failed to start the incident workflow",
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+
+	instance := &response.IncidentWorkflowInstance
+	p.postIncidentWorkflowStatus(account, instance.ID, incidentID, incidentWorkflowStarted, formatIncidentWorkflowStatus(workflow.IncidentWorkflow.Name, incidentWorkflowStarted, user.Username))
+
+	return instance, nil
+}
+
+// postIncidentWorkflowStatus posts the status of an incident workflow instance to the threads of
+// the channels following the incident. Each status of an instance is posted once, however often
+// it is delivered.
+func (p *Plugin) postIncidentWorkflowStatus(account, instanceID, incidentID, status, message string) {
+	if instanceID != "" {
+		first, err := p.kvstore.MarkIncidentWorkflowStatusPosted(instanceID, status, statusUpdateMirrorTTL)
+		if err != nil {
+			p.client.Log.Error("Failed to mark incident workflow status posted", "error", err.Error(), "instance_id", instanceID)
+			return
+		}
+		if !first {
+			return
+		}
+	}
+
+	p.postIncidentReply(account, incidentID, message)
+}
+
+// openIncidentWorkflowDialog opens the dialog for starting an incident workflow on an incident
+// of an account. If there are no workflows to choose from, a message explaining why is returned
+// instead.
+func (p *Plugin) openIncidentWorkflowDialog(triggerID, account, incidentID string) (string, error) {
+	client, apiErr := p.accountClient(account)
+	if apiErr != nil {
+		return apiErr.Message, nil
+	}
+
+	workflows, err := p.getIncidentWorkflows(client, "")
+	if err != nil {
+		return "", err
+	}
+	if len(workflows) == 0 {
+		return "No incident workflows were found in PagerDuty.", nil
+	}
+
+	options := make([]*model.PostActionOptions, 0, len(workflows))
+	for _, workflow := range workflows {
+		options = append(options, &model.PostActionOptions{Text: workflow.Name, Value: workflow.ID})
+	}
+
+	return "", p.client.Frontend.OpenInteractiveDialog(model.OpenDialogRequest{
+		TriggerId: triggerID,
+		URL:       fmt.Sprintf("/plugins/%s%s", p.API.GetPluginID(), incidentWorkflowDialogPath),
+		Dialog: model.Dialog{
+			CallbackId:       incidentID,
+			State:            account,
+			Title:            "Run Workflow",
+			IntroductionText: fmt.Sprintf("Start an incident workflow on incident **%s**. Its progress is posted in the incident's thread.", incidentID),
+			SubmitLabel:      "Run",
+			Elements: []model.DialogElement{{
+				DisplayName: "Workflow",
+				Name:        incidentWorkflowField,
+				Type:        "select",
+				Options:     options,
+			}},
+		},
+	})
+}
+
+// handleIncidentWorkflowAction receives a click on the "Run workflow" button of an incident post.
+func (p *Plugin) handleIncidentWorkflowAction(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	p.client.Log.Debug("handleIncidentWorkflowAction called", "user_id", userID)
+
+	var request model.PostActionIntegrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		p.client.Log.Warn("Failed to decode incident workflow action", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.incident_workflow.decode.error",
+			Message:    "Invalid request body",
+			StatusCode: http.StatusBadRequest,
+		})
+		return
+	}
+
+	response := &model.PostActionIntegrationResponse{}
+	incidentID, _ := request.Context["incident_id"].(string)
+	account, _ := request.Context["account"].(string)
+	if message, err := p.openIncidentWorkflowDialog(request.TriggerId, account, incidentID); err != nil {
+		p.client.Log.Error("Failed to open incident workflow dialog", "error", err.Error(), "incident_id", incidentID)
+		response.EphemeralText = "Failed to open the workflow dialog."
+	} else if message != "" {
+		response.EphemeralText = message
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		p.client.Log.Error("Failed to encode action response", "error", err.Error())
+	}
+}
+
+// handleIncidentWorkflowDialog receives the submission of the incident workflow dialog.
+func (p *Plugin) handleIncidentWorkflowDialog(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	p.client.Log.Debug("handleIncidentWorkflowDialog called", "user_id", userID)
+
+	var submission model.SubmitDialogRequest
+	if err := json.NewDecoder(r.Body).Decode(&submission); err != nil {
+		p.client.Log.Warn("Failed to decode incident workflow dialog submission", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.incident_workflow.decode.error",
+			Message:    "Invalid request body",
+			StatusCode: http.StatusBadRequest,
+		})
+		return
+	}
+
+	if submission.Cancelled {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	workflowID, _ := submission.Submission[incidentWorkflowField].(string)
+
+	response := &model.SubmitDialogResponse{}
+	if workflowID == "" {
+		response.Errors = map[string]string{incidentWorkflowField: "Select a workflow to run."}
+	} else if _, apiErr := p.startIncidentWorkflow(userID, submission.State, submission.CallbackId, workflowID); apiErr != nil {
+		response.Error = apiErr.Message
+	} else {
+		p.client.Log.Info("Successfully started incident workflow", "incident_id", submission.CallbackId, "incident_workflow_id", workflowID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		p.client.Log.Error("Failed to encode dialog response", "error", err.Error())
+	}
+}
+
+// formatIncidentWorkflowStatus formats the status of an incident workflow run. The user who
+// started it is only known for workflows started from Mattermost.
+func formatIncidentWorkflowStatus(workflowName, status, username string) string {
+	switch {
+	case status == incidentWorkflowStarted && username != "":
+		return fmt.Sprintf(":arrow_forward: @%s started the **%s** workflow.", username, workflowName)
+	case status == incidentWorkflowStarted:
+		return fmt.Sprintf(":arrow_forward: The **%s** workflow started.", workflowName)
+	case status == incidentWorkflowCompleted:
+		return fmt.Sprintf(":white_check_mark: The **%s** workflow completed.", workflowName)
+	default:
+		return fmt.Sprintf("The **%s** workflow is %s.", workflowName, status)
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
+	"github.com/svelle/mattermost-pagerduty-plugin/server/store/kvstore"
+)
+
+func TestFormatIncidentWorkflowStatus(t *testing.T) {
+	assert.Equal(t, ":arrow_forward: @alice started the **Major Incident** workflow.", formatIncidentWorkflowStatus("Major Incident", incidentWorkflowStarted, "alice"))
+	assert.Equal(t, ":arrow_forward: The **Major Incident** workflow started.", formatIncidentWorkflowStatus("Major Incident", incidentWorkflowStarted, ""))
+	assert.Equal(t, ":white_check_mark: The **Major Incident** workflow completed.", formatIncidentWorkflowStatus("Major Incident", incidentWorkflowCompleted, ""))
+}
+
+// serveIncidentWorkflows serves the Major Incident workflow PIW1, whose instances fail to start
+// with the given status unless it is zero, and records the incidents it was started on.
+func serveIncidentWorkflows(startStatus int, started *[]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/incident_workflows/PIW1":
+			_ = json.NewEncoder(w).Encode(pagerduty.IncidentWorkflowResponse{
+				IncidentWorkflow: pagerduty.IncidentWorkflow{ID: "PIW1", Name: "Major Incident"},
+			})
+		case r.Method == http.MethodPost && r.URL.Path == "/incident_workflows/PIW1/instances":
+			if startStatus != 0 {
+				w.WriteHeader(startStatus)
+				return
+			}
+			var request pagerduty.IncidentWorkflowInstanceRequest
+			_ = json.NewDecoder(r.Body).Decode(&request)
+			*started = append(*started, request.IncidentWorkflowInstance.Incident.ID)
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(pagerduty.IncidentWorkflowInstanceResponse{
+				IncidentWorkflowInstance: pagerduty.IncidentWorkflowInstance{ID: "PIWI1", Incident: request.IncidentWorkflowInstance.Incident},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+}
+
+func TestPlugin_handleStartIncidentWorkflow(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		userID         string
+		body           string
+		config         *configuration
+		startStatus    int
+		expectedStatus int
+		expectStarted  bool
+	}{
+		{
+			name:           "started",
+			path:           "/api/v1/incidents/PINC1/workflows",
+			userID:         "test-user-id",
+			body:           `{"incident_workflow_id": "PIW1"}`,
+			expectedStatus: http.StatusCreated,
+			expectStarted:  true,
+		},
+		{
+			name:           "plugin not configured",
+			path:           "/api/v1/incidents/PINC1/workflows",
+			userID:         "test-user-id",
+			body:           `{"incident_workflow_id": "PIW1"}`,
+			config:         &configuration{},
+			expectedStatus: http.StatusNotImplemented,
+		},
+		{
+			name:           "invalid body",
+			path:           "/api/v1/incidents/PINC1/workflows",
+			userID:         "test-user-id",
+			body:           `{`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "missing workflow",
+			path:           "/api/v1/incidents/PINC1/workflows",
+			userID:         "test-user-id",
+			body:           `{}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown account",
+			path:           "/api/v1/incidents/PINC1/workflows?account=eu",
+			userID:         "test-user-id",
+			body:           `{"incident_workflow_id": "PIW1"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown workflow",
+			path:           "/api/v1/incidents/PINC1/workflows",
+			userID:         "test-user-id",
+			body:           `{"incident_workflow_id": "PIW2"}`,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "PagerDuty fails to start the workflow",
+			path:           "/api/v1/incidents/PINC1/workflows",
+			userID:         "test-user-id",
+			body:           `{"incident_workflow_id": "PIW1"}`,
+			startStatus:    http.StatusInternalServerError,
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:           "guests may not respond by default",
+			path:           "/api/v1/incidents/PINC1/workflows",
+			userID:         "guest-user-id",
+			body:           `{"incident_workflow_id": "PIW1"}`,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:   "users outside the respond allowlist",
+			path:   "/api/v1/incidents/PINC1/workflows",
+			userID: "test-user-id",
+			body:   `{"incident_workflow_id": "PIW1"}`,
+			config: &configuration{APIToken: "token", allowlists: map[string][]allowlistEntry{
+				permissionRespond: {{Kind: allowlistRole, Value: "responder"}},
+			}},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:   "system admins outside the respond allowlist",
+			path:   "/api/v1/incidents/PINC1/workflows",
+			userID: "admin-user-id",
+			body:   `{"incident_workflow_id": "PIW1"}`,
+			config: &configuration{APIToken: "token", allowlists: map[string][]allowlistEntry{
+				permissionRespond: {{Kind: allowlistRole, Value: "responder"}},
+			}},
+			expectedStatus: http.StatusCreated,
+			expectStarted:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin, _, _ := setupHandlerTestPlugin(t)
+			if tt.config != nil {
+				plugin.setConfiguration(tt.config)
+			}
+			var started []string
+			usePagerDutyServer(t, plugin, serveIncidentWorkflows(tt.startStatus, &started))
+
+			w := serveTestRequest(plugin, http.MethodPost, tt.path, tt.userID, tt.body)
+			assert.Equal(t, tt.expectedStatus, w.Code, w.Body.String())
+
+			if !tt.expectStarted {
+				assert.Empty(t, started)
+				return
+			}
+			assert.Equal(t, []string{"PINC1"}, started)
+			var instance pagerduty.IncidentWorkflowInstance
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&instance))
+			assert.Equal(t, "PIWI1", instance.ID)
+		})
+	}
+}
+
+func TestPlugin_startIncidentWorkflow(t *testing.T) {
+	t.Run("announces the workflow in the incident thread once", func(t *testing.T) {
+		plugin, api, _ := setupHandlerTestPlugin(t)
+		plugin.botUserID = "bot-user-id"
+		var started []string
+		usePagerDutyServer(t, plugin, serveIncidentWorkflows(0, &started))
+		require.NoError(t, plugin.kvstore.SaveIncidentSubscription(&kvstore.IncidentSubscription{IncidentID: "PINC1", ChannelID: "channel1", PostID: "post1"}))
+
+		api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.ChannelId == "channel1" && post.RootId == "post1" && post.UserId == "bot-user-id" &&
+				post.Message == ":arrow_forward: @user started the **Major Incident** workflow."
+		})).Return(&model.Post{Id: "reply1"}, nil).Once()
+
+		instance, apiErr := plugin.startIncidentWorkflow("test-user-id", "", "PINC1", "PIW1")
+		require.Nil(t, apiErr)
+		assert.Equal(t, "PIWI1", instance.ID)
+
+		// The started status delivered by the webhook afterwards is not posted again.
+		plugin.postIncidentWorkflowStatus("", "PIWI1", "PINC1", incidentWorkflowStarted, "started")
+	})
+
+	t.Run("records the outcome in the audit trail", func(t *testing.T) {
+		plugin, _, _ := setupHandlerTestPlugin(t)
+		var started []string
+		usePagerDutyServer(t, plugin, serveIncidentWorkflows(0, &started))
+
+		_, apiErr := plugin.startIncidentWorkflow("test-user-id", "", "PINC1", "PIW2")
+		require.NotNil(t, apiErr)
+
+		records, recordsErr := plugin.getAuditRecords(AuditQuery{})
+		require.Nil(t, recordsErr)
+		require.Len(t, records, 1)
+		assert.Equal(t, auditActionStartIncidentWorkflow, records[0].Action)
+		assert.Equal(t, "PINC1", records[0].Target)
+		assert.Equal(t, "user", records[0].Username)
+		assert.Equal(t, auditResult(apiErr), records[0].Result)
+		assert.Equal(t, "Incident workflow PIW2 was not found", records[0].Error)
+	})
+}
+
+func TestPlugin_handleIncidentWorkflowDialog(t *testing.T) {
+	submit := func(t *testing.T, plugin *Plugin, userID string, request model.SubmitDialogRequest) *model.SubmitDialogResponse {
+		body, err := json.Marshal(request)
+		require.NoError(t, err)
+		w := serveTestRequest(plugin, http.MethodPost, incidentWorkflowDialogPath, userID, string(body))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var response model.SubmitDialogResponse
+		if w.Body.Len() > 0 {
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		}
+		return &response
+	}
+
+	t.Run("cancelled", func(t *testing.T) {
+		plugin, _, _ := setupHandlerTestPlugin(t)
+		var started []string
+		usePagerDutyServer(t, plugin, serveIncidentWorkflows(0, &started))
+
+		response := submit(t, plugin, "test-user-id", model.SubmitDialogRequest{CallbackId: "PINC1", Cancelled: true})
+		assert.Empty(t, response.Error)
+		assert.Empty(t, started)
+	})
+
+	t.Run("no workflow selected", func(t *testing.T) {
+		plugin, _, _ := setupHandlerTestPlugin(t)
+		var started []string
+		usePagerDutyServer(t, plugin, serveIncidentWorkflows(0, &started))
+
+		response := submit(t, plugin, "test-user-id", model.SubmitDialogRequest{CallbackId: "PINC1", Submission: map[string]interface{}{}})
+		assert.Equal(t, map[string]string{incidentWorkflowField: "Select a workflow to run."}, response.Errors)
+		assert.Empty(t, started)
+	})
+
+	t.Run("workflow fails to start", func(t *testing.T) {
+		plugin, _, _ := setupHandlerTestPlugin(t)
+		var started []string
+		usePagerDutyServer(t, plugin, serveIncidentWorkflows(http.StatusForbidden, &started))
+
+		response := submit(t, plugin, "test-user-id", model.SubmitDialogRequest{CallbackId: "PINC1", Submission: map[string]interface{}{incidentWorkflowField: "PIW1"}})
+		assert.Equal(t, "Failed to start the incident workflow", response.Error)
+	})
+
+	t.Run("started on the account of the incident", func(t *testing.T) {
+		plugin, _, _ := setupHandlerTestPlugin(t)
+		var started []string
+		usePagerDutyServer(t, plugin, serveIncidentWorkflows(0, &started))
+		plugin.setConfiguration(&configuration{APIToken: "token", accounts: []*pagerDutyAccount{{Name: "eu", APIToken: "eu-token"}}})
+
+		response := submit(t, plugin, "test-user-id", model.SubmitDialogRequest{CallbackId: "PINC1", State: "eu", Submission: map[string]interface{}{incidentWorkflowField: "PIW1"}})
+		assert.Empty(t, response.Error)
+		assert.Empty(t, response.Errors)
+		assert.Equal(t, []string{"PINC1"}, started)
+	})
+
+	t.Run("guests may not submit", func(t *testing.T) {
+		plugin, _, _ := setupHandlerTestPlugin(t)
+		var started []string
+		usePagerDutyServer(t, plugin, serveIncidentWorkflows(0, &started))
+
+		w := serveTestRequest(plugin, http.MethodPost, incidentWorkflowDialogPath, "guest-user-id", `{"callback_id": "PINC1", "submission": {"workflow": "PIW1"}}`)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Empty(t, started)
+	})
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/pluginapi/cluster"
+	"github.com/pkg/errors"
+)
+
+const (
+	// backgroundJobKey identifies the background job across the cluster, ensuring only one
+	// node runs it at a time.
+	backgroundJobKey = "pagerduty_background_job"
+
+	// backgroundJobInterval is how often the background job runs.
+	backgroundJobInterval = time.Minute
+)
+
+// startBackgroundJob schedules the periodic job that drives time-based features such as
+// handoff reminders, scheduled roster and service status posts, on-call group syncs,
+// channel on-call displays, on-call custom statuses and maintenance window announcements.
+func (p *Plugin) startBackgroundJob() error {
+	job, err := cluster.Schedule(
+		p.API,
+		backgroundJobKey,
+		cluster.MakeWaitForRoundedInterval(backgroundJobInterval),
+		p.runBackgroundJob,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to schedule background job")
+	}
+
+	p.backgroundJob = job
+	return nil
+}
+
+// stopBackgroundJob stops the periodic job, if it is running.
+func (p *Plugin) stopBackgroundJob() {
+	if p.backgroundJob == nil {
+		return
+	}
+
+	if err := p.backgroundJob.Close(); err != nil {
+		p.client.Log.Error("Failed to close background job", "error", err)
+	}
+	p.backgroundJob = nil
+}
+
+func (p *Plugin) runBackgroundJob() {
+	config := p.getConfiguration()
+	if err := config.IsValid(); err != nil {
+		return
+	}
+
+	now := time.Now()
+
+	if config.EnableHandoffReminders {
+		p.runHandoffReminders(now)
+	}
+
+	if err := p.runRosterPosts(now); err != nil {
+		p.client.Log.Error("Failed to post scheduled rosters", "error", err.Error())
+	}
+
+	if err := p.runImpactPosts(now); err != nil {
+		p.client.Log.Error("Failed to post scheduled service statuses", "error", err.Error())
+	}
+
+	if err := p.runGroupSyncs(now); err != nil {
+		p.client.Log.Error("Failed to sync on-call groups", "error", err.Error())
+	}
+
+	if err := p.runChannelOnCalls(now); err != nil {
+		p.client.Log.Error("Failed to refresh channel on-calls", "error", err.Error())
+	}
+
+	if err := p.runMaintenanceWindows(now); err != nil {
+		p.client.Log.Error("Failed to announce maintenance windows", "error", err.Error())
+	}
+
+	if config.EnableOnCallStatus {
+		if err := p.runOnCallStatuses(now); err != nil {
+			p.client.Log.Error("Failed to update on-call custom statuses", "error", err.Error())
+		}
+	}
+}
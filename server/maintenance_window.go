@@ -0,0 +1,261 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
+	"github.com/svelle/mattermost-pagerduty-plugin/server/store/kvstore"
+)
+
+// maxMaintenanceWindowDuration bounds how long services can be put into maintenance from
+// Mattermost, so that a mistyped duration does not silence them for weeks.
+const maxMaintenanceWindowDuration = 7 * 24 * time.Hour
+
+// CreateMaintenanceWindowRequest represents the request body for putting services into
+// maintenance. The window starts right away unless a start time is given, and its start and
+// end are announced in the channel if one is given.
+type CreateMaintenanceWindowRequest struct {
+	ServiceIDs  []string `json:"service_ids"`
+	Duration    string   `json:"duration"`
+	StartTime   string   `json:"start_time,omitempty"`
+	Description string   `json:"description,omitempty"`
+	ChannelID   string   `json:"channel_id,omitempty"`
+}
+
+// createMaintenanceWindow creates a maintenance window in a PagerDuty account on behalf of the
+// given user.
+func (p *Plugin) createMaintenanceWindow(userID, account string, req *CreateMaintenanceWindowRequest) (_ *pagerduty.MaintenanceWindow, apiErr *APIError) {
+	defer func() {
+		p.recordAudit(userID, account, auditActionCreateMaintenance, strings.Join(req.ServiceIDs, ","), apiErr)
+	}()
+
+	if len(req.ServiceIDs) == 0 || req.Duration == "" {
+		return nil, &APIError{
+			ID:         "api.pagerduty.maintenance.fields.missing",
+			Message:    "service_ids and duration are required",
+			StatusCode: http.StatusBadRequest,
+		}
+	}
+
+	duration, err := time.ParseDuration(req.Duration)
+	if err != nil || duration <= 0 || duration > maxMaintenanceWindowDuration {
+		return nil, &APIError{
+			ID:         "api.pagerduty.maintenance.duration.invalid",
+			Message:    fmt.Sprintf("Invalid duration %q. Use e.g. 30m or 2h, up to %s.", req.Duration, maxMaintenanceWindowDuration),
+			StatusCode: http.StatusBadRequest,
+		}
+	}
+
+	now := time.Now()
+	start := now
+	if req.StartTime != "" {
+		if start, err = time.Parse(time.RFC3339, req.StartTime); err != nil {
+			return nil, &APIError{
+				ID:         "api.pagerduty.maintenance.start.invalid",
+				Message:    "start_time must be an RFC 3339 timestamp",
+				StatusCode: http.StatusBadRequest,
+			}
+		}
+		if start.Before(now) {
+			start = now
+		}
+	}
+	end := start.Add(duration)
+
+	if req.ChannelID != "" && !p.client.User.HasPermissionToChannel(userID, req.ChannelID, model.PermissionCreatePost) {
+		return nil, &APIError{
+			ID:         "api.pagerduty.maintenance.permission",
+			Message:    "You do not have permission to post in this channel",
+			StatusCode: http.StatusForbidden,
+		}
+	}
+
+	user, err := p.client.User.Get(userID)
+	if err != nil {
+		p.client.Log.Error("Failed to get user", "error", err.Error(), "user_id", userID)
+		return nil, &APIError{
+			ID:         "api.pagerduty.maintenance.user.error",
+			Message:    "Failed to retrieve user",
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+
+	client, apiErr := p.accountClient(account)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	response, err := client.CreateMaintenanceWindow(user.Email, start, end, req.Description, req.ServiceIDs)
+	if err != nil {
+		p.client.Log.Error("Failed to create maintenance window in PagerDuty", "error", err.Error())
+		return nil, &APIError{
+			ID:         "api.pagerduty.maintenance.create.error",
+			Message:    "Failed to create maintenance window",
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+	window := &response.MaintenanceWindow
+
+	if req.ChannelID == "" {
+		return window, nil
+	}
+
+	announced := &kvstore.MaintenanceWindow{
+		ID:          window.ID,
+		Account:     account,
+		ChannelID:   req.ChannelID,
+		ServiceIDs:  req.ServiceIDs,
+		Description: req.Description,
+		HtmlURL:     window.HtmlURL,
+		StartAt:     start.UnixMilli(),
+		EndAt:       end.UnixMilli(),
+		CreatorID:   userID,
+		CreateAt:    model.GetMillis(),
+	}
+	for _, service := range window.Services {
+		announced.ServiceNames = append(announced.ServiceNames, service.Summary)
+	}
+
+	if !start.After(now) {
+		if err := p.postMaintenanceWindowStart(announced); err != nil {
+			p.client.Log.Error("Failed to announce maintenance window", "error", err.Error(), "maintenance_window_id", window.ID)
+		} else {
+			announced.StartPostedAt = model.GetMillis()
+		}
+	}
+
+	if err := p.kvstore.SaveMaintenanceWindow(announced); err != nil {
+		p.client.Log.Error("Failed to save maintenance window", "error", err.Error(), "maintenance_window_id", window.ID)
+	}
+
+	return window, nil
+}
+
+// deleteMaintenanceWindow deletes a future maintenance window or ends an ongoing one, noting in
+// its channel that it was ended early.
+func (p *Plugin) deleteMaintenanceWindow(userID, account, windowID string) (apiErr *APIError) {
+	defer func() { p.recordAudit(userID, account, auditActionDeleteMaintenance, windowID, apiErr) }()
+
+	client, apiErr := p.accountClient(account)
+	if apiErr != nil {
+		return apiErr
+	}
+
+	if err := client.DeleteMaintenanceWindow(windowID); err != nil {
+		p.client.Log.Error("Failed to delete maintenance window in PagerDuty", "error", err.Error(), "maintenance_window_id", windowID)
+		return &APIError{
+			ID:         "api.pagerduty.maintenance.delete.error",
+			Message:    "Failed to delete maintenance window",
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+
+	announced, err := p.kvstore.GetMaintenanceWindow(account, windowID)
+	if err != nil {
+		p.client.Log.Error("Failed to get maintenance window", "error", err.Error(), "maintenance_window_id", windowID)
+		return nil
+	}
+	if announced == nil {
+		return nil
+	}
+
+	if announced.StartPostedAt != 0 {
+		message := fmt.Sprintf(":white_check_mark: Maintenance of %s was ended early", formatMaintenanceServices(announced))
+		if user, err := p.client.User.Get(userID); err == nil {
+			message += " by @" + user.Username
+		}
+		if err := p.postMaintenanceWindowMessage(announced, message+"."); err != nil {
+			p.client.Log.Error("Failed to announce maintenance window end", "error", err.Error(), "maintenance_window_id", windowID)
+		}
+	}
+
+	if err := p.kvstore.DeleteMaintenanceWindow(account, windowID); err != nil {
+		p.client.Log.Error("Failed to delete maintenance window", "error", err.Error(), "maintenance_window_id", windowID)
+	}
+	return nil
+}
+
+// runMaintenanceWindows announces the maintenance windows that have started or ended.
+func (p *Plugin) runMaintenanceWindows(now time.Time) error {
+	windows, err := p.kvstore.ListMaintenanceWindows()
+	if err != nil {
+		return errors.Wrap(err, "failed to list maintenance windows")
+	}
+
+	for _, window := range windows {
+		if window.EndAt <= now.UnixMilli() {
+			message := fmt.Sprintf(":white_check_mark: Maintenance of %s has ended.", formatMaintenanceServices(window))
+			if err := p.postMaintenanceWindowMessage(window, message); err != nil {
+				p.client.Log.Error("Failed to announce maintenance window end", "error", err.Error(), "maintenance_window_id", window.ID)
+			}
+			if err := p.kvstore.DeleteMaintenanceWindow(window.Account, window.ID); err != nil {
+				p.client.Log.Error("Failed to delete maintenance window", "error", err.Error(), "maintenance_window_id", window.ID)
+			}
+			continue
+		}
+
+		if window.StartPostedAt != 0 || window.StartAt > now.UnixMilli() {
+			continue
+		}
+
+		if err := p.postMaintenanceWindowStart(window); err != nil {
+			p.client.Log.Error("Failed to announce maintenance window", "error", err.Error(), "maintenance_window_id", window.ID)
+			continue
+		}
+
+		window.StartPostedAt = now.UnixMilli()
+		if err := p.kvstore.SaveMaintenanceWindow(window); err != nil {
+			p.client.Log.Error("Failed to update maintenance window", "error", err.Error(), "maintenance_window_id", window.ID)
+		}
+	}
+
+	return nil
+}
+
+func (p *Plugin) postMaintenanceWindowStart(window *kvstore.MaintenanceWindow) error {
+	verb := "are"
+	if len(window.ServiceIDs) == 1 {
+		verb = "is"
+	}
+
+	end := time.UnixMilli(window.EndAt).UTC()
+	message := fmt.Sprintf(":construction: %s %s in maintenance until %s.", formatMaintenanceServices(window), verb, end.Format("Mon Jan 2, 15:04 MST"))
+	if window.Description != "" {
+		message += "\n> " + window.Description
+	}
+	if creator, err := p.client.User.Get(window.CreatorID); err == nil {
+		message += fmt.Sprintf("\n_Started by @%s._", creator.Username)
+	}
+
+	return p.postMaintenanceWindowMessage(window, message)
+}
+
+func (p *Plugin) postMaintenanceWindowMessage(window *kvstore.MaintenanceWindow, message string) error {
+	post := &model.Post{
+		UserId:    p.botUserID,
+		ChannelId: window.ChannelID,
+		Message:   message,
+	}
+	return p.client.Post.CreatePost(post)
+}
+
+// formatMaintenanceServices lists the services of a maintenance window by name, linking to the
+// window in PagerDuty.
+func formatMaintenanceServices(window *kvstore.MaintenanceWindow) string {
+	names := window.ServiceNames
+	if len(names) == 0 {
+		names = window.ServiceIDs
+	}
+
+	services := "**" + strings.Join(names, ", ") + "**"
+	if window.HtmlURL != "" {
+		services = fmt.Sprintf("[%s](%s)", services, window.HtmlURL)
+	}
+	return services
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/store/kvstore"
+)
+
+func TestPlugin_createMaintenanceWindowValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		req  CreateMaintenanceWindowRequest
+		id   string
+	}{
+		{
+			name: "missing services",
+			req:  CreateMaintenanceWindowRequest{Duration: "1h"},
+			id:   "api.pagerduty.maintenance.fields.missing",
+		},
+		{
+			name: "missing duration",
+			req:  CreateMaintenanceWindowRequest{ServiceIDs: []string{"SVC1"}},
+			id:   "api.pagerduty.maintenance.fields.missing",
+		},
+		{
+			name: "unparseable duration",
+			req:  CreateMaintenanceWindowRequest{ServiceIDs: []string{"SVC1"}, Duration: "two hours"},
+			id:   "api.pagerduty.maintenance.duration.invalid",
+		},
+		{
+			name: "negative duration",
+			req:  CreateMaintenanceWindowRequest{ServiceIDs: []string{"SVC1"}, Duration: "-1h"},
+			id:   "api.pagerduty.maintenance.duration.invalid",
+		},
+		{
+			name: "duration too long",
+			req:  CreateMaintenanceWindowRequest{ServiceIDs: []string{"SVC1"}, Duration: "200h"},
+			id:   "api.pagerduty.maintenance.duration.invalid",
+		},
+		{
+			name: "invalid start time",
+			req:  CreateMaintenanceWindowRequest{ServiceIDs: []string{"SVC1"}, Duration: "1h", StartTime: "tomorrow"},
+			id:   "api.pagerduty.maintenance.start.invalid",
+		},
+	}
+
+	plugin := &Plugin{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			window, apiErr := plugin.createMaintenanceWindow("user1", "", &tt.req)
+			assert.Nil(t, window)
+			require.NotNil(t, apiErr)
+			assert.Equal(t, tt.id, apiErr.ID)
+			assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+		})
+	}
+}
+
+func TestFormatMaintenanceServices(t *testing.T) {
+	tests := []struct {
+		name     string
+		window   *kvstore.MaintenanceWindow
+		expected string
+	}{
+		{
+			name: "names with link",
+			window: &kvstore.MaintenanceWindow{
+				ServiceIDs:   []string{"SVC1", "SVC2"},
+				ServiceNames: []string{"Checkout", "Database"},
+				HtmlURL:      "https://example.pagerduty.com/maintenance_windows/MW1",
+			},
+			expected: "[**Checkout, Database**](https://example.pagerduty.com/maintenance_windows/MW1)",
+		},
+		{
+			name: "falls back to IDs",
+			window: &kvstore.MaintenanceWindow{
+				ServiceIDs: []string{"SVC1"},
+			},
+			expected: "**SVC1**",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, formatMaintenanceServices(tt.window))
+		})
+	}
+}
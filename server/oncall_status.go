@@ -0,0 +1,275 @@
+package main
+
+import (
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
+	"github.com/svelle/mattermost-pagerduty-plugin/server/store/kvstore"
+)
+
+const (
+	// onCallStatusLookahead is how far ahead shifts are looked up, and therefore the longest
+	// a user's status goes without being checked.
+	onCallStatusLookahead = time.Hour
+
+	// onCallStatusBatchSize bounds the number of users whose on-calls are requested at once,
+	// keeping each response within a single page.
+	onCallStatusBatchSize = 20
+
+	onCallStatusEmoji = "pager"
+)
+
+// enableOnCallStatus opts a user in to having their custom status set while on call.
+func (p *Plugin) enableOnCallStatus(user *model.User) (*kvstore.OnCallStatus, error) {
+	pdUser, err := p.getPagerDutyUserForMattermostUser("", user)
+	if err != nil {
+		return nil, err
+	}
+	if pdUser == nil {
+		return nil, nil
+	}
+
+	status := &kvstore.OnCallStatus{
+		UserID:          user.Id,
+		PagerDutyUserID: pdUser.ID,
+		CreateAt:        model.GetMillis(),
+	}
+
+	if existing, err := p.kvstore.GetOnCallStatus(user.Id); err == nil && existing != nil {
+		status.StatusText = existing.StatusText
+	}
+
+	if err := p.kvstore.SaveOnCallStatus(status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// disableOnCallStatus opts a user out, clearing the custom status set by the plugin if it is
+// still in place.
+func (p *Plugin) disableOnCallStatus(userID string) error {
+	status, err := p.kvstore.GetOnCallStatus(userID)
+	if err != nil {
+		return err
+	}
+	if status == nil {
+		return nil
+	}
+
+	user, err := p.client.User.Get(userID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get user")
+	}
+	if err := p.clearOnCallStatus(status, user); err != nil {
+		return err
+	}
+
+	return p.kvstore.DeleteOnCallStatus(userID)
+}
+
+// requestOnCallStatusRefresh marks every on-call status as due, so that they are checked on
+// the next run of the background job.
+func (p *Plugin) requestOnCallStatusRefresh() {
+	statuses, err := p.kvstore.ListOnCallStatuses()
+	if err != nil {
+		p.client.Log.Error("Failed to list on-call statuses", "error", err.Error())
+		return
+	}
+
+	for _, status := range statuses {
+		if status.NextCheckAt == 0 {
+			continue
+		}
+		status.NextCheckAt = 0
+		if err := p.kvstore.SaveOnCallStatus(status); err != nil {
+			p.client.Log.Error("Failed to update on-call status", "error", err.Error(), "user_id", status.UserID)
+		}
+	}
+}
+
+// runOnCallStatuses sets or clears the custom status of every opted-in user whose shift may
+// have started or ended.
+func (p *Plugin) runOnCallStatuses(now time.Time) error {
+	statuses, err := p.kvstore.ListOnCallStatuses()
+	if err != nil {
+		return errors.Wrap(err, "failed to list on-call statuses")
+	}
+
+	var due []*kvstore.OnCallStatus
+	for _, status := range statuses {
+		if status.NextCheckAt <= now.UnixMilli() {
+			due = append(due, status)
+		}
+	}
+
+	config := p.getConfiguration()
+	client := p.createPagerDutyClient(config.APIToken, config.APIBaseURL)
+
+	for start := 0; start < len(due); start += onCallStatusBatchSize {
+		batch := due[start:min(start+onCallStatusBatchSize, len(due))]
+
+		pdUserIDs := make([]string, 0, len(batch))
+		for _, status := range batch {
+			pdUserIDs = append(pdUserIDs, status.PagerDutyUserID)
+		}
+
+		response, err := client.GetOnCallsForUsers(pdUserIDs, now, now.Add(onCallStatusLookahead))
+		if err != nil {
+			return errors.Wrap(err, "failed to get on-calls for users")
+		}
+
+		for _, status := range batch {
+			var oncalls []pagerduty.OnCall
+			for _, oncall := range response.OnCalls {
+				if oncall.User.ID == status.PagerDutyUserID {
+					oncalls = append(oncalls, oncall)
+				}
+			}
+
+			if err := p.reconcileOnCallStatus(status, oncalls, now); err != nil {
+				p.client.Log.Error("Failed to update on-call custom status", "error", err.Error(), "user_id", status.UserID)
+				continue
+			}
+
+			if err := p.kvstore.SaveOnCallStatus(status); err != nil {
+				p.client.Log.Error("Failed to update on-call status", "error", err.Error(), "user_id", status.UserID)
+			}
+		}
+	}
+
+	return nil
+}
+
+// reconcileOnCallStatus sets the user's custom status if they are currently on call and clears
+// it otherwise. A custom status the user set themselves is never overwritten.
+func (p *Plugin) reconcileOnCallStatus(status *kvstore.OnCallStatus, oncalls []pagerduty.OnCall, now time.Time) error {
+	user, err := p.client.User.Get(status.UserID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get user")
+	}
+
+	status.NextCheckAt = nextOnCallStatusCheck(oncalls, now).UnixMilli()
+
+	customStatus := onCallCustomStatus(oncalls, now, user)
+	if customStatus == nil {
+		return p.clearOnCallStatus(status, user)
+	}
+
+	current := user.GetCustomStatus()
+	if current != nil && current.Text != "" && current.Text != status.StatusText {
+		return nil
+	}
+	if current != nil && current.Text == customStatus.Text && current.ExpiresAt.Equal(customStatus.ExpiresAt) {
+		return nil
+	}
+
+	if appErr := p.API.UpdateUserCustomStatus(user.Id, customStatus); appErr != nil {
+		return errors.Wrap(appErr, "failed to set custom status")
+	}
+	status.StatusText = customStatus.Text
+	return nil
+}
+
+func (p *Plugin) clearOnCallStatus(status *kvstore.OnCallStatus, user *model.User) error {
+	if status.StatusText == "" {
+		return nil
+	}
+
+	if current := user.GetCustomStatus(); current != nil && current.Text == status.StatusText {
+		if appErr := p.API.RemoveUserCustomStatus(user.Id); appErr != nil {
+			return errors.Wrap(appErr, "failed to clear custom status")
+		}
+	}
+
+	status.StatusText = ""
+	return nil
+}
+
+// onCallCustomStatus returns the custom status for a user's current shift, or nil if they are
+// not on call. The shift at the lowest escalation level is shown.
+func onCallCustomStatus(oncalls []pagerduty.OnCall, now time.Time, user *model.User) *model.CustomStatus {
+	var current *pagerduty.OnCall
+	var currentEnd time.Time
+	for i, oncall := range oncalls {
+		start, err := time.Parse(time.RFC3339, oncall.Start)
+		if err == nil && start.After(now) {
+			continue
+		}
+
+		var end time.Time
+		if oncall.End != "" {
+			if end, err = time.Parse(time.RFC3339, oncall.End); err != nil || !end.After(now) {
+				continue
+			}
+		}
+
+		if current == nil || oncall.EscalationLevel < current.EscalationLevel {
+			current = &oncalls[i]
+			currentEnd = end
+		}
+	}
+	if current == nil {
+		return nil
+	}
+
+	name := current.Schedule.Name
+	if name == "" && current.EscalationPolicy != nil {
+		name = current.EscalationPolicy.Name
+	}
+
+	text := "On call"
+	if name != "" {
+		text += " for " + name
+	}
+
+	customStatus := &model.CustomStatus{Emoji: onCallStatusEmoji}
+	if !currentEnd.IsZero() {
+		text += " until " + formatShiftEndForUser(currentEnd, now, user)
+		customStatus.Duration = "date_and_time"
+		customStatus.ExpiresAt = currentEnd.UTC()
+	}
+
+	if runes := []rune(text); len(runes) > model.CustomStatusTextMaxRunes {
+		text = string(runes[:model.CustomStatusTextMaxRunes])
+	}
+	customStatus.Text = text
+
+	return customStatus
+}
+
+// nextOnCallStatusCheck returns the next shift start or end after now, or the end of the
+// lookahead window if there is none.
+func nextOnCallStatusCheck(oncalls []pagerduty.OnCall, now time.Time) time.Time {
+	next := now.Add(onCallStatusLookahead)
+	for _, oncall := range oncalls {
+		for _, boundary := range []string{oncall.Start, oncall.End} {
+			t, err := time.Parse(time.RFC3339, boundary)
+			if err == nil && t.After(now) && t.Before(next) {
+				next = t
+			}
+		}
+	}
+	return next
+}
+
+// formatShiftEndForUser formats the end of a shift in the user's time zone, adding the day if
+// it is not today.
+func formatShiftEndForUser(end, now time.Time, user *model.User) string {
+	location := time.UTC
+	if loc, err := time.LoadLocation(user.GetPreferredTimezone()); err == nil {
+		location = loc
+	}
+
+	end = end.In(location)
+	now = now.In(location)
+	if end.YearDay() == now.YearDay() && end.Year() == now.Year() {
+		return end.Format("15:04")
+	}
+	if end.Sub(now) < 6*24*time.Hour {
+		return end.Format("Mon 15:04")
+	}
+	return end.Format("Jan 2, 15:04")
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
+)
+
+func TestOnCallCustomStatus(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	user := &model.User{Timezone: model.StringMap{"useAutomaticTimezone": "false", "manualTimezone": "Europe/Berlin"}}
+
+	t.Run("not on call", func(t *testing.T) {
+		oncalls := []pagerduty.OnCall{
+			{Start: "2024-01-01T10:30:00Z", End: "2024-01-01T18:00:00Z", Schedule: pagerduty.Schedule{Name: "Payments"}},
+		}
+		assert.Nil(t, onCallCustomStatus(oncalls, now, user))
+	})
+
+	t.Run("lowest escalation level wins", func(t *testing.T) {
+		oncalls := []pagerduty.OnCall{
+			{
+				Start:            "2024-01-01T08:00:00Z",
+				End:              "2024-01-02T08:00:00Z",
+				EscalationLevel:  2,
+				EscalationPolicy: &pagerduty.EscalationPolicy{Name: "Ops"},
+			},
+			{
+				Start:           "2024-01-01T09:00:00Z",
+				End:             "2024-01-01T17:00:00Z",
+				EscalationLevel: 1,
+				Schedule:        pagerduty.Schedule{Name: "Payments"},
+			},
+		}
+
+		status := onCallCustomStatus(oncalls, now, user)
+		require.NotNil(t, status)
+		assert.Equal(t, "On call for Payments until 18:00", status.Text)
+		assert.Equal(t, onCallStatusEmoji, status.Emoji)
+		assert.Equal(t, "date_and_time", status.Duration)
+		assert.True(t, status.ExpiresAt.Equal(time.Date(2024, 1, 1, 17, 0, 0, 0, time.UTC)))
+	})
+
+	t.Run("permanent on-call", func(t *testing.T) {
+		oncalls := []pagerduty.OnCall{
+			{EscalationLevel: 1, EscalationPolicy: &pagerduty.EscalationPolicy{Name: "Ops"}},
+		}
+
+		status := onCallCustomStatus(oncalls, now, user)
+		require.NotNil(t, status)
+		assert.Equal(t, "On call for Ops", status.Text)
+		assert.True(t, status.ExpiresAt.IsZero())
+	})
+}
+
+func TestNextOnCallStatusCheck(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	oncalls := []pagerduty.OnCall{
+		{Start: "2024-01-01T09:00:00Z", End: "2024-01-01T10:40:00Z"},
+		{Start: "2024-01-01T10:20:00Z", End: "2024-01-01T18:00:00Z"},
+	}
+	assert.Equal(t, time.Date(2024, 1, 1, 10, 20, 0, 0, time.UTC), nextOnCallStatusCheck(oncalls, now))
+
+	assert.Equal(t, now.Add(onCallStatusLookahead), nextOnCallStatusCheck(nil, now))
+}
+
+func TestFormatShiftEndForUser(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	user := &model.User{}
+
+	assert.Equal(t, "18:00", formatShiftEndForUser(time.Date(2024, 1, 1, 18, 0, 0, 0, time.UTC), now, user))
+	assert.Equal(t, "Tue 09:00", formatShiftEndForUser(time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC), now, user))
+	assert.Equal(t, "Jan 15, 09:00", formatShiftEndForUser(time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC), now, user))
+}
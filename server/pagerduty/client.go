@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
@@ -15,6 +16,13 @@ import (
 const (
 	defaultBaseURL = "https://api.pagerduty.com"
 	apiVersion     = "2"
+
+	// maxPageSize is the largest page size accepted by the PagerDuty REST API.
+	maxPageSize = 100
+
+	// earlyAccessHeader opts in to PagerDuty APIs that are still in early access, such as
+	// business service impacts and status dashboards.
+	earlyAccessHeader = "X-EARLY-ACCESS"
 )
 
 // HTTPClient interface for mocking in tests
@@ -28,6 +36,21 @@ type Client struct {
 	httpClient HTTPClient
 }
 
+// APIError is returned when the PagerDuty REST API answers a request with an error status.
+type APIError struct {
+	StatusCode int
+	Message    string
+	Code       int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("PagerDuty API error: %s (code: %d)", e.Message, e.Code)
+	}
+	return fmt.Sprintf("PagerDuty API error: HTTP %d - %s", e.StatusCode, e.Body)
+}
+
 func NewClient(apiToken, baseURL string) *Client {
 	if baseURL == "" {
 		baseURL = defaultBaseURL
@@ -47,6 +70,12 @@ func (c *Client) doRequest(method, path string, params url.Values) ([]byte, erro
 }
 
 func (c *Client) doRequestWithBody(method, path string, params url.Values, body interface{}) ([]byte, error) {
+	return c.doRequestWithHeaders(method, path, params, body, nil)
+}
+
+// doRequestWithHeaders executes a request with additional headers, such as the From header
+// identifying the user on whose behalf a change is made
+func (c *Client) doRequestWithHeaders(method, path string, params url.Values, body interface{}, headers http.Header) ([]byte, error) {
 	u, err := url.Parse(c.baseURL + path)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to parse URL")
@@ -73,6 +102,11 @@ func (c *Client) doRequestWithBody(method, path string, params url.Values, body
 	req.Header.Set("Authorization", "Token token="+c.apiToken)
 	req.Header.Set("Accept", "application/vnd.pagerduty+json;version="+apiVersion)
 	req.Header.Set("Content-Type", "application/json")
+	for name, values := range headers {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -86,20 +120,29 @@ func (c *Client) doRequestWithBody(method, path string, params url.Values, body
 	}
 
 	if resp.StatusCode >= 400 {
+		apiErr := &APIError{StatusCode: resp.StatusCode, Body: string(responseBody)}
 		var errorResp ErrorResponse
 		if err := json.Unmarshal(responseBody, &errorResp); err == nil && errorResp.Error.Message != "" {
-			return nil, fmt.Errorf("PagerDuty API error: %s (code: %d)", errorResp.Error.Message, errorResp.Error.Code)
+			apiErr.Message = errorResp.Error.Message
+			apiErr.Code = errorResp.Error.Code
 		}
-		return nil, fmt.Errorf("PagerDuty API error: HTTP %d - %s", resp.StatusCode, string(responseBody))
+		return nil, apiErr
 	}
 
 	return responseBody, nil
 }
 
 func (c *Client) GetSchedules(limit, offset int) (*SchedulesResponse, error) {
+	return c.GetSchedulesForTeams(limit, offset, nil)
+}
+
+// GetSchedulesForTeams retrieves a list of schedules belonging to any of the given teams, or
+// all schedules if no teams are given
+func (c *Client) GetSchedulesForTeams(limit, offset int, teamIDs []string) (*SchedulesResponse, error) {
 	params := url.Values{}
 	params.Set("limit", fmt.Sprintf("%d", limit))
 	params.Set("offset", fmt.Sprintf("%d", offset))
+	addTeamIDs(params, teamIDs)
 
 	body, err := c.doRequest("GET", "/schedules", params)
 	if err != nil {
@@ -169,11 +212,122 @@ func (c *Client) GetOnCallsForSchedule(scheduleID string) (*OnCallsResponse, err
 	return c.GetOnCalls(params)
 }
 
+// GetOnCallsForSchedules retrieves the current on-call users of the given schedules
+func (c *Client) GetOnCallsForSchedules(scheduleIDs []string) (*OnCallsResponse, error) {
+	params := url.Values{}
+	for _, scheduleID := range scheduleIDs {
+		params.Add("schedule_ids[]", scheduleID)
+	}
+	params.Add("include[]", "users")
+	params.Add("include[]", "schedules")
+	params.Add("include[]", "escalation_policies")
+	params.Set("earliest", "true")
+	params.Set("limit", fmt.Sprintf("%d", maxPageSize))
+
+	return c.GetOnCalls(params)
+}
+
+// GetOnCallsForEscalationPolicies retrieves the current on-call users at every level of the
+// given escalation policies
+func (c *Client) GetOnCallsForEscalationPolicies(escalationPolicyIDs []string) (*OnCallsResponse, error) {
+	params := url.Values{}
+	for _, escalationPolicyID := range escalationPolicyIDs {
+		params.Add("escalation_policy_ids[]", escalationPolicyID)
+	}
+	params.Add("include[]", "users")
+	params.Add("include[]", "schedules")
+	params.Add("include[]", "escalation_policies")
+	params.Set("earliest", "true")
+	params.Set("limit", fmt.Sprintf("%d", maxPageSize))
+
+	return c.GetOnCalls(params)
+}
+
+// GetOnCallsForUsers retrieves the on-call entries of the given users overlapping the given
+// time range
+func (c *Client) GetOnCallsForUsers(userIDs []string, since, until time.Time) (*OnCallsResponse, error) {
+	params := url.Values{}
+	for _, userID := range userIDs {
+		params.Add("user_ids[]", userID)
+	}
+	params.Set("since", since.UTC().Format(time.RFC3339))
+	params.Set("until", until.UTC().Format(time.RFC3339))
+	params.Set("time_zone", "UTC")
+	params.Add("include[]", "schedules")
+	params.Add("include[]", "escalation_policies")
+	params.Set("limit", fmt.Sprintf("%d", maxPageSize))
+
+	return c.GetOnCalls(params)
+}
+
+// GetOnCallsBetween retrieves every on-call entry overlapping the given time range,
+// following pagination until all entries have been read.
+func (c *Client) GetOnCallsBetween(since, until time.Time) (*OnCallsResponse, error) {
+	params := url.Values{}
+	params.Set("since", since.UTC().Format(time.RFC3339))
+	params.Set("until", until.UTC().Format(time.RFC3339))
+	params.Set("time_zone", "UTC")
+	params.Add("include[]", "users")
+	params.Set("limit", fmt.Sprintf("%d", maxPageSize))
+
+	result := &OnCallsResponse{OnCalls: []OnCall{}}
+	for offset := 0; ; {
+		params.Set("offset", fmt.Sprintf("%d", offset))
+
+		page, err := c.GetOnCalls(params)
+		if err != nil {
+			return nil, err
+		}
+
+		result.OnCalls = append(result.OnCalls, page.OnCalls...)
+		if !page.More || len(page.OnCalls) == 0 {
+			break
+		}
+		offset += len(page.OnCalls)
+	}
+
+	result.Total = len(result.OnCalls)
+	return result, nil
+}
+
+// GetUserByEmail retrieves the PagerDuty user with the given email address, returning nil if
+// there is none
+func (c *Client) GetUserByEmail(email string) (*User, error) {
+	params := url.Values{}
+	params.Set("query", email)
+
+	body, err := c.doRequest("GET", "/users", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var response UsersResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal users response")
+	}
+
+	// The query also matches names and partial addresses.
+	for i := range response.Users {
+		if strings.EqualFold(response.Users[i].Email, email) {
+			return &response.Users[i], nil
+		}
+	}
+
+	return nil, nil
+}
+
 // GetServices retrieves a list of services from PagerDuty
 func (c *Client) GetServices(limit, offset int) (*ServicesResponse, error) {
+	return c.GetServicesForTeams(limit, offset, nil)
+}
+
+// GetServicesForTeams retrieves a list of services belonging to any of the given teams, or all
+// services if no teams are given
+func (c *Client) GetServicesForTeams(limit, offset int, teamIDs []string) (*ServicesResponse, error) {
 	params := url.Values{}
 	params.Set("limit", fmt.Sprintf("%d", limit))
 	params.Set("offset", fmt.Sprintf("%d", offset))
+	addTeamIDs(params, teamIDs)
 
 	body, err := c.doRequest("GET", "/services", params)
 	if err != nil {
@@ -188,12 +342,151 @@ func (c *Client) GetServices(limit, offset int) (*ServicesResponse, error) {
 	return &response, nil
 }
 
-// CreateIncident creates a new incident in PagerDuty
-func (c *Client) CreateIncident(title, description, serviceID string, assigneeIDs []string) (*CreateIncidentResponse, error) {
+// GetService retrieves a single service with its escalation policy and integrations
+func (c *Client) GetService(serviceID string) (*ServiceResponse, error) {
+	params := url.Values{}
+	params.Add("include[]", "escalation_policies")
+	params.Add("include[]", "integrations")
+
+	body, err := c.doRequest("GET", fmt.Sprintf("/services/%s", url.PathEscape(serviceID)), params)
+	if err != nil {
+		return nil, err
+	}
+
+	var response ServiceResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal service response")
+	}
+
+	return &response, nil
+}
+
+// GetServiceDependencies retrieves the services a technical service depends on and the
+// services depending on it
+func (c *Client) GetServiceDependencies(serviceID string) (*ServiceDependenciesResponse, error) {
+	body, err := c.doRequest("GET", fmt.Sprintf("/service_dependencies/technical_services/%s", url.PathEscape(serviceID)), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var response ServiceDependenciesResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal service dependencies response")
+	}
+
+	return &response, nil
+}
+
+// ListEscalationPolicies retrieves a list of escalation policies from PagerDuty using the given
+// filters, with their targets included
+func (c *Client) ListEscalationPolicies(params url.Values) (*EscalationPoliciesResponse, error) {
+	if params == nil {
+		params = url.Values{}
+	}
+	params.Set("include[]", "targets")
+
+	body, err := c.doRequest("GET", "/escalation_policies", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var response EscalationPoliciesResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal escalation policies response")
+	}
+
+	return &response, nil
+}
+
+// GetEscalationPolicy retrieves a single escalation policy with its targets
+func (c *Client) GetEscalationPolicy(escalationPolicyID string) (*EscalationPolicyResponse, error) {
+	params := url.Values{}
+	params.Set("include[]", "targets")
+
+	body, err := c.doRequest("GET", fmt.Sprintf("/escalation_policies/%s", url.PathEscape(escalationPolicyID)), params)
+	if err != nil {
+		return nil, err
+	}
+
+	var response EscalationPolicyResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal escalation policy response")
+	}
+
+	return &response, nil
+}
+
+// ListTeams retrieves a list of teams from PagerDuty using the given filters
+func (c *Client) ListTeams(params url.Values) (*TeamsResponse, error) {
+	if params == nil {
+		params = url.Values{}
+	}
+
+	body, err := c.doRequest("GET", "/teams", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var response TeamsResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal teams response")
+	}
+
+	return &response, nil
+}
+
+// GetTeam retrieves a single team
+func (c *Client) GetTeam(teamID string) (*TeamResponse, error) {
+	body, err := c.doRequest("GET", fmt.Sprintf("/teams/%s", url.PathEscape(teamID)), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var response TeamResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal team response")
+	}
+
+	return &response, nil
+}
+
+// ListTeamMembers retrieves the members of a team with their user details
+func (c *Client) ListTeamMembers(teamID string, limit, offset int) (*TeamMembersResponse, error) {
+	params := url.Values{}
+	params.Set("limit", fmt.Sprintf("%d", limit))
+	params.Set("offset", fmt.Sprintf("%d", offset))
+	params.Set("include[]", "users")
+
+	body, err := c.doRequest("GET", fmt.Sprintf("/teams/%s/members", url.PathEscape(teamID)), params)
+	if err != nil {
+		return nil, err
+	}
+
+	var response TeamMembersResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal team members response")
+	}
+
+	return &response, nil
+}
+
+// addTeamIDs restricts a list request to the given teams
+func addTeamIDs(params url.Values, teamIDs []string) {
+	for _, teamID := range teamIDs {
+		params.Add("team_ids[]", teamID)
+	}
+}
+
+// CreateIncident creates a new incident in PagerDuty. An empty urgency leaves it to the
+// urgency rule of the service. A non-empty incident key makes PagerDuty reject the incident
+// while another open incident of the service has the same key.
+func (c *Client) CreateIncident(title, description, serviceID, urgency, incidentKey string, assigneeIDs []string) (*CreateIncidentResponse, error) {
 	incident := Incident{
 		Type:        "incident",
 		Title:       title,
 		Description: description,
+		Urgency:     urgency,
+		IncidentKey: incidentKey,
 		Service: ServiceReference{
 			ID:   serviceID,
 			Type: "service_reference",
@@ -230,3 +523,539 @@ func (c *Client) CreateIncident(title, description, serviceID string, assigneeID
 
 	return &response, nil
 }
+
+// GetIncident retrieves a single incident
+func (c *Client) GetIncident(incidentID string) (*IncidentResponse, error) {
+	body, err := c.doRequest("GET", fmt.Sprintf("/incidents/%s", url.PathEscape(incidentID)), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var response IncidentResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal incident response")
+	}
+
+	return &response, nil
+}
+
+// MergeIncidents merges the source incidents into the parent incident on behalf of the
+// PagerDuty user with the given email. The alerts of the source incidents move to the parent,
+// and the source incidents are resolved.
+func (c *Client) MergeIncidents(parentIncidentID, fromEmail string, sourceIncidentIDs []string) (*IncidentResponse, error) {
+	headers := http.Header{}
+	if fromEmail != "" {
+		headers.Set("From", fromEmail)
+	}
+
+	request := MergeIncidentsRequest{}
+	for _, sourceIncidentID := range sourceIncidentIDs {
+		request.SourceIncidents = append(request.SourceIncidents, IncidentReference{
+			ID:   sourceIncidentID,
+			Type: "incident_reference",
+		})
+	}
+
+	body, err := c.doRequestWithHeaders("PUT", fmt.Sprintf("/incidents/%s/merge", url.PathEscape(parentIncidentID)), nil, request, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	var response IncidentResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal merge incidents response")
+	}
+
+	return &response, nil
+}
+
+// ListRelatedIncidents retrieves the recent incidents PagerDuty considers related to an
+// incident
+func (c *Client) ListRelatedIncidents(incidentID string) (*RelatedIncidentsResponse, error) {
+	headers := http.Header{}
+	headers.Set(earlyAccessHeader, "related-incidents")
+
+	body, err := c.doRequestWithHeaders("GET", fmt.Sprintf("/incidents/%s/related_incidents", url.PathEscape(incidentID)), nil, nil, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	var response RelatedIncidentsResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal related incidents response")
+	}
+
+	return &response, nil
+}
+
+// CreateResponderRequest asks users or escalation policies to help with an incident on behalf of
+// the PagerDuty user with the given email, who is also recorded as the requester
+func (c *Client) CreateResponderRequest(incidentID, fromEmail, requesterID, message string, targets []ResponderRequestTarget) (*ResponderRequestResponse, error) {
+	headers := http.Header{}
+	if fromEmail != "" {
+		headers.Set("From", fromEmail)
+	}
+
+	request := CreateResponderRequest{
+		RequesterID: requesterID,
+		Message:     message,
+	}
+	for _, target := range targets {
+		request.ResponderRequestTargets = append(request.ResponderRequestTargets, ResponderRequestTargetWrapper{
+			ResponderRequestTarget: ResponderRequestTarget{ID: target.ID, Type: target.Type},
+		})
+	}
+
+	body, err := c.doRequestWithHeaders("POST", fmt.Sprintf("/incidents/%s/responder_requests", url.PathEscape(incidentID)), nil, request, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	var response ResponderRequestResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal responder request response")
+	}
+
+	return &response, nil
+}
+
+// GetIncidentAlerts retrieves a page of the alerts of an incident using the given filters, e.g.
+// statuses[] or alert_key
+func (c *Client) GetIncidentAlerts(incidentID string, params url.Values) (*AlertsResponse, error) {
+	if params == nil {
+		params = url.Values{}
+	}
+
+	body, err := c.doRequest("GET", fmt.Sprintf("/incidents/%s/alerts", url.PathEscape(incidentID)), params)
+	if err != nil {
+		return nil, err
+	}
+
+	var response AlertsResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal alerts response")
+	}
+
+	return &response, nil
+}
+
+// GetAllIncidentAlerts retrieves every alert of an incident, following pagination until all
+// alerts have been read.
+func (c *Client) GetAllIncidentAlerts(incidentID string) (*AlertsResponse, error) {
+	params := url.Values{}
+	params.Set("limit", fmt.Sprintf("%d", maxPageSize))
+
+	result := &AlertsResponse{Alerts: []Alert{}}
+	for offset := 0; ; {
+		params.Set("offset", fmt.Sprintf("%d", offset))
+
+		page, err := c.GetIncidentAlerts(incidentID, params)
+		if err != nil {
+			return nil, err
+		}
+
+		result.Alerts = append(result.Alerts, page.Alerts...)
+		if !page.More || len(page.Alerts) == 0 {
+			break
+		}
+		offset += len(page.Alerts)
+	}
+
+	result.Total = len(result.Alerts)
+	return result, nil
+}
+
+// ResolveAlert resolves a single alert of an incident on behalf of the PagerDuty user with the
+// given email
+func (c *Client) ResolveAlert(incidentID, alertID, fromEmail string) (*AlertResponse, error) {
+	return c.updateAlert(incidentID, alertID, fromEmail, AlertUpdate{Type: "alert", Status: AlertStatusResolved})
+}
+
+// MoveAlert moves a single alert of an incident to another incident on behalf of the PagerDuty
+// user with the given email
+func (c *Client) MoveAlert(incidentID, alertID, toIncidentID, fromEmail string) (*AlertResponse, error) {
+	return c.updateAlert(incidentID, alertID, fromEmail, AlertUpdate{
+		Type:     "alert",
+		Incident: &IncidentReference{ID: toIncidentID, Type: "incident_reference"},
+	})
+}
+
+func (c *Client) updateAlert(incidentID, alertID, fromEmail string, update AlertUpdate) (*AlertResponse, error) {
+	headers := http.Header{}
+	if fromEmail != "" {
+		headers.Set("From", fromEmail)
+	}
+
+	path := fmt.Sprintf("/incidents/%s/alerts/%s", url.PathEscape(incidentID), url.PathEscape(alertID))
+	body, err := c.doRequestWithHeaders("PUT", path, nil, UpdateAlertRequest{Alert: update}, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	var response AlertResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal alert response")
+	}
+
+	return &response, nil
+}
+
+// ListStatusUpdates retrieves the status updates sent for an incident
+func (c *Client) ListStatusUpdates(incidentID string) (*StatusUpdatesResponse, error) {
+	params := url.Values{}
+	params.Set("limit", fmt.Sprintf("%d", maxPageSize))
+
+	body, err := c.doRequest("GET", fmt.Sprintf("/incidents/%s/status_updates", url.PathEscape(incidentID)), params)
+	if err != nil {
+		return nil, err
+	}
+
+	var response StatusUpdatesResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal status updates response")
+	}
+
+	return &response, nil
+}
+
+// CreateStatusUpdate sends a status update for an incident to its stakeholders on behalf of
+// the PagerDuty user with the given email
+func (c *Client) CreateStatusUpdate(incidentID, fromEmail, message, subject string) (*StatusUpdateResponse, error) {
+	headers := http.Header{}
+	if fromEmail != "" {
+		headers.Set("From", fromEmail)
+	}
+
+	request := CreateStatusUpdateRequest{
+		Message: message,
+		Subject: subject,
+	}
+
+	body, err := c.doRequestWithHeaders("POST", fmt.Sprintf("/incidents/%s/status_updates", url.PathEscape(incidentID)), nil, request, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	var response StatusUpdateResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal create status update response")
+	}
+
+	return &response, nil
+}
+
+// GetIncidents retrieves a list of incidents from PagerDuty using the given filters
+func (c *Client) GetIncidents(params url.Values) (*IncidentsResponse, error) {
+	if params == nil {
+		params = url.Values{}
+	}
+
+	body, err := c.doRequest("GET", "/incidents", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var response IncidentsResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal incidents response")
+	}
+
+	return &response, nil
+}
+
+// GetOpenIncidents retrieves all triggered and acknowledged incidents from PagerDuty, following
+// pagination
+func (c *Client) GetOpenIncidents() (*IncidentsResponse, error) {
+	params := url.Values{}
+	params.Add("statuses[]", "triggered")
+	params.Add("statuses[]", "acknowledged")
+	params.Set("limit", fmt.Sprintf("%d", maxPageSize))
+
+	result := &IncidentsResponse{Incidents: []Incident{}}
+	for offset := 0; ; {
+		params.Set("offset", fmt.Sprintf("%d", offset))
+
+		page, err := c.GetIncidents(params)
+		if err != nil {
+			return nil, err
+		}
+
+		result.Incidents = append(result.Incidents, page.Incidents...)
+		if !page.More || len(page.Incidents) == 0 {
+			break
+		}
+		offset += len(page.Incidents)
+	}
+
+	result.Total = len(result.Incidents)
+	return result, nil
+}
+
+// ListIncidentWorkflows retrieves a list of incident workflows from PagerDuty using the given
+// filters, e.g. query or team_ids[]
+func (c *Client) ListIncidentWorkflows(params url.Values) (*IncidentWorkflowsResponse, error) {
+	if params == nil {
+		params = url.Values{}
+	}
+
+	body, err := c.doRequest("GET", "/incident_workflows", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var response IncidentWorkflowsResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal incident workflows response")
+	}
+
+	return &response, nil
+}
+
+// GetIncidentWorkflow retrieves a single incident workflow by ID
+func (c *Client) GetIncidentWorkflow(incidentWorkflowID string) (*IncidentWorkflowResponse, error) {
+	body, err := c.doRequest("GET", fmt.Sprintf("/incident_workflows/%s", url.PathEscape(incidentWorkflowID)), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var response IncidentWorkflowResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal incident workflow response")
+	}
+
+	return &response, nil
+}
+
+// StartIncidentWorkflow starts an incident workflow on an incident
+func (c *Client) StartIncidentWorkflow(incidentWorkflowID, incidentID string) (*IncidentWorkflowInstanceResponse, error) {
+	request := IncidentWorkflowInstanceRequest{
+		IncidentWorkflowInstance: IncidentWorkflowInstance{
+			Incident: IncidentReference{
+				ID:   incidentID,
+				Type: "incident_reference",
+			},
+		},
+	}
+
+	body, err := c.doRequestWithBody("POST", fmt.Sprintf("/incident_workflows/%s/instances", url.PathEscape(incidentWorkflowID)), nil, request)
+	if err != nil {
+		return nil, err
+	}
+
+	var response IncidentWorkflowInstanceResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal incident workflow instance response")
+	}
+
+	return &response, nil
+}
+
+// ListMaintenanceWindows retrieves a list of maintenance windows from PagerDuty using the given
+// filters, e.g. filter=ongoing or service_ids[]
+func (c *Client) ListMaintenanceWindows(params url.Values) (*MaintenanceWindowsResponse, error) {
+	if params == nil {
+		params = url.Values{}
+	}
+
+	body, err := c.doRequest("GET", "/maintenance_windows", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var response MaintenanceWindowsResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal maintenance windows response")
+	}
+
+	return &response, nil
+}
+
+// CreateMaintenanceWindow puts services into maintenance between the given times on behalf of
+// the PagerDuty user with the given email
+func (c *Client) CreateMaintenanceWindow(fromEmail string, startTime, endTime time.Time, description string, serviceIDs []string) (*MaintenanceWindowResponse, error) {
+	window := MaintenanceWindow{
+		Type:        "maintenance_window",
+		StartTime:   startTime.UTC().Format(time.RFC3339),
+		EndTime:     endTime.UTC().Format(time.RFC3339),
+		Description: description,
+	}
+	for _, serviceID := range serviceIDs {
+		window.Services = append(window.Services, ServiceReference{
+			ID:   serviceID,
+			Type: "service_reference",
+		})
+	}
+
+	headers := http.Header{}
+	if fromEmail != "" {
+		headers.Set("From", fromEmail)
+	}
+
+	request := CreateMaintenanceWindowRequest{
+		MaintenanceWindow: window,
+	}
+
+	body, err := c.doRequestWithHeaders("POST", "/maintenance_windows", nil, request, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	var response MaintenanceWindowResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal create maintenance window response")
+	}
+
+	return &response, nil
+}
+
+// DeleteMaintenanceWindow deletes a future maintenance window or ends an ongoing one
+func (c *Client) DeleteMaintenanceWindow(maintenanceWindowID string) error {
+	_, err := c.doRequest("DELETE", fmt.Sprintf("/maintenance_windows/%s", url.PathEscape(maintenanceWindowID)), nil)
+	return err
+}
+
+// ListBusinessServices retrieves a list of business services from PagerDuty using the given
+// filters
+func (c *Client) ListBusinessServices(params url.Values) (*BusinessServicesResponse, error) {
+	if params == nil {
+		params = url.Values{}
+	}
+
+	body, err := c.doRequest("GET", "/business_services", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var response BusinessServicesResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal business services response")
+	}
+
+	return &response, nil
+}
+
+// GetBusinessService retrieves a single business service
+func (c *Client) GetBusinessService(businessServiceID string) (*BusinessServiceResponse, error) {
+	body, err := c.doRequest("GET", fmt.Sprintf("/business_services/%s", url.PathEscape(businessServiceID)), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var response BusinessServiceResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal business service response")
+	}
+
+	return &response, nil
+}
+
+// GetBusinessServiceImpacts retrieves the current impact status of the given business
+// services, or of the most impacted business services if none are given
+func (c *Client) GetBusinessServiceImpacts(businessServiceIDs []string) (*ImpactsResponse, error) {
+	params := url.Values{}
+	for _, businessServiceID := range businessServiceIDs {
+		params.Add("ids[]", businessServiceID)
+	}
+
+	headers := http.Header{}
+	headers.Set(earlyAccessHeader, "business-impact-early-access")
+
+	body, err := c.doRequestWithHeaders("GET", "/business_services/impacts", params, nil, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	var response ImpactsResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal business service impacts response")
+	}
+
+	return &response, nil
+}
+
+// ListStatusDashboards retrieves the status dashboards of the account
+func (c *Client) ListStatusDashboards() (*StatusDashboardsResponse, error) {
+	headers := http.Header{}
+	headers.Set(earlyAccessHeader, "status-dashboards")
+
+	body, err := c.doRequestWithHeaders("GET", "/status_dashboards", nil, nil, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	var response StatusDashboardsResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal status dashboards response")
+	}
+
+	return &response, nil
+}
+
+// GetStatusDashboard retrieves a single status dashboard
+func (c *Client) GetStatusDashboard(statusDashboardID string) (*StatusDashboardResponse, error) {
+	headers := http.Header{}
+	headers.Set(earlyAccessHeader, "status-dashboards")
+
+	body, err := c.doRequestWithHeaders("GET", fmt.Sprintf("/status_dashboards/%s", url.PathEscape(statusDashboardID)), nil, nil, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	var response StatusDashboardResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal status dashboard response")
+	}
+
+	return &response, nil
+}
+
+// GetStatusDashboardImpacts retrieves the current impact status of the business services on a
+// status dashboard
+func (c *Client) GetStatusDashboardImpacts(statusDashboardID string) (*ImpactsResponse, error) {
+	headers := http.Header{}
+	headers.Set(earlyAccessHeader, "status-dashboards")
+
+	body, err := c.doRequestWithHeaders("GET", fmt.Sprintf("/status_dashboards/%s/service_impacts", url.PathEscape(statusDashboardID)), nil, nil, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	var response ImpactsResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal status dashboard impacts response")
+	}
+
+	return &response, nil
+}
+
+// GetAbilities retrieves the abilities of the account, such as teams or
+// event_rules. It is a lightweight request that any valid token may make.
+func (c *Client) GetAbilities() (*AbilitiesResponse, error) {
+	body, err := c.doRequest("GET", "/abilities", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var response AbilitiesResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal abilities response")
+	}
+
+	return &response, nil
+}
+
+// GetCurrentUser retrieves the user a user-level token acts as, including their role.
+// Account-level API keys act as no user, and PagerDuty rejects the request with 400 Bad Request.
+func (c *Client) GetCurrentUser() (*User, error) {
+	body, err := c.doRequest("GET", "/users/me", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var response UserResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal user response")
+	}
+
+	return &response.User, nil
+}
@@ -1,6 +1,7 @@
 package pagerduty
 
 import (
+	"encoding/json"
 	"errors"
 	"io"
 	"net/http"
@@ -471,6 +472,1018 @@ func TestClient_GetOnCallsForSchedule(t *testing.T) {
 	assert.NotNil(t, response)
 }
 
+func TestClient_GetOnCallsForSchedules(t *testing.T) {
+	client := &Client{
+		baseURL:  "https://api.pagerduty.com",
+		apiToken: "test-token",
+		httpClient: &mockHTTPClient{
+			doFunc: func(req *http.Request) (*http.Response, error) {
+				query := req.URL.Query()
+				assert.Equal(t, []string{"SCHED1", "SCHED2"}, query["schedule_ids[]"])
+				assert.Equal(t, []string{"users", "schedules", "escalation_policies"}, query["include[]"])
+				assert.Equal(t, "true", query.Get("earliest"))
+
+				return newMockResponse(200, `{"oncalls": [{"user": {"id": "USER1"}, "schedule": {"id": "SCHED1"}}]}`), nil
+			},
+		},
+	}
+
+	response, err := client.GetOnCallsForSchedules([]string{"SCHED1", "SCHED2"})
+	require.NoError(t, err)
+	require.Len(t, response.OnCalls, 1)
+	assert.Equal(t, "USER1", response.OnCalls[0].User.ID)
+}
+
+func TestClient_GetOnCallsForEscalationPolicies(t *testing.T) {
+	client := &Client{
+		baseURL:  "https://api.pagerduty.com",
+		apiToken: "test-token",
+		httpClient: &mockHTTPClient{
+			doFunc: func(req *http.Request) (*http.Response, error) {
+				query := req.URL.Query()
+				assert.Equal(t, []string{"EP1"}, query["escalation_policy_ids[]"])
+				assert.Empty(t, query["schedule_ids[]"])
+
+				return newMockResponse(200, `{"oncalls": []}`), nil
+			},
+		},
+	}
+
+	response, err := client.GetOnCallsForEscalationPolicies([]string{"EP1"})
+	require.NoError(t, err)
+	assert.NotNil(t, response)
+}
+
+func TestClient_GetOnCallsForUsers(t *testing.T) {
+	client := &Client{
+		baseURL:  "https://api.pagerduty.com",
+		apiToken: "test-token",
+		httpClient: &mockHTTPClient{
+			doFunc: func(req *http.Request) (*http.Response, error) {
+				query := req.URL.Query()
+				assert.Equal(t, []string{"USER1", "USER2"}, query["user_ids[]"])
+				assert.Equal(t, "2024-01-01T10:00:00Z", query.Get("since"))
+				assert.Equal(t, "2024-01-01T11:00:00Z", query.Get("until"))
+				assert.Empty(t, query.Get("earliest"))
+
+				return newMockResponse(200, `{"oncalls": [{"user": {"id": "USER1"}, "escalation_level": 1}]}`), nil
+			},
+		},
+	}
+
+	since := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	response, err := client.GetOnCallsForUsers([]string{"USER1", "USER2"}, since, since.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, response.OnCalls, 1)
+	assert.Equal(t, "USER1", response.OnCalls[0].User.ID)
+}
+
+func TestClient_GetUserByEmail(t *testing.T) {
+	client := &Client{
+		baseURL:  "https://api.pagerduty.com",
+		apiToken: "test-token",
+		httpClient: &mockHTTPClient{
+			doFunc: func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "/users", req.URL.Path)
+				assert.Contains(t, req.URL.Query().Get("query"), "@example.com")
+
+				return newMockResponse(200, `{"users": [
+					{"id": "USER2", "email": "malice@example.com"},
+					{"id": "USER1", "email": "Alice@example.com"}
+				]}`), nil
+			},
+		},
+	}
+
+	user, err := client.GetUserByEmail("alice@example.com")
+	require.NoError(t, err)
+	require.NotNil(t, user)
+	assert.Equal(t, "USER1", user.ID)
+
+	user, err = client.GetUserByEmail("bob@example.com")
+	require.NoError(t, err)
+	assert.Nil(t, user)
+}
+
+func TestClient_GetOnCallsBetween(t *testing.T) {
+	since := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+	until := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	requests := 0
+	client := &Client{
+		baseURL:  "https://api.pagerduty.com",
+		apiToken: "test-token",
+		httpClient: &mockHTTPClient{
+			doFunc: func(req *http.Request) (*http.Response, error) {
+				query := req.URL.Query()
+				assert.Equal(t, "2024-01-01T08:00:00Z", query.Get("since"))
+				assert.Equal(t, "2024-01-01T10:00:00Z", query.Get("until"))
+				assert.Equal(t, []string{"users"}, query["include[]"])
+				assert.Empty(t, query.Get("earliest"))
+
+				requests++
+				if query.Get("offset") == "0" {
+					return newMockResponse(200, `{
+						"oncalls": [{"user": {"id": "USER1"}, "schedule": {"id": "SCHED1"}, "start": "2024-01-01T00:00:00Z", "end": "2024-01-01T09:00:00Z"}],
+						"more": true
+					}`), nil
+				}
+
+				assert.Equal(t, "1", query.Get("offset"))
+				return newMockResponse(200, `{
+					"oncalls": [{"user": {"id": "USER2"}, "schedule": {"id": "SCHED1"}, "start": "2024-01-01T09:00:00Z", "end": "2024-01-01T17:00:00Z"}],
+					"more": false
+				}`), nil
+			},
+		},
+	}
+
+	response, err := client.GetOnCallsBetween(since, until)
+	require.NoError(t, err)
+	assert.Equal(t, 2, requests)
+	require.Len(t, response.OnCalls, 2)
+	assert.Equal(t, "USER1", response.OnCalls[0].User.ID)
+	assert.Equal(t, "USER2", response.OnCalls[1].User.ID)
+	assert.Equal(t, 2, response.Total)
+}
+
+func TestClient_GetOpenIncidents(t *testing.T) {
+	tests := []struct {
+		name     string
+		mockFunc func(req *http.Request) (*http.Response, error)
+		want     *IncidentsResponse
+		wantErr  bool
+	}{
+		{
+			name: "successful response",
+			mockFunc: func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "/incidents", req.URL.Path)
+				assert.Equal(t, []string{"triggered", "acknowledged"}, req.URL.Query()["statuses[]"])
+
+				return newMockResponse(200, `{
+					"incidents": [
+						{
+							"id": "INC1",
+							"type": "incident",
+							"title": "Database is down",
+							"status": "triggered",
+							"urgency": "high",
+							"incident_number": 42,
+							"service": {"id": "SVC1", "type": "service_reference", "summary": "Database"},
+							"escalation_policy": {"id": "EP1", "type": "escalation_policy_reference", "summary": "Ops"}
+						}
+					]
+				}`), nil
+			},
+			want: &IncidentsResponse{
+				ListResponse: ListResponse{Total: 1},
+				Incidents: []Incident{
+					{
+						ID:             "INC1",
+						Type:           "incident",
+						Title:          "Database is down",
+						Status:         "triggered",
+						Urgency:        "high",
+						IncidentNumber: 42,
+						Service: ServiceReference{
+							ID:      "SVC1",
+							Type:    "service_reference",
+							Summary: "Database",
+						},
+						EscalationPolicy: &EscalationPolicyReference{
+							ID:      "EP1",
+							Type:    "escalation_policy_reference",
+							Summary: "Ops",
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "multiple pages",
+			mockFunc: func(req *http.Request) (*http.Response, error) {
+				if req.URL.Query().Get("offset") == "0" {
+					return newMockResponse(200, `{"incidents": [{"id": "INC1"}], "more": true}`), nil
+				}
+				assert.Equal(t, "1", req.URL.Query().Get("offset"))
+				return newMockResponse(200, `{"incidents": [{"id": "INC2"}], "more": false}`), nil
+			},
+			want: &IncidentsResponse{
+				ListResponse: ListResponse{Total: 2},
+				Incidents:    []Incident{{ID: "INC1"}, {ID: "INC2"}},
+			},
+		},
+		{
+			name: "API error",
+			mockFunc: func(req *http.Request) (*http.Response, error) {
+				return newMockResponse(403, `{"error": {"message": "Forbidden"}}`), nil
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &Client{
+				baseURL:  "https://api.pagerduty.com",
+				apiToken: "test-token",
+				httpClient: &mockHTTPClient{
+					doFunc: tt.mockFunc,
+				},
+			}
+
+			got, err := client.GetOpenIncidents()
+
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestClient_ListEscalationPolicies(t *testing.T) {
+	client := &Client{
+		baseURL:  "https://api.pagerduty.com",
+		apiToken: "test-token",
+		httpClient: &mockHTTPClient{
+			doFunc: func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "/escalation_policies", req.URL.Path)
+				assert.Equal(t, "targets", req.URL.Query().Get("include[]"))
+				assert.Equal(t, "ops", req.URL.Query().Get("query"))
+
+				return newMockResponse(200, `{
+					"escalation_policies": [
+						{
+							"id": "EP1",
+							"name": "Ops",
+							"num_loops": 2,
+							"escalation_rules": [
+								{
+									"id": "RULE1",
+									"escalation_delay_in_minutes": 30,
+									"targets": [
+										{"id": "SCHED1", "type": "schedule_reference", "summary": "Primary"},
+										{"id": "USER1", "type": "user", "name": "Alice", "email": "alice@example.com"}
+									]
+								}
+							],
+							"services": [{"id": "SVC1", "type": "service_reference", "summary": "Database"}],
+							"teams": [{"id": "TEAM1", "type": "team_reference", "summary": "Platform"}]
+						}
+					],
+					"more": false
+				}`), nil
+			},
+		},
+	}
+
+	response, err := client.ListEscalationPolicies(url.Values{"query": []string{"ops"}})
+	require.NoError(t, err)
+	require.Len(t, response.EscalationPolicies, 1)
+
+	policy := response.EscalationPolicies[0]
+	assert.Equal(t, "Ops", policy.Name)
+	assert.Equal(t, 2, policy.NumLoops)
+	require.Len(t, policy.EscalationRules, 1)
+	assert.Equal(t, 30, policy.EscalationRules[0].EscalationDelayInMinutes)
+	assert.Equal(t, []EscalationTarget{
+		{ID: "SCHED1", Type: "schedule_reference", Summary: "Primary"},
+		{ID: "USER1", Type: "user", Name: "Alice", Email: "alice@example.com"},
+	}, policy.EscalationRules[0].Targets)
+	assert.Equal(t, []ServiceReference{{ID: "SVC1", Type: "service_reference", Summary: "Database"}}, policy.Services)
+	assert.Equal(t, []TeamReference{{ID: "TEAM1", Type: "team_reference", Summary: "Platform"}}, policy.Teams)
+}
+
+func TestClient_GetEscalationPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		mockFunc func(req *http.Request) (*http.Response, error)
+		wantErr  bool
+	}{
+		{
+			name: "successful response",
+			mockFunc: func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "/escalation_policies/EP1", req.URL.Path)
+				assert.Equal(t, "targets", req.URL.Query().Get("include[]"))
+
+				return newMockResponse(200, `{"escalation_policy": {"id": "EP1", "name": "Ops"}}`), nil
+			},
+		},
+		{
+			name: "not found",
+			mockFunc: func(req *http.Request) (*http.Response, error) {
+				return newMockResponse(404, `{"error": {"message": "Not Found", "code": 2100}}`), nil
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &Client{
+				baseURL:  "https://api.pagerduty.com",
+				apiToken: "test-token",
+				httpClient: &mockHTTPClient{
+					doFunc: tt.mockFunc,
+				},
+			}
+
+			got, err := client.GetEscalationPolicy("EP1")
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "Ops", got.EscalationPolicy.Name)
+		})
+	}
+}
+
+func TestClient_GetSchedulesForTeams(t *testing.T) {
+	client := &Client{
+		baseURL:  "https://api.pagerduty.com",
+		apiToken: "test-token",
+		httpClient: &mockHTTPClient{
+			doFunc: func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "/schedules", req.URL.Path)
+				assert.Equal(t, []string{"TEAM1", "TEAM2"}, req.URL.Query()["team_ids[]"])
+
+				return newMockResponse(200, `{"schedules": [{"id": "SCHED1", "name": "Primary"}]}`), nil
+			},
+		},
+	}
+
+	response, err := client.GetSchedulesForTeams(100, 0, []string{"TEAM1", "TEAM2"})
+	require.NoError(t, err)
+	require.Len(t, response.Schedules, 1)
+	assert.Equal(t, "SCHED1", response.Schedules[0].ID)
+}
+
+func TestClient_GetServicesForTeams(t *testing.T) {
+	client := &Client{
+		baseURL:  "https://api.pagerduty.com",
+		apiToken: "test-token",
+		httpClient: &mockHTTPClient{
+			doFunc: func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "/services", req.URL.Path)
+				assert.Equal(t, []string{"TEAM1"}, req.URL.Query()["team_ids[]"])
+
+				return newMockResponse(200, `{"services": []}`), nil
+			},
+		},
+	}
+
+	_, err := client.GetServicesForTeams(100, 0, []string{"TEAM1"})
+	require.NoError(t, err)
+}
+
+func TestClient_ListTeams(t *testing.T) {
+	client := &Client{
+		baseURL:  "https://api.pagerduty.com",
+		apiToken: "test-token",
+		httpClient: &mockHTTPClient{
+			doFunc: func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "/teams", req.URL.Path)
+
+				return newMockResponse(200, `{
+					"teams": [
+						{"id": "TEAM1", "type": "team", "name": "Platform", "parent": {"id": "TEAM0", "type": "team_reference"}}
+					]
+				}`), nil
+			},
+		},
+	}
+
+	response, err := client.ListTeams(nil)
+	require.NoError(t, err)
+	require.Len(t, response.Teams, 1)
+	assert.Equal(t, "Platform", response.Teams[0].Name)
+	assert.Equal(t, &TeamReference{ID: "TEAM0", Type: "team_reference"}, response.Teams[0].Parent)
+}
+
+func TestClient_GetTeam(t *testing.T) {
+	client := &Client{
+		baseURL:  "https://api.pagerduty.com",
+		apiToken: "test-token",
+		httpClient: &mockHTTPClient{
+			doFunc: func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "/teams/TEAM1", req.URL.Path)
+
+				return newMockResponse(200, `{"team": {"id": "TEAM1", "name": "Platform"}}`), nil
+			},
+		},
+	}
+
+	response, err := client.GetTeam("TEAM1")
+	require.NoError(t, err)
+	assert.Equal(t, "Platform", response.Team.Name)
+}
+
+func TestClient_ListTeamMembers(t *testing.T) {
+	client := &Client{
+		baseURL:  "https://api.pagerduty.com",
+		apiToken: "test-token",
+		httpClient: &mockHTTPClient{
+			doFunc: func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "/teams/TEAM1/members", req.URL.Path)
+				assert.Equal(t, "users", req.URL.Query().Get("include[]"))
+
+				return newMockResponse(200, `{
+					"members": [
+						{"user": {"id": "USER1", "name": "Alice", "email": "alice@example.com"}, "role": "manager"}
+					]
+				}`), nil
+			},
+		},
+	}
+
+	response, err := client.ListTeamMembers("TEAM1", 100, 0)
+	require.NoError(t, err)
+	require.Len(t, response.Members, 1)
+	assert.Equal(t, "manager", response.Members[0].Role)
+	assert.Equal(t, "alice@example.com", response.Members[0].User.Email)
+}
+
+func TestClient_GetService(t *testing.T) {
+	client := &Client{
+		baseURL:  "https://api.pagerduty.com",
+		apiToken: "test-token",
+		httpClient: &mockHTTPClient{
+			doFunc: func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "/services/SVC1", req.URL.Path)
+				assert.Equal(t, []string{"escalation_policies", "integrations"}, req.URL.Query()["include[]"])
+
+				return newMockResponse(200, `{
+					"service": {
+						"id": "SVC1",
+						"name": "Database",
+						"status": "active",
+						"escalation_policy": {"id": "EP1", "type": "escalation_policy", "name": "Ops"},
+						"integrations": [{"id": "INT1", "type": "events_api_v2_inbound_integration", "name": "Events", "integration_key": "key"}],
+						"teams": [{"id": "TEAM1", "type": "team_reference", "summary": "Platform"}],
+						"acknowledgement_timeout": 1800,
+						"auto_resolve_timeout": null,
+						"incident_urgency_rule": {
+							"type": "use_support_hours",
+							"during_support_hours": {"type": "constant", "urgency": "high"},
+							"outside_support_hours": {"type": "constant", "urgency": "low"}
+						},
+						"support_hours": {
+							"type": "fixed_time_per_day",
+							"time_zone": "Europe/Berlin",
+							"days_of_week": [1, 2, 3, 4, 5],
+							"start_time": "09:00:00",
+							"end_time": "17:00:00"
+						}
+					}
+				}`), nil
+			},
+		},
+	}
+
+	response, err := client.GetService("SVC1")
+	require.NoError(t, err)
+
+	service := response.Service
+	assert.Equal(t, "Database", service.Name)
+	require.NotNil(t, service.EscalationPolicy)
+	assert.Equal(t, "Ops", service.EscalationPolicy.Name)
+	require.Len(t, service.Integrations, 1)
+	assert.Equal(t, "key", service.Integrations[0].IntegrationKey)
+	assert.Equal(t, []TeamReference{{ID: "TEAM1", Type: "team_reference", Summary: "Platform"}}, service.Teams)
+	require.NotNil(t, service.AcknowledgementTimeout)
+	assert.Equal(t, 1800, *service.AcknowledgementTimeout)
+	assert.Nil(t, service.AutoResolveTimeout)
+	assert.Equal(t, &IncidentUrgencyRule{
+		Type:                "use_support_hours",
+		DuringSupportHours:  &IncidentUrgencyType{Type: "constant", Urgency: "high"},
+		OutsideSupportHours: &IncidentUrgencyType{Type: "constant", Urgency: "low"},
+	}, service.IncidentUrgencyRule)
+	assert.Equal(t, &SupportHours{
+		Type:       "fixed_time_per_day",
+		TimeZone:   "Europe/Berlin",
+		DaysOfWeek: []int{1, 2, 3, 4, 5},
+		StartTime:  "09:00:00",
+		EndTime:    "17:00:00",
+	}, service.SupportHours)
+}
+
+func TestClient_GetServiceDependencies(t *testing.T) {
+	client := &Client{
+		baseURL:  "https://api.pagerduty.com",
+		apiToken: "test-token",
+		httpClient: &mockHTTPClient{
+			doFunc: func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "/service_dependencies/technical_services/SVC1", req.URL.Path)
+
+				return newMockResponse(200, `{
+					"relationships": [
+						{
+							"id": "DEP1",
+							"type": "service_dependency",
+							"supporting_service": {"id": "SVC2", "type": "technical_service_reference"},
+							"dependent_service": {"id": "SVC1", "type": "technical_service_reference"}
+						}
+					]
+				}`), nil
+			},
+		},
+	}
+
+	response, err := client.GetServiceDependencies("SVC1")
+	require.NoError(t, err)
+	require.Len(t, response.Relationships, 1)
+	assert.Equal(t, "SVC2", response.Relationships[0].SupportingService.ID)
+	assert.Equal(t, "SVC1", response.Relationships[0].DependentService.ID)
+}
+
+func TestClient_ListMaintenanceWindows(t *testing.T) {
+	client := &Client{
+		baseURL:  "https://api.pagerduty.com",
+		apiToken: "test-token",
+		httpClient: &mockHTTPClient{
+			doFunc: func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "GET", req.Method)
+				assert.Equal(t, "/maintenance_windows", req.URL.Path)
+				assert.Equal(t, "ongoing", req.URL.Query().Get("filter"))
+
+				return newMockResponse(200, `{
+					"maintenance_windows": [
+						{
+							"id": "MW1",
+							"type": "maintenance_window",
+							"start_time": "2024-01-01T10:00:00Z",
+							"end_time": "2024-01-01T12:00:00Z",
+							"description": "Deploy",
+							"services": [{"id": "SVC1", "type": "service_reference", "summary": "Checkout"}]
+						}
+					],
+					"limit": 25,
+					"offset": 0,
+					"more": false
+				}`), nil
+			},
+		},
+	}
+
+	params := url.Values{}
+	params.Set("filter", "ongoing")
+	response, err := client.ListMaintenanceWindows(params)
+	require.NoError(t, err)
+	require.Len(t, response.MaintenanceWindows, 1)
+	assert.Equal(t, "MW1", response.MaintenanceWindows[0].ID)
+	assert.Equal(t, "Checkout", response.MaintenanceWindows[0].Services[0].Summary)
+}
+
+func TestClient_CreateMaintenanceWindow(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	end := start.Add(2 * time.Hour)
+
+	client := &Client{
+		baseURL:  "https://api.pagerduty.com",
+		apiToken: "test-token",
+		httpClient: &mockHTTPClient{
+			doFunc: func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "POST", req.Method)
+				assert.Equal(t, "/maintenance_windows", req.URL.Path)
+				assert.Equal(t, "alice@example.com", req.Header.Get("From"))
+
+				var request CreateMaintenanceWindowRequest
+				require.NoError(t, json.NewDecoder(req.Body).Decode(&request))
+				assert.Equal(t, "maintenance_window", request.MaintenanceWindow.Type)
+				assert.Equal(t, "2024-01-01T10:00:00Z", request.MaintenanceWindow.StartTime)
+				assert.Equal(t, "2024-01-01T12:00:00Z", request.MaintenanceWindow.EndTime)
+				assert.Equal(t, "Deploy", request.MaintenanceWindow.Description)
+				assert.Equal(t, []ServiceReference{{ID: "SVC1", Type: "service_reference"}}, request.MaintenanceWindow.Services)
+
+				return newMockResponse(201, `{
+					"maintenance_window": {
+						"id": "MW1",
+						"type": "maintenance_window",
+						"start_time": "2024-01-01T10:00:00Z",
+						"end_time": "2024-01-01T12:00:00Z",
+						"services": [{"id": "SVC1", "type": "service_reference", "summary": "Checkout"}]
+					}
+				}`), nil
+			},
+		},
+	}
+
+	response, err := client.CreateMaintenanceWindow("alice@example.com", start, end, "Deploy", []string{"SVC1"})
+	require.NoError(t, err)
+	assert.Equal(t, "MW1", response.MaintenanceWindow.ID)
+}
+
+func TestClient_DeleteMaintenanceWindow(t *testing.T) {
+	client := &Client{
+		baseURL:  "https://api.pagerduty.com",
+		apiToken: "test-token",
+		httpClient: &mockHTTPClient{
+			doFunc: func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "DELETE", req.Method)
+				assert.Equal(t, "/maintenance_windows/MW1", req.URL.Path)
+				return newMockResponse(204, ""), nil
+			},
+		},
+	}
+
+	require.NoError(t, client.DeleteMaintenanceWindow("MW1"))
+}
+
+func TestClient_GetBusinessServiceImpacts(t *testing.T) {
+	client := &Client{
+		baseURL:  "https://api.pagerduty.com",
+		apiToken: "test-token",
+		httpClient: &mockHTTPClient{
+			doFunc: func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "/business_services/impacts", req.URL.Path)
+				assert.Equal(t, []string{"BS1", "BS2"}, req.URL.Query()["ids[]"])
+				assert.Equal(t, "business-impact-early-access", req.Header.Get("X-EARLY-ACCESS"))
+
+				return newMockResponse(200, `{
+					"services": [
+						{
+							"id": "BS1",
+							"name": "Checkout",
+							"type": "business_service",
+							"status": "impacted",
+							"additional_fields": {"highest_impacting_priority": {"id": "P1", "order": 256}}
+						},
+						{"id": "BS2", "name": "Search", "type": "business_service", "status": "not_impacted"}
+					],
+					"limit": 100,
+					"more": false
+				}`), nil
+			},
+		},
+	}
+
+	response, err := client.GetBusinessServiceImpacts([]string{"BS1", "BS2"})
+	require.NoError(t, err)
+	require.Len(t, response.Services, 2)
+	assert.Equal(t, ImpactStatusImpacted, response.Services[0].Status)
+	require.NotNil(t, response.Services[0].AdditionalFields)
+	assert.Equal(t, 256, response.Services[0].AdditionalFields.HighestImpactingPriority.Order)
+	assert.Equal(t, ImpactStatusNotImpacted, response.Services[1].Status)
+	assert.Nil(t, response.Services[1].AdditionalFields)
+}
+
+func TestClient_GetStatusDashboardImpacts(t *testing.T) {
+	client := &Client{
+		baseURL:  "https://api.pagerduty.com",
+		apiToken: "test-token",
+		httpClient: &mockHTTPClient{
+			doFunc: func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "/status_dashboards/SD1/service_impacts", req.URL.Path)
+				assert.Equal(t, "status-dashboards", req.Header.Get("X-EARLY-ACCESS"))
+
+				return newMockResponse(200, `{"services": [{"id": "BS1", "name": "Checkout", "status": "not_impacted"}]}`), nil
+			},
+		},
+	}
+
+	response, err := client.GetStatusDashboardImpacts("SD1")
+	require.NoError(t, err)
+	require.Len(t, response.Services, 1)
+	assert.Equal(t, "Checkout", response.Services[0].Name)
+}
+
+func TestClient_GetIncident(t *testing.T) {
+	client := &Client{
+		baseURL:  "https://api.pagerduty.com",
+		apiToken: "test-token",
+		httpClient: &mockHTTPClient{
+			doFunc: func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "/incidents/INC1", req.URL.Path)
+				return newMockResponse(200, `{"incident": {"id": "INC1", "title": "Checkout is down", "status": "triggered", "incident_number": 42}}`), nil
+			},
+		},
+	}
+
+	response, err := client.GetIncident("INC1")
+	require.NoError(t, err)
+	assert.Equal(t, "Checkout is down", response.Incident.Title)
+	assert.Equal(t, 42, response.Incident.IncidentNumber)
+}
+
+func TestClient_ListStatusUpdates(t *testing.T) {
+	client := &Client{
+		baseURL:  "https://api.pagerduty.com",
+		apiToken: "test-token",
+		httpClient: &mockHTTPClient{
+			doFunc: func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "GET", req.Method)
+				assert.Equal(t, "/incidents/INC1/status_updates", req.URL.Path)
+				return newMockResponse(200, `{
+					"status_updates": [
+						{"id": "SU1", "message": "Investigating", "sender": {"id": "USER1", "type": "user_reference", "summary": "Alice"}, "created_at": "2024-01-01T10:00:00Z"}
+					]
+				}`), nil
+			},
+		},
+	}
+
+	response, err := client.ListStatusUpdates("INC1")
+	require.NoError(t, err)
+	require.Len(t, response.StatusUpdates, 1)
+	assert.Equal(t, "Investigating", response.StatusUpdates[0].Message)
+	assert.Equal(t, "Alice", response.StatusUpdates[0].Sender.Summary)
+}
+
+func TestClient_CreateStatusUpdate(t *testing.T) {
+	client := &Client{
+		baseURL:  "https://api.pagerduty.com",
+		apiToken: "test-token",
+		httpClient: &mockHTTPClient{
+			doFunc: func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "POST", req.Method)
+				assert.Equal(t, "/incidents/INC1/status_updates", req.URL.Path)
+				assert.Equal(t, "alice@example.com", req.Header.Get("From"))
+
+				var request CreateStatusUpdateRequest
+				require.NoError(t, json.NewDecoder(req.Body).Decode(&request))
+				assert.Equal(t, CreateStatusUpdateRequest{Message: "Rolled back", Subject: "Mitigated"}, request)
+
+				return newMockResponse(201, `{"status_update": {"id": "SU1", "message": "Rolled back", "subject": "Mitigated"}}`), nil
+			},
+		},
+	}
+
+	response, err := client.CreateStatusUpdate("INC1", "alice@example.com", "Rolled back", "Mitigated")
+	require.NoError(t, err)
+	assert.Equal(t, "SU1", response.StatusUpdate.ID)
+}
+
+func TestClient_MergeIncidents(t *testing.T) {
+	client := &Client{
+		baseURL:  "https://api.pagerduty.com",
+		apiToken: "test-token",
+		httpClient: &mockHTTPClient{
+			doFunc: func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "PUT", req.Method)
+				assert.Equal(t, "/incidents/INC1/merge", req.URL.Path)
+				assert.Equal(t, "alice@example.com", req.Header.Get("From"))
+
+				var request MergeIncidentsRequest
+				require.NoError(t, json.NewDecoder(req.Body).Decode(&request))
+				require.Len(t, request.SourceIncidents, 2)
+				assert.Equal(t, IncidentReference{ID: "INC2", Type: "incident_reference"}, request.SourceIncidents[0])
+				assert.Equal(t, "INC3", request.SourceIncidents[1].ID)
+
+				return newMockResponse(200, `{"incident": {"id": "INC1", "title": "Checkout is down", "status": "triggered"}}`), nil
+			},
+		},
+	}
+
+	response, err := client.MergeIncidents("INC1", "alice@example.com", []string{"INC2", "INC3"})
+	require.NoError(t, err)
+	assert.Equal(t, "INC1", response.Incident.ID)
+}
+
+func TestClient_ListRelatedIncidents(t *testing.T) {
+	client := &Client{
+		baseURL:  "https://api.pagerduty.com",
+		apiToken: "test-token",
+		httpClient: &mockHTTPClient{
+			doFunc: func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "/incidents/INC1/related_incidents", req.URL.Path)
+				assert.Equal(t, "related-incidents", req.Header.Get(earlyAccessHeader))
+
+				return newMockResponse(200, `{"related_incidents": [{"incident": {"id": "INC2", "title": "Payments slow"}, "relationships": [{"type": "machine_learning_inferred", "metadata": {}}]}]}`), nil
+			},
+		},
+	}
+
+	response, err := client.ListRelatedIncidents("INC1")
+	require.NoError(t, err)
+	require.Len(t, response.RelatedIncidents, 1)
+	assert.Equal(t, "INC2", response.RelatedIncidents[0].Incident.ID)
+	assert.Equal(t, "machine_learning_inferred", response.RelatedIncidents[0].Relationships[0].Type)
+}
+
+func TestClient_ListIncidentWorkflows(t *testing.T) {
+	client := &Client{
+		baseURL:  "https://api.pagerduty.com",
+		apiToken: "test-token",
+		httpClient: &mockHTTPClient{
+			doFunc: func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "/incident_workflows", req.URL.Path)
+				assert.Equal(t, "major", req.URL.Query().Get("query"))
+
+				return newMockResponse(200, `{"incident_workflows": [{"id": "WF1", "name": "Major Incident", "is_enabled": true}], "limit": 25, "offset": 0, "more": false}`), nil
+			},
+		},
+	}
+
+	response, err := client.ListIncidentWorkflows(url.Values{"query": {"major"}})
+	require.NoError(t, err)
+	require.Len(t, response.IncidentWorkflows, 1)
+	assert.Equal(t, "Major Incident", response.IncidentWorkflows[0].Name)
+}
+
+func TestClient_StartIncidentWorkflow(t *testing.T) {
+	client := &Client{
+		baseURL:  "https://api.pagerduty.com",
+		apiToken: "test-token",
+		httpClient: &mockHTTPClient{
+			doFunc: func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "POST", req.Method)
+				assert.Equal(t, "/incident_workflows/WF1/instances", req.URL.Path)
+
+				var request IncidentWorkflowInstanceRequest
+				require.NoError(t, json.NewDecoder(req.Body).Decode(&request))
+				assert.Equal(t, IncidentReference{ID: "INC1", Type: "incident_reference"}, request.IncidentWorkflowInstance.Incident)
+
+				return newMockResponse(201, `{"incident_workflow_instance": {"id": "WFI1", "type": "incident_workflow_instance", "incident": {"id": "INC1", "type": "incident_reference"}}}`), nil
+			},
+		},
+	}
+
+	response, err := client.StartIncidentWorkflow("WF1", "INC1")
+	require.NoError(t, err)
+	assert.Equal(t, "WFI1", response.IncidentWorkflowInstance.ID)
+}
+
+func TestClient_CreateResponderRequest(t *testing.T) {
+	client := &Client{
+		baseURL:  "https://api.pagerduty.com",
+		apiToken: "test-token",
+		httpClient: &mockHTTPClient{
+			doFunc: func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "POST", req.Method)
+				assert.Equal(t, "/incidents/INC1/responder_requests", req.URL.Path)
+				assert.Equal(t, "alice@example.com", req.Header.Get("From"))
+
+				var request CreateResponderRequest
+				require.NoError(t, json.NewDecoder(req.Body).Decode(&request))
+				assert.Equal(t, "PUSER1", request.RequesterID)
+				assert.Equal(t, "Need a DBA", request.Message)
+				require.Len(t, request.ResponderRequestTargets, 2)
+				assert.Equal(t, ResponderRequestTarget{ID: "PUSER2", Type: ResponderRequestTargetUser}, request.ResponderRequestTargets[0].ResponderRequestTarget)
+				assert.Equal(t, ResponderRequestTarget{ID: "PEP1", Type: ResponderRequestTargetEscalationPolicy}, request.ResponderRequestTargets[1].ResponderRequestTarget)
+
+				return newMockResponse(200, `{"responder_request": {"message": "Need a DBA", "responder_request_targets": [{"responder_request_target": {"id": "PUSER2", "type": "user_reference", "incidents_responders": [{"state": "pending", "user": {"id": "PUSER2", "type": "user_reference", "summary": "Bob"}}]}}]}}`), nil
+			},
+		},
+	}
+
+	targets := []ResponderRequestTarget{
+		{ID: "PUSER2", Type: ResponderRequestTargetUser},
+		{ID: "PEP1", Type: ResponderRequestTargetEscalationPolicy},
+	}
+	response, err := client.CreateResponderRequest("INC1", "alice@example.com", "PUSER1", "Need a DBA", targets)
+	require.NoError(t, err)
+	require.Len(t, response.ResponderRequest.ResponderRequestTargets, 1)
+	target := response.ResponderRequest.ResponderRequestTargets[0].ResponderRequestTarget
+	require.Len(t, target.IncidentsResponders, 1)
+	assert.Equal(t, ResponderStatePending, target.IncidentsResponders[0].State)
+}
+
+func TestClient_GetAllIncidentAlerts(t *testing.T) {
+	calls := 0
+	client := &Client{
+		baseURL:  "https://api.pagerduty.com",
+		apiToken: "test-token",
+		httpClient: &mockHTTPClient{
+			doFunc: func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "/incidents/INC1/alerts", req.URL.Path)
+				calls++
+				if req.URL.Query().Get("offset") == "0" {
+					return newMockResponse(200, `{"alerts": [{"id": "A1", "summary": "CPU high", "status": "triggered", "body": {"type": "alert_body", "details": {"host": "db-1"}}}], "more": true}`), nil
+				}
+				assert.Equal(t, "1", req.URL.Query().Get("offset"))
+				return newMockResponse(200, `{"alerts": [{"id": "A2", "summary": "CPU high", "status": "resolved"}], "more": false}`), nil
+			},
+		},
+	}
+
+	response, err := client.GetAllIncidentAlerts("INC1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+	require.Len(t, response.Alerts, 2)
+	assert.Equal(t, 2, response.Total)
+	assert.Equal(t, "db-1", response.Alerts[0].Body.Details["host"])
+}
+
+func TestClient_UpdateAlert(t *testing.T) {
+	t.Run("resolve", func(t *testing.T) {
+		client := &Client{
+			baseURL:  "https://api.pagerduty.com",
+			apiToken: "test-token",
+			httpClient: &mockHTTPClient{
+				doFunc: func(req *http.Request) (*http.Response, error) {
+					assert.Equal(t, "PUT", req.Method)
+					assert.Equal(t, "/incidents/INC1/alerts/A1", req.URL.Path)
+					assert.Equal(t, "alice@example.com", req.Header.Get("From"))
+
+					var request UpdateAlertRequest
+					require.NoError(t, json.NewDecoder(req.Body).Decode(&request))
+					assert.Equal(t, AlertUpdate{Type: "alert", Status: AlertStatusResolved}, request.Alert)
+
+					return newMockResponse(200, `{"alert": {"id": "A1", "status": "resolved"}}`), nil
+				},
+			},
+		}
+
+		response, err := client.ResolveAlert("INC1", "A1", "alice@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, AlertStatusResolved, response.Alert.Status)
+	})
+
+	t.Run("move", func(t *testing.T) {
+		client := &Client{
+			baseURL:  "https://api.pagerduty.com",
+			apiToken: "test-token",
+			httpClient: &mockHTTPClient{
+				doFunc: func(req *http.Request) (*http.Response, error) {
+					var request UpdateAlertRequest
+					require.NoError(t, json.NewDecoder(req.Body).Decode(&request))
+					assert.Empty(t, request.Alert.Status)
+					require.NotNil(t, request.Alert.Incident)
+					assert.Equal(t, "INC2", request.Alert.Incident.ID)
+
+					return newMockResponse(200, `{"alert": {"id": "A1", "incident": {"id": "INC2"}}}`), nil
+				},
+			},
+		}
+
+		response, err := client.MoveAlert("INC1", "A1", "INC2", "alice@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, "INC2", response.Alert.Incident.ID)
+	})
+}
+
+func TestClient_GetAbilities(t *testing.T) {
+	client := &Client{
+		baseURL:  "https://api.pagerduty.com",
+		apiToken: "test-token",
+		httpClient: &mockHTTPClient{
+			doFunc: func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "GET", req.Method)
+				assert.Equal(t, "/abilities", req.URL.Path)
+				return newMockResponse(200, `{"abilities": ["teams", "event_rules"]}`), nil
+			},
+		},
+	}
+
+	response, err := client.GetAbilities()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"teams", "event_rules"}, response.Abilities)
+
+	client.httpClient = &mockHTTPClient{
+		doFunc: func(req *http.Request) (*http.Response, error) {
+			return newMockResponse(401, `{"error": {"message": "Unauthorized", "code": 2006}}`), nil
+		},
+	}
+	_, err = client.GetAbilities()
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, 401, apiErr.StatusCode)
+	assert.Equal(t, "PagerDuty API error: Unauthorized (code: 2006)", err.Error())
+}
+
+func TestClient_GetCurrentUser(t *testing.T) {
+	t.Run("user-level token", func(t *testing.T) {
+		client := &Client{
+			baseURL:  "https://api.pagerduty.com",
+			apiToken: "test-token",
+			httpClient: &mockHTTPClient{
+				doFunc: func(req *http.Request) (*http.Response, error) {
+					assert.Equal(t, "GET", req.Method)
+					assert.Equal(t, "/users/me", req.URL.Path)
+					return newMockResponse(200, `{"user": {"id": "PUSER1", "email": "alice@example.com", "role": "read_only_user"}}`), nil
+				},
+			},
+		}
+
+		user, err := client.GetCurrentUser()
+		require.NoError(t, err)
+		assert.Equal(t, "PUSER1", user.ID)
+		assert.Equal(t, "read_only_user", user.Role)
+	})
+
+	t.Run("account-level API key", func(t *testing.T) {
+		client := &Client{
+			baseURL:  "https://api.pagerduty.com",
+			apiToken: "test-token",
+			httpClient: &mockHTTPClient{
+				doFunc: func(req *http.Request) (*http.Response, error) {
+					return newMockResponse(400, `{"error": {"message": "Invalid Input Provided", "code": 2001}}`), nil
+				},
+			},
+		}
+
+		_, err := client.GetCurrentUser()
+		var apiErr *APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, 400, apiErr.StatusCode)
+	})
+}
+
 // Test the actual HTTP client interface
 func TestClient_HTTPClientInterface(t *testing.T) {
 	// Ensure our mock implements the same interface as http.Client
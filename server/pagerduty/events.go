@@ -0,0 +1,156 @@
+package pagerduty
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultEventsBaseURL is the base URL of the PagerDuty Events API in the US service region
+const DefaultEventsBaseURL = "https://events.pagerduty.com"
+
+// EventsClient sends events to the PagerDuty Events API. Unlike the REST API, events are
+// authenticated by the routing key of the integration receiving them.
+type EventsClient struct {
+	baseURL    string
+	httpClient HTTPClient
+}
+
+func NewEventsClient(baseURL string) *EventsClient {
+	if baseURL == "" {
+		baseURL = DefaultEventsBaseURL
+	}
+
+	return &EventsClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// Event actions of the Events API v2
+const (
+	EventActionTrigger     = "trigger"
+	EventActionAcknowledge = "acknowledge"
+	EventActionResolve     = "resolve"
+)
+
+// Severities of alerts triggered through the Events API v2
+const (
+	SeverityCritical = "critical"
+	SeverityError    = "error"
+	SeverityWarning  = "warning"
+	SeverityInfo     = "info"
+)
+
+// Link is a link attached to an event
+type Link struct {
+	Href string `json:"href"`
+	Text string `json:"text,omitempty"`
+}
+
+// ChangeEvent records a change, such as a deploy, on the service of an integration
+type ChangeEvent struct {
+	RoutingKey string             `json:"routing_key"`
+	Payload    ChangeEventPayload `json:"payload"`
+	Links      []Link             `json:"links,omitempty"`
+}
+
+// ChangeEventPayload describes a change event
+type ChangeEventPayload struct {
+	Summary       string                 `json:"summary"`
+	Timestamp     string                 `json:"timestamp,omitempty"`
+	Source        string                 `json:"source,omitempty"`
+	CustomDetails map[string]interface{} `json:"custom_details,omitempty"`
+}
+
+// Event triggers, acknowledges or resolves an alert on the service of an integration. Events
+// with the same dedup key apply to the same alert; the payload is only required for triggers.
+type Event struct {
+	RoutingKey  string        `json:"routing_key"`
+	EventAction string        `json:"event_action"`
+	DedupKey    string        `json:"dedup_key,omitempty"`
+	Payload     *EventPayload `json:"payload,omitempty"`
+	Client      string        `json:"client,omitempty"`
+	ClientURL   string        `json:"client_url,omitempty"`
+	Links       []Link        `json:"links,omitempty"`
+}
+
+// EventPayload describes the alert triggered by an event
+type EventPayload struct {
+	Summary       string                 `json:"summary"`
+	Source        string                 `json:"source"`
+	Severity      string                 `json:"severity"`
+	Timestamp     string                 `json:"timestamp,omitempty"`
+	Component     string                 `json:"component,omitempty"`
+	Group         string                 `json:"group,omitempty"`
+	Class         string                 `json:"class,omitempty"`
+	CustomDetails map[string]interface{} `json:"custom_details,omitempty"`
+}
+
+// EventResponse is the response of the Events API to an accepted event
+type EventResponse struct {
+	Status   string   `json:"status"`
+	Message  string   `json:"message"`
+	DedupKey string   `json:"dedup_key,omitempty"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+// SendChangeEvent sends a change event to the service of the event's routing key
+func (c *EventsClient) SendChangeEvent(event *ChangeEvent) (*EventResponse, error) {
+	return c.send("/v2/change/enqueue", event)
+}
+
+// SendEvent sends an alert event to the service of the event's routing key
+func (c *EventsClient) SendEvent(event *Event) (*EventResponse, error) {
+	return c.send("/v2/enqueue", event)
+}
+
+func (c *EventsClient) send(path string, event interface{}) (*EventResponse, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal event")
+	}
+
+	req, err := http.NewRequest("POST", c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to execute request")
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read response body")
+	}
+
+	var response EventResponse
+	if resp.StatusCode >= 400 {
+		if err := json.Unmarshal(responseBody, &response); err == nil && response.Message != "" {
+			message := response.Message
+			if len(response.Errors) > 0 {
+				message += ": " + strings.Join(response.Errors, "; ")
+			}
+			return nil, fmt.Errorf("PagerDuty Events API error: %s (HTTP %d)", message, resp.StatusCode)
+		}
+		return nil, fmt.Errorf("PagerDuty Events API error: HTTP %d - %s", resp.StatusCode, string(responseBody))
+	}
+
+	if err := json.Unmarshal(responseBody, &response); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal event response")
+	}
+
+	return &response, nil
+}
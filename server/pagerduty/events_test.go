@@ -0,0 +1,103 @@
+package pagerduty
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventsClient_SendChangeEvent(t *testing.T) {
+	tests := []struct {
+		name          string
+		statusCode    int
+		responseBody  string
+		expectedError string
+	}{
+		{
+			name:         "accepted",
+			statusCode:   http.StatusAccepted,
+			responseBody: `{"status": "success", "message": "Change event processed"}`,
+		},
+		{
+			name:          "invalid routing key",
+			statusCode:    http.StatusBadRequest,
+			responseBody:  `{"status": "invalid event", "message": "Event object is invalid", "errors": ["Invalid routing key"]}`,
+			expectedError: "PagerDuty Events API error: Event object is invalid: Invalid routing key (HTTP 400)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &EventsClient{
+				baseURL: "https://events.example.com",
+				httpClient: &mockHTTPClient{
+					doFunc: func(req *http.Request) (*http.Response, error) {
+						assert.Equal(t, "POST", req.Method)
+						assert.Equal(t, "https://events.example.com/v2/change/enqueue", req.URL.String())
+						assert.Empty(t, req.Header.Get("Authorization"))
+
+						body, err := io.ReadAll(req.Body)
+						require.NoError(t, err)
+						var event ChangeEvent
+						require.NoError(t, json.Unmarshal(body, &event))
+						assert.Equal(t, "R0UTINGKEY", event.RoutingKey)
+						assert.Equal(t, "Deployed v2.1", event.Payload.Summary)
+						assert.Equal(t, "Mattermost", event.Payload.Source)
+
+						return newMockResponse(tt.statusCode, tt.responseBody), nil
+					},
+				},
+			}
+
+			response, err := client.SendChangeEvent(&ChangeEvent{
+				RoutingKey: "R0UTINGKEY",
+				Payload: ChangeEventPayload{
+					Summary: "Deployed v2.1",
+					Source:  "Mattermost",
+				},
+			})
+			if tt.expectedError != "" {
+				require.Error(t, err)
+				assert.Equal(t, tt.expectedError, err.Error())
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "success", response.Status)
+		})
+	}
+}
+
+func TestEventsClient_SendEvent(t *testing.T) {
+	client := &EventsClient{
+		baseURL: "https://events.example.com",
+		httpClient: &mockHTTPClient{
+			doFunc: func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "POST", req.Method)
+				assert.Equal(t, "https://events.example.com/v2/enqueue", req.URL.String())
+
+				body, err := io.ReadAll(req.Body)
+				require.NoError(t, err)
+				var event map[string]interface{}
+				require.NoError(t, json.Unmarshal(body, &event))
+				assert.Equal(t, "resolve", event["event_action"])
+				assert.Equal(t, "nagios-db-1", event["dedup_key"])
+				assert.NotContains(t, event, "payload")
+
+				return newMockResponse(http.StatusAccepted, `{"status": "success", "message": "Event processed", "dedup_key": "nagios-db-1"}`), nil
+			},
+		},
+	}
+
+	response, err := client.SendEvent(&Event{
+		RoutingKey:  "R0UTINGKEY",
+		EventAction: EventActionResolve,
+		DedupKey:    "nagios-db-1",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "nagios-db-1", response.DedupKey)
+}
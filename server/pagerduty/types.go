@@ -11,6 +11,10 @@ type Schedule struct {
 	ScheduleLayers   []ScheduleLayer  `json:"schedule_layers,omitempty"`
 	OverrideSubcycle OverrideSubcycle `json:"override_subcycle,omitempty"`
 	FinalSchedule    FinalSchedule    `json:"final_schedule,omitempty"`
+
+	// Account is the name of the plugin's PagerDuty account the schedule belongs to, set by
+	// the plugin when merging the schedules of several accounts.
+	Account string `json:"account,omitempty"`
 }
 
 type ScheduleLayer struct {
@@ -74,13 +78,93 @@ type OnCall struct {
 	EscalationLevel  int               `json:"escalation_level"`
 	Start            string            `json:"start"`
 	End              string            `json:"end"`
+
+	// Account is the name of the plugin's PagerDuty account the on-call belongs to, set by the
+	// plugin when merging the on-calls of several accounts.
+	Account string `json:"account,omitempty"`
 }
 
 type EscalationPolicy struct {
-	ID          string `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	NumLoops    int    `json:"num_loops"`
+	ID                         string             `json:"id"`
+	Type                       string             `json:"type,omitempty"`
+	Summary                    string             `json:"summary,omitempty"`
+	Name                       string             `json:"name"`
+	Description                string             `json:"description"`
+	NumLoops                   int                `json:"num_loops"`
+	OnCallHandoffNotifications string             `json:"on_call_handoff_notifications,omitempty"`
+	EscalationRules            []EscalationRule   `json:"escalation_rules,omitempty"`
+	Services                   []ServiceReference `json:"services,omitempty"`
+	Teams                      []TeamReference    `json:"teams,omitempty"`
+	HtmlURL                    string             `json:"html_url,omitempty"`
+}
+
+// EscalationRule is a level of an escalation policy
+type EscalationRule struct {
+	ID                       string             `json:"id"`
+	EscalationDelayInMinutes int                `json:"escalation_delay_in_minutes"`
+	Targets                  []EscalationTarget `json:"targets"`
+}
+
+// EscalationTarget is a user or schedule notified at a level of an escalation policy
+type EscalationTarget struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Summary string `json:"summary,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Email   string `json:"email,omitempty"`
+	HtmlURL string `json:"html_url,omitempty"`
+}
+
+// TeamReference represents a reference to a team
+type TeamReference struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Summary string `json:"summary,omitempty"`
+}
+
+// Team represents a PagerDuty team
+type Team struct {
+	ID          string         `json:"id"`
+	Type        string         `json:"type,omitempty"`
+	Summary     string         `json:"summary,omitempty"`
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Parent      *TeamReference `json:"parent,omitempty"`
+	HtmlURL     string         `json:"html_url,omitempty"`
+}
+
+// TeamsResponse wraps the teams list response
+type TeamsResponse struct {
+	ListResponse
+	Teams []Team `json:"teams"`
+}
+
+// TeamResponse wraps a single team response
+type TeamResponse struct {
+	Team Team `json:"team"`
+}
+
+// TeamMember is a user's membership in a team
+type TeamMember struct {
+	User User   `json:"user"`
+	Role string `json:"role"`
+}
+
+// TeamMembersResponse wraps the team members list response
+type TeamMembersResponse struct {
+	ListResponse
+	Members []TeamMember `json:"members"`
+}
+
+// EscalationPoliciesResponse wraps the escalation policies list response
+type EscalationPoliciesResponse struct {
+	ListResponse
+	EscalationPolicies []EscalationPolicy `json:"escalation_policies"`
+}
+
+// EscalationPolicyResponse wraps a single escalation policy response
+type EscalationPolicyResponse struct {
+	EscalationPolicy EscalationPolicy `json:"escalation_policy"`
 }
 
 type ListResponse struct {
@@ -93,11 +177,35 @@ type ListResponse struct {
 type SchedulesResponse struct {
 	ListResponse
 	Schedules []Schedule `json:"schedules"`
+
+	// AccountErrors are the accounts whose schedules could not be retrieved, set by the plugin
+	// when merging the schedules of several accounts.
+	AccountErrors []AccountError `json:"account_errors,omitempty"`
 }
 
 type OnCallsResponse struct {
 	ListResponse
 	OnCalls []OnCall `json:"oncalls"`
+
+	// AccountErrors are the accounts whose on-calls could not be retrieved, set by the plugin
+	// when merging the on-calls of several accounts.
+	AccountErrors []AccountError `json:"account_errors,omitempty"`
+}
+
+// AccountError is a failure of one of the plugin's PagerDuty accounts in a view merged across
+// accounts
+type AccountError struct {
+	Account string `json:"account"`
+	Message string `json:"message"`
+}
+
+type UsersResponse struct {
+	ListResponse
+	Users []User `json:"users"`
+}
+
+type UserResponse struct {
+	User User `json:"user"`
 }
 
 type ErrorResponse struct {
@@ -108,6 +216,11 @@ type ErrorResponse struct {
 	} `json:"error"`
 }
 
+// AbilitiesResponse lists the abilities of an account
+type AbilitiesResponse struct {
+	Abilities []string `json:"abilities"`
+}
+
 // ScheduleResponse wraps a single schedule with details
 type ScheduleResponse struct {
 	Schedule ScheduleDetail `json:"schedule"`
@@ -134,12 +247,81 @@ type RenderedScheduleEntry struct {
 
 // Service represents a PagerDuty service
 type Service struct {
-	ID          string `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Type        string `json:"type"`
-	Summary     string `json:"summary"`
-	Status      string `json:"status"`
+	ID                     string               `json:"id"`
+	Name                   string               `json:"name"`
+	Description            string               `json:"description"`
+	Type                   string               `json:"type"`
+	Summary                string               `json:"summary"`
+	Status                 string               `json:"status"`
+	HtmlURL                string               `json:"html_url,omitempty"`
+	EscalationPolicy       *EscalationPolicy    `json:"escalation_policy,omitempty"`
+	Integrations           []Integration        `json:"integrations,omitempty"`
+	Teams                  []TeamReference      `json:"teams,omitempty"`
+	AcknowledgementTimeout *int                 `json:"acknowledgement_timeout,omitempty"`
+	AutoResolveTimeout     *int                 `json:"auto_resolve_timeout,omitempty"`
+	IncidentUrgencyRule    *IncidentUrgencyRule `json:"incident_urgency_rule,omitempty"`
+	SupportHours           *SupportHours        `json:"support_hours,omitempty"`
+	AlertCreation          string               `json:"alert_creation,omitempty"`
+	LastIncidentTimestamp  string               `json:"last_incident_timestamp,omitempty"`
+}
+
+// Integration is a way for events to reach a service, such as an Events API v2 integration
+type Integration struct {
+	ID             string `json:"id"`
+	Type           string `json:"type"`
+	Summary        string `json:"summary,omitempty"`
+	Name           string `json:"name,omitempty"`
+	IntegrationKey string `json:"integration_key,omitempty"`
+	HtmlURL        string `json:"html_url,omitempty"`
+}
+
+// IncidentUrgencyRule decides the urgency of a service's incidents, either constant or
+// depending on its support hours
+type IncidentUrgencyRule struct {
+	Type                string               `json:"type"`
+	Urgency             string               `json:"urgency,omitempty"`
+	DuringSupportHours  *IncidentUrgencyType `json:"during_support_hours,omitempty"`
+	OutsideSupportHours *IncidentUrgencyType `json:"outside_support_hours,omitempty"`
+}
+
+// IncidentUrgencyType is the urgency applied during or outside support hours
+type IncidentUrgencyType struct {
+	Type    string `json:"type"`
+	Urgency string `json:"urgency"`
+}
+
+// SupportHours are the hours during which a service is supported
+type SupportHours struct {
+	Type       string `json:"type"`
+	TimeZone   string `json:"time_zone"`
+	DaysOfWeek []int  `json:"days_of_week"`
+	StartTime  string `json:"start_time"`
+	EndTime    string `json:"end_time"`
+}
+
+// ServiceResponse wraps a single service response
+type ServiceResponse struct {
+	Service Service `json:"service"`
+}
+
+// ServiceDependency is a relationship in which one service supports another
+type ServiceDependency struct {
+	ID                string                     `json:"id"`
+	Type              string                     `json:"type"`
+	SupportingService ServiceDependencyReference `json:"supporting_service"`
+	DependentService  ServiceDependencyReference `json:"dependent_service"`
+}
+
+// ServiceDependencyReference is a technical or business service in a dependency
+type ServiceDependencyReference struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Summary string `json:"summary,omitempty"`
+}
+
+// ServiceDependenciesResponse wraps the service dependencies response
+type ServiceDependenciesResponse struct {
+	Relationships []ServiceDependency `json:"relationships"`
 }
 
 // ServicesResponse wraps the services list response
@@ -150,8 +332,16 @@ type ServicesResponse struct {
 
 // ServiceReference represents a reference to a service
 type ServiceReference struct {
-	ID   string `json:"id"`
-	Type string `json:"type"`
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Summary string `json:"summary,omitempty"`
+}
+
+// EscalationPolicyReference represents a reference to an escalation policy
+type EscalationPolicyReference struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Summary string `json:"summary,omitempty"`
 }
 
 // AssigneeReference represents a reference to an assignee
@@ -167,16 +357,90 @@ type Assignment struct {
 
 // Incident represents a PagerDuty incident
 type Incident struct {
-	ID           string         `json:"id"`
-	Type         string         `json:"type"`
-	Title        string         `json:"title"`
-	Description  string         `json:"description,omitempty"`
-	Service      ServiceReference `json:"service"`
-	Assignments  []Assignment   `json:"assignments,omitempty"`
-	Status       string         `json:"status,omitempty"`
-	CreatedAt    string         `json:"created_at,omitempty"`
-	IncidentKey  string         `json:"incident_key,omitempty"`
-	HtmlURL      string         `json:"html_url,omitempty"`
+	ID               string                     `json:"id"`
+	Type             string                     `json:"type"`
+	Title            string                     `json:"title"`
+	Description      string                     `json:"description,omitempty"`
+	Service          ServiceReference           `json:"service"`
+	Assignments      []Assignment               `json:"assignments,omitempty"`
+	Status           string                     `json:"status,omitempty"`
+	Urgency          string                     `json:"urgency,omitempty"`
+	IncidentNumber   int                        `json:"incident_number,omitempty"`
+	EscalationPolicy *EscalationPolicyReference `json:"escalation_policy,omitempty"`
+	CreatedAt        string                     `json:"created_at,omitempty"`
+	IncidentKey      string                     `json:"incident_key,omitempty"`
+	HtmlURL          string                     `json:"html_url,omitempty"`
+
+	IncidentsResponders []IncidentsResponder `json:"incidents_responders,omitempty"`
+}
+
+// IncidentResponse wraps a single incident response
+type IncidentResponse struct {
+	Incident Incident `json:"incident"`
+}
+
+// MergeIncidentsRequest represents the request to merge incidents into a parent incident
+type MergeIncidentsRequest struct {
+	SourceIncidents []IncidentReference `json:"source_incidents"`
+}
+
+// RelatedIncident is an incident PagerDuty considers related to another, with the reasons why
+type RelatedIncident struct {
+	Incident      Incident                      `json:"incident"`
+	Relationships []RelatedIncidentRelationship `json:"relationships"`
+}
+
+// RelatedIncidentRelationship is a reason two incidents are related, such as being inferred by
+// machine learning or sharing a service dependency
+type RelatedIncidentRelationship struct {
+	Type     string                 `json:"type"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// RelatedIncidentsResponse wraps the related incidents response
+type RelatedIncidentsResponse struct {
+	RelatedIncidents []RelatedIncident `json:"related_incidents"`
+}
+
+// IncidentReference represents a reference to an incident
+type IncidentReference struct {
+	ID      string `json:"id"`
+	Type    string `json:"type,omitempty"`
+	Summary string `json:"summary,omitempty"`
+	HtmlURL string `json:"html_url,omitempty"`
+}
+
+// StatusUpdate is a message about an incident sent to its stakeholders
+type StatusUpdate struct {
+	ID          string         `json:"id"`
+	Message     string         `json:"message"`
+	Subject     string         `json:"subject,omitempty"`
+	HtmlMessage string         `json:"html_message,omitempty"`
+	Sender      *UserReference `json:"sender,omitempty"`
+	CreatedAt   string         `json:"created_at,omitempty"`
+}
+
+// StatusUpdatesResponse wraps the status updates list response
+type StatusUpdatesResponse struct {
+	ListResponse
+	StatusUpdates []StatusUpdate `json:"status_updates"`
+}
+
+// StatusUpdateResponse wraps a single status update response
+type StatusUpdateResponse struct {
+	StatusUpdate StatusUpdate `json:"status_update"`
+}
+
+// CreateStatusUpdateRequest represents the request to send a status update
+type CreateStatusUpdateRequest struct {
+	Message string `json:"message"`
+	Subject string `json:"subject,omitempty"`
+}
+
+// IncidentsResponse wraps the incidents list response
+type IncidentsResponse struct {
+	ListResponse
+	Incidents []Incident `json:"incidents"`
 }
 
 // CreateIncidentRequest represents the request to create an incident
@@ -188,3 +452,280 @@ type CreateIncidentRequest struct {
 type CreateIncidentResponse struct {
 	Incident Incident `json:"incident"`
 }
+
+// MaintenanceWindow is a period during which services do not create incidents
+type MaintenanceWindow struct {
+	ID             string             `json:"id,omitempty"`
+	Type           string             `json:"type"`
+	Summary        string             `json:"summary,omitempty"`
+	SequenceNumber int                `json:"sequence_number,omitempty"`
+	StartTime      string             `json:"start_time"`
+	EndTime        string             `json:"end_time"`
+	Description    string             `json:"description,omitempty"`
+	Services       []ServiceReference `json:"services"`
+	Teams          []TeamReference    `json:"teams,omitempty"`
+	CreatedBy      *UserReference     `json:"created_by,omitempty"`
+	HtmlURL        string             `json:"html_url,omitempty"`
+}
+
+// MaintenanceWindowsResponse wraps the maintenance windows list response
+type MaintenanceWindowsResponse struct {
+	ListResponse
+	MaintenanceWindows []MaintenanceWindow `json:"maintenance_windows"`
+}
+
+// MaintenanceWindowResponse wraps a single maintenance window response
+type MaintenanceWindowResponse struct {
+	MaintenanceWindow MaintenanceWindow `json:"maintenance_window"`
+}
+
+// CreateMaintenanceWindowRequest represents the request to create a maintenance window
+type CreateMaintenanceWindowRequest struct {
+	MaintenanceWindow MaintenanceWindow `json:"maintenance_window"`
+}
+
+// BusinessService is a service as seen by the business, such as "Checkout", supported by
+// technical services
+type BusinessService struct {
+	ID             string         `json:"id"`
+	Type           string         `json:"type,omitempty"`
+	Summary        string         `json:"summary,omitempty"`
+	Name           string         `json:"name"`
+	Description    string         `json:"description,omitempty"`
+	PointOfContact string         `json:"point_of_contact,omitempty"`
+	Team           *TeamReference `json:"team,omitempty"`
+	HtmlURL        string         `json:"html_url,omitempty"`
+}
+
+// BusinessServicesResponse wraps the business services list response
+type BusinessServicesResponse struct {
+	ListResponse
+	BusinessServices []BusinessService `json:"business_services"`
+}
+
+// BusinessServiceResponse wraps a single business service response
+type BusinessServiceResponse struct {
+	BusinessService BusinessService `json:"business_service"`
+}
+
+// Impact statuses of a business service
+const (
+	ImpactStatusImpacted    = "impacted"
+	ImpactStatusNotImpacted = "not_impacted"
+)
+
+// Impact is the current impact status of a business service
+type Impact struct {
+	ID               string                  `json:"id"`
+	Type             string                  `json:"type,omitempty"`
+	Name             string                  `json:"name"`
+	Status           string                  `json:"status"`
+	AdditionalFields *ImpactAdditionalFields `json:"additional_fields,omitempty"`
+}
+
+// ImpactAdditionalFields holds details of an impact, such as the priority of the incidents
+// causing it
+type ImpactAdditionalFields struct {
+	HighestImpactingPriority *PriorityReference `json:"highest_impacting_priority,omitempty"`
+}
+
+// PriorityReference represents a reference to an incident priority
+type PriorityReference struct {
+	ID      string `json:"id"`
+	Type    string `json:"type,omitempty"`
+	Summary string `json:"summary,omitempty"`
+	Order   int    `json:"order,omitempty"`
+}
+
+// ImpactsResponse wraps the business service impacts response
+type ImpactsResponse struct {
+	ListResponse
+	Services []Impact `json:"services"`
+}
+
+// StatusDashboard is a curated set of business services whose impact is shown together
+type StatusDashboard struct {
+	ID      string `json:"id"`
+	Type    string `json:"type,omitempty"`
+	URLSlug string `json:"url_slug"`
+	Name    string `json:"name"`
+}
+
+// StatusDashboardsResponse wraps the status dashboards list response
+type StatusDashboardsResponse struct {
+	StatusDashboards []StatusDashboard `json:"status_dashboards"`
+}
+
+// StatusDashboardResponse wraps a single status dashboard response
+type StatusDashboardResponse struct {
+	StatusDashboard StatusDashboard `json:"status_dashboard"`
+}
+
+// IncidentWorkflow is a sequence of actions, such as creating a conference bridge or adding
+// responders, that can be started on an incident
+type IncidentWorkflow struct {
+	ID          string         `json:"id"`
+	Type        string         `json:"type,omitempty"`
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	HtmlURL     string         `json:"html_url,omitempty"`
+	IsEnabled   bool           `json:"is_enabled"`
+	Team        *TeamReference `json:"team,omitempty"`
+}
+
+// IncidentWorkflowsResponse wraps the incident workflows list response
+type IncidentWorkflowsResponse struct {
+	ListResponse
+	IncidentWorkflows []IncidentWorkflow `json:"incident_workflows"`
+}
+
+// IncidentWorkflowResponse wraps a single incident workflow response
+type IncidentWorkflowResponse struct {
+	IncidentWorkflow IncidentWorkflow `json:"incident_workflow"`
+}
+
+// IncidentWorkflowReference represents a reference to an incident workflow
+type IncidentWorkflowReference struct {
+	ID      string `json:"id"`
+	Type    string `json:"type,omitempty"`
+	Summary string `json:"summary,omitempty"`
+	HtmlURL string `json:"html_url,omitempty"`
+}
+
+// IncidentWorkflowInstance is a single run of an incident workflow on an incident
+type IncidentWorkflowInstance struct {
+	ID       string            `json:"id,omitempty"`
+	Type     string            `json:"type,omitempty"`
+	Incident IncidentReference `json:"incident"`
+}
+
+// IncidentWorkflowInstanceRequest is the request body for starting an incident workflow
+type IncidentWorkflowInstanceRequest struct {
+	IncidentWorkflowInstance IncidentWorkflowInstance `json:"incident_workflow_instance"`
+}
+
+// IncidentWorkflowInstanceResponse wraps a single incident workflow instance response
+type IncidentWorkflowInstanceResponse struct {
+	IncidentWorkflowInstance IncidentWorkflowInstance `json:"incident_workflow_instance"`
+}
+
+// Responder request target types
+const (
+	ResponderRequestTargetUser             = "user_reference"
+	ResponderRequestTargetEscalationPolicy = "escalation_policy_reference"
+)
+
+// Incident responder states
+const (
+	ResponderStatePending  = "pending"
+	ResponderStateJoined   = "joined"
+	ResponderStateDeclined = "declined"
+)
+
+// IncidentsResponder is a user asked to help with an incident, and whether they accepted
+type IncidentsResponder struct {
+	State       string             `json:"state"`
+	User        UserReference      `json:"user"`
+	Incident    *IncidentReference `json:"incident,omitempty"`
+	UpdatedAt   string             `json:"updated_at,omitempty"`
+	Message     string             `json:"message,omitempty"`
+	Requester   *UserReference     `json:"requester,omitempty"`
+	RequestedAt string             `json:"requested_at,omitempty"`
+}
+
+// ResponderRequestTarget is a user or escalation policy asked to respond to an incident
+type ResponderRequestTarget struct {
+	ID                  string               `json:"id"`
+	Type                string               `json:"type"`
+	Summary             string               `json:"summary,omitempty"`
+	IncidentsResponders []IncidentsResponder `json:"incidents_responders,omitempty"`
+}
+
+// ResponderRequestTargetWrapper wraps a responder request target, as the API expects
+type ResponderRequestTargetWrapper struct {
+	ResponderRequestTarget ResponderRequestTarget `json:"responder_request_target"`
+}
+
+// CreateResponderRequest is the request body for asking additional responders to help with an
+// incident
+type CreateResponderRequest struct {
+	RequesterID             string                          `json:"requester_id"`
+	Message                 string                          `json:"message"`
+	ResponderRequestTargets []ResponderRequestTargetWrapper `json:"responder_request_targets"`
+}
+
+// ResponderRequest is a request for additional responders to help with an incident
+type ResponderRequest struct {
+	Incident                *IncidentReference              `json:"incident,omitempty"`
+	Requester               *UserReference                  `json:"requester,omitempty"`
+	RequestedAt             string                          `json:"requested_at,omitempty"`
+	Message                 string                          `json:"message"`
+	ResponderRequestTargets []ResponderRequestTargetWrapper `json:"responder_request_targets"`
+}
+
+// ResponderRequestResponse wraps a single responder request response
+type ResponderRequestResponse struct {
+	ResponderRequest ResponderRequest `json:"responder_request"`
+}
+
+// Alert statuses
+const (
+	AlertStatusTriggered = "triggered"
+	AlertStatusResolved  = "resolved"
+)
+
+// Alert is a single event grouped into an incident, such as a monitoring check failing
+type Alert struct {
+	ID         string             `json:"id"`
+	Type       string             `json:"type,omitempty"`
+	Summary    string             `json:"summary,omitempty"`
+	HtmlURL    string             `json:"html_url,omitempty"`
+	CreatedAt  string             `json:"created_at,omitempty"`
+	Status     string             `json:"status,omitempty"`
+	AlertKey   string             `json:"alert_key,omitempty"`
+	Severity   string             `json:"severity,omitempty"`
+	Suppressed bool               `json:"suppressed,omitempty"`
+	Service    *ServiceReference  `json:"service,omitempty"`
+	Incident   *IncidentReference `json:"incident,omitempty"`
+	Body       *AlertBody         `json:"body,omitempty"`
+}
+
+// AlertBody is the payload an alert was triggered with
+type AlertBody struct {
+	Type     string         `json:"type,omitempty"`
+	Contexts []AlertContext `json:"contexts,omitempty"`
+	// Details are the custom details of the alert, e.g. the custom_details of an Events API
+	// v2 event
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// AlertContext is a link or image attached to an alert
+type AlertContext struct {
+	Type string `json:"type"`
+	Href string `json:"href,omitempty"`
+	Src  string `json:"src,omitempty"`
+	Text string `json:"text,omitempty"`
+}
+
+// AlertsResponse wraps the alerts list response
+type AlertsResponse struct {
+	ListResponse
+	Alerts []Alert `json:"alerts"`
+}
+
+// AlertResponse wraps a single alert response
+type AlertResponse struct {
+	Alert Alert `json:"alert"`
+}
+
+// UpdateAlertRequest is the request body for resolving an alert or moving it to another incident
+type UpdateAlertRequest struct {
+	Alert AlertUpdate `json:"alert"`
+}
+
+// AlertUpdate holds the changes made to an alert
+type AlertUpdate struct {
+	Type     string             `json:"type"`
+	Status   string             `json:"status,omitempty"`
+	Incident *IncidentReference `json:"incident,omitempty"`
+}
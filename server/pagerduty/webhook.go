@@ -0,0 +1,73 @@
+package pagerduty
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// WebhookSignatureHeader is the header carrying the signatures of a V3 webhook payload
+const WebhookSignatureHeader = "X-PagerDuty-Signature"
+
+// WebhookPayload is the body of a V3 webhook delivery
+type WebhookPayload struct {
+	Event WebhookEvent `json:"event"`
+}
+
+// WebhookEvent describes a single event delivered by a V3 webhook subscription
+type WebhookEvent struct {
+	ID           string          `json:"id"`
+	EventType    string          `json:"event_type"`
+	ResourceType string          `json:"resource_type"`
+	OccurredAt   time.Time       `json:"occurred_at"`
+	Agent        *UserReference  `json:"agent,omitempty"`
+	Data         json.RawMessage `json:"data"`
+}
+
+// VerifyWebhookSignature checks the signature header of a webhook delivery against the
+// subscription's secret. PagerDuty may send several comma-separated signatures while a
+// secret is being rotated; the payload is valid if any of them matches.
+func VerifyWebhookSignature(body []byte, signatureHeader, secret string) bool {
+	if secret == "" || signatureHeader == "" {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	expected := "v1=" + hex.EncodeToString(mac.Sum(nil))
+
+	for _, signature := range strings.Split(signatureHeader, ",") {
+		if hmac.Equal([]byte(strings.TrimSpace(signature)), []byte(expected)) {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookStatusUpdate is the data of an incident.status_update_published event
+type WebhookStatusUpdate struct {
+	StatusUpdate
+	Incident IncidentReference `json:"incident"`
+}
+
+// WebhookIncidentWorkflowInstance is the data of an incident.workflow.started or
+// incident.workflow.completed event
+type WebhookIncidentWorkflowInstance struct {
+	ID               string                    `json:"id"`
+	Type             string                    `json:"type"`
+	Incident         IncidentReference         `json:"incident"`
+	IncidentWorkflow IncidentWorkflowReference `json:"incident_workflow"`
+}
+
+// WebhookIncidentResponder is the data of an incident.responder.added or
+// incident.responder.replied event
+type WebhookIncidentResponder struct {
+	Incident         IncidentReference          `json:"incident"`
+	User             *UserReference             `json:"user,omitempty"`
+	EscalationPolicy *EscalationPolicyReference `json:"escalation_policy,omitempty"`
+	Message          string                     `json:"message,omitempty"`
+	State            string                     `json:"state,omitempty"`
+}
@@ -0,0 +1,64 @@
+package pagerduty
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	body := []byte(`{"event": {"event_type": "incident.triggered"}}`)
+
+	sign := func(secret string) string {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		return "v1=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	tests := []struct {
+		name   string
+		header string
+		secret string
+		want   bool
+	}{
+		{
+			name:   "valid signature",
+			header: sign("secret"),
+			secret: "secret",
+			want:   true,
+		},
+		{
+			name:   "one of several signatures matches",
+			header: sign("old-secret") + ", " + sign("secret"),
+			secret: "secret",
+			want:   true,
+		},
+		{
+			name:   "wrong secret",
+			header: sign("other"),
+			secret: "secret",
+			want:   false,
+		},
+		{
+			name:   "missing header",
+			header: "",
+			secret: "secret",
+			want:   false,
+		},
+		{
+			name:   "no secret configured",
+			header: sign(""),
+			secret: "",
+			want:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, VerifyWebhookSignature(body, tt.header, tt.secret))
+		})
+	}
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/store/kvstore"
+)
+
+// Fields of an incident a paging policy can require.
+const (
+	pagingFieldDescription = "description"
+	pagingFieldAssignees   = "assignees"
+	pagingFieldUrgency     = "urgency"
+)
+
+// Rules applied to paging during the quiet hours of a service.
+const (
+	quietHoursBlock      = "block"
+	quietHoursConfirm    = "confirm"
+	quietHoursLowUrgency = "low_urgency"
+)
+
+const (
+	urgencyHigh = "high"
+	urgencyLow  = "low"
+)
+
+// SavePagingPolicyRequest represents the request body for setting the paging policy of a service
+type SavePagingPolicyRequest struct {
+	Allowlist                       string              `json:"allowlist,omitempty"`
+	RequiredFields                  []string            `json:"required_fields,omitempty"`
+	RequireConfirmation             bool                `json:"require_confirmation,omitempty"`
+	RequireHighUrgencyJustification bool                `json:"require_high_urgency_justification,omitempty"`
+	QuietHours                      *kvstore.QuietHours `json:"quiet_hours,omitempty"`
+}
+
+// getPagingPolicy returns the paging policy of a service of an account. Services without one get
+// an empty policy, which restricts nothing.
+func (p *Plugin) getPagingPolicy(account, serviceID string) (*kvstore.PagingPolicy, *APIError) {
+	policy, err := p.kvstore.GetPagingPolicy(account, serviceID)
+	if err != nil {
+		p.client.Log.Error("Failed to get paging policy", "error", err.Error(), "service_id", serviceID)
+		return nil, &APIError{
+			ID:         "api.pagerduty.paging_policy.get.error",
+			Message:    "Failed to retrieve the paging policy",
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+	if policy == nil {
+		policy = &kvstore.PagingPolicy{ServiceID: serviceID, Account: account}
+	}
+	return policy, nil
+}
+
+// getPagingPolicies returns the paging policies of all services for a plugin admin.
+func (p *Plugin) getPagingPolicies(userID string) ([]*kvstore.PagingPolicy, *APIError) {
+	if apiErr := p.checkPermission(userID, permissionAdmin); apiErr != nil {
+		return nil, apiErr
+	}
+
+	policies, err := p.kvstore.ListPagingPolicies()
+	if err != nil {
+		p.client.Log.Error("Failed to list paging policies", "error", err.Error())
+		return nil, &APIError{
+			ID:         "api.pagerduty.paging_policy.list.error",
+			Message:    "Failed to retrieve paging policies",
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+
+	slices.SortFunc(policies, func(a, b *kvstore.PagingPolicy) int {
+		return strings.Compare(strings.ToLower(a.ServiceName), strings.ToLower(b.ServiceName))
+	})
+	return policies, nil
+}
+
+// savePagingPolicy validates and stores the paging policy of a service of an account on behalf
+// of a plugin admin, replacing any previous one.
+func (p *Plugin) savePagingPolicy(userID, account, serviceID string, req *SavePagingPolicyRequest) (_ *kvstore.PagingPolicy, apiErr *APIError) {
+	defer func() { p.recordAudit(userID, account, auditActionSavePagingPolicy, serviceID, apiErr) }()
+
+	if apiErr := p.checkPermission(userID, permissionAdmin); apiErr != nil {
+		return nil, apiErr
+	}
+
+	policy := &kvstore.PagingPolicy{
+		ServiceID:                       serviceID,
+		Account:                         account,
+		Allowlist:                       strings.TrimSpace(req.Allowlist),
+		RequiredFields:                  req.RequiredFields,
+		RequireConfirmation:             req.RequireConfirmation,
+		RequireHighUrgencyJustification: req.RequireHighUrgencyJustification,
+		QuietHours:                      req.QuietHours,
+		UpdatedBy:                       userID,
+		UpdateAt:                        model.GetMillis(),
+	}
+	if apiErr := validatePagingPolicy(policy); apiErr != nil {
+		return nil, apiErr
+	}
+
+	client, apiErr := p.accountClient(account)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	service, err := client.GetService(serviceID)
+	if err != nil {
+		p.client.Log.Warn("Failed to get service from PagerDuty", "error", err.Error(), "service_id", serviceID)
+		return nil, &APIError{
+			ID:         "api.pagerduty.paging_policy.service.not_found",
+			Message:    fmt.Sprintf("Service %s was not found", serviceID),
+			StatusCode: http.StatusNotFound,
+		}
+	}
+	policy.ServiceName = service.Service.Name
+
+	if err := p.kvstore.SavePagingPolicy(policy); err != nil {
+		p.client.Log.Error("Failed to save paging policy", "error", err.Error(), "service_id", serviceID)
+		return nil, &APIError{
+			ID:         "api.pagerduty.paging_policy.save.error",
+			Message:    "Failed to save the paging policy",
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+	return policy, nil
+}
+
+// deletePagingPolicy removes the paging policy of a service of an account on behalf of a plugin
+// admin.
+func (p *Plugin) deletePagingPolicy(userID, account, serviceID string) (apiErr *APIError) {
+	defer func() { p.recordAudit(userID, account, auditActionDeletePagingPolicy, serviceID, apiErr) }()
+
+	if apiErr := p.checkPermission(userID, permissionAdmin); apiErr != nil {
+		return apiErr
+	}
+
+	if err := p.kvstore.DeletePagingPolicy(account, serviceID); err != nil {
+		p.client.Log.Error("Failed to delete paging policy", "error", err.Error(), "service_id", serviceID)
+		return &APIError{
+			ID:         "api.pagerduty.paging_policy.delete.error",
+			Message:    "Failed to delete the paging policy",
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+	return nil
+}
+
+// validatePagingPolicy checks the allowlist, required fields and quiet hours of a paging policy.
+func validatePagingPolicy(policy *kvstore.PagingPolicy) *APIError {
+	invalid := func(id, message string) *APIError {
+		return &APIError{ID: "api.pagerduty.paging_policy." + id, Message: message, StatusCode: http.StatusBadRequest}
+	}
+
+	if _, err := parseAllowlist(policy.Allowlist); err != nil {
+		return invalid("allowlist.invalid", "Invalid allowlist: "+err.Error())
+	}
+
+	for _, field := range policy.RequiredFields {
+		if !slices.Contains([]string{pagingFieldDescription, pagingFieldAssignees, pagingFieldUrgency}, field) {
+			return invalid("required_fields.invalid", fmt.Sprintf("Invalid required field %s. Use description, assignees or urgency", field))
+		}
+	}
+
+	if quietHours := policy.QuietHours; quietHours != nil {
+		if _, err := parseClock(quietHours.Start); err != nil {
+			return invalid("quiet_hours.invalid", "Invalid quiet hours start: "+err.Error())
+		}
+		if _, err := parseClock(quietHours.End); err != nil {
+			return invalid("quiet_hours.invalid", "Invalid quiet hours end: "+err.Error())
+		}
+		if _, err := time.LoadLocation(quietHours.TimeZone); err != nil {
+			return invalid("quiet_hours.invalid", fmt.Sprintf("Invalid quiet hours time zone %s", quietHours.TimeZone))
+		}
+		if !slices.Contains([]string{quietHoursBlock, quietHoursConfirm, quietHoursLowUrgency}, quietHours.Rule) {
+			return invalid("quiet_hours.invalid", "Invalid quiet hours rule. Use block, confirm or low_urgency")
+		}
+	}
+
+	return nil
+}
+
+// checkPagingPolicy enforces the paging policy of the service of a new incident, downgrading
+// its urgency during quiet hours if the policy says so.
+func (p *Plugin) checkPagingPolicy(userID, account string, req *CreateIncidentRequest, now time.Time) *APIError {
+	policy, apiErr := p.getPagingPolicy(account, req.ServiceID)
+	if apiErr != nil {
+		return apiErr
+	}
+
+	if policy.Allowlist != "" && !p.client.User.HasPermissionTo(userID, model.PermissionManageSystem) {
+		entries, _ := parseAllowlist(policy.Allowlist)
+		if !p.matchesAllowlist(userID, entries) {
+			return &APIError{
+				ID:         "api.pagerduty.paging_policy.permission",
+				Message:    fmt.Sprintf("You are not allowed to page %s", pagingPolicyServiceName(policy)),
+				StatusCode: http.StatusForbidden,
+			}
+		}
+	}
+
+	return applyPagingPolicy(policy, req, now)
+}
+
+// applyPagingPolicy checks a new incident against the required fields, quiet hours,
+// justification and confirmation rules of a paging policy. Confirmation is checked last, so
+// that it is only asked for once everything else is in order.
+func applyPagingPolicy(policy *kvstore.PagingPolicy, req *CreateIncidentRequest, now time.Time) *APIError {
+	service := pagingPolicyServiceName(policy)
+
+	for _, field := range policy.RequiredFields {
+		missing := (field == pagingFieldDescription && strings.TrimSpace(req.Description) == "") ||
+			(field == pagingFieldAssignees && len(req.AssigneeIDs) == 0) ||
+			(field == pagingFieldUrgency && req.Urgency == "")
+		if missing {
+			return &APIError{
+				ID:         "api.pagerduty.paging_policy.field.missing",
+				Message:    fmt.Sprintf("Paging %s requires the %s field", service, field),
+				StatusCode: http.StatusBadRequest,
+			}
+		}
+	}
+
+	confirm := policy.RequireConfirmation
+	if quietHours := policy.QuietHours; quietHours != nil && inQuietHours(quietHours, now) {
+		switch quietHours.Rule {
+		case quietHoursBlock:
+			return &APIError{
+				ID:         "api.pagerduty.paging_policy.quiet_hours",
+				Message:    fmt.Sprintf("Paging %s is not allowed during its quiet hours, %s", service, formatQuietHours(quietHours)),
+				StatusCode: http.StatusForbidden,
+			}
+		case quietHoursConfirm:
+			confirm = true
+		case quietHoursLowUrgency:
+			req.Urgency = urgencyLow
+		}
+	}
+
+	// Without an urgency, the service's urgency rule applies, which is usually high.
+	if policy.RequireHighUrgencyJustification && req.Urgency != urgencyLow && strings.TrimSpace(req.Justification) == "" {
+		return &APIError{
+			ID:         "api.pagerduty.paging_policy.justification.missing",
+			Message:    fmt.Sprintf("Paging %s at high urgency requires a justification", service),
+			StatusCode: http.StatusBadRequest,
+		}
+	}
+
+	if confirm && !req.Confirmed {
+		return &APIError{
+			ID:         "api.pagerduty.paging_policy.confirmation.required",
+			Message:    fmt.Sprintf("Paging %s requires confirmation. Resend the request with confirmed set to true", service),
+			StatusCode: http.StatusPreconditionRequired,
+		}
+	}
+
+	return nil
+}
+
+// inQuietHours reports whether a time falls within quiet hours, which may span midnight.
+func inQuietHours(quietHours *kvstore.QuietHours, now time.Time) bool {
+	start, err := parseClock(quietHours.Start)
+	if err != nil {
+		return false
+	}
+	end, err := parseClock(quietHours.End)
+	if err != nil {
+		return false
+	}
+	location, err := time.LoadLocation(quietHours.TimeZone)
+	if err != nil {
+		return false
+	}
+
+	local := now.In(location)
+	minute := local.Hour()*60 + local.Minute()
+	if start <= end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+// parseClock parses a time of day such as 22:00 into minutes since midnight.
+func parseClock(value string) (int, error) {
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a time of day such as 22:00", value)
+	}
+	return clock.Hour()*60 + clock.Minute(), nil
+}
+
+func formatQuietHours(quietHours *kvstore.QuietHours) string {
+	timeZone := quietHours.TimeZone
+	if timeZone == "" {
+		timeZone = "UTC"
+	}
+	return fmt.Sprintf("%s to %s %s", quietHours.Start, quietHours.End, timeZone)
+}
+
+func pagingPolicyServiceName(policy *kvstore.PagingPolicy) string {
+	if policy.ServiceName != "" {
+		return policy.ServiceName
+	}
+	return policy.ServiceID
+}
+
+// formatPagingPolicy describes what it takes to page a service.
+func formatPagingPolicy(policy *kvstore.PagingPolicy) string {
+	var rules []string
+	if policy.Allowlist != "" {
+		rules = append(rules, fmt.Sprintf("Only `%s` can page", strings.Join(strings.Fields(strings.ReplaceAll(policy.Allowlist, ",", " ")), ", ")))
+	}
+	if len(policy.RequiredFields) > 0 {
+		rules = append(rules, "Required fields: "+strings.Join(policy.RequiredFields, ", "))
+	}
+	if policy.RequireConfirmation {
+		rules = append(rules, "Pages must be confirmed")
+	}
+	if policy.RequireHighUrgencyJustification {
+		rules = append(rules, "High urgency pages need a justification")
+	}
+	if quietHours := policy.QuietHours; quietHours != nil {
+		action := map[string]string{
+			quietHoursBlock:      "paging is blocked",
+			quietHoursConfirm:    "pages must be confirmed",
+			quietHoursLowUrgency: "pages are low urgency",
+		}[quietHours.Rule]
+		rules = append(rules, fmt.Sprintf("Quiet hours %s: %s", formatQuietHours(quietHours), action))
+	}
+
+	if len(rules) == 0 {
+		return fmt.Sprintf("%s has no paging policy.", pagingPolicyServiceName(policy))
+	}
+	return fmt.Sprintf("**%s** (`%s`)\n* %s", pagingPolicyServiceName(policy), policy.ServiceID, strings.Join(rules, "\n* "))
+}
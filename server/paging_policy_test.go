@@ -0,0 +1,184 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/store/kvstore"
+)
+
+func TestValidatePagingPolicy(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     kvstore.PagingPolicy
+		expectedID string
+	}{
+		{
+			name: "valid",
+			policy: kvstore.PagingPolicy{
+				Allowlist:      "team:team1, role:system_user",
+				RequiredFields: []string{"description", "urgency"},
+				QuietHours:     &kvstore.QuietHours{Start: "22:00", End: "07:00", TimeZone: "Europe/Berlin", Rule: "confirm"},
+			},
+		},
+		{
+			name:       "invalid allowlist",
+			policy:     kvstore.PagingPolicy{Allowlist: "user:alice"},
+			expectedID: "api.pagerduty.paging_policy.allowlist.invalid",
+		},
+		{
+			name:       "unknown required field",
+			policy:     kvstore.PagingPolicy{RequiredFields: []string{"priority"}},
+			expectedID: "api.pagerduty.paging_policy.required_fields.invalid",
+		},
+		{
+			name:       "invalid quiet hours time",
+			policy:     kvstore.PagingPolicy{QuietHours: &kvstore.QuietHours{Start: "10pm", End: "07:00", Rule: "block"}},
+			expectedID: "api.pagerduty.paging_policy.quiet_hours.invalid",
+		},
+		{
+			name:       "invalid quiet hours time zone",
+			policy:     kvstore.PagingPolicy{QuietHours: &kvstore.QuietHours{Start: "22:00", End: "07:00", TimeZone: "Mars/Olympus", Rule: "block"}},
+			expectedID: "api.pagerduty.paging_policy.quiet_hours.invalid",
+		},
+		{
+			name:       "invalid quiet hours rule",
+			policy:     kvstore.PagingPolicy{QuietHours: &kvstore.QuietHours{Start: "22:00", End: "07:00", Rule: "silence"}},
+			expectedID: "api.pagerduty.paging_policy.quiet_hours.invalid",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiErr := validatePagingPolicy(&tt.policy)
+			if tt.expectedID == "" {
+				assert.Nil(t, apiErr)
+				return
+			}
+			require.NotNil(t, apiErr)
+			assert.Equal(t, tt.expectedID, apiErr.ID)
+		})
+	}
+}
+
+func TestApplyPagingPolicy(t *testing.T) {
+	night := time.Date(2024, 1, 15, 2, 30, 0, 0, time.UTC)
+	day := time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC)
+	quietHours := func(rule string) *kvstore.QuietHours {
+		return &kvstore.QuietHours{Start: "22:00", End: "07:00", Rule: rule}
+	}
+
+	tests := []struct {
+		name            string
+		policy          kvstore.PagingPolicy
+		req             CreateIncidentRequest
+		now             time.Time
+		expectedID      string
+		expectedStatus  int
+		expectedUrgency string
+	}{
+		{
+			name:   "no restrictions",
+			policy: kvstore.PagingPolicy{},
+			req:    CreateIncidentRequest{Title: "Database down"},
+			now:    night,
+		},
+		{
+			name:           "missing required description",
+			policy:         kvstore.PagingPolicy{RequiredFields: []string{"description"}},
+			req:            CreateIncidentRequest{Title: "Database down", Description: "  "},
+			now:            day,
+			expectedID:     "api.pagerduty.paging_policy.field.missing",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "confirmation required",
+			policy:         kvstore.PagingPolicy{RequireConfirmation: true},
+			req:            CreateIncidentRequest{Title: "Database down"},
+			now:            day,
+			expectedID:     "api.pagerduty.paging_policy.confirmation.required",
+			expectedStatus: http.StatusPreconditionRequired,
+		},
+		{
+			name:   "confirmed",
+			policy: kvstore.PagingPolicy{RequireConfirmation: true},
+			req:    CreateIncidentRequest{Title: "Database down", Confirmed: true},
+			now:    day,
+		},
+		{
+			name:           "high urgency without justification",
+			policy:         kvstore.PagingPolicy{RequireHighUrgencyJustification: true},
+			req:            CreateIncidentRequest{Title: "Database down", Confirmed: true},
+			now:            day,
+			expectedID:     "api.pagerduty.paging_policy.justification.missing",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:            "low urgency needs no justification",
+			policy:          kvstore.PagingPolicy{RequireHighUrgencyJustification: true},
+			req:             CreateIncidentRequest{Title: "Database down", Urgency: "low"},
+			now:             day,
+			expectedUrgency: "low",
+		},
+		{
+			name:           "blocked during quiet hours",
+			policy:         kvstore.PagingPolicy{QuietHours: quietHours("block")},
+			req:            CreateIncidentRequest{Title: "Database down", Confirmed: true},
+			now:            night,
+			expectedID:     "api.pagerduty.paging_policy.quiet_hours",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:   "allowed outside quiet hours",
+			policy: kvstore.PagingPolicy{QuietHours: quietHours("block")},
+			req:    CreateIncidentRequest{Title: "Database down"},
+			now:    day,
+		},
+		{
+			name:           "confirmation required during quiet hours",
+			policy:         kvstore.PagingPolicy{QuietHours: quietHours("confirm")},
+			req:            CreateIncidentRequest{Title: "Database down"},
+			now:            night,
+			expectedID:     "api.pagerduty.paging_policy.confirmation.required",
+			expectedStatus: http.StatusPreconditionRequired,
+		},
+		{
+			name:            "downgraded to low urgency during quiet hours",
+			policy:          kvstore.PagingPolicy{QuietHours: quietHours("low_urgency"), RequireHighUrgencyJustification: true},
+			req:             CreateIncidentRequest{Title: "Database down", Urgency: "high"},
+			now:             night,
+			expectedUrgency: "low",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			apiErr := applyPagingPolicy(&tt.policy, &req, tt.now)
+			if tt.expectedID == "" {
+				assert.Nil(t, apiErr)
+				assert.Equal(t, tt.expectedUrgency, req.Urgency)
+				return
+			}
+			require.NotNil(t, apiErr)
+			assert.Equal(t, tt.expectedID, apiErr.ID)
+			assert.Equal(t, tt.expectedStatus, apiErr.StatusCode)
+		})
+	}
+}
+
+func TestInQuietHours(t *testing.T) {
+	overnight := &kvstore.QuietHours{Start: "22:00", End: "07:00", TimeZone: "America/New_York"}
+	// 03:00 UTC is 22:00 in New York in winter.
+	assert.True(t, inQuietHours(overnight, time.Date(2024, 1, 15, 3, 0, 0, 0, time.UTC)))
+	assert.False(t, inQuietHours(overnight, time.Date(2024, 1, 15, 2, 59, 0, 0, time.UTC)))
+	assert.False(t, inQuietHours(overnight, time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)))
+
+	lunch := &kvstore.QuietHours{Start: "12:00", End: "13:00"}
+	assert.True(t, inQuietHours(lunch, time.Date(2024, 1, 15, 12, 30, 0, 0, time.UTC)))
+	assert.False(t, inQuietHours(lunch, time.Date(2024, 1, 15, 13, 0, 0, 0, time.UTC)))
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+// Operations that are allowed to the users of an allowlist. System admins are allowed all of
+// them.
+const (
+	permissionRead    = "read"
+	permissionPage    = "page"
+	permissionRespond = "respond"
+	permissionAdmin   = "admin"
+)
+
+// Kinds of allowlist entries, written as kind:value.
+const (
+	allowlistRole    = "role"
+	allowlistTeam    = "team"
+	allowlistGroup   = "group"
+	allowlistChannel = "channel"
+)
+
+// defaultAllowlist allows an operation to every member of the system, but not to guests, when
+// its allowlist is left empty. The admin operation has no default, leaving it to system admins.
+var defaultAllowlist = []allowlistEntry{{Kind: allowlistRole, Value: model.SystemUserRoleId}}
+
+// permissionDescriptions complete "You do not have permission to ..." for each operation.
+var permissionDescriptions = map[string]string{
+	permissionRead:    "view PagerDuty data",
+	permissionPage:    "page in PagerDuty",
+	permissionRespond: "update PagerDuty incidents or manage PagerDuty posts in channels",
+	permissionAdmin:   "administer the PagerDuty plugin",
+}
+
+// allowlistEntry allows an operation to users with a role, or to the members of a team, group
+// or channel.
+type allowlistEntry struct {
+	Kind  string
+	Value string
+}
+
+// parseAllowlist parses a comma or newline separated list of kind:value entries, such as
+// role:system_user, team:<team-id>, group:<group-id-or-name> or channel:<channel-id>.
+func parseAllowlist(value string) ([]allowlistEntry, error) {
+	var entries []allowlistEntry
+	var invalid []string
+	for _, field := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == '\n' }) {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		kind, value, ok := strings.Cut(field, ":")
+		kind, value = strings.ToLower(strings.TrimSpace(kind)), strings.TrimSpace(value)
+		if !ok || value == "" || !slices.Contains([]string{allowlistRole, allowlistTeam, allowlistGroup, allowlistChannel}, kind) {
+			invalid = append(invalid, field)
+			continue
+		}
+		entries = append(entries, allowlistEntry{Kind: kind, Value: value})
+	}
+
+	if len(invalid) > 0 {
+		return entries, fmt.Errorf("invalid allowlist entries %s, expected role:, team:, group: or channel: followed by a name or ID", strings.Join(invalid, ", "))
+	}
+	return entries, nil
+}
+
+// hasPermission reports whether the user may perform an operation: system admins always may,
+// other users if they match an entry of the operation's allowlist.
+func (p *Plugin) hasPermission(userID, permission string) bool {
+	if p.client.User.HasPermissionTo(userID, model.PermissionManageSystem) {
+		return true
+	}
+
+	return p.matchesAllowlist(userID, p.getConfiguration().allowlist(permission))
+}
+
+// matchesAllowlist reports whether the user matches any entry of an allowlist.
+func (p *Plugin) matchesAllowlist(userID string, entries []allowlistEntry) bool {
+	if len(entries) == 0 {
+		return false
+	}
+
+	user, err := p.client.User.Get(userID)
+	if err != nil {
+		p.client.Log.Warn("Failed to get user for permission check", "error", err.Error(), "user_id", userID)
+		return false
+	}
+
+	var groups []*model.Group
+	groupsLoaded := false
+	for _, entry := range entries {
+		switch entry.Kind {
+		case allowlistRole:
+			if slices.Contains(strings.Fields(user.Roles), entry.Value) {
+				return true
+			}
+		case allowlistTeam:
+			if member, err := p.client.Team.GetMember(entry.Value, userID); err == nil && member.DeleteAt == 0 {
+				return true
+			}
+		case allowlistChannel:
+			if _, err := p.client.Channel.GetMember(entry.Value, userID); err == nil {
+				return true
+			}
+		case allowlistGroup:
+			if !groupsLoaded {
+				if groups, err = p.client.Group.ListForUser(userID); err != nil {
+					p.client.Log.Warn("Failed to get groups for permission check", "error", err.Error(), "user_id", userID)
+				}
+				groupsLoaded = true
+			}
+			if slices.ContainsFunc(groups, func(group *model.Group) bool {
+				return group.Id == entry.Value || (group.Name != nil && *group.Name == entry.Value)
+			}) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkPermission returns a 403 error if the user may not perform an operation.
+func (p *Plugin) checkPermission(userID, permission string) *APIError {
+	if p.hasPermission(userID, permission) {
+		return nil
+	}
+
+	return &APIError{
+		ID:         "api.pagerduty.permission." + permission,
+		Message:    fmt.Sprintf("You do not have permission to %s", permissionDescriptions[permission]),
+		StatusCode: http.StatusForbidden,
+	}
+}
+
+// requirePermission wraps a handler so that it is only served to users who may perform the
+// operation. A denied write never reaches the handler that would record it, so the denial is
+// recorded in the audit trail here.
+func (p *Plugin) requirePermission(permission string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := r.Header.Get("Mattermost-User-ID")
+		if apiErr := p.checkPermission(userID, permission); apiErr != nil {
+			if r.Method != http.MethodGet {
+				p.recordAudit(userID, requestAccount(r), requestAuditAction(r), mux.Vars(r)["id"], apiErr)
+			}
+			p.handleError(w, r, apiErr)
+			return
+		}
+
+		handler(w, r)
+	}
+}
+
+// commandPermission returns the operation a slash command performs, given its command and
+// subcommand, or an empty string for commands anyone may run, such as help.
+func commandPermission(fields []string) string {
+	if len(fields) == 0 {
+		return ""
+	}
+
+	subcommand := ""
+	if len(fields) > 1 {
+		subcommand = fields[1]
+	}
+
+	switch fields[0] {
+	case "groupsync", "alertrule":
+		return permissionAdmin
+	case "status":
+		return permissionRead
+	case "roster", "channel", "team", "impact":
+		// Changing what the plugin posts in channels and how teams are scoped is a write, while
+		// listing and refreshing it only reads.
+		switch subcommand {
+		case "add", "remove", "post", "header", "pin", "set", "clear", "schedule":
+			return permissionRespond
+		}
+		return permissionRead
+	case "incident":
+		switch subcommand {
+		case "responders":
+			return permissionPage
+		case "merge", "alert", "update", "workflow", "subscribe", "unsubscribe":
+			return permissionRespond
+		}
+		return permissionRead
+	case "maintenance":
+		if subcommand == "start" || subcommand == "end" {
+			return permissionRespond
+		}
+		return permissionRead
+	case "change":
+		if subcommand == "list" {
+			return permissionRead
+		}
+		return permissionRespond
+	case "pagingpolicy":
+		if subcommand == "show" {
+			return permissionRead
+		}
+		return permissionAdmin
+	}
+	return ""
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAllowlist(t *testing.T) {
+	t.Run("valid entries", func(t *testing.T) {
+		entries, err := parseAllowlist("role:system_user, Team:team1\ngroup:sre,\n channel: channel1 ")
+		require.NoError(t, err)
+		assert.Equal(t, []allowlistEntry{
+			{Kind: allowlistRole, Value: "system_user"},
+			{Kind: allowlistTeam, Value: "team1"},
+			{Kind: allowlistGroup, Value: "sre"},
+			{Kind: allowlistChannel, Value: "channel1"},
+		}, entries)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		entries, err := parseAllowlist("")
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("invalid entries are skipped", func(t *testing.T) {
+		entries, err := parseAllowlist("role:system_user, user:alice, team:")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "user:alice, team:")
+		assert.Equal(t, []allowlistEntry{{Kind: allowlistRole, Value: "system_user"}}, entries)
+	})
+}
+
+func TestConfigurationAllowlist(t *testing.T) {
+	config := &configuration{allowlists: map[string][]allowlistEntry{
+		permissionPage: {{Kind: allowlistTeam, Value: "team1"}},
+	}}
+
+	assert.Equal(t, []allowlistEntry{{Kind: allowlistTeam, Value: "team1"}}, config.allowlist(permissionPage))
+	assert.Equal(t, defaultAllowlist, config.allowlist(permissionRead))
+	assert.Equal(t, defaultAllowlist, config.allowlist(permissionRespond))
+	assert.Empty(t, config.allowlist(permissionAdmin))
+}
+
+func TestCommandPermission(t *testing.T) {
+	tests := []struct {
+		command  string
+		expected string
+	}{
+		{"help", ""},
+		{"", ""},
+		{"groupsync list", permissionAdmin},
+		{"alertrule add PSVC123 ^CRITICAL", permissionAdmin},
+		{"roster list", permissionRead},
+		{"roster add S123 UTC 0 9 * * 1", permissionRespond},
+		{"roster remove R1", permissionRespond},
+		{"roster post R1", permissionRespond},
+		{"channel refresh", permissionRead},
+		{"channel header S123", permissionRespond},
+		{"channel remove", permissionRespond},
+		{"team show", permissionRead},
+		{"team set PT123", permissionRespond},
+		{"team clear", permissionRespond},
+		{"impact list", permissionRead},
+		{"impact services", permissionRead},
+		{"impact post service:PBS1", permissionRespond},
+		{"impact schedule service:PBS1 UTC 0 9 * * 1", permissionRespond},
+		{"impact remove I1", permissionRespond},
+		{"status on", permissionRead},
+		{"incident list", permissionRead},
+		{"incident subscribe P123", permissionRespond},
+		{"incident unsubscribe P123", permissionRespond},
+		{"incident responders P123", permissionPage},
+		{"incident alert resolve P123 A1", permissionRespond},
+		{"incident merge", permissionRespond},
+		{"maintenance list", permissionRead},
+		{"maintenance start PSVC123 2h", permissionRespond},
+		{"change list", permissionRead},
+		{"change send PSVC123 Deployed", permissionRespond},
+		{"pagingpolicy show PSVC123", permissionRead},
+		{"pagingpolicy remove PSVC123", permissionAdmin},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			assert.Equal(t, tt.expected, commandPermission(strings.Fields(tt.command)))
+		})
+	}
+}
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"strings"
 	"sync"
 
+	"github.com/mattermost/mattermost/server/public/model"
 	"github.com/mattermost/mattermost/server/public/plugin"
 	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/mattermost/mattermost/server/public/pluginapi/cluster"
 	"github.com/pkg/errors"
 
 	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
@@ -31,6 +34,36 @@ type Plugin struct {
 	// createPagerDutyClient is a function to create PagerDuty clients.
 	// This can be overridden in tests to inject mock clients.
 	createPagerDutyClient func(apiToken, baseURL string) *pagerduty.Client
+
+	// createEventsClient is a function to create PagerDuty Events API clients.
+	// This can be overridden in tests to inject mock clients.
+	createEventsClient func(baseURL string) *pagerduty.EventsClient
+
+	// botUserID is the ID of the bot user that posts on behalf of the plugin.
+	botUserID string
+
+	// backgroundJob runs periodic work such as handoff reminders once per cluster.
+	backgroundJob *cluster.Job
+
+	// encryptionLock serializes changes of the key secrets are encrypted with at rest.
+	encryptionLock sync.Mutex
+
+	// currentEncryptionKey is the key secrets are encrypted with at rest.
+	currentEncryptionKey []byte
+
+	// routingKeyLock synchronizes access to the routing keys.
+	routingKeyLock sync.Mutex
+
+	// routingKeys caches the routing keys of services by account and service ID, for the rules
+	// matching posted messages.
+	routingKeys map[string]*cachedRoutingKey
+
+	// pagerDutyUserLock synchronizes access to the PagerDuty users.
+	pagerDutyUserLock sync.Mutex
+
+	// pagerDutyUsers caches the PagerDuty users of Mattermost users by account and email, for
+	// the audit trail.
+	pagerDutyUsers map[string]*cachedPagerDutyUser
 }
 
 // OnActivate is invoked when the plugin is activated. If an error is returned, the plugin will be deactivated.
@@ -40,6 +73,7 @@ func (p *Plugin) OnActivate() error {
 
 	// Initialize the PagerDuty client factory with the default implementation
 	p.createPagerDutyClient = pagerduty.NewClient
+	p.createEventsClient = pagerduty.NewEventsClient
 
 	p.kvstore = kvstore.NewKVStore(p.client)
 
@@ -51,15 +85,40 @@ func (p *Plugin) OnActivate() error {
 	siteURL := *config.ServiceSettings.SiteURL
 	p.client.Log.Debug("Site URL configured", "url", siteURL)
 
-	// Slash commands and bot removed - sidebar only
+	if err := p.setupEncryption(p.getConfiguration()); err != nil {
+		p.client.Log.Error("Failed to set up encryption of stored secrets", "error", err)
+		return errors.Wrap(err, "failed to set up encryption of stored secrets")
+	}
+
+	botUserID, err := p.client.Bot.EnsureBot(&model.Bot{
+		Username:    "pagerduty",
+		DisplayName: "PagerDuty",
+		Description: "Created by the PagerDuty plugin.",
+	})
+	if err != nil {
+		p.client.Log.Error("Failed to ensure bot user", "error", err)
+		return errors.Wrap(err, "failed to ensure bot user")
+	}
+	p.botUserID = botUserID
+
+	if err := p.registerCommands(); err != nil {
+		p.client.Log.Error("Failed to register commands", "error", err)
+		return err
+	}
+
+	if err := p.startBackgroundJob(); err != nil {
+		p.client.Log.Error("Failed to start background job", "error", err)
+		return err
+	}
 
 	// Log plugin configuration status
 	pluginConfig := p.getConfiguration()
 	if err := pluginConfig.IsValid(); err != nil {
 		p.client.Log.Warn("Plugin configuration is not valid", "error", err)
 	} else {
-		p.client.Log.Info("Plugin configuration is valid", "base_url", pluginConfig.APIBaseURL)
+		p.client.Log.Info("Plugin configuration is valid", "base_url", pluginConfig.APIBaseURL, "accounts", strings.Join(pluginConfig.accountNames(), ", "))
 	}
+	go p.checkConnections(pluginConfig)
 
 	p.client.Log.Info("PagerDuty plugin activated successfully")
 	return nil
@@ -70,7 +129,22 @@ func (p *Plugin) OnDeactivate() error {
 	if p.client != nil {
 		p.client.Log.Info("PagerDuty plugin deactivating")
 	}
+
+	p.stopBackgroundJob()
+
 	return nil
 }
 
+// MessageHasBeenPosted is invoked after a message is posted, turning messages that match the
+// change event rules of their channel into PagerDuty change events, and those that match an
+// alert rule into alerts.
+func (p *Plugin) MessageHasBeenPosted(_ *plugin.Context, post *model.Post) {
+	if err := p.getConfiguration().IsValid(); err != nil {
+		return
+	}
+
+	p.sendMatchingChangeEvents(post)
+	p.sendMatchingAlertEvents(post)
+}
+
 // See https://developers.mattermost.com/extend/plugins/server/reference/
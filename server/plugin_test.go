@@ -1,8 +1,10 @@
 package main
 
 import (
+	"bytes"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/mattermost/mattermost/server/public/model"
@@ -13,6 +15,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
+	"github.com/svelle/mattermost-pagerduty-plugin/server/store/kvstore"
 )
 
 func TestPlugin_OnActivate(t *testing.T) {
@@ -32,6 +35,7 @@ func TestPlugin_OnActivate(t *testing.T) {
 		api.On("LogDebug", mock.Anything, mock.Anything, mock.Anything).Return().Maybe()
 		api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything).Return().Maybe()
 		api.On("LogError", mock.Anything).Return().Maybe()
+		setupActivationMocks(api)
 
 		plugin := &Plugin{}
 		plugin.SetAPI(api)
@@ -42,6 +46,10 @@ func TestPlugin_OnActivate(t *testing.T) {
 		assert.NotNil(t, plugin.client)
 		assert.NotNil(t, plugin.kvstore)
 		assert.NotNil(t, plugin.createPagerDutyClient)
+		assert.Equal(t, "bot-user-id", plugin.botUserID)
+		assert.NotNil(t, plugin.backgroundJob)
+
+		plugin.stopBackgroundJob()
 	})
 
 	// Test missing site URL
@@ -66,6 +74,107 @@ func TestPlugin_OnActivate(t *testing.T) {
 	})
 }
 
+// setupActivationMocks sets up the API calls made while generating the encryption key, ensuring
+// the bot user, registering commands and scheduling the background job.
+func setupActivationMocks(api *plugintest.API) {
+	api.On("GetServerVersion").Return("9.11.0").Maybe()
+	api.On("GetPluginConfig").Return(map[string]interface{}{}).Maybe()
+	api.On("SavePluginConfig", mock.Anything).Return(nil).Maybe()
+	api.On("KVSetWithOptions", mock.Anything, mock.Anything, mock.Anything).Return(true, nil).Maybe()
+	api.On("KVGet", mock.Anything).Return(nil, nil).Maybe()
+	api.On("KVList", mock.Anything, mock.Anything).Return([]string{}, nil).Maybe()
+	api.On("EnsureBotUser", mock.AnythingOfType("*model.Bot")).Return("bot-user-id", nil)
+	api.On("RegisterCommand", mock.AnythingOfType("*model.Command")).Return(nil)
+}
+
+// setupHandlerTestPlugin returns a plugin backed by an in-memory KV store, for which
+// admin-user-id is a system admin, test-user-id a regular user and guest-user-id a guest. Log
+// calls are allowed at every level.
+func setupHandlerTestPlugin(t *testing.T) (*Plugin, *plugintest.API, map[string][]byte) {
+	api := &plugintest.API{}
+	t.Cleanup(func() { api.AssertExpectations(t) })
+
+	for _, level := range []string{"LogDebug", "LogInfo", "LogWarn", "LogError"} {
+		args := []interface{}{mock.Anything}
+		for i := 0; i < 5; i++ {
+			api.On(level, args...).Maybe()
+			args = append(args, mock.Anything, mock.Anything)
+		}
+	}
+	users := map[string]*model.User{
+		"admin-user-id": {Id: "admin-user-id", Username: "admin", Roles: model.SystemAdminRoleId},
+		"test-user-id":  {Id: "test-user-id", Username: "user", Roles: model.SystemUserRoleId},
+		"guest-user-id": {Id: "guest-user-id", Username: "guest", Roles: model.SystemGuestRoleId},
+	}
+	for id, user := range users {
+		api.On("HasPermissionTo", id, model.PermissionManageSystem).Return(id == "admin-user-id").Maybe()
+		api.On("GetUser", id).Return(user, nil).Maybe()
+	}
+	values := mockKVStore(api)
+
+	plugin := &Plugin{}
+	plugin.SetAPI(api)
+	plugin.client = pluginapi.NewClient(api, nil)
+	plugin.kvstore = kvstore.NewKVStore(plugin.client)
+	plugin.setConfiguration(&configuration{APIToken: "token"})
+	return plugin, api, values
+}
+
+// serveTestRequest serves an API request of a user to the plugin.
+func serveTestRequest(plugin *Plugin, method, path, userID, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r.Header.Set("Mattermost-User-ID", userID)
+	plugin.ServeHTTP(nil, w, r)
+	return w
+}
+
+// usePagerDutyServer makes the plugin's PagerDuty clients send their requests to a test server
+// serving handler.
+func usePagerDutyServer(t *testing.T, plugin *Plugin, handler http.HandlerFunc) {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	plugin.createPagerDutyClient = func(token, _ string) *pagerduty.Client {
+		return pagerduty.NewClient(token, server.URL)
+	}
+}
+
+// mockKVStore backs the KV methods of a plugintest.API with a map.
+func mockKVStore(api *plugintest.API) map[string][]byte {
+	values := map[string][]byte{}
+	api.On("KVGet", mock.Anything).Return(func(key string) []byte {
+		return values[key]
+	}, func(string) *model.AppError {
+		return nil
+	}).Maybe()
+	api.On("KVSetWithOptions", mock.Anything, mock.Anything, mock.Anything).Return(func(key string, value []byte, options model.PluginKVSetOptions) bool {
+		if options.Atomic && !bytes.Equal(values[key], options.OldValue) {
+			return false
+		}
+		if value == nil {
+			delete(values, key)
+		} else {
+			values[key] = value
+		}
+		return true
+	}, func(string, []byte, model.PluginKVSetOptions) *model.AppError {
+		return nil
+	}).Maybe()
+	api.On("KVList", mock.Anything, mock.Anything).Return(func(page, _ int) []string {
+		if page > 0 {
+			return nil
+		}
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		return keys
+	}, func(int, int) *model.AppError {
+		return nil
+	}).Maybe()
+	return values
+}
+
 func TestPlugin_OnDeactivate(t *testing.T) {
 	api := &plugintest.API{}
 	defer api.AssertExpectations(t)
@@ -114,6 +223,71 @@ func TestPlugin_ServeHTTP(t *testing.T) {
 				p.configuration = &configuration{}
 			},
 		},
+		{
+			name:           "guest denied",
+			method:         http.MethodGet,
+			path:           "/api/v1/schedules",
+			userID:         "guest-user-id",
+			expectedStatus: http.StatusForbidden,
+			setupPlugin: func(p *Plugin) {
+				p.configuration = &configuration{}
+			},
+		},
+		{
+			name:           "user outside the page allowlist denied",
+			method:         http.MethodPost,
+			path:           "/api/v1/incidents",
+			userID:         "test-user-id",
+			expectedStatus: http.StatusForbidden,
+			setupPlugin: func(p *Plugin) {
+				p.configuration = &configuration{allowlists: map[string][]allowlistEntry{
+					permissionPage: {{Kind: allowlistRole, Value: "oncall_responder"}},
+				}}
+			},
+		},
+		{
+			name:           "webhook without secret",
+			method:         http.MethodPost,
+			path:           "/webhook",
+			userID:         "",
+			expectedStatus: http.StatusNotImplemented,
+			setupPlugin: func(p *Plugin) {
+				p.configuration = &configuration{}
+			},
+		},
+		{
+			name:           "webhook without signature",
+			method:         http.MethodPost,
+			path:           "/webhook",
+			userID:         "",
+			expectedStatus: http.StatusUnauthorized,
+			setupPlugin: func(p *Plugin) {
+				p.configuration = &configuration{WebhookSecret: "secret"}
+			},
+		},
+		{
+			name:           "webhook for an unknown account",
+			method:         http.MethodPost,
+			path:           "/webhook?account=eu",
+			userID:         "",
+			expectedStatus: http.StatusBadRequest,
+			setupPlugin: func(p *Plugin) {
+				p.configuration = &configuration{WebhookSecret: "secret"}
+			},
+		},
+		{
+			name:           "webhook for an account without secret",
+			method:         http.MethodPost,
+			path:           "/webhook?account=eu",
+			userID:         "",
+			expectedStatus: http.StatusNotImplemented,
+			setupPlugin: func(p *Plugin) {
+				p.configuration = &configuration{
+					WebhookSecret: "secret",
+					accounts:      []*pagerDutyAccount{{Name: "eu", APIToken: "token"}},
+				}
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -126,6 +300,9 @@ func TestPlugin_ServeHTTP(t *testing.T) {
 			api.On("LogInfo", mock.Anything, mock.Anything, mock.Anything).Maybe()
 			api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything).Maybe()
 			api.On("LogError", mock.Anything, mock.Anything, mock.Anything).Maybe()
+			api.On("HasPermissionTo", mock.Anything, model.PermissionManageSystem).Return(false).Maybe()
+			api.On("GetUser", "test-user-id").Return(&model.User{Id: "test-user-id", Roles: model.SystemUserRoleId}, nil).Maybe()
+			api.On("GetUser", "guest-user-id").Return(&model.User{Id: "guest-user-id", Roles: model.SystemGuestRoleId}, nil).Maybe()
 
 			plugin := &Plugin{}
 			plugin.SetAPI(api)
@@ -182,6 +359,22 @@ func TestPlugin_Configuration(t *testing.T) {
 				},
 				wantErr: false,
 			},
+			{
+				name: "base URL without https",
+				config: configuration{
+					APIToken:   "test-token",
+					APIBaseURL: "http://api.pagerduty.com",
+				},
+				wantErr: true,
+			},
+			{
+				name: "events base URL without https",
+				config: configuration{
+					APIToken:         "test-token",
+					EventsAPIBaseURL: "http://events.pagerduty.com",
+				},
+				wantErr: true,
+			},
 		}
 
 		for _, tt := range tests {
@@ -259,6 +452,7 @@ func TestPlugin_Integration(t *testing.T) {
 		api.On("LogDebug", mock.Anything, mock.Anything, mock.Anything).Return().Maybe()
 		api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything).Return().Maybe()
 		api.On("LogError", mock.Anything).Return().Maybe()
+		setupActivationMocks(api)
 
 		plugin := &Plugin{}
 		plugin.SetAPI(api)
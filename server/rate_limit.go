@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/store/kvstore"
+)
+
+const (
+	// rateLimitWindow is the period the configured limits apply to. A bucket holds up to a
+	// window's worth of requests, so that short bursts are allowed.
+	rateLimitWindow = time.Minute
+
+	// maxRateLimitAttempts is how often taking a token is retried when another node of the
+	// cluster takes one from the same bucket at the same time.
+	maxRateLimitAttempts = 5
+)
+
+// globalRateLimitKey is the key of the bucket shared by every request to the plugin's API.
+const globalRateLimitKey = "global"
+
+// rateLimitBucket is a bucket a request takes a token from, holding up to limit tokens.
+type rateLimitBucket struct {
+	key   string
+	limit int
+}
+
+// RateLimitRequests is middleware limiting how often each user may call each endpoint, and how
+// often all users together may call the plugin's API, so that a runaway script cannot exhaust
+// the PagerDuty API quota for everyone. Write endpoints have a tighter per-user limit than read
+// endpoints. Requests over a limit are rejected with 429 Too Many Requests and a Retry-After
+// header.
+func (p *Plugin) RateLimitRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		config := p.getConfiguration()
+		userID := r.Header.Get("Mattermost-User-ID")
+		endpoint := rateLimitEndpoint(r)
+
+		userLimit := config.ReadRateLimitPerMinute
+		if r.Method != http.MethodGet {
+			userLimit = config.WriteRateLimitPerMinute
+		}
+
+		var buckets []rateLimitBucket
+		if userLimit > 0 {
+			buckets = append(buckets, rateLimitBucket{key: userID + "_" + endpoint, limit: userLimit})
+		}
+		if config.GlobalRateLimitPerMinute > 0 {
+			buckets = append(buckets, rateLimitBucket{key: globalRateLimitKey, limit: config.GlobalRateLimitPerMinute})
+		}
+
+		retryAfter, err := p.takeRateLimitTokens(buckets, time.Now())
+		if err != nil {
+			// Requests are let through rather than failing the plugin's API with the KV store.
+			p.client.Log.Warn("Failed to apply rate limit", "error", err.Error(), "endpoint", endpoint)
+		}
+		if retryAfter > 0 {
+			p.client.Log.Info("Rate limit exceeded", "user_id", userID, "endpoint", endpoint)
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			p.handleError(w, r, &APIError{
+				ID:         "api.pagerduty.rate_limit.exceeded",
+				Message:    fmt.Sprintf("Too many requests. Try again in %s.", retryAfter.Round(time.Second)),
+				StatusCode: http.StatusTooManyRequests,
+			})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimitEndpoint identifies the endpoint of a request by its method and route, so that
+// requests for different incidents or services share a limit.
+func rateLimitEndpoint(r *http.Request) string {
+	path := r.URL.Path
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			path = template
+		}
+	}
+	return r.Method + " " + path
+}
+
+// takeRateLimitTokens takes a token from each bucket, returning how long to wait for the next
+// token if any bucket is empty. Every bucket is checked before a token is taken from any, so
+// that a request rejected by one limit does not use up the others. A token taken before another
+// node of the cluster changed a bucket that is still to be taken from is kept.
+func (p *Plugin) takeRateLimitTokens(buckets []rateLimitBucket, now time.Time) (time.Duration, error) {
+	taken := make([]bool, len(buckets))
+	for attempt := 0; attempt < maxRateLimitAttempts; attempt++ {
+		current := make([]*kvstore.RateLimitBucket, len(buckets))
+		updated := make([]*kvstore.RateLimitBucket, len(buckets))
+		var retryAfter time.Duration
+		for i, bucket := range buckets {
+			if taken[i] {
+				continue
+			}
+
+			var err error
+			if current[i], err = p.kvstore.GetRateLimitBucket(bucket.key); err != nil {
+				return 0, err
+			}
+
+			var wait time.Duration
+			updated[i], wait = takeToken(current[i], bucket.limit, now)
+			retryAfter = max(retryAfter, wait)
+		}
+		if retryAfter > 0 {
+			return retryAfter, nil
+		}
+
+		done := true
+		for i, bucket := range buckets {
+			if taken[i] {
+				continue
+			}
+
+			saved, err := p.kvstore.SaveRateLimitBucket(bucket.key, current[i], updated[i], rateLimitWindow)
+			if err != nil {
+				return 0, err
+			}
+			taken[i] = saved
+			done = done && saved
+		}
+		if done {
+			return 0, nil
+		}
+	}
+
+	return 0, errors.New("too many concurrent updates of the rate limit buckets")
+}
+
+// takeToken refills a bucket at limit tokens per window, up to limit tokens, and takes a token
+// from it. It returns the updated bucket, or how long to wait for the next token if there is
+// none. A bucket that does not exist yet is full.
+func takeToken(bucket *kvstore.RateLimitBucket, limit int, now time.Time) (*kvstore.RateLimitBucket, time.Duration) {
+	perNanosecond := float64(limit) / float64(rateLimitWindow)
+
+	tokens := float64(limit)
+	if bucket != nil {
+		elapsed := now.Sub(time.UnixMilli(bucket.UpdateAt))
+		if elapsed < 0 {
+			elapsed = 0
+		}
+		tokens = math.Min(float64(limit), bucket.Tokens+float64(elapsed)*perNanosecond)
+	}
+
+	if tokens < 1 {
+		return nil, time.Duration(math.Ceil((1 - tokens) / perNanosecond))
+	}
+
+	return &kvstore.RateLimitBucket{Tokens: tokens - 1, UpdateAt: now.UnixMilli()}, 0
+}
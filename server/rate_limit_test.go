@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/store/kvstore"
+)
+
+func TestTakeToken(t *testing.T) {
+	now := time.Date(2024, 1, 15, 9, 30, 0, 0, time.UTC)
+
+	t.Run("new bucket is full", func(t *testing.T) {
+		bucket, retryAfter := takeToken(nil, 10, now)
+		require.NotNil(t, bucket)
+		assert.Zero(t, retryAfter)
+		assert.Equal(t, 9.0, bucket.Tokens)
+		assert.Equal(t, now.UnixMilli(), bucket.UpdateAt)
+	})
+
+	t.Run("empty bucket asks to wait for the next token", func(t *testing.T) {
+		bucket, retryAfter := takeToken(&kvstore.RateLimitBucket{Tokens: 0.5, UpdateAt: now.UnixMilli()}, 10, now)
+		assert.Nil(t, bucket)
+		assert.Equal(t, 3*time.Second, retryAfter)
+	})
+
+	t.Run("bucket refills over time", func(t *testing.T) {
+		bucket, retryAfter := takeToken(&kvstore.RateLimitBucket{Tokens: 0, UpdateAt: now.Add(-30 * time.Second).UnixMilli()}, 10, now)
+		require.NotNil(t, bucket)
+		assert.Zero(t, retryAfter)
+		assert.InDelta(t, 4.0, bucket.Tokens, 0.001)
+	})
+
+	t.Run("bucket refills up to the limit", func(t *testing.T) {
+		bucket, retryAfter := takeToken(&kvstore.RateLimitBucket{Tokens: 2, UpdateAt: now.Add(-time.Hour).UnixMilli()}, 10, now)
+		require.NotNil(t, bucket)
+		assert.Zero(t, retryAfter)
+		assert.Equal(t, 9.0, bucket.Tokens)
+	})
+}
+
+func TestRateLimitEndpoint(t *testing.T) {
+	var endpoint string
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/incidents/{id}/merge", func(_ http.ResponseWriter, r *http.Request) {
+		endpoint = rateLimitEndpoint(r)
+	}).Methods(http.MethodPut)
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/api/v1/incidents/PINC1/merge", nil))
+	assert.Equal(t, "PUT /api/v1/incidents/{id}/merge", endpoint)
+}
+
+func TestPlugin_RateLimitRequests(t *testing.T) {
+	plugin, _, values := setupHandlerTestPlugin(t)
+	plugin.setConfiguration(&configuration{APIToken: "token", ReadRateLimitPerMinute: 2, GlobalRateLimitPerMinute: 2})
+	usePagerDutyServer(t, plugin, func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"schedules": [], "oncalls": []}`))
+	})
+
+	w := serveTestRequest(plugin, http.MethodGet, "/api/v1/schedules", "test-user-id", "")
+	assert.NotEqual(t, http.StatusTooManyRequests, w.Code)
+
+	// The global limit applies to every endpoint together.
+	w = serveTestRequest(plugin, http.MethodGet, "/api/v1/oncalls", "admin-user-id", "")
+	assert.NotEqual(t, http.StatusTooManyRequests, w.Code)
+
+	// A request the global limit rejects does not use up the user's limit.
+	w = serveTestRequest(plugin, http.MethodGet, "/api/v1/schedules", "test-user-id", "")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	var bucket kvstore.RateLimitBucket
+	require.NoError(t, json.Unmarshal(values["rate_limit_test-user-id_GET /api/v1/schedules"], &bucket))
+	assert.InDelta(t, 1, bucket.Tokens, 0.1)
+}
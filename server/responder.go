@@ -0,0 +1,334 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
+)
+
+const (
+	responderActionPath = "/api/v1/actions/responders"
+	responderDialogPath = "/api/v1/dialogs/responders"
+
+	responderUserField             = "user"
+	responderEscalationPolicyField = "escalation_policy"
+	responderMessageField          = "message"
+)
+
+// AddRespondersRequest represents the request body for asking additional responders to help
+// with an incident. Users are given by their Mattermost ID and matched to PagerDuty users by
+// email address.
+type AddRespondersRequest struct {
+	UserIDs             []string `json:"user_ids,omitempty"`
+	EscalationPolicyIDs []string `json:"escalation_policy_ids,omitempty"`
+	Message             string   `json:"message"`
+}
+
+// addResponders asks users and escalation policies to help with an incident of an account on
+// behalf of the given user, and notes the request in the threads of the channels following the
+// incident.
+func (p *Plugin) addResponders(userID, account, incidentID string, req *AddRespondersRequest) (_ *pagerduty.ResponderRequest, apiErr *APIError) {
+	defer func() { p.recordAudit(userID, account, auditActionAddResponders, incidentID, apiErr) }()
+
+	if len(req.UserIDs) == 0 && len(req.EscalationPolicyIDs) == 0 {
+		return nil, &APIError{
+			ID:         "api.pagerduty.responders.targets.missing",
+			Message:    "At least one user or escalation policy is required",
+			StatusCode: http.StatusBadRequest,
+		}
+	}
+	if strings.TrimSpace(req.Message) == "" {
+		return nil, &APIError{
+			ID:         "api.pagerduty.responders.message.missing",
+			Message:    "A message is required",
+			StatusCode: http.StatusBadRequest,
+		}
+	}
+
+	user, err := p.client.User.Get(userID)
+	if err != nil {
+		p.client.Log.Error("Failed to get user", "error", err.Error(), "user_id", userID)
+		return nil, &APIError{
+			ID:         "api.pagerduty.responders.user.error",
+			Message:    "Failed to retrieve user",
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+
+	requester, err := p.getPagerDutyUserForMattermostUser(account, user)
+	if err != nil {
+		p.client.Log.Error("Failed to get PagerDuty user", "error", err.Error(), "user_id", userID)
+		return nil, &APIError{
+			ID:         "api.pagerduty.responders.user.error",
+			Message:    "Failed to retrieve your PagerDuty user",
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+	if requester == nil {
+		return nil, &APIError{
+			ID:         "api.pagerduty.responders.requester.not_found",
+			Message:    "No PagerDuty user matches the email address of your account",
+			StatusCode: http.StatusBadRequest,
+		}
+	}
+
+	var targets []pagerduty.ResponderRequestTarget
+	var names []string
+	for _, responderID := range req.UserIDs {
+		responder, err := p.client.User.Get(responderID)
+		if err != nil {
+			return nil, &APIError{
+				ID:         "api.pagerduty.responders.target.not_found",
+				Message:    fmt.Sprintf("User %s was not found", responderID),
+				StatusCode: http.StatusBadRequest,
+			}
+		}
+
+		pdUser, err := p.getPagerDutyUserForMattermostUser(account, responder)
+		if err != nil {
+			p.client.Log.Error("Failed to get PagerDuty user", "error", err.Error(), "user_id", responderID)
+			return nil, &APIError{
+				ID:         "api.pagerduty.responders.target.error",
+				Message:    fmt.Sprintf("Failed to retrieve the PagerDuty user of @%s", responder.Username),
+				StatusCode: http.StatusInternalServerError,
+			}
+		}
+		if pdUser == nil {
+			return nil, &APIError{
+				ID:         "api.pagerduty.responders.target.not_found",
+				Message:    fmt.Sprintf("No PagerDuty user matches the email address of @%s", responder.Username),
+				StatusCode: http.StatusBadRequest,
+			}
+		}
+
+		targets = append(targets, pagerduty.ResponderRequestTarget{ID: pdUser.ID, Type: pagerduty.ResponderRequestTargetUser})
+		names = append(names, "@"+responder.Username)
+	}
+
+	client, apiErr := p.accountClient(account)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	for _, policyID := range req.EscalationPolicyIDs {
+		policy, err := client.GetEscalationPolicy(policyID)
+		if err != nil {
+			p.client.Log.Warn("Failed to get escalation policy from PagerDuty", "error", err.Error(), "escalation_policy_id", policyID)
+			return nil, &APIError{
+				ID:         "api.pagerduty.responders.target.not_found",
+				Message:    fmt.Sprintf("Escalation policy %s was not found", policyID),
+				StatusCode: http.StatusBadRequest,
+			}
+		}
+
+		targets = append(targets, pagerduty.ResponderRequestTarget{ID: policyID, Type: pagerduty.ResponderRequestTargetEscalationPolicy})
+		names = append(names, policy.EscalationPolicy.Name)
+	}
+
+	response, err := client.CreateResponderRequest(incidentID, user.Email, requester.ID, req.Message, targets)
+	if err != nil {
+		p.client.Log.Error("Failed to create responder request in PagerDuty", "error", err.Error(), "incident_id", incidentID)
+		return nil, &APIError{
+			ID:         "api.pagerduty.responders.create.error",
+			Message:    "Failed to request responders",
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+
+	p.postIncidentReply(account, incidentID, fmt.Sprintf(":sos: @%s asked %s to respond: %s", user.Username, strings.Join(names, ", "), req.Message))
+
+	return &response.ResponderRequest, nil
+}
+
+// updateIncidentResponder refreshes the responders listed in the summary of an incident. Replies
+// to a request, accepting or declining it, are also noted in the threads of the channels
+// following the incident; requests themselves are noted when they are made.
+func (p *Plugin) updateIncidentResponder(account string, responder *pagerduty.WebhookIncidentResponder, replied bool) {
+	if replied && responder.User != nil {
+		p.postIncidentReply(account, responder.Incident.ID, formatResponderReply(responder.User.Summary, responder.State, responder.Message))
+	}
+
+	client, apiErr := p.accountClient(account)
+	if apiErr != nil {
+		p.client.Log.Warn("Failed to get incident from PagerDuty", "error", apiErr.Message, "incident_id", responder.Incident.ID)
+		return
+	}
+
+	incident, err := client.GetIncident(responder.Incident.ID)
+	if err != nil {
+		p.client.Log.Warn("Failed to get incident from PagerDuty", "error", err.Error(), "incident_id", responder.Incident.ID)
+		return
+	}
+	p.refreshIncidentPosts(account, &incident.Incident)
+}
+
+// openResponderDialog opens the dialog for asking additional responders to help with an
+// incident of an account.
+func (p *Plugin) openResponderDialog(triggerID, account, incidentID string) error {
+	client, apiErr := p.accountClient(account)
+	if apiErr != nil {
+		return errors.New(apiErr.Message)
+	}
+
+	params := url.Values{}
+	params.Set("limit", "100")
+	policies, err := client.ListEscalationPolicies(params)
+	if err != nil {
+		return err
+	}
+
+	options := make([]*model.PostActionOptions, 0, len(policies.EscalationPolicies))
+	for _, policy := range policies.EscalationPolicies {
+		options = append(options, &model.PostActionOptions{Text: policy.Name, Value: policy.ID})
+	}
+
+	return p.client.Frontend.OpenInteractiveDialog(model.OpenDialogRequest{
+		TriggerId: triggerID,
+		URL:       fmt.Sprintf("/plugins/%s%s", p.API.GetPluginID(), responderDialogPath),
+		Dialog: model.Dialog{
+			CallbackId:       incidentID,
+			State:            account,
+			Title:            "Add Responders",
+			IntroductionText: fmt.Sprintf("Ask a user or an escalation policy to help with incident **%s**.", incidentID),
+			SubmitLabel:      "Request",
+			Elements: []model.DialogElement{
+				{
+					DisplayName: "User",
+					Name:        responderUserField,
+					Type:        "select",
+					DataSource:  "users",
+					Optional:    true,
+					HelpText:    "Matched to a PagerDuty user by email address.",
+				},
+				{
+					DisplayName: "Escalation policy",
+					Name:        responderEscalationPolicyField,
+					Type:        "select",
+					Options:     options,
+					Optional:    true,
+					HelpText:    "Whoever is on call for the policy is asked to respond.",
+				},
+				{
+					DisplayName: "Message",
+					Name:        responderMessageField,
+					Type:        "textarea",
+					Placeholder: "Why their help is needed",
+				},
+			},
+		},
+	})
+}
+
+// handleResponderAction receives a click on the "Add responders" button of an incident post.
+func (p *Plugin) handleResponderAction(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	p.client.Log.Debug("handleResponderAction called", "user_id", userID)
+
+	var request model.PostActionIntegrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		p.client.Log.Warn("Failed to decode responder action", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.responders.decode.error",
+			Message:    "Invalid request body",
+			StatusCode: http.StatusBadRequest,
+		})
+		return
+	}
+
+	response := &model.PostActionIntegrationResponse{}
+	incidentID, _ := request.Context["incident_id"].(string)
+	account, _ := request.Context["account"].(string)
+	if err := p.openResponderDialog(request.TriggerId, account, incidentID); err != nil {
+		p.client.Log.Error("Failed to open responder dialog", "error", err.Error(), "incident_id", incidentID)
+		response.EphemeralText = "Failed to open the responder dialog."
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		p.client.Log.Error("Failed to encode action response", "error", err.Error())
+	}
+}
+
+// handleResponderDialog receives the submission of the responder dialog.
+func (p *Plugin) handleResponderDialog(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	p.client.Log.Debug("handleResponderDialog called", "user_id", userID)
+
+	var submission model.SubmitDialogRequest
+	if err := json.NewDecoder(r.Body).Decode(&submission); err != nil {
+		p.client.Log.Warn("Failed to decode responder dialog submission", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.responders.decode.error",
+			Message:    "Invalid request body",
+			StatusCode: http.StatusBadRequest,
+		})
+		return
+	}
+
+	if submission.Cancelled {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	req := &AddRespondersRequest{}
+	if responderID, _ := submission.Submission[responderUserField].(string); responderID != "" {
+		req.UserIDs = []string{responderID}
+	}
+	if policyID, _ := submission.Submission[responderEscalationPolicyField].(string); policyID != "" {
+		req.EscalationPolicyIDs = []string{policyID}
+	}
+	req.Message, _ = submission.Submission[responderMessageField].(string)
+
+	response := &model.SubmitDialogResponse{}
+	switch {
+	case len(req.UserIDs) == 0 && len(req.EscalationPolicyIDs) == 0:
+		response.Errors = map[string]string{responderUserField: "Select a user or an escalation policy."}
+	case strings.TrimSpace(req.Message) == "":
+		response.Errors = map[string]string{responderMessageField: "A message is required."}
+	default:
+		if _, apiErr := p.addResponders(userID, submission.State, submission.CallbackId, req); apiErr != nil {
+			response.Error = apiErr.Message
+		} else {
+			p.client.Log.Info("Successfully requested responders", "incident_id", submission.CallbackId)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		p.client.Log.Error("Failed to encode dialog response", "error", err.Error())
+	}
+}
+
+// formatResponderReply formats a responder accepting or declining a request to help.
+func formatResponderReply(name, state, message string) string {
+	var reply string
+	switch state {
+	case pagerduty.ResponderStateJoined:
+		reply = fmt.Sprintf(":white_check_mark: %s accepted the request to respond.", name)
+	case pagerduty.ResponderStateDeclined:
+		reply = fmt.Sprintf(":no_entry_sign: %s declined the request to respond.", name)
+	default:
+		reply = fmt.Sprintf("%s is %s.", name, state)
+	}
+
+	if message != "" {
+		reply += "\n> " + message
+	}
+	return reply
+}
+
+// formatIncidentResponders lists the responders of an incident with their state.
+func formatIncidentResponders(responders []pagerduty.IncidentsResponder) string {
+	formatted := make([]string, 0, len(responders))
+	for _, responder := range responders {
+		formatted = append(formatted, fmt.Sprintf("%s (%s)", responder.User.Summary, responder.State))
+	}
+	return strings.Join(formatted, ", ")
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
+	"github.com/svelle/mattermost-pagerduty-plugin/server/store/kvstore"
+)
+
+func TestFormatResponderReply(t *testing.T) {
+	assert.Equal(t, ":white_check_mark: Bob accepted the request to respond.", formatResponderReply("Bob", pagerduty.ResponderStateJoined, ""))
+	assert.Equal(t, ":no_entry_sign: Bob declined the request to respond.\n> On a flight", formatResponderReply("Bob", pagerduty.ResponderStateDeclined, "On a flight"))
+}
+
+// respondingIncident is the incident served by serveResponderPagerDuty, which Bob joined.
+var respondingIncident = pagerduty.Incident{
+	ID:     "PINC1",
+	Title:  "Database down",
+	Status: "triggered",
+	IncidentsResponders: []pagerduty.IncidentsResponder{
+		{State: pagerduty.ResponderStateJoined, User: pagerduty.UserReference{ID: "PBOB", Summary: "Bob"}},
+	},
+}
+
+// serveResponderPagerDuty serves respondingIncident, the users Alice and Bob, the escalation
+// policy PEP1, and records the responder requests made.
+func serveResponderPagerDuty(requests *[]pagerduty.CreateResponderRequest) http.HandlerFunc {
+	users := map[string]pagerduty.User{
+		"alice@example.com": {ID: "PALICE", Name: "Alice", Email: "alice@example.com"},
+		"bob@example.com":   {ID: "PBOB", Name: "Bob", Email: "bob@example.com"},
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/incidents/PINC1":
+			_ = json.NewEncoder(w).Encode(pagerduty.IncidentResponse{Incident: respondingIncident})
+		case r.Method == http.MethodGet && r.URL.Path == "/users":
+			response := pagerduty.UsersResponse{Users: []pagerduty.User{}}
+			if user, ok := users[r.URL.Query().Get("query")]; ok {
+				response.Users = append(response.Users, user)
+			}
+			_ = json.NewEncoder(w).Encode(response)
+		case r.Method == http.MethodGet && r.URL.Path == "/escalation_policies/PEP1":
+			_ = json.NewEncoder(w).Encode(pagerduty.EscalationPolicyResponse{
+				EscalationPolicy: pagerduty.EscalationPolicy{ID: "PEP1", Name: "Database"},
+			})
+		case r.Method == http.MethodPost && r.URL.Path == "/incidents/PINC1/responder_requests":
+			var request pagerduty.CreateResponderRequest
+			_ = json.NewDecoder(r.Body).Decode(&request)
+			*requests = append(*requests, request)
+			_ = json.NewEncoder(w).Encode(pagerduty.ResponderRequestResponse{
+				ResponderRequest: pagerduty.ResponderRequest{Message: request.Message, ResponderRequestTargets: request.ResponderRequestTargets},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+}
+
+// deliverWebhookEvent delivers a webhook event signed with the secret of the default account.
+func deliverWebhookEvent(t *testing.T, plugin *Plugin, eventType string, data interface{}) {
+	encoded, err := json.Marshal(data)
+	require.NoError(t, err)
+	body, err := json.Marshal(pagerduty.WebhookPayload{Event: pagerduty.WebhookEvent{ID: "E1", EventType: eventType, Data: encoded}})
+	require.NoError(t, err)
+	deliverWebhookPayload(t, plugin, body)
+}
+
+// deliverWebhookPayload delivers a raw webhook payload signed with the secret of the default
+// account.
+func deliverWebhookPayload(t *testing.T, plugin *Plugin, body []byte) {
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(string(body)))
+	r.Header.Set(pagerduty.WebhookSignatureHeader, "v1="+hex.EncodeToString(mac.Sum(nil)))
+	plugin.ServeHTTP(nil, w, r)
+	require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+}
+
+func TestPlugin_handleWebhook_responderEvents(t *testing.T) {
+	tests := []struct {
+		name          string
+		eventType     string
+		state         string
+		message       string
+		postMessage   string
+		expectedReply string
+		expectUpdate  bool
+	}{
+		{
+			name:          "request accepted",
+			eventType:     "incident.responder.replied",
+			state:         pagerduty.ResponderStateJoined,
+			postMessage:   "outdated summary",
+			expectedReply: ":white_check_mark: Bob accepted the request to respond.",
+			expectUpdate:  true,
+		},
+		{
+			name:          "request declined",
+			eventType:     "incident.responder.replied",
+			state:         pagerduty.ResponderStateDeclined,
+			message:       "On a flight",
+			postMessage:   "outdated summary",
+			expectedReply: ":no_entry_sign: Bob declined the request to respond.\n> On a flight",
+			expectUpdate:  true,
+		},
+		{
+			name:         "request made",
+			eventType:    "incident.responder.added",
+			state:        "pending",
+			postMessage:  "outdated summary",
+			expectUpdate: true,
+		},
+		{
+			name:        "summary already up to date",
+			eventType:   "incident.responder.added",
+			state:       "pending",
+			postMessage: formatIncidentSummary(&respondingIncident),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin, api, _ := setupHandlerTestPlugin(t)
+			plugin.botUserID = "bot-user-id"
+			plugin.setConfiguration(&configuration{APIToken: "token", WebhookSecret: "secret"})
+			var requests []pagerduty.CreateResponderRequest
+			usePagerDutyServer(t, plugin, serveResponderPagerDuty(&requests))
+			require.NoError(t, plugin.kvstore.SaveIncidentSubscription(&kvstore.IncidentSubscription{IncidentID: "PINC1", ChannelID: "channel1", PostID: "post1"}))
+
+			api.On("GetPost", "post1").Return(&model.Post{Id: "post1", ChannelId: "channel1", Message: tt.postMessage}, nil).Once()
+			if tt.expectedReply != "" {
+				api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+					return post.ChannelId == "channel1" && post.RootId == "post1" && post.Message == tt.expectedReply
+				})).Return(&model.Post{Id: "reply1"}, nil).Once()
+			}
+			if tt.expectUpdate {
+				api.On("UpdatePost", mock.MatchedBy(func(post *model.Post) bool {
+					return post.Id == "post1" && post.Message == formatIncidentSummary(&respondingIncident)
+				})).Return(&model.Post{Id: "post1"}, nil).Once()
+			}
+
+			deliverWebhookEvent(t, plugin, tt.eventType, pagerduty.WebhookIncidentResponder{
+				Incident: pagerduty.IncidentReference{ID: "PINC1"},
+				User:     &pagerduty.UserReference{ID: "PBOB", Summary: "Bob"},
+				State:    tt.state,
+				Message:  tt.message,
+			})
+		})
+	}
+}
+
+func TestPlugin_handleResponderDialog(t *testing.T) {
+	setup := func(t *testing.T) (*Plugin, *[]pagerduty.CreateResponderRequest) {
+		plugin, api, _ := setupHandlerTestPlugin(t)
+		api.On("GetUser", "alice-user-id").Return(&model.User{Id: "alice-user-id", Username: "alice", Email: "alice@example.com", Roles: model.SystemUserRoleId}, nil).Maybe()
+		api.On("GetUser", "bob-user-id").Return(&model.User{Id: "bob-user-id", Username: "bob", Email: "bob@example.com", Roles: model.SystemUserRoleId}, nil).Maybe()
+		api.On("GetUser", "carol-user-id").Return(&model.User{Id: "carol-user-id", Username: "carol", Email: "carol@example.com", Roles: model.SystemUserRoleId}, nil).Maybe()
+		api.On("HasPermissionTo", "alice-user-id", model.PermissionManageSystem).Return(false).Maybe()
+
+		var requests []pagerduty.CreateResponderRequest
+		usePagerDutyServer(t, plugin, serveResponderPagerDuty(&requests))
+		return plugin, &requests
+	}
+
+	submit := func(t *testing.T, plugin *Plugin, userID string, submission map[string]interface{}) *model.SubmitDialogResponse {
+		body, err := json.Marshal(model.SubmitDialogRequest{CallbackId: "PINC1", Submission: submission})
+		require.NoError(t, err)
+		w := serveTestRequest(plugin, http.MethodPost, responderDialogPath, userID, string(body))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var response model.SubmitDialogResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		return &response
+	}
+
+	t.Run("no target selected", func(t *testing.T) {
+		plugin, requests := setup(t)
+
+		response := submit(t, plugin, "alice-user-id", map[string]interface{}{responderMessageField: "Please help"})
+		assert.Equal(t, map[string]string{responderUserField: "Select a user or an escalation policy."}, response.Errors)
+		assert.Empty(t, *requests)
+	})
+
+	t.Run("no message", func(t *testing.T) {
+		plugin, requests := setup(t)
+
+		response := submit(t, plugin, "alice-user-id", map[string]interface{}{responderUserField: "bob-user-id", responderMessageField: "  "})
+		assert.Equal(t, map[string]string{responderMessageField: "A message is required."}, response.Errors)
+		assert.Empty(t, *requests)
+	})
+
+	t.Run("requester not in PagerDuty", func(t *testing.T) {
+		plugin, requests := setup(t)
+
+		response := submit(t, plugin, "test-user-id", map[string]interface{}{responderUserField: "bob-user-id", responderMessageField: "Please help"})
+		assert.Equal(t, "No PagerDuty user matches the email address of your account", response.Error)
+		assert.Empty(t, *requests)
+	})
+
+	t.Run("responder not in PagerDuty", func(t *testing.T) {
+		plugin, requests := setup(t)
+
+		response := submit(t, plugin, "alice-user-id", map[string]interface{}{responderUserField: "carol-user-id", responderMessageField: "Please help"})
+		assert.Equal(t, "No PagerDuty user matches the email address of @carol", response.Error)
+		assert.Empty(t, *requests)
+	})
+
+	t.Run("unknown escalation policy", func(t *testing.T) {
+		plugin, requests := setup(t)
+
+		response := submit(t, plugin, "alice-user-id", map[string]interface{}{responderEscalationPolicyField: "PEP2", responderMessageField: "Please help"})
+		assert.Equal(t, "Escalation policy PEP2 was not found", response.Error)
+		assert.Empty(t, *requests)
+	})
+
+	t.Run("user and escalation policy requested", func(t *testing.T) {
+		plugin, requests := setup(t)
+
+		response := submit(t, plugin, "alice-user-id", map[string]interface{}{
+			responderUserField:             "bob-user-id",
+			responderEscalationPolicyField: "PEP1",
+			responderMessageField:          "Please help",
+		})
+		assert.Empty(t, response.Error)
+		assert.Empty(t, response.Errors)
+
+		require.Len(t, *requests, 1)
+		assert.Equal(t, "PALICE", (*requests)[0].RequesterID)
+		assert.Equal(t, "Please help", (*requests)[0].Message)
+		assert.Equal(t, []pagerduty.ResponderRequestTargetWrapper{
+			{ResponderRequestTarget: pagerduty.ResponderRequestTarget{ID: "PBOB", Type: pagerduty.ResponderRequestTargetUser}},
+			{ResponderRequestTarget: pagerduty.ResponderRequestTarget{ID: "PEP1", Type: pagerduty.ResponderRequestTargetEscalationPolicy}},
+		}, (*requests)[0].ResponderRequestTargets)
+	})
+
+	t.Run("guests may not page", func(t *testing.T) {
+		plugin, requests := setup(t)
+
+		body := `{"callback_id": "PINC1", "submission": {"user": "bob-user-id", "message": "Please help"}}`
+		w := serveTestRequest(plugin, http.MethodPost, responderDialogPath, "guest-user-id", body)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Empty(t, *requests)
+	})
+}
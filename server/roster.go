@@ -0,0 +1,314 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/cron"
+	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
+	"github.com/svelle/mattermost-pagerduty-plugin/server/store/kvstore"
+)
+
+// rosterMaxDelay is how late a roster post may be before it is skipped, e.g. after the
+// plugin was disabled for a while.
+const rosterMaxDelay = time.Hour
+
+// CreateRosterRequest represents the request body for creating a scheduled roster post
+type CreateRosterRequest struct {
+	ChannelID           string   `json:"channel_id"`
+	CronExpression      string   `json:"cron_expression"`
+	TimeZone            string   `json:"time_zone"`
+	ScheduleIDs         []string `json:"schedule_ids,omitempty"`
+	EscalationPolicyIDs []string `json:"escalation_policy_ids,omitempty"`
+}
+
+// canManageRosters reports whether the user may manage the rosters of a channel, which
+// requires being allowed to post in it.
+func (p *Plugin) canManageRosters(userID, channelID string) bool {
+	return p.client.User.HasPermissionToChannel(userID, channelID, model.PermissionCreatePost)
+}
+
+// createRoster validates and stores a new roster of an account on behalf of the given user.
+func (p *Plugin) createRoster(userID, account string, req *CreateRosterRequest) (*kvstore.Roster, *APIError) {
+	if apiErr := p.checkAccount(account); apiErr != nil {
+		return nil, apiErr
+	}
+	if len(req.ScheduleIDs) == 0 && len(req.EscalationPolicyIDs) == 0 {
+		return nil, &APIError{
+			ID:         "api.pagerduty.roster.targets.missing",
+			Message:    "At least one schedule or escalation policy is required",
+			StatusCode: http.StatusBadRequest,
+		}
+	}
+
+	timeZone, apiErr := p.validateScheduledPost("roster", userID, req.ChannelID, req.CronExpression, req.TimeZone)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	roster := &kvstore.Roster{
+		ID:                  model.NewId(),
+		Account:             account,
+		ChannelID:           req.ChannelID,
+		CronExpression:      req.CronExpression,
+		TimeZone:            timeZone,
+		ScheduleIDs:         req.ScheduleIDs,
+		EscalationPolicyIDs: req.EscalationPolicyIDs,
+		CreatorID:           userID,
+		CreateAt:            model.GetMillis(),
+	}
+
+	if err := p.kvstore.SaveRoster(roster); err != nil {
+		p.client.Log.Error("Failed to save roster", "error", err.Error())
+		return nil, &APIError{
+			ID:         "api.pagerduty.roster.save.error",
+			Message:    "Failed to save roster",
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+
+	return roster, nil
+}
+
+// validateScheduledPost checks the channel, cron expression and time zone of a recurring post of
+// the given kind, such as a roster, and that the user may post in the channel. It returns the
+// time zone to use, defaulting to UTC.
+func (p *Plugin) validateScheduledPost(kind, userID, channelID, cronExpression, timeZone string) (string, *APIError) {
+	if channelID == "" || cronExpression == "" {
+		return "", &APIError{
+			ID:         fmt.Sprintf("api.pagerduty.%s.fields.missing", kind),
+			Message:    "channel_id and cron_expression are required",
+			StatusCode: http.StatusBadRequest,
+		}
+	}
+
+	if _, err := cron.Parse(cronExpression); err != nil {
+		return "", &APIError{
+			ID:         fmt.Sprintf("api.pagerduty.%s.cron.invalid", kind),
+			Message:    fmt.Sprintf("Invalid cron expression: %s", err.Error()),
+			StatusCode: http.StatusBadRequest,
+		}
+	}
+
+	if timeZone == "" {
+		timeZone = "UTC"
+	}
+	if _, err := time.LoadLocation(timeZone); err != nil {
+		return "", &APIError{
+			ID:         fmt.Sprintf("api.pagerduty.%s.timezone.invalid", kind),
+			Message:    fmt.Sprintf("Invalid time zone: %s", timeZone),
+			StatusCode: http.StatusBadRequest,
+		}
+	}
+
+	if !p.client.User.HasPermissionToChannel(userID, channelID, model.PermissionCreatePost) {
+		return "", &APIError{
+			ID:         fmt.Sprintf("api.pagerduty.%s.permission", kind),
+			Message:    "You do not have permission to post in this channel",
+			StatusCode: http.StatusForbidden,
+		}
+	}
+
+	return timeZone, nil
+}
+
+// getChannelRosters returns the rosters posting to a channel, oldest first.
+func (p *Plugin) getChannelRosters(channelID string) ([]*kvstore.Roster, error) {
+	rosters, err := p.kvstore.ListRosters()
+	if err != nil {
+		return nil, err
+	}
+
+	channelRosters := []*kvstore.Roster{}
+	for _, roster := range rosters {
+		if roster.ChannelID == channelID {
+			channelRosters = append(channelRosters, roster)
+		}
+	}
+
+	sort.Slice(channelRosters, func(i, j int) bool {
+		return channelRosters[i].CreateAt < channelRosters[j].CreateAt
+	})
+
+	return channelRosters, nil
+}
+
+// nextRosterPost returns the next time a roster is due to be posted after its last post.
+func nextRosterPost(roster *kvstore.Roster) (time.Time, error) {
+	return nextScheduledPost(roster.CronExpression, roster.TimeZone, max(roster.CreateAt, roster.LastPostAt))
+}
+
+// nextScheduledPost returns the next time a post on the given cron schedule is due after the
+// last one, in milliseconds.
+func nextScheduledPost(cronExpression, timeZone string, last int64) (time.Time, error) {
+	schedule, err := cron.Parse(cronExpression)
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "failed to parse cron expression")
+	}
+
+	location, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "failed to load time zone")
+	}
+
+	return schedule.Next(time.UnixMilli(last).In(location)), nil
+}
+
+// runRosterPosts posts every roster that has come due since its last post.
+func (p *Plugin) runRosterPosts(now time.Time) error {
+	rosters, err := p.kvstore.ListRosters()
+	if err != nil {
+		return errors.Wrap(err, "failed to list rosters")
+	}
+
+	for _, roster := range rosters {
+		next, err := nextRosterPost(roster)
+		if err != nil {
+			p.client.Log.Warn("Skipping invalid roster", "roster_id", roster.ID, "error", err.Error())
+			continue
+		}
+		if next.IsZero() || next.After(now) {
+			continue
+		}
+
+		if now.Sub(next) > rosterMaxDelay {
+			p.client.Log.Info("Skipping overdue roster post", "roster_id", roster.ID, "due", next.Format(time.RFC3339))
+		} else if err := p.postRoster(roster); err != nil {
+			p.client.Log.Error("Failed to post roster", "roster_id", roster.ID, "error", err.Error())
+			continue
+		}
+
+		roster.LastPostAt = now.UnixMilli()
+		if err := p.kvstore.SaveRoster(roster); err != nil {
+			p.client.Log.Error("Failed to update roster", "roster_id", roster.ID, "error", err.Error())
+		}
+	}
+
+	return nil
+}
+
+// postRoster posts the current on-call users of a roster's schedules and escalation
+// policies to its channel.
+func (p *Plugin) postRoster(roster *kvstore.Roster) error {
+	scheduleOnCalls, policyOnCalls, err := p.getOnCallsForTargets(roster.Account, roster.ScheduleIDs, roster.EscalationPolicyIDs)
+	if err != nil {
+		return err
+	}
+
+	location, err := time.LoadLocation(roster.TimeZone)
+	if err != nil {
+		location = time.UTC
+	}
+
+	post := &model.Post{
+		UserId:    p.botUserID,
+		ChannelId: roster.ChannelID,
+		Message:   p.formatRoster(roster, scheduleOnCalls, policyOnCalls, time.Now().In(location)),
+	}
+	return p.client.Post.CreatePost(post)
+}
+
+// getOnCallsForTargets returns the current on-calls of the given schedules and escalation
+// policies of an account, the latter at every escalation level.
+func (p *Plugin) getOnCallsForTargets(account string, scheduleIDs, policyIDs []string) (scheduleOnCalls, policyOnCalls []pagerduty.OnCall, err error) {
+	client, apiErr := p.accountClient(account)
+	if apiErr != nil {
+		return nil, nil, errors.New(apiErr.Message)
+	}
+
+	if len(scheduleIDs) > 0 {
+		oncalls, err := client.GetOnCallsForSchedules(scheduleIDs)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to get on-calls for schedules")
+		}
+		scheduleOnCalls = oncalls.OnCalls
+	}
+	if len(policyIDs) > 0 {
+		oncalls, err := client.GetOnCallsForEscalationPolicies(policyIDs)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to get on-calls for escalation policies")
+		}
+		policyOnCalls = oncalls.OnCalls
+	}
+
+	return scheduleOnCalls, policyOnCalls, nil
+}
+
+// nextShiftBoundary returns the earliest end of the given on-calls after now, or now plus
+// maxInterval if that comes first.
+func nextShiftBoundary(oncalls []pagerduty.OnCall, now time.Time, maxInterval time.Duration) time.Time {
+	next := now.Add(maxInterval)
+	for _, oncall := range oncalls {
+		end, err := time.Parse(time.RFC3339, oncall.End)
+		if err == nil && end.After(now) && end.Before(next) {
+			next = end
+		}
+	}
+	return next
+}
+
+func (p *Plugin) formatRoster(roster *kvstore.Roster, scheduleOnCalls, policyOnCalls []pagerduty.OnCall, now time.Time) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "#### On-call roster for %s\n", now.Format("Monday, January 2"))
+
+	formatUntil := func(oncall pagerduty.OnCall) string {
+		end, err := time.Parse(time.RFC3339, oncall.End)
+		if err != nil {
+			return ""
+		}
+		return " until " + end.In(now.Location()).Format("Mon Jan 2, 15:04 MST")
+	}
+
+	for _, scheduleID := range roster.ScheduleIDs {
+		var entries []string
+		name := scheduleID
+		for _, oncall := range scheduleOnCalls {
+			if oncall.Schedule.ID != scheduleID {
+				continue
+			}
+			name = oncall.Schedule.Name
+			entry := p.formatPagerDutyUser(oncall.User) + formatUntil(oncall)
+			if !slices.Contains(entries, entry) {
+				entries = append(entries, entry)
+			}
+		}
+
+		if len(entries) == 0 {
+			entries = []string{"_Nobody is on call_"}
+		}
+		fmt.Fprintf(&sb, "**%s:** %s\n", name, strings.Join(entries, ", "))
+	}
+
+	for _, policyID := range roster.EscalationPolicyIDs {
+		var oncalls []pagerduty.OnCall
+		name := policyID
+		for _, oncall := range policyOnCalls {
+			if oncall.EscalationPolicy == nil || oncall.EscalationPolicy.ID != policyID {
+				continue
+			}
+			name = oncall.EscalationPolicy.Name
+			oncalls = append(oncalls, oncall)
+		}
+
+		sort.SliceStable(oncalls, func(i, j int) bool {
+			return oncalls[i].EscalationLevel < oncalls[j].EscalationLevel
+		})
+
+		fmt.Fprintf(&sb, "**%s:**\n", name)
+		if len(oncalls) == 0 {
+			sb.WriteString("- _Nobody is on call_\n")
+		}
+		for _, oncall := range oncalls {
+			fmt.Fprintf(&sb, "- Level %d: %s%s\n", oncall.EscalationLevel, p.formatPagerDutyUser(oncall.User), formatUntil(oncall))
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
+	"github.com/svelle/mattermost-pagerduty-plugin/server/store/kvstore"
+)
+
+func TestNextRosterPost(t *testing.T) {
+	createAt := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		roster  *kvstore.Roster
+		want    time.Time
+		wantErr bool
+	}{
+		{
+			name: "first post after creation",
+			roster: &kvstore.Roster{
+				CronExpression: "0 9 * * *",
+				TimeZone:       "UTC",
+				CreateAt:       createAt.UnixMilli(),
+			},
+			want: time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "after the last post",
+			roster: &kvstore.Roster{
+				CronExpression: "0 9 * * *",
+				TimeZone:       "UTC",
+				CreateAt:       createAt.UnixMilli(),
+				LastPostAt:     time.Date(2024, 1, 5, 9, 0, 10, 0, time.UTC).UnixMilli(),
+			},
+			want: time.Date(2024, 1, 6, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "evaluated in the roster's time zone",
+			roster: &kvstore.Roster{
+				CronExpression: "0 9 * * *",
+				TimeZone:       "America/New_York",
+				CreateAt:       createAt.UnixMilli(),
+			},
+			want: time.Date(2024, 1, 1, 14, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "invalid time zone",
+			roster: &kvstore.Roster{
+				CronExpression: "0 9 * * *",
+				TimeZone:       "Mars/Olympus",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := nextRosterPost(tt.roster)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, tt.want.Equal(got), "got %s", got)
+		})
+	}
+}
+
+func TestPlugin_formatRoster(t *testing.T) {
+	plugin := &Plugin{}
+	now := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	roster := &kvstore.Roster{
+		ScheduleIDs:         []string{"SCHED1", "SCHED2"},
+		EscalationPolicyIDs: []string{"EP1"},
+	}
+
+	scheduleOnCalls := []pagerduty.OnCall{
+		{
+			User:     pagerduty.User{ID: "USER1", Name: "Alice"},
+			Schedule: pagerduty.Schedule{ID: "SCHED1", Name: "Primary"},
+			End:      "2024-01-02T09:00:00Z",
+		},
+		{
+			// The same schedule referenced by a second escalation policy.
+			User:     pagerduty.User{ID: "USER1", Name: "Alice"},
+			Schedule: pagerduty.Schedule{ID: "SCHED1", Name: "Primary"},
+			End:      "2024-01-02T09:00:00Z",
+		},
+	}
+
+	policyOnCalls := []pagerduty.OnCall{
+		{
+			User:             pagerduty.User{ID: "USER3", Name: "Carol"},
+			EscalationPolicy: &pagerduty.EscalationPolicy{ID: "EP1", Name: "Ops"},
+			EscalationLevel:  2,
+		},
+		{
+			User:             pagerduty.User{ID: "USER2", Name: "Bob"},
+			EscalationPolicy: &pagerduty.EscalationPolicy{ID: "EP1", Name: "Ops"},
+			EscalationLevel:  1,
+			End:              "2024-01-01T17:00:00Z",
+		},
+	}
+
+	message := plugin.formatRoster(roster, scheduleOnCalls, policyOnCalls, now)
+
+	assert.Equal(t, "#### On-call roster for Monday, January 1\n"+
+		"**Primary:** Alice until Tue Jan 2, 09:00 UTC\n"+
+		"**SCHED2:** _Nobody is on call_\n"+
+		"**Ops:**\n"+
+		"- Level 1: Bob until Mon Jan 1, 17:00 UTC\n"+
+		"- Level 2: Carol\n", message)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
+)
+
+// ServiceDetailResponse is a service with everything needed to know who will be alerted
+// before paging it
+type ServiceDetailResponse struct {
+	Service            pagerduty.Service                      `json:"service"`
+	EscalationPolicy   *EscalationChain                       `json:"escalation_policy,omitempty"`
+	SupportingServices []pagerduty.ServiceDependencyReference `json:"supporting_services"`
+	DependentServices  []pagerduty.ServiceDependencyReference `json:"dependent_services"`
+}
+
+// getServiceDetail looks up a service, resolves its escalation policy to the current on-calls
+// and lists the services it depends on and that depend on it.
+func (p *Plugin) getServiceDetail(client *pagerduty.Client, serviceID string) (*ServiceDetailResponse, error) {
+	service, err := client.GetService(serviceID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get service")
+	}
+
+	detail := &ServiceDetailResponse{
+		Service:            service.Service,
+		SupportingServices: []pagerduty.ServiceDependencyReference{},
+		DependentServices:  []pagerduty.ServiceDependencyReference{},
+	}
+
+	if service.Service.EscalationPolicy != nil {
+		policy, err := client.GetEscalationPolicy(service.Service.EscalationPolicy.ID)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get escalation policy")
+		}
+
+		chains, err := p.resolveEscalationChains(client, []pagerduty.EscalationPolicy{policy.EscalationPolicy})
+		if err != nil {
+			return nil, err
+		}
+		detail.EscalationPolicy = &chains[0]
+	}
+
+	dependencies, err := client.GetServiceDependencies(serviceID)
+	if err != nil {
+		// Service dependencies are not available on every plan.
+		p.client.Log.Warn("Failed to get service dependencies", "error", err.Error(), "service_id", serviceID)
+		return detail, nil
+	}
+
+	names := map[string]string{}
+	for _, relationship := range dependencies.Relationships {
+		switch serviceID {
+		case relationship.DependentService.ID:
+			detail.SupportingServices = append(detail.SupportingServices, p.describeDependency(client, relationship.SupportingService, names))
+		case relationship.SupportingService.ID:
+			detail.DependentServices = append(detail.DependentServices, p.describeDependency(client, relationship.DependentService, names))
+		}
+	}
+
+	return detail, nil
+}
+
+// describeDependency fills in the name of a technical service in a dependency, which PagerDuty
+// only returns by ID.
+func (p *Plugin) describeDependency(client *pagerduty.Client, reference pagerduty.ServiceDependencyReference, names map[string]string) pagerduty.ServiceDependencyReference {
+	if reference.Summary != "" || reference.Type != "technical_service_reference" {
+		return reference
+	}
+
+	if name, ok := names[reference.ID]; ok {
+		reference.Summary = name
+		return reference
+	}
+
+	if service, err := client.GetService(reference.ID); err == nil {
+		names[reference.ID] = service.Service.Name
+		reference.Summary = service.Service.Name
+	}
+	return reference
+}
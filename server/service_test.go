@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
+)
+
+func TestPlugin_getServiceDetail(t *testing.T) {
+	responses := map[string]string{
+		"/services/SVC1": `{"service": {"id": "SVC1", "name": "Checkout", "escalation_policy": {"id": "EP1", "name": "Ops"}}}`,
+		"/services/SVC2": `{"service": {"id": "SVC2", "name": "Database"}}`,
+		"/services/SVC3": `{"service": {"id": "SVC3", "name": "Storefront"}}`,
+		"/escalation_policies/EP1": `{"escalation_policy": {"id": "EP1", "name": "Ops", "escalation_rules": [
+			{"id": "RULE1", "escalation_delay_in_minutes": 30, "targets": [{"id": "SCHED1", "type": "schedule_reference"}]}
+		]}}`,
+		"/oncalls": `{"oncalls": [{"user": {"id": "USER1", "name": "Alice"}, "escalation_policy": {"id": "EP1"}, "escalation_level": 1}]}`,
+		"/service_dependencies/technical_services/SVC1": `{"relationships": [
+			{"id": "DEP1", "supporting_service": {"id": "SVC2", "type": "technical_service_reference"}, "dependent_service": {"id": "SVC1", "type": "technical_service_reference"}},
+			{"id": "DEP2", "supporting_service": {"id": "SVC1", "type": "technical_service_reference"}, "dependent_service": {"id": "SVC3", "type": "technical_service_reference"}},
+			{"id": "DEP3", "supporting_service": {"id": "SVC1", "type": "technical_service_reference"}, "dependent_service": {"id": "BIZ1", "type": "business_service_reference"}}
+		]}`,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	api := &plugintest.API{}
+	api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+
+	plugin := &Plugin{}
+	plugin.SetAPI(api)
+	plugin.client = pluginapi.NewClient(api, nil)
+
+	detail, err := plugin.getServiceDetail(pagerduty.NewClient("test-token", server.URL), "SVC1")
+	require.NoError(t, err)
+
+	assert.Equal(t, "Checkout", detail.Service.Name)
+	require.NotNil(t, detail.EscalationPolicy)
+	require.Len(t, detail.EscalationPolicy.Levels, 1)
+	require.Len(t, detail.EscalationPolicy.Levels[0].OnCalls, 1)
+	assert.Equal(t, "Alice", detail.EscalationPolicy.Levels[0].OnCalls[0].User.Name)
+
+	assert.Equal(t, []pagerduty.ServiceDependencyReference{
+		{ID: "SVC2", Type: "technical_service_reference", Summary: "Database"},
+	}, detail.SupportingServices)
+	assert.Equal(t, []pagerduty.ServiceDependencyReference{
+		{ID: "SVC3", Type: "technical_service_reference", Summary: "Storefront"},
+		{ID: "BIZ1", Type: "business_service_reference"},
+	}, detail.DependentServices)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
+)
+
+const (
+	statusUpdateDialogPath = "/api/v1/dialogs/status_update"
+
+	statusUpdateSubjectField = "subject"
+	statusUpdateMessageField = "message"
+)
+
+// CreateStatusUpdateRequest represents the request body for sending a status update
+type CreateStatusUpdateRequest struct {
+	Message string `json:"message"`
+	Subject string `json:"subject,omitempty"`
+}
+
+// createStatusUpdate sends a status update for an incident of an account to its stakeholders on
+// behalf of the given user, and mirrors it to the channels following the incident.
+func (p *Plugin) createStatusUpdate(userID, account, incidentID string, req *CreateStatusUpdateRequest) (_ *pagerduty.StatusUpdate, apiErr *APIError) {
+	defer func() { p.recordAudit(userID, account, auditActionCreateStatusUpdate, incidentID, apiErr) }()
+
+	if strings.TrimSpace(req.Message) == "" {
+		return nil, &APIError{
+			ID:         "api.pagerduty.status_update.message.missing",
+			Message:    "A message is required",
+			StatusCode: http.StatusBadRequest,
+		}
+	}
+
+	user, err := p.client.User.Get(userID)
+	if err != nil {
+		p.client.Log.Error("Failed to get user", "error", err.Error(), "user_id", userID)
+		return nil, &APIError{
+			ID:         "api.pagerduty.status_update.user.error",
+			Message:    "Failed to retrieve user",
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+
+	client, apiErr := p.accountClient(account)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	response, err := client.CreateStatusUpdate(incidentID, user.Email, req.Message, req.Subject)
+	if err != nil {
+		p.client.Log.Error("Failed to create status update in PagerDuty", "error", err.Error(), "incident_id", incidentID)
+		return nil, &APIError{
+			ID:         "api.pagerduty.status_update.create.error",
+			Message:    "Failed to send status update",
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+
+	update := &response.StatusUpdate
+	if update.Sender == nil {
+		update.Sender = &pagerduty.UserReference{Summary: user.GetDisplayName(model.ShowFullName)}
+	}
+	p.mirrorStatusUpdate(account, incidentID, update)
+
+	return update, nil
+}
+
+// openStatusUpdateDialog opens the dialog for sending a status update on an incident of an
+// account.
+func (p *Plugin) openStatusUpdateDialog(triggerID, account, incidentID string) error {
+	return p.client.Frontend.OpenInteractiveDialog(model.OpenDialogRequest{
+		TriggerId: triggerID,
+		URL:       fmt.Sprintf("/plugins/%s%s", p.API.GetPluginID(), statusUpdateDialogPath),
+		Dialog: model.Dialog{
+			CallbackId:       incidentID,
+			State:            account,
+			Title:            "Post Status Update",
+			IntroductionText: fmt.Sprintf("Send a status update on incident **%s** to its stakeholders.", incidentID),
+			SubmitLabel:      "Post",
+			Elements: []model.DialogElement{
+				{
+					DisplayName: "Subject",
+					Name:        statusUpdateSubjectField,
+					Type:        "text",
+					Optional:    true,
+					HelpText:    "Used as the subject of the email sent to stakeholders.",
+				},
+				{
+					DisplayName: "Message",
+					Name:        statusUpdateMessageField,
+					Type:        "textarea",
+				},
+			},
+		},
+	})
+}
+
+// handleStatusUpdateDialog receives the submission of the status update dialog.
+func (p *Plugin) handleStatusUpdateDialog(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	p.client.Log.Debug("handleStatusUpdateDialog called", "user_id", userID)
+
+	var submission model.SubmitDialogRequest
+	if err := json.NewDecoder(r.Body).Decode(&submission); err != nil {
+		p.client.Log.Warn("Failed to decode status update dialog submission", "error", err)
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.status_update.decode.error",
+			Message:    "Invalid request body",
+			StatusCode: http.StatusBadRequest,
+		})
+		return
+	}
+
+	if submission.Cancelled {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	req := &CreateStatusUpdateRequest{}
+	req.Subject, _ = submission.Submission[statusUpdateSubjectField].(string)
+	req.Message, _ = submission.Submission[statusUpdateMessageField].(string)
+
+	response := &model.SubmitDialogResponse{}
+	if strings.TrimSpace(req.Message) == "" {
+		response.Errors = map[string]string{statusUpdateMessageField: "A message is required."}
+	} else if _, apiErr := p.createStatusUpdate(userID, submission.State, submission.CallbackId, req); apiErr != nil {
+		response.Error = apiErr.Message
+	} else {
+		p.client.Log.Info("Successfully posted status update", "incident_id", submission.CallbackId)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		p.client.Log.Error("Failed to encode dialog response", "error", err.Error())
+	}
+}
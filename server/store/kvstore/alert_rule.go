@@ -0,0 +1,138 @@
+package kvstore
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/pkg/errors"
+)
+
+const (
+	alertRulesKey        = "alert_rules"
+	alertRuleEventPrefix = "alert_rule_events_"
+
+	// maxAlertRuleCountAttempts is how often incrementing an event count is retried when another
+	// node of the cluster increments it at the same time.
+	maxAlertRuleCountAttempts = 5
+
+	// maxAlertRuleUpdateAttempts is how often a change of the alert rules is retried when another
+	// node of the cluster saves them at the same time.
+	maxAlertRuleUpdateAttempts = 5
+)
+
+// AlertRule turns the messages of a channel that match a pattern into PagerDuty alerts, for
+// systems that can only post to Mattermost. Rules are defined by plugin admins and stored
+// together, so that a single read finds them for every message posted.
+type AlertRule struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	ChannelID string `json:"channel_id"`
+	ServiceID string `json:"service_id"`
+
+	// Account is the PagerDuty account of the service, empty for the default account.
+	Account string `json:"account,omitempty"`
+
+	// AuthorIDs limits the rule to messages of these users or bots. BotsOnly limits it to
+	// messages of bots and incoming webhooks.
+	AuthorIDs []string `json:"author_ids,omitempty"`
+	BotsOnly  bool     `json:"bots_only,omitempty"`
+
+	// TriggerPattern triggers an alert for matching messages, and ResolvePattern resolves the
+	// alert with the same dedup key.
+	TriggerPattern string `json:"trigger_pattern"`
+	ResolvePattern string `json:"resolve_pattern,omitempty"`
+
+	// SeverityMap maps the capture group named severity to a PagerDuty severity, falling back
+	// to DefaultSeverity.
+	SeverityMap     map[string]string `json:"severity_map,omitempty"`
+	DefaultSeverity string            `json:"default_severity,omitempty"`
+
+	// DedupKeyTemplate builds the dedup key of an alert from the named capture groups of the
+	// matching pattern, e.g. {{host}}-{{check}}.
+	DedupKeyTemplate string `json:"dedup_key_template,omitempty"`
+
+	MaxEventsPerMinute int  `json:"max_events_per_minute,omitempty"`
+	DryRun             bool `json:"dry_run,omitempty"`
+
+	CreatorID string `json:"creator_id"`
+	CreateAt  int64  `json:"create_at"`
+	UpdateAt  int64  `json:"update_at,omitempty"`
+}
+
+// UpdateAlertRules applies a change to the alert rules and saves them, returning the saved
+// rules. If another node of the cluster saves the rules meanwhile, the change is applied again
+// to the rules it saved. An error returned by the change is returned as is, without saving.
+func (kv Client) UpdateAlertRules(change func(rules []*AlertRule) ([]*AlertRule, error)) ([]*AlertRule, error) {
+	for attempt := 0; attempt < maxAlertRuleUpdateAttempts; attempt++ {
+		var data []byte
+		if err := kv.client.KV.Get(alertRulesKey, &data); err != nil {
+			return nil, errors.Wrap(err, "failed to get alert rules")
+		}
+
+		var rules []*AlertRule
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, &rules); err != nil {
+				return nil, errors.Wrap(err, "failed to decode alert rules")
+			}
+		}
+
+		rules, err := change(rules)
+		if err != nil {
+			return nil, err
+		}
+
+		updated, err := json.Marshal(rules)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to encode alert rules")
+		}
+		saved, err := kv.client.KV.Set(alertRulesKey, updated, pluginapi.SetAtomic(data))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to save alert rules")
+		}
+		if saved {
+			return rules, nil
+		}
+	}
+
+	return nil, errors.New("failed to save alert rules: too many concurrent updates")
+}
+
+// GetAlertRules retrieves the alert rules
+func (kv Client) GetAlertRules() ([]*AlertRule, error) {
+	var rules []*AlertRule
+	if err := kv.client.KV.Get(alertRulesKey, &rules); err != nil {
+		return nil, errors.Wrap(err, "failed to get alert rules")
+	}
+	return rules, nil
+}
+
+// IncrementAlertRuleEventCount counts an event of an alert rule in the current window, and
+// returns how many events the rule has sent in it. Counts are shared by every node of the
+// cluster.
+func (kv Client) IncrementAlertRuleEventCount(ruleID string, window time.Duration, now time.Time) (int, error) {
+	key := alertRuleEventPrefix + ruleID + "_" + strconv.FormatInt(now.Unix()/int64(window/time.Second), 10)
+
+	for attempt := 0; attempt < maxAlertRuleCountAttempts; attempt++ {
+		var count int
+		if err := kv.client.KV.Get(key, &count); err != nil {
+			return 0, errors.Wrap(err, "failed to get alert rule event count")
+		}
+
+		var oldValue interface{}
+		if count > 0 {
+			oldValue = count
+		}
+
+		saved, err := kv.client.KV.Set(key, count+1, pluginapi.SetAtomic(oldValue), pluginapi.SetExpiry(2*window))
+		if err != nil {
+			return 0, errors.Wrap(err, "failed to save alert rule event count")
+		}
+		if saved {
+			return count + 1, nil
+		}
+	}
+
+	return 0, errors.New("failed to increment alert rule event count: too many concurrent updates")
+}
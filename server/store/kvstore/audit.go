@@ -0,0 +1,154 @@
+package kvstore
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/pkg/errors"
+)
+
+const (
+	auditRecordPrefix          = "audit_"
+	auditDayPrefix             = "audit_day_"
+	auditDayFormat             = "20060102"
+	configurationAuditedPrefix = "configuration_audited_"
+
+	// auditDayExpiryMargin keeps the index of the records of a day until every record of the
+	// day expired.
+	auditDayExpiryMargin = 48 * time.Hour
+)
+
+// AuditRecord is an entry of the audit trail of the write actions performed through the plugin.
+type AuditRecord struct {
+	ID string `json:"id"`
+
+	// UserID is the Mattermost user who performed the action, empty for actions of the system
+	// such as configuration changes.
+	UserID   string `json:"user_id,omitempty"`
+	Username string `json:"username,omitempty"`
+
+	// PagerDutyUserID is the PagerDuty user the Mattermost user is mapped to, if any.
+	PagerDutyUserID    string `json:"pagerduty_user_id,omitempty"`
+	PagerDutyUserEmail string `json:"pagerduty_user_email,omitempty"`
+
+	// Account is the PagerDuty account the action was performed on, empty for the default
+	// account.
+	Account string `json:"account,omitempty"`
+
+	Action string `json:"action"`
+	Target string `json:"target"`
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+
+	// Details describe the action further, such as the settings a configuration change changed.
+	Details string `json:"details,omitempty"`
+
+	CreateAt int64 `json:"create_at"`
+}
+
+// auditRecordKey orders the keys of records by the time they were created.
+func auditRecordKey(record *AuditRecord) string {
+	return fmt.Sprintf("%s%013d_%s", auditRecordPrefix, record.CreateAt, record.ID)
+}
+
+// auditDayKey is the key of the index of the records created on the UTC day of createAt, in
+// milliseconds, so that records can be listed by time without listing every key.
+func auditDayKey(createAt int64) string {
+	return auditDayPrefix + time.UnixMilli(createAt).UTC().Format(auditDayFormat)
+}
+
+// AppendAuditRecord adds a record to the audit trail, kept for retention. Records are never
+// overwritten. The record is added to the index of its day before it is saved, so that a saved
+// record is always listed; an index entry whose record was never saved is skipped.
+func (kv Client) AppendAuditRecord(record *AuditRecord, retention time.Duration) error {
+	key := auditRecordKey(record)
+	if err := kv.addAuditRecordToDay(record.CreateAt, key, retention); err != nil {
+		return errors.Wrap(err, "failed to index audit record")
+	}
+
+	saved, err := kv.client.KV.Set(key, record, pluginapi.SetAtomic(nil), pluginapi.SetExpiry(retention))
+	if err != nil {
+		return errors.Wrap(err, "failed to save audit record")
+	}
+	if !saved {
+		return errors.Errorf("failed to save audit record: record %s already exists", record.ID)
+	}
+	return nil
+}
+
+// addAuditRecordToDay adds the key of a record to the index of its day, which outlives the
+// records of the day.
+func (kv Client) addAuditRecordToDay(createAt int64, key string, retention time.Duration) error {
+	return kv.updateIndex(auditDayKey(createAt), retention+auditDayExpiryMargin, func(keys []string) ([]string, bool) {
+		i, found := slices.BinarySearch(keys, key)
+		if found {
+			return keys, false
+		}
+		return slices.Insert(keys, i, key), true
+	})
+}
+
+// MarkConfigurationChangeAudited records that the configuration change identified by hash was
+// recorded in the audit trail. It returns false if it was already recorded, as every node of
+// the cluster is notified of each change.
+func (kv Client) MarkConfigurationChangeAudited(hash string, ttl time.Duration) (bool, error) {
+	saved, err := kv.client.KV.Set(configurationAuditedPrefix+hash, true, pluginapi.SetAtomic(nil), pluginapi.SetExpiry(ttl))
+	if err != nil {
+		return false, errors.Wrap(err, "failed to mark configuration change audited")
+	}
+	return saved, nil
+}
+
+// ListAuditRecords retrieves up to limit audit records created between since and until, in
+// milliseconds, that match, newest first. The days are read newest first and reading stops once
+// limit records matched; a limit that is not positive lists every matching record.
+func (kv Client) ListAuditRecords(since, until int64, limit int, match func(*AuditRecord) bool) ([]*AuditRecord, error) {
+	var records []*AuditRecord
+	first := time.UnixMilli(since).UTC().Truncate(24 * time.Hour)
+	for day := time.UnixMilli(until).UTC().Truncate(24 * time.Hour); !day.Before(first); day = day.Add(-24 * time.Hour) {
+		var keys []string
+		if err := kv.client.KV.Get(auditDayKey(day.UnixMilli()), &keys); err != nil {
+			return nil, errors.Wrap(err, "failed to list audit records")
+		}
+
+		for i := len(keys) - 1; i >= 0; i-- {
+			createAt, ok := auditRecordCreateAt(keys[i])
+			if !ok || createAt < since || createAt > until {
+				continue
+			}
+
+			var record *AuditRecord
+			if err := kv.client.KV.Get(keys[i], &record); err != nil {
+				return nil, errors.Wrapf(err, "failed to get audit record %s", keys[i])
+			}
+			// Records expire before the index of their day, and a record whose save failed
+			// after it was indexed was never stored.
+			if record == nil || !match(record) {
+				continue
+			}
+
+			records = append(records, record)
+			if len(records) == limit {
+				return records, nil
+			}
+		}
+	}
+	return records, nil
+}
+
+// auditRecordCreateAt parses the time a record was created from its key.
+func auditRecordCreateAt(key string) (int64, bool) {
+	millis, _, found := strings.Cut(strings.TrimPrefix(key, auditRecordPrefix), "_")
+	if !found || len(millis) != 13 {
+		return 0, false
+	}
+	createAt, err := strconv.ParseInt(millis, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return createAt, true
+}
@@ -0,0 +1,49 @@
+package kvstore
+
+import (
+	"github.com/pkg/errors"
+)
+
+const changeEventRulesPrefix = "change_event_rules_"
+
+// ChangeEventRule turns the messages of a channel that match a pattern into PagerDuty change
+// events on a service. The routing key of the service is looked up when a message matches, so
+// that it is never exposed to channel members. The rules of a channel are stored together, so that a single read finds
+// them for every message posted.
+type ChangeEventRule struct {
+	ID          string `json:"id"`
+	ChannelID   string `json:"channel_id"`
+	Pattern     string `json:"pattern"`
+	ServiceID   string `json:"service_id"`
+	ServiceName string `json:"service_name,omitempty"`
+	CreatorID   string `json:"creator_id"`
+	CreateAt    int64  `json:"create_at"`
+
+	// Account is the PagerDuty account of the rule, empty for the default account.
+	Account string `json:"account,omitempty"`
+}
+
+// SaveChangeEventRules replaces the change event rules of a channel, deleting them if there are
+// none left
+func (kv Client) SaveChangeEventRules(channelID string, rules []*ChangeEventRule) error {
+	if len(rules) == 0 {
+		if err := kv.client.KV.Delete(changeEventRulesPrefix + channelID); err != nil {
+			return errors.Wrap(err, "failed to delete change event rules")
+		}
+		return nil
+	}
+
+	if _, err := kv.client.KV.Set(changeEventRulesPrefix+channelID, rules); err != nil {
+		return errors.Wrap(err, "failed to save change event rules")
+	}
+	return nil
+}
+
+// GetChangeEventRules retrieves the change event rules of a channel
+func (kv Client) GetChangeEventRules(channelID string) ([]*ChangeEventRule, error) {
+	var rules []*ChangeEventRule
+	if err := kv.client.KV.Get(changeEventRulesPrefix+channelID, &rules); err != nil {
+		return nil, errors.Wrap(err, "failed to get change event rules")
+	}
+	return rules, nil
+}
@@ -0,0 +1,80 @@
+package kvstore
+
+import (
+	"github.com/pkg/errors"
+)
+
+const channelOnCallPrefix = "channel_oncall_"
+
+const (
+	// ChannelOnCallModeHeader shows the on-calls in a fragment of the channel header
+	ChannelOnCallModeHeader = "header"
+
+	// ChannelOnCallModePost shows the on-calls in a post pinned to the channel
+	ChannelOnCallModePost = "post"
+)
+
+// ChannelOnCall keeps the current on-calls of a set of schedules or escalation policies
+// visible in a channel, either in its header or in a pinned post. A channel has at most one.
+type ChannelOnCall struct {
+	ChannelID           string   `json:"channel_id"`
+	Mode                string   `json:"mode"`
+	ScheduleIDs         []string `json:"schedule_ids,omitempty"`
+	EscalationPolicyIDs []string `json:"escalation_policy_ids,omitempty"`
+	CreatorID           string   `json:"creator_id"`
+	CreateAt            int64    `json:"create_at"`
+
+	// Fragment is the text last written to the channel, used to find and replace it.
+	Fragment     string `json:"fragment,omitempty"`
+	PostID       string `json:"post_id,omitempty"`
+	LastUpdateAt int64  `json:"last_update_at,omitempty"`
+	NextUpdateAt int64  `json:"next_update_at,omitempty"`
+
+	// Account is the PagerDuty account of the on-call display, empty for the default account.
+	Account string `json:"account,omitempty"`
+}
+
+// SaveChannelOnCall creates or updates the on-call display of a channel
+func (kv Client) SaveChannelOnCall(channelOnCall *ChannelOnCall) error {
+	if err := kv.setIndexed(channelOnCallPrefix, channelOnCallPrefix+channelOnCall.ChannelID, channelOnCall); err != nil {
+		return errors.Wrap(err, "failed to save channel on-call")
+	}
+	return nil
+}
+
+// GetChannelOnCall retrieves the on-call display of a channel, returning nil if it does not exist
+func (kv Client) GetChannelOnCall(channelID string) (*ChannelOnCall, error) {
+	var channelOnCall *ChannelOnCall
+	if err := kv.client.KV.Get(channelOnCallPrefix+channelID, &channelOnCall); err != nil {
+		return nil, errors.Wrap(err, "failed to get channel on-call")
+	}
+	return channelOnCall, nil
+}
+
+// DeleteChannelOnCall removes the on-call display of a channel
+func (kv Client) DeleteChannelOnCall(channelID string) error {
+	if err := kv.deleteIndexed(channelOnCallPrefix, channelOnCallPrefix+channelID); err != nil {
+		return errors.Wrap(err, "failed to delete channel on-call")
+	}
+	return nil
+}
+
+// ListChannelOnCalls retrieves the on-call displays of all channels
+func (kv Client) ListChannelOnCalls() ([]*ChannelOnCall, error) {
+	keys, err := kv.listIndexedKeys(channelOnCallPrefix)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list channel on-calls")
+	}
+
+	channelOnCalls := make([]*ChannelOnCall, 0, len(keys))
+	for _, key := range keys {
+		var channelOnCall *ChannelOnCall
+		if err := kv.client.KV.Get(key, &channelOnCall); err != nil {
+			return nil, errors.Wrapf(err, "failed to get channel on-call %s", key)
+		}
+		if channelOnCall != nil {
+			channelOnCalls = append(channelOnCalls, channelOnCall)
+		}
+	}
+	return channelOnCalls, nil
+}
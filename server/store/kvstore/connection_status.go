@@ -0,0 +1,40 @@
+package kvstore
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const connectionStatusesKey = "connection_statuses"
+
+// ConnectionStatus is the outcome of checking the connection to a PagerDuty account when the
+// configuration changed. Statuses are shared by every node of the cluster, so that admins see
+// the same outcome whichever node serves them.
+type ConnectionStatus struct {
+	Account    string    `json:"account"`
+	APIBaseURL string    `json:"api_base_url"`
+	Connected  bool      `json:"connected"`
+	Access     string    `json:"access,omitempty"`
+	Abilities  []string  `json:"abilities,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+// SaveConnectionStatuses replaces the connection statuses of every account
+func (kv Client) SaveConnectionStatuses(statuses []*ConnectionStatus) error {
+	if _, err := kv.client.KV.Set(connectionStatusesKey, statuses); err != nil {
+		return errors.Wrap(err, "failed to save connection statuses")
+	}
+	return nil
+}
+
+// GetConnectionStatuses retrieves the connection statuses of every account, returning nil if
+// they were never checked
+func (kv Client) GetConnectionStatuses() ([]*ConnectionStatus, error) {
+	var statuses []*ConnectionStatus
+	if err := kv.client.KV.Get(connectionStatusesKey, &statuses); err != nil {
+		return nil, errors.Wrap(err, "failed to get connection statuses")
+	}
+	return statuses, nil
+}
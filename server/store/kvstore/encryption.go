@@ -0,0 +1,317 @@
+package kvstore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/pkg/errors"
+)
+
+const (
+	// EncryptionKeySize is the size of AES-256 keys.
+	EncryptionKeySize = 32
+
+	// previousEncryptionKeysKey stores the keys secrets may still be encrypted with while they
+	// are re-encrypted with a new key, encrypted with the new key.
+	previousEncryptionKeysKey = "encryption_previous_keys"
+)
+
+// secretKeyPrefixes are the prefixes of the keys of records that are encrypted at rest. Records
+// stored under them are re-encrypted by EncryptSecrets when the key changes, and migrated if
+// they were stored before encryption existed.
+var secretKeyPrefixes = []string{
+	botAccessTokenKey,
+	previousEncryptionKeysKey,
+}
+
+// ErrUnknownEncryptionKey is returned for records encrypted with a key the store no longer has,
+// such as after the key was replaced while the plugin was not running.
+var ErrUnknownEncryptionKey = errors.New("record is encrypted with an unknown key")
+
+// encryptedRecord is how encrypted records are stored. Records stored before encryption existed
+// are the plain JSON of the value, which has no key ID.
+type encryptedRecord struct {
+	KeyID      string `json:"key_id"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// Encryptor seals values with AES-GCM using its current key, and opens values sealed with the
+// current key or any of the previous keys it was given.
+type Encryptor struct {
+	currentKeyID string
+	ciphers      map[string]cipher.AEAD
+}
+
+// DeriveEncryptionKey derives an AES-256 key from a secret configured by the admin, which may be
+// any string.
+func DeriveEncryptionKey(secret string) []byte {
+	key := sha256.Sum256([]byte(secret))
+	return key[:]
+}
+
+// encryptionKeyID identifies a key in encrypted records without revealing it.
+func encryptionKeyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
+
+// NewEncryptor returns an Encryptor that seals values with key, and can still open values sealed
+// with the previous keys.
+func NewEncryptor(key []byte, previousKeys ...[]byte) (*Encryptor, error) {
+	e := &Encryptor{
+		currentKeyID: encryptionKeyID(key),
+		ciphers:      map[string]cipher.AEAD{},
+	}
+
+	for _, k := range append([][]byte{key}, previousKeys...) {
+		if len(k) != EncryptionKeySize {
+			return nil, errors.Errorf("encryption keys must be %d bytes", EncryptionKeySize)
+		}
+
+		block, err := aes.NewCipher(k)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create cipher")
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create GCM cipher")
+		}
+		e.ciphers[encryptionKeyID(k)] = aead
+	}
+	return e, nil
+}
+
+// Seal encrypts a value with the current key. The additional data, such as the key the record
+// is stored under, is authenticated but not encrypted, so that a record cannot be moved to
+// another key.
+func (e *Encryptor) Seal(plaintext, additionalData []byte) ([]byte, error) {
+	aead := e.ciphers[e.currentKeyID]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errors.Wrap(err, "failed to generate nonce")
+	}
+
+	return json.Marshal(&encryptedRecord{
+		KeyID:      e.currentKeyID,
+		Nonce:      nonce,
+		Ciphertext: aead.Seal(nil, nonce, plaintext, additionalData),
+	})
+}
+
+// Open decrypts a value sealed with the current key or one of the previous keys. It also
+// reports whether the value needs to be sealed again with the current key, because it was
+// sealed with a previous key or stored before encryption existed, in which case it is returned
+// as is.
+func (e *Encryptor) Open(data, additionalData []byte) ([]byte, bool, error) {
+	var record encryptedRecord
+	if err := json.Unmarshal(data, &record); err != nil || record.KeyID == "" {
+		return data, true, nil
+	}
+
+	aead, ok := e.ciphers[record.KeyID]
+	if !ok {
+		return nil, false, ErrUnknownEncryptionKey
+	}
+	if len(record.Nonce) != aead.NonceSize() {
+		return nil, false, errors.New("encrypted record has an invalid nonce")
+	}
+
+	plaintext, err := aead.Open(nil, record.Nonce, record.Ciphertext, additionalData)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "failed to decrypt record")
+	}
+	return plaintext, record.KeyID != e.currentKeyID, nil
+}
+
+// encryption holds the Encryptor of a store, which changes when the key is rotated. It is shared
+// by the copies of a Client.
+type encryption struct {
+	lock      sync.RWMutex
+	encryptor *Encryptor
+}
+
+func (kv Client) getEncryptor() (*Encryptor, error) {
+	kv.encryption.lock.RLock()
+	defer kv.encryption.lock.RUnlock()
+	if kv.encryption.encryptor == nil {
+		return nil, errors.New("no encryption key is set")
+	}
+	return kv.encryption.encryptor, nil
+}
+
+// previousEncryptionKey is a key secrets may still be encrypted with.
+type previousEncryptionKey struct {
+	ID  string `json:"id"`
+	Key []byte `json:"key"`
+}
+
+// GetPreviousEncryptionKeys returns the keys secrets may still be encrypted with, as stored by
+// SavePreviousEncryptionKeys. It returns ErrUnknownEncryptionKey if they were stored under a key
+// the store does not have.
+func (kv Client) GetPreviousEncryptionKeys() ([][]byte, error) {
+	var stored []previousEncryptionKey
+	if err := kv.getSecret(previousEncryptionKeysKey, &stored); err != nil {
+		return nil, errors.Wrap(err, "failed to get previous encryption keys")
+	}
+
+	keys := make([][]byte, 0, len(stored))
+	for _, key := range stored {
+		if encryptionKeyID(key.Key) != key.ID {
+			return nil, errors.Errorf("previous encryption key %s is corrupted", key.ID)
+		}
+		keys = append(keys, key.Key)
+	}
+	return keys, nil
+}
+
+// SavePreviousEncryptionKeys stores the keys secrets may still be encrypted with until
+// EncryptSecrets re-encrypts them, encrypted with the current key, so that a node that stops
+// meanwhile can finish the migration.
+func (kv Client) SavePreviousEncryptionKeys(keys [][]byte) error {
+	stored := make([]previousEncryptionKey, 0, len(keys))
+	for _, key := range keys {
+		stored = append(stored, previousEncryptionKey{ID: encryptionKeyID(key), Key: key})
+	}
+	if err := kv.setSecret(previousEncryptionKeysKey, stored); err != nil {
+		return errors.Wrap(err, "failed to save previous encryption keys")
+	}
+	return nil
+}
+
+// DeletePreviousEncryptionKeys removes the previous keys once every secret is re-encrypted.
+func (kv Client) DeletePreviousEncryptionKeys() error {
+	if err := kv.client.KV.Delete(previousEncryptionKeysKey); err != nil {
+		return errors.Wrap(err, "failed to delete previous encryption keys")
+	}
+	return nil
+}
+
+// SetEncryptionKeys sets the key secrets are encrypted with, and the previous keys they may
+// still be encrypted with until EncryptSecrets re-encrypts them.
+func (kv Client) SetEncryptionKeys(key []byte, previousKeys ...[]byte) error {
+	encryptor, err := NewEncryptor(key, previousKeys...)
+	if err != nil {
+		return err
+	}
+
+	kv.encryption.lock.Lock()
+	defer kv.encryption.lock.Unlock()
+	kv.encryption.encryptor = encryptor
+	return nil
+}
+
+// EncryptSecrets encrypts the secrets stored before encryption existed and re-encrypts those
+// encrypted with a previous key, returning how many records it changed. Records that changed
+// meanwhile are left to the node that changed them, and records encrypted with an unknown key
+// are left as they are.
+func (kv Client) EncryptSecrets() (int, error) {
+	encryptor, err := kv.getEncryptor()
+	if err != nil {
+		return 0, err
+	}
+
+	keys, err := kv.listKeysMatching(isSecretKey)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to list secrets")
+	}
+
+	changed := 0
+	for _, key := range keys {
+		var data []byte
+		if err := kv.client.KV.Get(key, &data); err != nil {
+			return changed, errors.Wrapf(err, "failed to get secret %s", key)
+		}
+		if len(data) == 0 {
+			continue
+		}
+
+		plaintext, stale, err := encryptor.Open(data, []byte(key))
+		if errors.Is(err, ErrUnknownEncryptionKey) {
+			// The secret cannot be recovered, and is replaced when it is next needed.
+			continue
+		} else if err != nil {
+			return changed, errors.Wrapf(err, "failed to decrypt secret %s", key)
+		}
+		if !stale {
+			continue
+		}
+
+		sealed, err := encryptor.Seal(plaintext, []byte(key))
+		if err != nil {
+			return changed, errors.Wrapf(err, "failed to encrypt secret %s", key)
+		}
+		saved, err := kv.client.KV.Set(key, sealed, pluginapi.SetAtomic(data))
+		if err != nil {
+			return changed, errors.Wrapf(err, "failed to save secret %s", key)
+		}
+		if saved {
+			changed++
+		}
+	}
+	return changed, nil
+}
+
+// setSecret stores a value encrypted under a key, which must start with one of the
+// secretKeyPrefixes.
+func (kv Client) setSecret(key string, value interface{}) error {
+	if !isSecretKey(key) {
+		return errors.Errorf("key %s is not registered as a secret", key)
+	}
+
+	encryptor, err := kv.getEncryptor()
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := json.Marshal(value)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal secret")
+	}
+	sealed, err := encryptor.Seal(plaintext, []byte(key))
+	if err != nil {
+		return err
+	}
+
+	_, err = kv.client.KV.Set(key, sealed)
+	return err
+}
+
+// getSecret retrieves a value stored with setSecret, or before encryption existed, into out. It
+// leaves out unchanged if there is no value.
+func (kv Client) getSecret(key string, out interface{}) error {
+	encryptor, err := kv.getEncryptor()
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	if err := kv.client.KV.Get(key, &data); err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	plaintext, _, err := encryptor.Open(data, []byte(key))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(plaintext, out)
+}
+
+func isSecretKey(key string) bool {
+	for _, prefix := range secretKeyPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package kvstore
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptor(t *testing.T) {
+	oldKey := DeriveEncryptionKey("old secret")
+	newKey := DeriveEncryptionKey("new secret")
+
+	t.Run("round trip", func(t *testing.T) {
+		encryptor, err := NewEncryptor(newKey)
+		require.NoError(t, err)
+
+		sealed, err := encryptor.Seal([]byte(`{"token":"secret"}`), []byte("bot_access_token"))
+		require.NoError(t, err)
+		assert.False(t, bytes.Contains(sealed, []byte("secret")))
+
+		plaintext, stale, err := encryptor.Open(sealed, []byte("bot_access_token"))
+		require.NoError(t, err)
+		assert.False(t, stale)
+		assert.Equal(t, `{"token":"secret"}`, string(plaintext))
+
+		_, _, err = encryptor.Open(sealed, []byte("other_key"))
+		assert.Error(t, err)
+	})
+
+	t.Run("previous key", func(t *testing.T) {
+		oldEncryptor, err := NewEncryptor(oldKey)
+		require.NoError(t, err)
+		sealed, err := oldEncryptor.Seal([]byte("value"), nil)
+		require.NoError(t, err)
+
+		encryptor, err := NewEncryptor(newKey, oldKey)
+		require.NoError(t, err)
+		plaintext, stale, err := encryptor.Open(sealed, nil)
+		require.NoError(t, err)
+		assert.True(t, stale)
+		assert.Equal(t, "value", string(plaintext))
+
+		encryptor, err = NewEncryptor(newKey)
+		require.NoError(t, err)
+		_, _, err = encryptor.Open(sealed, nil)
+		assert.ErrorIs(t, err, ErrUnknownEncryptionKey)
+	})
+
+	t.Run("stored before encryption", func(t *testing.T) {
+		encryptor, err := NewEncryptor(newKey)
+		require.NoError(t, err)
+
+		plaintext, stale, err := encryptor.Open([]byte(`{"id":"token-id","token":"secret"}`), nil)
+		require.NoError(t, err)
+		assert.True(t, stale)
+		assert.Equal(t, `{"id":"token-id","token":"secret"}`, string(plaintext))
+	})
+
+	t.Run("invalid key", func(t *testing.T) {
+		_, err := NewEncryptor([]byte("short"))
+		assert.Error(t, err)
+	})
+}
+
+func TestClient_secrets(t *testing.T) {
+	client, kv := newTestKVStore(t)
+	client.encryption = &encryption{}
+	key := DeriveEncryptionKey("secret")
+	require.NoError(t, client.SetEncryptionKeys(key))
+
+	t.Run("previous keys are kept encrypted", func(t *testing.T) {
+		previousKey := DeriveEncryptionKey("previous secret")
+		require.NoError(t, client.SavePreviousEncryptionKeys([][]byte{previousKey}))
+		assert.False(t, bytes.Contains(kv.values[previousEncryptionKeysKey], previousKey))
+
+		keys, err := client.GetPreviousEncryptionKeys()
+		require.NoError(t, err)
+		assert.Equal(t, [][]byte{previousKey}, keys)
+
+		require.NoError(t, client.SetEncryptionKeys(DeriveEncryptionKey("other secret")))
+		_, err = client.GetPreviousEncryptionKeys()
+		assert.ErrorIs(t, err, ErrUnknownEncryptionKey)
+
+		require.NoError(t, client.DeletePreviousEncryptionKeys())
+		keys, err = client.GetPreviousEncryptionKeys()
+		require.NoError(t, err)
+		assert.Empty(t, keys)
+	})
+}
+
+func TestClient_EncryptSecrets(t *testing.T) {
+	client, kv := newTestKVStore(t)
+	client.encryption = &encryption{}
+	require.NoError(t, client.SetEncryptionKeys(DeriveEncryptionKey("secret")))
+
+	// A secret stored before encryption existed, and a record that is not a secret.
+	kv.values[botAccessTokenKey] = []byte(`{"id":"token-id","token":"secret-token"}`)
+	kv.values[rosterPrefix+"roster1"] = []byte(`{"id":"roster1"}`)
+
+	encrypted, err := client.EncryptSecrets()
+	require.NoError(t, err)
+	assert.Equal(t, 1, encrypted)
+	assert.Equal(t, 1, kv.listed, "secrets must be found with a single scan of the keys")
+	assert.NotContains(t, string(kv.values[botAccessTokenKey]), "secret-token")
+	assert.Equal(t, `{"id":"roster1"}`, string(kv.values[rosterPrefix+"roster1"]))
+
+	token, err := client.GetBotAccessToken()
+	require.NoError(t, err)
+	assert.Equal(t, "secret-token", token.Token)
+}
@@ -0,0 +1,138 @@
+package kvstore
+
+import (
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/pkg/errors"
+)
+
+const (
+	groupSyncPrefix   = "group_sync_"
+	botAccessTokenKey = "bot_access_token"
+
+	// groupSyncRequestedAtKey records when a sync of every group was last requested. It is kept
+	// apart from the group syncs so that requests never overwrite the outcome of a sync.
+	groupSyncRequestedAtKey = "requested_group_sync_at"
+)
+
+// GroupSync keeps the members of a Mattermost custom group in sync with the users currently
+// on call for a set of schedules or escalation policies.
+type GroupSync struct {
+	ID                  string   `json:"id"`
+	GroupName           string   `json:"group_name"`
+	GroupID             string   `json:"group_id,omitempty"`
+	ScheduleIDs         []string `json:"schedule_ids,omitempty"`
+	EscalationPolicyIDs []string `json:"escalation_policy_ids,omitempty"`
+	CreatorID           string   `json:"creator_id"`
+	CreateAt            int64    `json:"create_at"`
+	LastSyncAt          int64    `json:"last_sync_at,omitempty"`
+	NextSyncAt          int64    `json:"next_sync_at,omitempty"`
+
+	// Account is the PagerDuty account of the group sync, empty for the default account.
+	Account string `json:"account,omitempty"`
+}
+
+// BotAccessToken is an access token of the plugin's bot user, used for operations that are
+// only available through the Mattermost REST API.
+type BotAccessToken struct {
+	ID    string `json:"id"`
+	Token string `json:"token"`
+}
+
+// SaveGroupSync creates or updates a group sync
+func (kv Client) SaveGroupSync(groupSync *GroupSync) error {
+	if err := kv.setIndexed(groupSyncPrefix, groupSyncPrefix+groupSync.ID, groupSync); err != nil {
+		return errors.Wrap(err, "failed to save group sync")
+	}
+	return nil
+}
+
+// UpdateGroupSync replaces a group sync only if it has not changed since it was read as old,
+// reporting whether it was replaced. A group sync deleted meanwhile is not recreated.
+func (kv Client) UpdateGroupSync(old, groupSync *GroupSync) (bool, error) {
+	saved, err := kv.client.KV.Set(groupSyncPrefix+groupSync.ID, groupSync, pluginapi.SetAtomic(old))
+	if err != nil {
+		return false, errors.Wrap(err, "failed to update group sync")
+	}
+	return saved, nil
+}
+
+// GetGroupSync retrieves a group sync by ID, returning nil if it does not exist
+func (kv Client) GetGroupSync(id string) (*GroupSync, error) {
+	var groupSync *GroupSync
+	if err := kv.client.KV.Get(groupSyncPrefix+id, &groupSync); err != nil {
+		return nil, errors.Wrap(err, "failed to get group sync")
+	}
+	return groupSync, nil
+}
+
+// DeleteGroupSync removes a group sync
+func (kv Client) DeleteGroupSync(id string) error {
+	if err := kv.deleteIndexed(groupSyncPrefix, groupSyncPrefix+id); err != nil {
+		return errors.Wrap(err, "failed to delete group sync")
+	}
+	return nil
+}
+
+// ListGroupSyncs retrieves all group syncs
+func (kv Client) ListGroupSyncs() ([]*GroupSync, error) {
+	keys, err := kv.listIndexedKeys(groupSyncPrefix)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list group syncs")
+	}
+
+	groupSyncs := make([]*GroupSync, 0, len(keys))
+	for _, key := range keys {
+		var groupSync *GroupSync
+		if err := kv.client.KV.Get(key, &groupSync); err != nil {
+			return nil, errors.Wrapf(err, "failed to get group sync %s", key)
+		}
+		if groupSync != nil {
+			groupSyncs = append(groupSyncs, groupSync)
+		}
+	}
+	return groupSyncs, nil
+}
+
+// RequestGroupSync records that every group should be synced because of a change at the given
+// time, in milliseconds.
+func (kv Client) RequestGroupSync(at int64) error {
+	if _, err := kv.client.KV.Set(groupSyncRequestedAtKey, at); err != nil {
+		return errors.Wrap(err, "failed to request group sync")
+	}
+	return nil
+}
+
+// GetGroupSyncRequestedAt returns when a sync of every group was last requested, in
+// milliseconds, or 0 if it never was.
+func (kv Client) GetGroupSyncRequestedAt() (int64, error) {
+	var at int64
+	if err := kv.client.KV.Get(groupSyncRequestedAtKey, &at); err != nil {
+		return 0, errors.Wrap(err, "failed to get group sync request")
+	}
+	return at, nil
+}
+
+// GetBotAccessToken retrieves the stored bot access token, returning nil if there is none
+func (kv Client) GetBotAccessToken() (*BotAccessToken, error) {
+	var token *BotAccessToken
+	if err := kv.getSecret(botAccessTokenKey, &token); err != nil {
+		return nil, errors.Wrap(err, "failed to get bot access token")
+	}
+	return token, nil
+}
+
+// SetBotAccessToken stores the bot access token, encrypted
+func (kv Client) SetBotAccessToken(token *BotAccessToken) error {
+	if err := kv.setSecret(botAccessTokenKey, token); err != nil {
+		return errors.Wrap(err, "failed to save bot access token")
+	}
+	return nil
+}
+
+// DeleteBotAccessToken removes the stored bot access token
+func (kv Client) DeleteBotAccessToken() error {
+	if err := kv.client.KV.Delete(botAccessTokenKey); err != nil {
+		return errors.Wrap(err, "failed to delete bot access token")
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package kvstore
+
+import (
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/pkg/errors"
+)
+
+const handoffNotifiedPrefix = "handoff_notified_"
+
+// MarkHandoffNotified records that the handoff notification identified by key has been sent.
+// It returns false if the notification was already recorded, so that each notification is
+// delivered only once even if the job runs on several nodes or is retried.
+func (kv Client) MarkHandoffNotified(key string, ttl time.Duration) (bool, error) {
+	saved, err := kv.client.KV.Set(handoffNotifiedPrefix+key, true, pluginapi.SetAtomic(nil), pluginapi.SetExpiry(ttl))
+	if err != nil {
+		return false, errors.Wrap(err, "failed to mark handoff notification")
+	}
+	return saved, nil
+}
@@ -0,0 +1,69 @@
+package kvstore
+
+import (
+	"github.com/pkg/errors"
+)
+
+const impactPostPrefix = "impact_post_"
+
+// ImpactPost is a recurring post of the impact status of business services, or of a status
+// dashboard, to a channel.
+type ImpactPost struct {
+	ID                 string   `json:"id"`
+	ChannelID          string   `json:"channel_id"`
+	CronExpression     string   `json:"cron_expression"`
+	TimeZone           string   `json:"time_zone"`
+	BusinessServiceIDs []string `json:"business_service_ids,omitempty"`
+	StatusDashboardID  string   `json:"status_dashboard_id,omitempty"`
+	CreatorID          string   `json:"creator_id"`
+	CreateAt           int64    `json:"create_at"`
+	LastPostAt         int64    `json:"last_post_at,omitempty"`
+
+	// Account is the PagerDuty account of the impact post, empty for the default account.
+	Account string `json:"account,omitempty"`
+}
+
+// SaveImpactPost creates or updates an impact post
+func (kv Client) SaveImpactPost(post *ImpactPost) error {
+	if err := kv.setIndexed(impactPostPrefix, impactPostPrefix+post.ID, post); err != nil {
+		return errors.Wrap(err, "failed to save impact post")
+	}
+	return nil
+}
+
+// GetImpactPost retrieves an impact post by ID, returning nil if it does not exist
+func (kv Client) GetImpactPost(id string) (*ImpactPost, error) {
+	var post *ImpactPost
+	if err := kv.client.KV.Get(impactPostPrefix+id, &post); err != nil {
+		return nil, errors.Wrap(err, "failed to get impact post")
+	}
+	return post, nil
+}
+
+// DeleteImpactPost removes an impact post
+func (kv Client) DeleteImpactPost(id string) error {
+	if err := kv.deleteIndexed(impactPostPrefix, impactPostPrefix+id); err != nil {
+		return errors.Wrap(err, "failed to delete impact post")
+	}
+	return nil
+}
+
+// ListImpactPosts retrieves all impact posts
+func (kv Client) ListImpactPosts() ([]*ImpactPost, error) {
+	keys, err := kv.listIndexedKeys(impactPostPrefix)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list impact posts")
+	}
+
+	posts := make([]*ImpactPost, 0, len(keys))
+	for _, key := range keys {
+		var post *ImpactPost
+		if err := kv.client.KV.Get(key, &post); err != nil {
+			return nil, errors.Wrapf(err, "failed to get impact post %s", key)
+		}
+		if post != nil {
+			posts = append(posts, post)
+		}
+	}
+	return posts, nil
+}
@@ -0,0 +1,56 @@
+package kvstore
+
+import (
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/pkg/errors"
+)
+
+const incidentRequestPrefix = "incident_request_"
+
+// IncidentRequest remembers a request to create an incident by its idempotency key, so that
+// repeating the request returns the incident it created instead of creating another one.
+type IncidentRequest struct {
+	// Response is the PagerDuty response of the created incident, empty while it is being
+	// created.
+	Response []byte `json:"response,omitempty"`
+	CreateAt int64  `json:"create_at"`
+}
+
+// ReserveIncidentRequest records that an incident is being created for an idempotency key, and
+// reports whether it did, which it does not if the key is already in use. The reservation
+// expires after ttl.
+func (kv Client) ReserveIncidentRequest(key string, request *IncidentRequest, ttl time.Duration) (bool, error) {
+	saved, err := kv.client.KV.Set(incidentRequestPrefix+key, request, pluginapi.SetAtomic(nil), pluginapi.SetExpiry(ttl))
+	if err != nil {
+		return false, errors.Wrap(err, "failed to reserve incident request")
+	}
+	return saved, nil
+}
+
+// SaveIncidentRequest records the incident created for an idempotency key, which is remembered
+// for ttl
+func (kv Client) SaveIncidentRequest(key string, request *IncidentRequest, ttl time.Duration) error {
+	if _, err := kv.client.KV.Set(incidentRequestPrefix+key, request, pluginapi.SetExpiry(ttl)); err != nil {
+		return errors.Wrap(err, "failed to save incident request")
+	}
+	return nil
+}
+
+// GetIncidentRequest retrieves the request for an idempotency key, returning nil if there is none
+func (kv Client) GetIncidentRequest(key string) (*IncidentRequest, error) {
+	var request *IncidentRequest
+	if err := kv.client.KV.Get(incidentRequestPrefix+key, &request); err != nil {
+		return nil, errors.Wrap(err, "failed to get incident request")
+	}
+	return request, nil
+}
+
+// DeleteIncidentRequest releases an idempotency key, so that a failed request can be retried
+func (kv Client) DeleteIncidentRequest(key string) error {
+	if err := kv.client.KV.Delete(incidentRequestPrefix + key); err != nil {
+		return errors.Wrap(err, "failed to delete incident request")
+	}
+	return nil
+}
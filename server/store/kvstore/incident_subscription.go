@@ -0,0 +1,95 @@
+package kvstore
+
+import (
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/pkg/errors"
+)
+
+const (
+	incidentSubscriptionPrefix = "incident_subscription_"
+	statusUpdateMirroredPrefix = "status_update_mirrored_"
+)
+
+// IncidentSubscription is a channel following an incident. The incident is summarized in a
+// post of the channel, under which its status updates are mirrored.
+type IncidentSubscription struct {
+	IncidentID string `json:"incident_id"`
+	Account    string `json:"account,omitempty"`
+	ChannelID  string `json:"channel_id"`
+	PostID     string `json:"post_id"`
+	CreatorID  string `json:"creator_id"`
+	CreateAt   int64  `json:"create_at"`
+}
+
+func incidentSubscriptionKey(account, incidentID, channelID string) string {
+	return incidentSubscriptionPrefix + accountKey(account, incidentID) + "_" + channelID
+}
+
+// SaveIncidentSubscription creates or updates an incident subscription
+func (kv Client) SaveIncidentSubscription(subscription *IncidentSubscription) error {
+	if _, err := kv.client.KV.Set(incidentSubscriptionKey(subscription.Account, subscription.IncidentID, subscription.ChannelID), subscription); err != nil {
+		return errors.Wrap(err, "failed to save incident subscription")
+	}
+	return nil
+}
+
+// GetIncidentSubscription retrieves the subscription of a channel to an incident, returning
+// nil if it does not exist
+func (kv Client) GetIncidentSubscription(account, incidentID, channelID string) (*IncidentSubscription, error) {
+	var subscription *IncidentSubscription
+	if err := kv.client.KV.Get(incidentSubscriptionKey(account, incidentID, channelID), &subscription); err != nil {
+		return nil, errors.Wrap(err, "failed to get incident subscription")
+	}
+	return subscription, nil
+}
+
+// DeleteIncidentSubscription removes the subscription of a channel to an incident
+func (kv Client) DeleteIncidentSubscription(account, incidentID, channelID string) error {
+	if err := kv.client.KV.Delete(incidentSubscriptionKey(account, incidentID, channelID)); err != nil {
+		return errors.Wrap(err, "failed to delete incident subscription")
+	}
+	return nil
+}
+
+// ListIncidentSubscriptions retrieves all incident subscriptions
+func (kv Client) ListIncidentSubscriptions() ([]*IncidentSubscription, error) {
+	return kv.listIncidentSubscriptions(incidentSubscriptionPrefix)
+}
+
+// ListIncidentSubscriptionsForIncident retrieves the subscriptions of every channel following
+// an incident
+func (kv Client) ListIncidentSubscriptionsForIncident(account, incidentID string) ([]*IncidentSubscription, error) {
+	return kv.listIncidentSubscriptions(incidentSubscriptionPrefix + accountKey(account, incidentID) + "_")
+}
+
+func (kv Client) listIncidentSubscriptions(prefix string) ([]*IncidentSubscription, error) {
+	keys, err := kv.listKeysWithPrefix(prefix)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list incident subscriptions")
+	}
+
+	subscriptions := make([]*IncidentSubscription, 0, len(keys))
+	for _, key := range keys {
+		var subscription *IncidentSubscription
+		if err := kv.client.KV.Get(key, &subscription); err != nil {
+			return nil, errors.Wrapf(err, "failed to get incident subscription %s", key)
+		}
+		if subscription != nil {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	return subscriptions, nil
+}
+
+// MarkStatusUpdateMirrored records that a status update has been mirrored to the channels
+// following its incident. It returns false if it was already recorded, so that an update sent
+// from Mattermost is not mirrored a second time when its webhook arrives.
+func (kv Client) MarkStatusUpdateMirrored(statusUpdateID string, ttl time.Duration) (bool, error) {
+	saved, err := kv.client.KV.Set(statusUpdateMirroredPrefix+statusUpdateID, true, pluginapi.SetAtomic(nil), pluginapi.SetExpiry(ttl))
+	if err != nil {
+		return false, errors.Wrap(err, "failed to mark status update mirrored")
+	}
+	return saved, nil
+}
@@ -0,0 +1,22 @@
+package kvstore
+
+import (
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/pkg/errors"
+)
+
+const incidentWorkflowStatusPrefix = "incident_workflow_status_"
+
+// MarkIncidentWorkflowStatusPosted records that a status of an incident workflow instance, e.g.
+// started or completed, has been posted to the channels following its incident. It returns false
+// if it was already recorded, so that a workflow started from Mattermost is not announced a
+// second time when its webhook arrives.
+func (kv Client) MarkIncidentWorkflowStatusPosted(instanceID, status string, ttl time.Duration) (bool, error) {
+	saved, err := kv.client.KV.Set(incidentWorkflowStatusPrefix+instanceID+"_"+status, true, pluginapi.SetAtomic(nil), pluginapi.SetExpiry(ttl))
+	if err != nil {
+		return false, errors.Wrap(err, "failed to mark incident workflow status posted")
+	}
+	return saved, nil
+}
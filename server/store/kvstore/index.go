@@ -0,0 +1,109 @@
+package kvstore
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/pkg/errors"
+)
+
+const (
+	// indexPrefix prefixes the keys of the indexes of the records stored under a prefix.
+	indexPrefix = "index_"
+
+	// maxIndexUpdateAttempts is how often a change of an index is retried when another node of
+	// the cluster changes it at the same time.
+	maxIndexUpdateAttempts = 10
+)
+
+// indexKey is the key of the index of the records stored under a prefix. Records the background
+// job goes through every minute keep their keys in an index, so that listing them reads a single
+// key instead of every key of the plugin.
+func indexKey(prefix string) string {
+	return indexPrefix + prefix
+}
+
+// setIndexed stores a record and adds its key to the index of its prefix.
+func (kv Client) setIndexed(prefix, key string, value interface{}) error {
+	if _, err := kv.client.KV.Set(key, value); err != nil {
+		return err
+	}
+	return kv.addToIndex(prefix, key)
+}
+
+// deleteIndexed deletes a record and removes its key from the index of its prefix.
+func (kv Client) deleteIndexed(prefix, key string) error {
+	if err := kv.client.KV.Delete(key); err != nil {
+		return err
+	}
+	return kv.removeFromIndex(prefix, key)
+}
+
+// listIndexedKeys lists the keys of the records stored under an indexed prefix, sorted.
+func (kv Client) listIndexedKeys(prefix string) ([]string, error) {
+	var keys []string
+	if err := kv.client.KV.Get(indexKey(prefix), &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (kv Client) addToIndex(prefix, key string) error {
+	return kv.updateIndex(indexKey(prefix), 0, func(keys []string) ([]string, bool) {
+		i, found := slices.BinarySearch(keys, key)
+		if found {
+			return keys, false
+		}
+		return slices.Insert(keys, i, key), true
+	})
+}
+
+func (kv Client) removeFromIndex(prefix, key string) error {
+	return kv.updateIndex(indexKey(prefix), 0, func(keys []string) ([]string, bool) {
+		i, found := slices.BinarySearch(keys, key)
+		if !found {
+			return keys, false
+		}
+		return slices.Delete(keys, i, i+1), true
+	})
+}
+
+// updateIndex applies a change to the sorted keys stored in an index, if the change reports
+// that it changed them, retrying with the keys saved meanwhile by other nodes of the cluster. A
+// non-zero expiry makes the index expire that long after its last change.
+func (kv Client) updateIndex(key string, expiry time.Duration, change func(keys []string) ([]string, bool)) error {
+	for attempt := 0; attempt < maxIndexUpdateAttempts; attempt++ {
+		var data []byte
+		if err := kv.client.KV.Get(key, &data); err != nil {
+			return errors.Wrap(err, "failed to get index")
+		}
+
+		var keys []string
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, &keys); err != nil {
+				return errors.Wrap(err, "failed to decode index")
+			}
+		}
+
+		keys, changed := change(keys)
+		if !changed {
+			return nil
+		}
+
+		updated, err := json.Marshal(keys)
+		if err != nil {
+			return errors.Wrap(err, "failed to encode index")
+		}
+		saved, err := kv.client.KV.Set(key, updated, pluginapi.SetAtomic(data), pluginapi.SetExpiry(expiry))
+		if err != nil {
+			return errors.Wrap(err, "failed to save index")
+		}
+		if saved {
+			return nil
+		}
+	}
+
+	return errors.New("failed to save index: too many concurrent updates")
+}
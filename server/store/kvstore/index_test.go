@@ -0,0 +1,170 @@
+package kvstore
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// testKV is a map backed plugin KV store that records the expiry of each key, the keys that
+// were read and how often every key was listed.
+type testKV struct {
+	values   map[string][]byte
+	expiries map[string]int64
+	read     map[string]bool
+	listed   int
+}
+
+func newTestKVStore(t *testing.T) (Client, *testKV) {
+	kv := &testKV{values: map[string][]byte{}, expiries: map[string]int64{}, read: map[string]bool{}}
+
+	api := &plugintest.API{}
+	t.Cleanup(func() { api.AssertExpectations(t) })
+	api.On("KVGet", mock.Anything).Return(func(key string) []byte {
+		kv.read[key] = true
+		return kv.values[key]
+	}, func(string) *model.AppError {
+		return nil
+	}).Maybe()
+	api.On("KVSetWithOptions", mock.Anything, mock.Anything, mock.Anything).Return(func(key string, value []byte, options model.PluginKVSetOptions) bool {
+		if options.Atomic && !bytes.Equal(kv.values[key], options.OldValue) {
+			return false
+		}
+		if value == nil {
+			delete(kv.values, key)
+			delete(kv.expiries, key)
+		} else {
+			kv.values[key] = value
+			kv.expiries[key] = options.ExpireInSeconds
+		}
+		return true
+	}, func(string, []byte, model.PluginKVSetOptions) *model.AppError {
+		return nil
+	}).Maybe()
+	api.On("KVList", mock.Anything, mock.Anything).Return(func(page, _ int) []string {
+		kv.listed++
+		if page > 0 {
+			return nil
+		}
+		keys := make([]string, 0, len(kv.values))
+		for key := range kv.values {
+			keys = append(keys, key)
+		}
+		return keys
+	}, func(int, int) *model.AppError {
+		return nil
+	}).Maybe()
+
+	return Client{client: pluginapi.NewClient(api, nil)}, kv
+}
+
+func TestClient_indexedRecords(t *testing.T) {
+	client, kv := newTestKVStore(t)
+
+	require.NoError(t, client.SaveRoster(&Roster{ID: "roster1"}))
+	require.NoError(t, client.SaveRoster(&Roster{ID: "roster2"}))
+	require.NoError(t, client.SaveRoster(&Roster{ID: "roster1", ChannelID: "channel1"}))
+	require.NoError(t, client.DeleteRoster("roster2"))
+
+	rosters, err := client.ListRosters()
+	require.NoError(t, err)
+	require.Len(t, rosters, 1)
+	assert.Equal(t, "channel1", rosters[0].ChannelID)
+
+	require.NoError(t, client.SaveGroupSync(&GroupSync{ID: "sync1"}))
+	groupSyncs, err := client.ListGroupSyncs()
+	require.NoError(t, err)
+	require.Len(t, groupSyncs, 1)
+	assert.Equal(t, "sync1", groupSyncs[0].ID)
+
+	require.NoError(t, client.SaveChannelOnCall(&ChannelOnCall{ChannelID: "channel1"}))
+	require.NoError(t, client.DeleteChannelOnCall("channel1"))
+	channelOnCalls, err := client.ListChannelOnCalls()
+	require.NoError(t, err)
+	assert.Empty(t, channelOnCalls)
+
+	require.NoError(t, client.SaveOnCallStatus(&OnCallStatus{UserID: "user1"}))
+	statuses, err := client.ListOnCallStatuses()
+	require.NoError(t, err)
+	require.Len(t, statuses, 1)
+	assert.Equal(t, "user1", statuses[0].UserID)
+
+	require.NoError(t, client.SaveMaintenanceWindow(&MaintenanceWindow{ID: "PMW1", Account: "eu"}))
+	windows, err := client.ListMaintenanceWindows()
+	require.NoError(t, err)
+	require.Len(t, windows, 1)
+	assert.Equal(t, "eu", windows[0].Account)
+
+	require.NoError(t, client.SaveImpactPost(&ImpactPost{ID: "post1"}))
+	impactPosts, err := client.ListImpactPosts()
+	require.NoError(t, err)
+	require.Len(t, impactPosts, 1)
+	assert.Equal(t, "post1", impactPosts[0].ID)
+
+	assert.Zero(t, kv.listed, "listing indexed records must not list every key")
+}
+
+func TestClient_auditRecords(t *testing.T) {
+	client, kv := newTestKVStore(t)
+	retention := 7 * 24 * time.Hour
+	day := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	for i, createAt := range []time.Time{
+		day.Add(-time.Minute),
+		day.Add(time.Hour),
+		day.Add(2 * time.Hour),
+		day.Add(25 * time.Hour),
+	} {
+		record := &AuditRecord{ID: fmt.Sprintf("record%d", i), CreateAt: createAt.UnixMilli()}
+		require.NoError(t, client.AppendAuditRecord(record, retention))
+		assert.Equal(t, int64(retention.Seconds()), kv.expiries[auditRecordKey(record)])
+	}
+	assert.Error(t, client.AppendAuditRecord(&AuditRecord{ID: "record1", CreateAt: day.Add(time.Hour).UnixMilli()}, retention))
+
+	assert.Equal(t, int64((retention + auditDayExpiryMargin).Seconds()), kv.expiries[auditDayKey(day.UnixMilli())])
+
+	ids := func(records []*AuditRecord) []string {
+		ids := make([]string, 0, len(records))
+		for _, record := range records {
+			ids = append(ids, record.ID)
+		}
+		return ids
+	}
+	all := func(*AuditRecord) bool { return true }
+
+	records, err := client.ListAuditRecords(day.Add(-time.Hour).UnixMilli(), day.Add(26*time.Hour).UnixMilli(), 0, all)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"record3", "record2", "record1", "record0"}, ids(records))
+
+	records, err = client.ListAuditRecords(day.Add(30*time.Minute).UnixMilli(), day.Add(2*time.Hour).UnixMilli(), 0, all)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"record2", "record1"}, ids(records))
+
+	records, err = client.ListAuditRecords(day.Add(-time.Hour).UnixMilli(), day.Add(26*time.Hour).UnixMilli(), 1, func(record *AuditRecord) bool {
+		return record.ID != "record3"
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"record2"}, ids(records))
+
+	// Reading stops at the day of the last record needed.
+	clear(kv.read)
+	_, err = client.ListAuditRecords(day.Add(-time.Hour).UnixMilli(), day.Add(26*time.Hour).UnixMilli(), 2, all)
+	require.NoError(t, err)
+	assert.NotContains(t, kv.read, auditDayKey(day.Add(-time.Hour).UnixMilli()))
+
+	// A record indexed whose save failed is skipped.
+	require.NoError(t, client.addAuditRecordToDay(day.UnixMilli(), auditRecordKey(&AuditRecord{ID: "unsaved", CreateAt: day.UnixMilli()}), retention))
+	records, err = client.ListAuditRecords(day.UnixMilli(), day.Add(time.Minute).UnixMilli(), 0, all)
+	require.NoError(t, err)
+	assert.Empty(t, records)
+
+	assert.Zero(t, kv.listed, "listing audit records must not list every key")
+}
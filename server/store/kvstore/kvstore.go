@@ -1,7 +1,113 @@
 package kvstore
 
+import "time"
+
 type KVStore interface {
 	// Methods for accessing cached PagerDuty data
 	GetCachedSchedules() ([]byte, error)
 	SetCachedSchedules(data []byte) error
+
+	// Methods for tracking handoff notifications
+	MarkHandoffNotified(key string, ttl time.Duration) (bool, error)
+
+	// Methods for managing scheduled on-call roster posts
+	SaveRoster(roster *Roster) error
+	GetRoster(id string) (*Roster, error)
+	DeleteRoster(id string) error
+	ListRosters() ([]*Roster, error)
+
+	// Methods for managing on-call user group syncs
+	SaveGroupSync(groupSync *GroupSync) error
+	GetGroupSync(id string) (*GroupSync, error)
+	DeleteGroupSync(id string) error
+	ListGroupSyncs() ([]*GroupSync, error)
+	UpdateGroupSync(old, groupSync *GroupSync) (bool, error)
+	RequestGroupSync(at int64) error
+	GetGroupSyncRequestedAt() (int64, error)
+
+	// Methods for managing scheduled business service impact posts
+	SaveImpactPost(post *ImpactPost) error
+	GetImpactPost(id string) (*ImpactPost, error)
+	DeleteImpactPost(id string) error
+	ListImpactPosts() ([]*ImpactPost, error)
+
+	// Methods for managing the on-call displays of channels
+	SaveChannelOnCall(channelOnCall *ChannelOnCall) error
+	GetChannelOnCall(channelID string) (*ChannelOnCall, error)
+	DeleteChannelOnCall(channelID string) error
+	ListChannelOnCalls() ([]*ChannelOnCall, error)
+
+	// Methods for managing on-call custom status opt-ins
+	SaveOnCallStatus(status *OnCallStatus) error
+	GetOnCallStatus(userID string) (*OnCallStatus, error)
+	DeleteOnCallStatus(userID string) error
+	ListOnCallStatuses() ([]*OnCallStatus, error)
+
+	// Methods for managing channel subscriptions to incidents
+	SaveIncidentSubscription(subscription *IncidentSubscription) error
+	GetIncidentSubscription(account, incidentID, channelID string) (*IncidentSubscription, error)
+	DeleteIncidentSubscription(account, incidentID, channelID string) error
+	ListIncidentSubscriptions() ([]*IncidentSubscription, error)
+	ListIncidentSubscriptionsForIncident(account, incidentID string) ([]*IncidentSubscription, error)
+	MarkStatusUpdateMirrored(statusUpdateID string, ttl time.Duration) (bool, error)
+
+	// Methods for announcing incident workflow runs
+	MarkIncidentWorkflowStatusPosted(instanceID, status string, ttl time.Duration) (bool, error)
+
+	// Methods for managing the default PagerDuty teams of Mattermost teams
+	SaveTeamMapping(mapping *TeamMapping) error
+	GetTeamMapping(account, teamID string) (*TeamMapping, error)
+	DeleteTeamMapping(account, teamID string) error
+
+	// Methods for managing announced maintenance windows
+	SaveMaintenanceWindow(window *MaintenanceWindow) error
+	GetMaintenanceWindow(account, id string) (*MaintenanceWindow, error)
+	DeleteMaintenanceWindow(account, id string) error
+	ListMaintenanceWindows() ([]*MaintenanceWindow, error)
+
+	// Methods for managing the change event rules of channels
+	SaveChangeEventRules(channelID string, rules []*ChangeEventRule) error
+	GetChangeEventRules(channelID string) ([]*ChangeEventRule, error)
+
+	// Methods for managing the alert rules and counting the events they send
+	UpdateAlertRules(change func(rules []*AlertRule) ([]*AlertRule, error)) ([]*AlertRule, error)
+	GetAlertRules() ([]*AlertRule, error)
+	IncrementAlertRuleEventCount(ruleID string, window time.Duration, now time.Time) (int, error)
+
+	// Methods for managing the paging policies of services
+	SavePagingPolicy(policy *PagingPolicy) error
+	GetPagingPolicy(account, serviceID string) (*PagingPolicy, error)
+	DeletePagingPolicy(account, serviceID string) error
+	ListPagingPolicies() ([]*PagingPolicy, error)
+
+	// Methods for deduplicating requests to create incidents
+	ReserveIncidentRequest(key string, request *IncidentRequest, ttl time.Duration) (bool, error)
+	SaveIncidentRequest(key string, request *IncidentRequest, ttl time.Duration) error
+	GetIncidentRequest(key string) (*IncidentRequest, error)
+	DeleteIncidentRequest(key string) error
+
+	// Methods for the audit trail of write actions
+	AppendAuditRecord(record *AuditRecord, retention time.Duration) error
+	ListAuditRecords(since, until int64, limit int, match func(*AuditRecord) bool) ([]*AuditRecord, error)
+	MarkConfigurationChangeAudited(hash string, ttl time.Duration) (bool, error)
+
+	// Methods for rate limiting the plugin's API
+	GetRateLimitBucket(key string) (*RateLimitBucket, error)
+	SaveRateLimitBucket(key string, oldBucket, newBucket *RateLimitBucket, ttl time.Duration) (bool, error)
+
+	// Methods for sharing the outcome of checking the connection to each PagerDuty account
+	SaveConnectionStatuses(statuses []*ConnectionStatus) error
+	GetConnectionStatuses() ([]*ConnectionStatus, error)
+
+	// Methods for managing the bot's REST API access token
+	GetBotAccessToken() (*BotAccessToken, error)
+	SetBotAccessToken(token *BotAccessToken) error
+	DeleteBotAccessToken() error
+
+	// Methods for encrypting secrets at rest
+	GetPreviousEncryptionKeys() ([][]byte, error)
+	SavePreviousEncryptionKeys(keys [][]byte) error
+	DeletePreviousEncryptionKeys() error
+	SetEncryptionKeys(key []byte, previousKeys ...[]byte) error
+	EncryptSecrets() (int, error)
 }
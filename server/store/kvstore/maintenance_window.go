@@ -0,0 +1,72 @@
+package kvstore
+
+import (
+	"github.com/pkg/errors"
+)
+
+const maintenanceWindowPrefix = "maintenance_window_"
+
+// MaintenanceWindow is a PagerDuty maintenance window whose start and end are announced in a
+// channel.
+type MaintenanceWindow struct {
+	ID            string   `json:"id"`
+	Account       string   `json:"account,omitempty"`
+	ChannelID     string   `json:"channel_id"`
+	ServiceIDs    []string `json:"service_ids"`
+	ServiceNames  []string `json:"service_names,omitempty"`
+	Description   string   `json:"description,omitempty"`
+	HtmlURL       string   `json:"html_url,omitempty"`
+	StartAt       int64    `json:"start_at"`
+	EndAt         int64    `json:"end_at"`
+	CreatorID     string   `json:"creator_id"`
+	CreateAt      int64    `json:"create_at"`
+	StartPostedAt int64    `json:"start_posted_at,omitempty"`
+}
+
+// SaveMaintenanceWindow creates or updates a maintenance window, keyed by its account and
+// PagerDuty ID
+func (kv Client) SaveMaintenanceWindow(window *MaintenanceWindow) error {
+	key := maintenanceWindowPrefix + accountKey(window.Account, window.ID)
+	if err := kv.setIndexed(maintenanceWindowPrefix, key, window); err != nil {
+		return errors.Wrap(err, "failed to save maintenance window")
+	}
+	return nil
+}
+
+// GetMaintenanceWindow retrieves a maintenance window by its account and PagerDuty ID, returning
+// nil if it does not exist
+func (kv Client) GetMaintenanceWindow(account, id string) (*MaintenanceWindow, error) {
+	var window *MaintenanceWindow
+	if err := kv.client.KV.Get(maintenanceWindowPrefix+accountKey(account, id), &window); err != nil {
+		return nil, errors.Wrap(err, "failed to get maintenance window")
+	}
+	return window, nil
+}
+
+// DeleteMaintenanceWindow removes a maintenance window
+func (kv Client) DeleteMaintenanceWindow(account, id string) error {
+	if err := kv.deleteIndexed(maintenanceWindowPrefix, maintenanceWindowPrefix+accountKey(account, id)); err != nil {
+		return errors.Wrap(err, "failed to delete maintenance window")
+	}
+	return nil
+}
+
+// ListMaintenanceWindows retrieves all maintenance windows
+func (kv Client) ListMaintenanceWindows() ([]*MaintenanceWindow, error) {
+	keys, err := kv.listIndexedKeys(maintenanceWindowPrefix)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list maintenance windows")
+	}
+
+	windows := make([]*MaintenanceWindow, 0, len(keys))
+	for _, key := range keys {
+		var window *MaintenanceWindow
+		if err := kv.client.KV.Get(key, &window); err != nil {
+			return nil, errors.Wrapf(err, "failed to get maintenance window %s", key)
+		}
+		if window != nil {
+			windows = append(windows, window)
+		}
+	}
+	return windows, nil
+}
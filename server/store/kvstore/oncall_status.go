@@ -0,0 +1,64 @@
+package kvstore
+
+import (
+	"github.com/pkg/errors"
+)
+
+const onCallStatusPrefix = "oncall_status_"
+
+// OnCallStatus records that a user opted in to having their custom status set while on call.
+type OnCallStatus struct {
+	UserID          string `json:"user_id"`
+	PagerDutyUserID string `json:"pagerduty_user_id"`
+	CreateAt        int64  `json:"create_at"`
+
+	// StatusText is the custom status text last set by the plugin, used to tell it apart
+	// from a status the user set themselves.
+	StatusText  string `json:"status_text,omitempty"`
+	NextCheckAt int64  `json:"next_check_at,omitempty"`
+}
+
+// SaveOnCallStatus creates or updates the on-call status opt-in of a user
+func (kv Client) SaveOnCallStatus(status *OnCallStatus) error {
+	if err := kv.setIndexed(onCallStatusPrefix, onCallStatusPrefix+status.UserID, status); err != nil {
+		return errors.Wrap(err, "failed to save on-call status")
+	}
+	return nil
+}
+
+// GetOnCallStatus retrieves the on-call status opt-in of a user, returning nil if they did not opt in
+func (kv Client) GetOnCallStatus(userID string) (*OnCallStatus, error) {
+	var status *OnCallStatus
+	if err := kv.client.KV.Get(onCallStatusPrefix+userID, &status); err != nil {
+		return nil, errors.Wrap(err, "failed to get on-call status")
+	}
+	return status, nil
+}
+
+// DeleteOnCallStatus removes the on-call status opt-in of a user
+func (kv Client) DeleteOnCallStatus(userID string) error {
+	if err := kv.deleteIndexed(onCallStatusPrefix, onCallStatusPrefix+userID); err != nil {
+		return errors.Wrap(err, "failed to delete on-call status")
+	}
+	return nil
+}
+
+// ListOnCallStatuses retrieves the on-call status opt-ins of all users
+func (kv Client) ListOnCallStatuses() ([]*OnCallStatus, error) {
+	keys, err := kv.listIndexedKeys(onCallStatusPrefix)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list on-call statuses")
+	}
+
+	statuses := make([]*OnCallStatus, 0, len(keys))
+	for _, key := range keys {
+		var status *OnCallStatus
+		if err := kv.client.KV.Get(key, &status); err != nil {
+			return nil, errors.Wrapf(err, "failed to get on-call status %s", key)
+		}
+		if status != nil {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses, nil
+}
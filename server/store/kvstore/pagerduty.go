@@ -1,6 +1,8 @@
 package kvstore
 
 import (
+	"strings"
+
 	"github.com/mattermost/mattermost/server/public/pluginapi"
 	"github.com/pkg/errors"
 )
@@ -9,12 +11,56 @@ import (
 // This allows us to better control which values are stored with which keys.
 
 type Client struct {
-	client *pluginapi.Client
+	client     *pluginapi.Client
+	encryption *encryption
 }
 
 func NewKVStore(client *pluginapi.Client) KVStore {
 	return Client{
-		client: client,
+		client:     client,
+		encryption: &encryption{},
+	}
+}
+
+// accountKey namespaces the ID of an object by the PagerDuty account it belongs to, as IDs are
+// only unique within an account. Objects of the default account keep the keys they had before
+// the plugin supported several accounts.
+func accountKey(account, id string) string {
+	if account == "" {
+		return id
+	}
+	return account + ":" + id
+}
+
+// listKeysPerPage is the page size used when listing keys by prefix.
+const listKeysPerPage = 100
+
+// listKeysWithPrefix lists all keys starting with prefix. Keys are filtered here rather than
+// with pluginapi.WithPrefix, which filters each page and so cannot signal the last page.
+func (kv Client) listKeysWithPrefix(prefix string) ([]string, error) {
+	return kv.listKeysMatching(func(key string) bool {
+		return strings.HasPrefix(key, prefix)
+	})
+}
+
+// listKeysMatching lists all keys for which match returns true, listing every key once.
+func (kv Client) listKeysMatching(match func(key string) bool) ([]string, error) {
+	var keys []string
+	for page := 0; ; page++ {
+		pageKeys, err := kv.client.KV.ListKeys(page, listKeysPerPage)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range pageKeys {
+			if match(key) {
+				keys = append(keys, key)
+			}
+		}
+
+		if len(pageKeys) < listKeysPerPage {
+			return keys, nil
+		}
 	}
 }
 
@@ -0,0 +1,86 @@
+package kvstore
+
+import (
+	"github.com/pkg/errors"
+)
+
+const pagingPolicyPrefix = "paging_policy_"
+
+// PagingPolicy restricts who can page a service and what it takes, so that paging a critical
+// service takes more than one click.
+type PagingPolicy struct {
+	ServiceID   string `json:"service_id"`
+	ServiceName string `json:"service_name,omitempty"`
+	Account     string `json:"account,omitempty"`
+
+	// Allowlist limits who can page the service, in the syntax of the plugin's permission
+	// allowlists. Empty allows everyone who may page.
+	Allowlist string `json:"allowlist,omitempty"`
+
+	// RequiredFields are the fields of an incident that must be filled in: description,
+	// assignees or urgency.
+	RequiredFields []string `json:"required_fields,omitempty"`
+
+	RequireConfirmation             bool `json:"require_confirmation,omitempty"`
+	RequireHighUrgencyJustification bool `json:"require_high_urgency_justification,omitempty"`
+
+	QuietHours *QuietHours `json:"quiet_hours,omitempty"`
+
+	UpdatedBy string `json:"updated_by"`
+	UpdateAt  int64  `json:"update_at"`
+}
+
+// QuietHours is a daily period, such as 22:00 to 07:00, during which paging a service is
+// blocked, needs confirmation or is downgraded to low urgency.
+type QuietHours struct {
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	TimeZone string `json:"time_zone,omitempty"`
+	Rule     string `json:"rule"`
+}
+
+// SavePagingPolicy creates or replaces the paging policy of a service
+func (kv Client) SavePagingPolicy(policy *PagingPolicy) error {
+	if _, err := kv.client.KV.Set(pagingPolicyPrefix+accountKey(policy.Account, policy.ServiceID), policy); err != nil {
+		return errors.Wrap(err, "failed to save paging policy")
+	}
+	return nil
+}
+
+// GetPagingPolicy retrieves the paging policy of a service of an account, returning nil if it
+// has none
+func (kv Client) GetPagingPolicy(account, serviceID string) (*PagingPolicy, error) {
+	var policy *PagingPolicy
+	if err := kv.client.KV.Get(pagingPolicyPrefix+accountKey(account, serviceID), &policy); err != nil {
+		return nil, errors.Wrap(err, "failed to get paging policy")
+	}
+	return policy, nil
+}
+
+// DeletePagingPolicy removes the paging policy of a service of an account
+func (kv Client) DeletePagingPolicy(account, serviceID string) error {
+	if err := kv.client.KV.Delete(pagingPolicyPrefix + accountKey(account, serviceID)); err != nil {
+		return errors.Wrap(err, "failed to delete paging policy")
+	}
+	return nil
+}
+
+// ListPagingPolicies retrieves the paging policies of all services
+func (kv Client) ListPagingPolicies() ([]*PagingPolicy, error) {
+	keys, err := kv.listKeysWithPrefix(pagingPolicyPrefix)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list paging policies")
+	}
+
+	policies := make([]*PagingPolicy, 0, len(keys))
+	for _, key := range keys {
+		var policy *PagingPolicy
+		if err := kv.client.KV.Get(key, &policy); err != nil {
+			return nil, errors.Wrapf(err, "failed to get paging policy %s", key)
+		}
+		if policy != nil {
+			policies = append(policies, policy)
+		}
+	}
+	return policies, nil
+}
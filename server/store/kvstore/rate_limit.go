@@ -0,0 +1,42 @@
+package kvstore
+
+import (
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/pkg/errors"
+)
+
+const rateLimitBucketPrefix = "rate_limit_"
+
+// RateLimitBucket is a token bucket limiting the requests made to the plugin's API. Buckets are
+// shared by every node of the cluster.
+type RateLimitBucket struct {
+	Tokens   float64 `json:"tokens"`
+	UpdateAt int64   `json:"update_at"`
+}
+
+// GetRateLimitBucket retrieves a token bucket, returning nil if it does not exist
+func (kv Client) GetRateLimitBucket(key string) (*RateLimitBucket, error) {
+	var bucket *RateLimitBucket
+	if err := kv.client.KV.Get(rateLimitBucketPrefix+key, &bucket); err != nil {
+		return nil, errors.Wrap(err, "failed to get rate limit bucket")
+	}
+	return bucket, nil
+}
+
+// SaveRateLimitBucket replaces a token bucket if it still holds the old value, which is nil for
+// a bucket that does not exist yet, and reports whether it did. Buckets expire after ttl, by
+// when they would have been refilled anyway.
+func (kv Client) SaveRateLimitBucket(key string, oldBucket, newBucket *RateLimitBucket, ttl time.Duration) (bool, error) {
+	var oldValue interface{}
+	if oldBucket != nil {
+		oldValue = oldBucket
+	}
+
+	saved, err := kv.client.KV.Set(rateLimitBucketPrefix+key, newBucket, pluginapi.SetAtomic(oldValue), pluginapi.SetExpiry(ttl))
+	if err != nil {
+		return false, errors.Wrap(err, "failed to save rate limit bucket")
+	}
+	return saved, nil
+}
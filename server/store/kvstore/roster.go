@@ -0,0 +1,68 @@
+package kvstore
+
+import (
+	"github.com/pkg/errors"
+)
+
+const rosterPrefix = "roster_"
+
+// Roster is a recurring post of the current on-call users to a channel.
+type Roster struct {
+	ID                  string   `json:"id"`
+	ChannelID           string   `json:"channel_id"`
+	CronExpression      string   `json:"cron_expression"`
+	TimeZone            string   `json:"time_zone"`
+	ScheduleIDs         []string `json:"schedule_ids,omitempty"`
+	EscalationPolicyIDs []string `json:"escalation_policy_ids,omitempty"`
+	CreatorID           string   `json:"creator_id"`
+	CreateAt            int64    `json:"create_at"`
+	LastPostAt          int64    `json:"last_post_at,omitempty"`
+
+	// Account is the PagerDuty account of the roster, empty for the default account.
+	Account string `json:"account,omitempty"`
+}
+
+// SaveRoster creates or updates a roster
+func (kv Client) SaveRoster(roster *Roster) error {
+	if err := kv.setIndexed(rosterPrefix, rosterPrefix+roster.ID, roster); err != nil {
+		return errors.Wrap(err, "failed to save roster")
+	}
+	return nil
+}
+
+// GetRoster retrieves a roster by ID, returning nil if it does not exist
+func (kv Client) GetRoster(id string) (*Roster, error) {
+	var roster *Roster
+	if err := kv.client.KV.Get(rosterPrefix+id, &roster); err != nil {
+		return nil, errors.Wrap(err, "failed to get roster")
+	}
+	return roster, nil
+}
+
+// DeleteRoster removes a roster
+func (kv Client) DeleteRoster(id string) error {
+	if err := kv.deleteIndexed(rosterPrefix, rosterPrefix+id); err != nil {
+		return errors.Wrap(err, "failed to delete roster")
+	}
+	return nil
+}
+
+// ListRosters retrieves all rosters
+func (kv Client) ListRosters() ([]*Roster, error) {
+	keys, err := kv.listIndexedKeys(rosterPrefix)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list rosters")
+	}
+
+	rosters := make([]*Roster, 0, len(keys))
+	for _, key := range keys {
+		var roster *Roster
+		if err := kv.client.KV.Get(key, &roster); err != nil {
+			return nil, errors.Wrapf(err, "failed to get roster %s", key)
+		}
+		if roster != nil {
+			rosters = append(rosters, roster)
+		}
+	}
+	return rosters, nil
+}
@@ -0,0 +1,43 @@
+package kvstore
+
+import (
+	"github.com/pkg/errors"
+)
+
+const teamMappingPrefix = "team_mapping_"
+
+// TeamMapping is the default set of PagerDuty teams of an account that requests made from a
+// Mattermost team are scoped to.
+type TeamMapping struct {
+	TeamID           string   `json:"team_id"`
+	Account          string   `json:"account,omitempty"`
+	PagerDutyTeamIDs []string `json:"pagerduty_team_ids"`
+	UpdatedBy        string   `json:"updated_by"`
+	UpdateAt         int64    `json:"update_at"`
+}
+
+// SaveTeamMapping creates or updates the PagerDuty teams of a Mattermost team
+func (kv Client) SaveTeamMapping(mapping *TeamMapping) error {
+	if _, err := kv.client.KV.Set(teamMappingPrefix+accountKey(mapping.Account, mapping.TeamID), mapping); err != nil {
+		return errors.Wrap(err, "failed to save team mapping")
+	}
+	return nil
+}
+
+// GetTeamMapping retrieves the PagerDuty teams of an account for a Mattermost team, returning nil
+// if there are none
+func (kv Client) GetTeamMapping(account, teamID string) (*TeamMapping, error) {
+	var mapping *TeamMapping
+	if err := kv.client.KV.Get(teamMappingPrefix+accountKey(account, teamID), &mapping); err != nil {
+		return nil, errors.Wrap(err, "failed to get team mapping")
+	}
+	return mapping, nil
+}
+
+// DeleteTeamMapping removes the PagerDuty teams of an account for a Mattermost team
+func (kv Client) DeleteTeamMapping(account, teamID string) error {
+	if err := kv.client.KV.Delete(teamMappingPrefix + accountKey(account, teamID)); err != nil {
+		return errors.Wrap(err, "failed to delete team mapping")
+	}
+	return nil
+}
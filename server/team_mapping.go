@@ -0,0 +1,101 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
+)
+
+const (
+	// teamIDsParam is the query parameter restricting a request to PagerDuty teams.
+	teamIDsParam = "team_ids"
+
+	// mattermostTeamIDParam is the query parameter naming the Mattermost team a request is
+	// made from, whose default PagerDuty teams apply when no team_ids are given.
+	mattermostTeamIDParam = "mattermost_team_id"
+)
+
+// getRequestTeamIDs returns the PagerDuty teams a request is restricted to: the team_ids it
+// passes, either repeated or comma-separated, or else the default teams of the Mattermost team
+// it is made from for the given account. An empty result means no restriction.
+func (p *Plugin) getRequestTeamIDs(r *http.Request, account string) []string {
+	query := r.URL.Query()
+
+	teamIDs := parseIDList(append(query[teamIDsParam], query[teamIDsParam+"[]"]...))
+	if len(teamIDs) > 0 {
+		return teamIDs
+	}
+
+	mattermostTeamID := query.Get(mattermostTeamIDParam)
+	if mattermostTeamID == "" {
+		return nil
+	}
+
+	mapping, err := p.kvstore.GetTeamMapping(account, mattermostTeamID)
+	if err != nil {
+		p.client.Log.Warn("Failed to get team mapping", "error", err.Error(), "team_id", mattermostTeamID)
+		return nil
+	}
+	if mapping == nil {
+		return nil
+	}
+	return mapping.PagerDutyTeamIDs
+}
+
+// parseIDList splits comma-separated IDs and drops empty entries.
+func parseIDList(values []string) []string {
+	var ids []string
+	for _, value := range values {
+		for _, id := range strings.Split(value, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// canManageTeamMapping reports whether the user may change the default PagerDuty teams of a
+// Mattermost team, which requires being a team admin.
+func (p *Plugin) canManageTeamMapping(userID, teamID string) bool {
+	return p.client.User.HasPermissionToTeam(userID, teamID, model.PermissionManageTeam)
+}
+
+// getOnCallsForTeams returns the current on-calls of every escalation policy belonging to the
+// given teams. PagerDuty does not filter on-calls by team directly.
+func (p *Plugin) getOnCallsForTeams(client *pagerduty.Client, teamIDs []string) (*pagerduty.OnCallsResponse, error) {
+	params := url.Values{}
+	params.Set("limit", "100")
+	for _, teamID := range teamIDs {
+		params.Add("team_ids[]", teamID)
+	}
+
+	policies, err := client.ListEscalationPolicies(params)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get escalation policies for teams")
+	}
+
+	result := &pagerduty.OnCallsResponse{OnCalls: []pagerduty.OnCall{}}
+	for start := 0; start < len(policies.EscalationPolicies); start += escalationPolicyOnCallBatchSize {
+		batch := policies.EscalationPolicies[start:min(start+escalationPolicyOnCallBatchSize, len(policies.EscalationPolicies))]
+
+		policyIDs := make([]string, 0, len(batch))
+		for _, policy := range batch {
+			policyIDs = append(policyIDs, policy.ID)
+		}
+
+		oncalls, err := client.GetOnCallsForEscalationPolicies(policyIDs)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get on-calls for escalation policies")
+		}
+		result.OnCalls = append(result.OnCalls, oncalls.OnCalls...)
+	}
+
+	result.Total = len(result.OnCalls)
+	return result, nil
+}
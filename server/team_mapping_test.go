@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/store/kvstore"
+)
+
+func TestPlugin_getRequestTeamIDs(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		account string
+		want    []string
+	}{
+		{
+			name:  "no teams",
+			query: "",
+			want:  nil,
+		},
+		{
+			name:  "comma-separated",
+			query: "team_ids=TEAM1,TEAM2",
+			want:  []string{"TEAM1", "TEAM2"},
+		},
+		{
+			name:  "repeated",
+			query: "team_ids[]=TEAM1&team_ids[]=TEAM2",
+			want:  []string{"TEAM1", "TEAM2"},
+		},
+		{
+			name:  "explicit teams override the default",
+			query: "team_ids=TEAM3&mattermost_team_id=mm-team",
+			want:  []string{"TEAM3"},
+		},
+		{
+			name:  "default teams of the Mattermost team",
+			query: "mattermost_team_id=mm-team",
+			want:  []string{"TEAM1", "TEAM2"},
+		},
+		{
+			name:  "Mattermost team without default teams",
+			query: "mattermost_team_id=other-team",
+			want:  nil,
+		},
+		{
+			name:    "default teams of the Mattermost team in another account",
+			query:   "mattermost_team_id=mm-team",
+			account: "eu",
+			want:    []string{"TEAM4"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &plugintest.API{}
+			api.On("KVGet", "team_mapping_mm-team").Return([]byte(`{"team_id":"mm-team","pagerduty_team_ids":["TEAM1","TEAM2"]}`), nil).Maybe()
+			api.On("KVGet", "team_mapping_other-team").Return(nil, nil).Maybe()
+			api.On("KVGet", "team_mapping_eu:mm-team").Return([]byte(`{"team_id":"mm-team","account":"eu","pagerduty_team_ids":["TEAM4"]}`), nil).Maybe()
+
+			plugin := &Plugin{}
+			plugin.SetAPI(api)
+			plugin.client = pluginapi.NewClient(api, nil)
+			plugin.kvstore = kvstore.NewKVStore(plugin.client)
+
+			r := httptest.NewRequest("GET", "/api/v1/schedules?"+tt.query, nil)
+			assert.Equal(t, tt.want, plugin.getRequestTeamIDs(r, tt.account))
+		})
+	}
+}
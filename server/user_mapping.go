@@ -0,0 +1,116 @@
+package main
+
+import (
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
+)
+
+const (
+	// pagerDutyUserCacheTTL is how long the PagerDuty user of a Mattermost user is reused when
+	// attributing audit records before it is looked up again.
+	pagerDutyUserCacheTTL = 10 * time.Minute
+
+	// pagerDutyUserErrorCacheTTL is how long a failed lookup is reused, so that every action
+	// does not wait for PagerDuty while it is unreachable.
+	pagerDutyUserErrorCacheTTL = time.Minute
+)
+
+// cachedPagerDutyUser is the outcome of looking up the PagerDuty user of a Mattermost user under
+// a configuration.
+type cachedPagerDutyUser struct {
+	config   *configuration
+	pdUser   *pagerduty.User
+	err      error
+	expireAt time.Time
+}
+
+// getMattermostUserForPagerDutyUser resolves the Mattermost account belonging to a PagerDuty user.
+// Accounts are matched by email address, which both systems require to be unique.
+func (p *Plugin) getMattermostUserForPagerDutyUser(pdUser pagerduty.User) (*model.User, error) {
+	if pdUser.Email == "" {
+		return nil, errors.Errorf("PagerDuty user %s has no email address", pdUser.ID)
+	}
+
+	user, err := p.client.User.GetByEmail(pdUser.Email)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to find Mattermost user for PagerDuty user %s", pdUser.ID)
+	}
+
+	return user, nil
+}
+
+// getPagerDutyUserForMattermostUser resolves the user of a PagerDuty account belonging to a
+// Mattermost account, returning nil if there is none.
+func (p *Plugin) getPagerDutyUserForMattermostUser(account string, user *model.User) (*pagerduty.User, error) {
+	if user.Email == "" {
+		return nil, nil
+	}
+
+	client, apiErr := p.accountClient(account)
+	if apiErr != nil {
+		return nil, errors.New(apiErr.Message)
+	}
+
+	pdUser, err := client.GetUserByEmail(user.Email)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to find PagerDuty user for Mattermost user %s", user.Id)
+	}
+
+	return pdUser, nil
+}
+
+// formatPagerDutyUser renders a PagerDuty user as an @mention when a matching Mattermost
+// account exists, falling back to the PagerDuty name otherwise.
+func (p *Plugin) formatPagerDutyUser(pdUser pagerduty.User) string {
+	if user, err := p.getMattermostUserForPagerDutyUser(pdUser); err == nil {
+		return "@" + user.Username
+	}
+
+	if pdUser.Name != "" {
+		return pdUser.Name
+	}
+	return pdUser.Summary
+}
+
+// getCachedPagerDutyUserForMattermostUser resolves the PagerDuty user of a Mattermost user like
+// getPagerDutyUserForMattermostUser, reusing the outcome of a recent lookup under the same
+// configuration, so that recording an action in the audit trail rarely asks PagerDuty.
+func (p *Plugin) getCachedPagerDutyUserForMattermostUser(account string, user *model.User) (*pagerduty.User, error) {
+	if user.Email == "" {
+		return nil, nil
+	}
+
+	config := p.getConfiguration()
+	key := account + "/" + user.Email
+
+	p.pagerDutyUserLock.Lock()
+	cached, ok := p.pagerDutyUsers[key]
+	p.pagerDutyUserLock.Unlock()
+	if ok && cached.config == config && time.Now().Before(cached.expireAt) {
+		return cached.pdUser, cached.err
+	}
+
+	pdUser, err := p.getPagerDutyUserForMattermostUser(account, user)
+
+	ttl := pagerDutyUserCacheTTL
+	if err != nil {
+		ttl = pagerDutyUserErrorCacheTTL
+	}
+
+	p.pagerDutyUserLock.Lock()
+	defer p.pagerDutyUserLock.Unlock()
+	if p.pagerDutyUsers == nil {
+		p.pagerDutyUsers = map[string]*cachedPagerDutyUser{}
+	}
+	p.pagerDutyUsers[key] = &cachedPagerDutyUser{
+		config:   config,
+		pdUser:   pdUser,
+		err:      err,
+		expireAt: time.Now().Add(ttl),
+	}
+	return pdUser, err
+}
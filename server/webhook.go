@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/svelle/mattermost-pagerduty-plugin/server/pagerduty"
+)
+
+// maxWebhookBodySize bounds the size of webhook payloads read from PagerDuty.
+const maxWebhookBodySize = 1 << 20
+
+// handleWebhook receives V3 webhook deliveries from PagerDuty. Requests are authenticated by
+// their signature rather than by a Mattermost session. Deliveries for an account other than the
+// default one name it with the account query parameter, and are verified with its secret.
+func (p *Plugin) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	account := p.getConfiguration().account(requestAccount(r))
+	if account == nil {
+		p.handleError(w, r, p.unknownAccountError(r.URL.Query().Get("account")))
+		return
+	}
+	if account.WebhookSecret == "" {
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.webhook.disabled",
+			Message:    "PagerDuty webhooks are not configured",
+			StatusCode: http.StatusNotImplemented,
+		})
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBodySize))
+	if err != nil {
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.webhook.read.error",
+			Message:    "Failed to read request body",
+			StatusCode: http.StatusBadRequest,
+		})
+		return
+	}
+
+	if !pagerduty.VerifyWebhookSignature(body, r.Header.Get(pagerduty.WebhookSignatureHeader), account.WebhookSecret) {
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.webhook.signature.invalid",
+			Message:    "Invalid webhook signature",
+			StatusCode: http.StatusUnauthorized,
+		})
+		return
+	}
+
+	var payload pagerduty.WebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		p.handleError(w, r, &APIError{
+			ID:         "api.pagerduty.webhook.decode.error",
+			Message:    "Invalid webhook payload",
+			StatusCode: http.StatusBadRequest,
+		})
+		return
+	}
+
+	p.handleWebhookEvent(account.Name, &payload.Event)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleWebhookEvent reacts to a verified webhook event of an account.
+func (p *Plugin) handleWebhookEvent(account string, event *pagerduty.WebhookEvent) {
+	p.client.Log.Debug("Received PagerDuty webhook event", "event_type", event.EventType, "event_id", event.ID, "account", displayAccountName(account))
+
+	switch event.EventType {
+	case "pagey.ping":
+		return
+	case "incident.status_update_published":
+		var update pagerduty.WebhookStatusUpdate
+		if err := json.Unmarshal(event.Data, &update); err != nil {
+			p.client.Log.Warn("Failed to decode status update event", "error", err.Error(), "event_id", event.ID)
+			return
+		}
+		p.mirrorStatusUpdate(account, update.Incident.ID, &update.StatusUpdate)
+	case "incident.workflow.started", "incident.workflow.completed":
+		var instance pagerduty.WebhookIncidentWorkflowInstance
+		if err := json.Unmarshal(event.Data, &instance); err != nil {
+			p.client.Log.Warn("Failed to decode incident workflow event", "error", err.Error(), "event_id", event.ID)
+			return
+		}
+
+		workflowName := instance.IncidentWorkflow.Summary
+		if workflowName == "" {
+			workflowName = instance.IncidentWorkflow.ID
+		}
+		status := strings.TrimPrefix(event.EventType, "incident.workflow.")
+		p.postIncidentWorkflowStatus(account, instance.ID, instance.Incident.ID, status, formatIncidentWorkflowStatus(workflowName, status, ""))
+	case "incident.responder.added", "incident.responder.replied":
+		var responder pagerduty.WebhookIncidentResponder
+		if err := json.Unmarshal(event.Data, &responder); err != nil {
+			p.client.Log.Warn("Failed to decode incident responder event", "error", err.Error(), "event_id", event.ID)
+			return
+		}
+		p.updateIncidentResponder(account, &responder, event.EventType == "incident.responder.replied")
+	default:
+		// PagerDuty sends no events for schedule, override or escalation policy changes. An
+		// incident being acknowledged, escalated, reassigned or delegated is the closest
+		// signal that responders changed, so on-call groups, channel displays and custom
+		// statuses are refreshed without waiting for the next shift boundary. Other events are
+		// ignored.
+		if onCallEventTypes[event.EventType] {
+			p.requestGroupSync()
+			p.requestChannelOnCallRefresh()
+			p.requestOnCallStatusRefresh()
+		}
+	}
+}
+
+// onCallEventTypes are the webhook event types that trigger a refresh of who is on call.
+var onCallEventTypes = map[string]bool{
+	"incident.acknowledged": true,
+	"incident.escalated":    true,
+	"incident.reassigned":   true,
+	"incident.delegated":    true,
+}
@@ -114,6 +114,23 @@ describe('Client', () => {
             });
             expect(result).toEqual(mockOnCalls);
         });
+
+        it('should fetch on-calls for a schedule of an account', async () => {
+            (global.fetch as jest.Mock).mockResolvedValueOnce({
+                ok: true,
+                json: async () => ({oncalls: []}),
+            });
+
+            await client.getOnCalls('SCHED1', 'eu');
+
+            expect(global.fetch).toHaveBeenCalledWith('http://localhost:8065/plugins/com.svelle.pagerduty-plugin/api/v1/oncalls?schedule_id=SCHED1&account=eu', {
+                method: 'GET',
+                credentials: 'include',
+                headers: {
+                    'Content-Type': 'application/json',
+                },
+            });
+        });
     });
 
     describe('getScheduleDetails', () => {
@@ -160,5 +177,60 @@ describe('Client', () => {
 
             await expect(client.getScheduleDetails('')).rejects.toThrow('Schedule ID is required');
         });
+
+        it('should fetch schedule details of an account', async () => {
+            (global.fetch as jest.Mock).mockResolvedValueOnce({
+                ok: true,
+                json: async () => ({schedule: {id: 'SCHED1', name: 'Primary On-Call'}}),
+            });
+
+            await client.getScheduleDetails('SCHED1', 'eu');
+
+            expect(global.fetch).toHaveBeenCalledWith('http://localhost:8065/plugins/com.svelle.pagerduty-plugin/api/v1/schedule?id=SCHED1&account=eu', {
+                method: 'GET',
+                credentials: 'include',
+                headers: {
+                    'Content-Type': 'application/json',
+                },
+            });
+        });
+    });
+
+    describe('createIncident', () => {
+        it('should create an incident in an account', async () => {
+            const mockIncident = {incident: {id: 'PINC1', title: 'Database down'}};
+            (global.fetch as jest.Mock).mockResolvedValueOnce({
+                ok: true,
+                json: async () => mockIncident,
+            });
+
+            const result = await client.createIncident('Database down', '', 'PSVC1', ['USER1'], {confirmed: true}, 'eu');
+
+            expect(global.fetch).toHaveBeenCalledWith('http://localhost:8065/plugins/com.svelle.pagerduty-plugin/api/v1/incidents?account=eu', {
+                method: 'POST',
+                credentials: 'include',
+                headers: {
+                    'Content-Type': 'application/json',
+                },
+                body: JSON.stringify({title: 'Database down', description: '', service_id: 'PSVC1', assignee_ids: ['USER1'], confirmed: true}),
+            });
+            expect(result).toEqual(mockIncident);
+        });
+
+        it('should look up services and paging policies in the account', async () => {
+            (global.fetch as jest.Mock).mockResolvedValueOnce({
+                ok: true,
+                json: async () => ({services: []}),
+            }).mockResolvedValueOnce({
+                ok: true,
+                json: async () => ({service_id: 'PSVC1'}),
+            });
+
+            await client.getServices('eu');
+            await client.getPagingPolicy('PSVC1', 'eu');
+
+            expect(global.fetch).toHaveBeenNthCalledWith(1, 'http://localhost:8065/plugins/com.svelle.pagerduty-plugin/api/v1/services?account=eu', expect.any(Object));
+            expect(global.fetch).toHaveBeenNthCalledWith(2, 'http://localhost:8065/plugins/com.svelle.pagerduty-plugin/api/v1/services/PSVC1/paging_policy?account=eu', expect.any(Object));
+        });
     });
 });
@@ -2,6 +2,7 @@
 // See LICENSE.txt for license information.
 
 import manifest from '@/manifest';
+import {CreateIncidentOptions, CreateIncidentRequest, PagingPolicy, PluginStatus} from '@/types/pagerduty';
 
 export class Client {
     private baseUrl: string;
@@ -29,9 +30,17 @@ export class Client {
         return response.json();
     }
 
-    async getOnCalls(scheduleId?: string) {
-        const params = scheduleId ? `?schedule_id=${scheduleId}` : '';
-        const response = await fetch(`${this.baseUrl}/oncalls${params}`, {
+    // A schedule belongs to a single account, which is the default account unless named.
+    async getOnCalls(scheduleId?: string, account?: string) {
+        const params = new URLSearchParams();
+        if (scheduleId) {
+            params.set('schedule_id', scheduleId);
+        }
+        if (account) {
+            params.set('account', account);
+        }
+        const query = params.toString() ? `?${params.toString()}` : '';
+        const response = await fetch(`${this.baseUrl}/oncalls${query}`, {
             method: 'GET',
             credentials: 'include',
             headers: {
@@ -47,8 +56,12 @@ export class Client {
         return response.json();
     }
 
-    async getScheduleDetails(scheduleId: string) {
-        const response = await fetch(`${this.baseUrl}/schedule?id=${scheduleId}`, {
+    async getScheduleDetails(scheduleId: string, account?: string) {
+        const params = new URLSearchParams({id: scheduleId});
+        if (account) {
+            params.set('account', account);
+        }
+        const response = await fetch(`${this.baseUrl}/schedule?${params.toString()}`, {
             method: 'GET',
             credentials: 'include',
             headers: {
@@ -64,8 +77,9 @@ export class Client {
         return response.json();
     }
 
-    async getServices() {
-        const response = await fetch(`${this.baseUrl}/services`, {
+    async getServices(account?: string) {
+        const query = account ? `?${new URLSearchParams({account}).toString()}` : '';
+        const response = await fetch(`${this.baseUrl}/services${query}`, {
             method: 'GET',
             credentials: 'include',
             headers: {
@@ -81,15 +95,35 @@ export class Client {
         return response.json();
     }
 
-    async createIncident(title: string, description: string, serviceId: string, assigneeIds?: string[]) {
-        const body = {
+    async getPagingPolicy(serviceId: string, account?: string): Promise<PagingPolicy> {
+        const query = account ? `?${new URLSearchParams({account}).toString()}` : '';
+        const response = await fetch(`${this.baseUrl}/services/${encodeURIComponent(serviceId)}/paging_policy${query}`, {
+            method: 'GET',
+            credentials: 'include',
+            headers: {
+                'Content-Type': 'application/json',
+            },
+        });
+
+        if (!response.ok) {
+            const error = await response.json();
+            throw new Error(error.message || 'Failed to fetch paging policy');
+        }
+
+        return response.json();
+    }
+
+    async createIncident(title: string, description: string, serviceId: string, assigneeIds?: string[], options?: CreateIncidentOptions, account?: string) {
+        const body: CreateIncidentRequest = {
             title,
             description,
             service_id: serviceId,
             assignee_ids: assigneeIds || [],
+            ...options,
         };
 
-        const response = await fetch(`${this.baseUrl}/incidents`, {
+        const query = account ? `?${new URLSearchParams({account}).toString()}` : '';
+        const response = await fetch(`${this.baseUrl}/incidents${query}`, {
             method: 'POST',
             credentials: 'include',
             headers: {
@@ -105,6 +139,23 @@ export class Client {
 
         return response.json();
     }
+
+    async getPluginStatus(): Promise<PluginStatus> {
+        const response = await fetch(`${this.baseUrl}/admin/status`, {
+            method: 'GET',
+            credentials: 'include',
+            headers: {
+                'Content-Type': 'application/json',
+            },
+        });
+
+        if (!response.ok) {
+            const error = await response.json();
+            throw new Error(error.message || 'Failed to fetch plugin status');
+        }
+
+        return response.json();
+    }
 }
 
 const client = new Client();
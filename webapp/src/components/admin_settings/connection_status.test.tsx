@@ -0,0 +1,90 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+import React from 'react';
+
+import ConnectionStatusSetting from './connection_status';
+
+import client from '@/client/client';
+import {render, screen, waitFor, fireEvent} from '@/test-utils';
+
+jest.mock('@/client/client');
+const mockClient = client as jest.Mocked<typeof client>;
+
+describe('ConnectionStatusSetting', () => {
+    beforeEach(() => {
+        jest.clearAllMocks();
+    });
+
+    it('should show the connection status of every account', async () => {
+        mockClient.getPluginStatus.mockResolvedValueOnce({
+            configuration_valid: true,
+            accounts: [
+                {account: 'default', api_base_url: '', connected: true, access: 'full', checked_at: '2025-03-01T12:00:00Z'},
+                {account: 'eu', api_base_url: 'https://api.eu.pagerduty.com', connected: true, access: 'unknown', checked_at: '2025-03-01T12:00:00Z'},
+                {account: 'broken', api_base_url: '', connected: false, error: 'Unauthorized', checked_at: '2025-03-01T12:00:00Z'},
+            ],
+        });
+
+        render(<ConnectionStatusSetting/>);
+
+        await waitFor(() => {
+            expect(screen.getByTestId('connection-status-default')).toHaveTextContent('Connected · Full access');
+        });
+        expect(screen.getByTestId('connection-status-eu')).toHaveTextContent('Access unknown');
+        expect(screen.getByTestId('connection-status-broken')).toHaveTextContent('Not connected');
+        expect(screen.getByTestId('connection-status-broken')).toHaveTextContent('Unauthorized');
+        expect(screen.queryByTestId('configuration-error')).not.toBeInTheDocument();
+        expect(screen.queryByTestId('accounts-error')).not.toBeInTheDocument();
+    });
+
+    it('should show the accounts left out as invalid', async () => {
+        mockClient.getPluginStatus.mockResolvedValueOnce({
+            configuration_valid: true,
+            accounts_error: 'ignored invalid accounts: account "us" has no api_token',
+            accounts: [
+                {account: 'default', api_base_url: '', connected: true, access: 'full', checked_at: '2025-03-01T12:00:00Z'},
+            ],
+        });
+
+        render(<ConnectionStatusSetting/>);
+
+        await waitFor(() => {
+            expect(screen.getByTestId('accounts-error')).toHaveTextContent('account "us" has no api_token');
+        });
+        expect(screen.getByTestId('connection-status-default')).toHaveTextContent('Connected · Full access');
+    });
+
+    it('should show an invalid configuration', async () => {
+        mockClient.getPluginStatus.mockResolvedValueOnce({
+            configuration_valid: false,
+            configuration_error: 'API token is required',
+            accounts: [],
+        });
+
+        render(<ConnectionStatusSetting/>);
+
+        await waitFor(() => {
+            expect(screen.getByTestId('configuration-error')).toHaveTextContent('API token is required');
+        });
+        expect(screen.getByText('The connection has not been checked yet. Save the configuration to check it.')).toBeInTheDocument();
+    });
+
+    it('should show errors and refresh', async () => {
+        mockClient.getPluginStatus.mockRejectedValueOnce(new Error('Forbidden'));
+
+        render(<ConnectionStatusSetting/>);
+
+        await waitFor(() => {
+            expect(screen.getByText('Error: Forbidden')).toBeInTheDocument();
+        });
+
+        mockClient.getPluginStatus.mockResolvedValueOnce({configuration_valid: true, accounts: []});
+        fireEvent.click(screen.getByText('Refresh'));
+
+        await waitFor(() => {
+            expect(screen.queryByText('Error: Forbidden')).not.toBeInTheDocument();
+        });
+        expect(mockClient.getPluginStatus).toHaveBeenCalledTimes(2);
+    });
+});
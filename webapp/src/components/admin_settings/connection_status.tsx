@@ -0,0 +1,112 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+import React, {useEffect, useState} from 'react';
+
+import client from '@/client/client';
+import type {ConnectionStatus, PluginStatus} from '@/types/pagerduty';
+
+interface Props {
+    helpText?: React.ReactNode;
+}
+
+const accessLabels: Record<string, string> = {
+    full: 'Full access',
+    read_only: 'Read-only',
+    unknown: 'Access unknown (account API key)',
+};
+
+const describeConnection = (status: ConnectionStatus) => {
+    if (!status.connected) {
+        return 'Not connected';
+    }
+    return `Connected · ${accessLabels[status.access || 'unknown'] || status.access}`;
+};
+
+// ConnectionStatusSetting shows in the System Console whether the plugin could connect to each
+// PagerDuty account when the configuration was last saved.
+const ConnectionStatusSetting: React.FC<Props> = ({helpText}) => {
+    const [status, setStatus] = useState<PluginStatus | null>(null);
+    const [loading, setLoading] = useState(true);
+    const [error, setError] = useState<string | null>(null);
+
+    useEffect(() => {
+        fetchStatus();
+    }, []);
+
+    const fetchStatus = async () => {
+        try {
+            setLoading(true);
+            setError(null);
+            setStatus(await client.getPluginStatus());
+        } catch (err) {
+            setError(err instanceof Error ? err.message : 'Failed to load the connection status');
+        } finally {
+            setLoading(false);
+        }
+    };
+
+    let content: React.ReactNode;
+    if (loading) {
+        content = <div>{'Loading connection status...'}</div>;
+    } else if (error) {
+        content = <div style={{color: '#d24b4e'}}>{`Error: ${error}`}</div>;
+    } else if (status) {
+        content = (
+            <div>
+                {!status.configuration_valid && (
+                    <div
+                        data-testid='configuration-error'
+                        style={{color: '#d24b4e', marginBottom: '8px'}}
+                    >
+                        {`The configuration is not valid: ${status.configuration_error}`}
+                    </div>
+                )}
+                {status.accounts_error && (
+                    <div
+                        data-testid='accounts-error'
+                        style={{color: '#d24b4e', marginBottom: '8px'}}
+                    >
+                        {`Some accounts are not used: ${status.accounts_error}`}
+                    </div>
+                )}
+                {status.accounts.length === 0 && (
+                    <div>{'The connection has not been checked yet. Save the configuration to check it.'}</div>
+                )}
+                {status.accounts.map((account) => (
+                    <div
+                        key={account.account}
+                        data-testid={`connection-status-${account.account}`}
+                        style={{marginBottom: '8px'}}
+                    >
+                        <strong>{account.account}</strong>
+                        {`: ${describeConnection(account)}`}
+                        {account.error && (
+                            <div style={{color: '#d24b4e'}}>{account.error}</div>
+                        )}
+                        <div style={{opacity: 0.6, fontSize: '12px'}}>
+                            {`Checked ${new Date(account.checked_at).toLocaleString()}`}
+                        </div>
+                    </div>
+                ))}
+            </div>
+        );
+    }
+
+    return (
+        <div>
+            {content}
+            <button
+                type='button'
+                className='btn btn-tertiary'
+                onClick={fetchStatus}
+                disabled={loading}
+            >
+                {'Refresh'}
+            </button>
+            {helpText && <div className='help-text'>{helpText}</div>}
+        </div>
+    );
+};
+
+export default ConnectionStatusSetting;
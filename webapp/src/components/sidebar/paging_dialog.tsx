@@ -4,18 +4,22 @@
 import React, {useState, useEffect} from 'react';
 
 import client from '@/client/client';
-import {Service, ServicesResponse, CreateIncidentResponse, User, Schedule} from '@/types/pagerduty';
+import {Service, ServicesResponse, CreateIncidentResponse, User, Schedule, PagingPolicy} from '@/types/pagerduty';
 import {Theme} from '@/types/theme';
 
 interface PagingDialogProps {
     theme: Theme;
     targetType: 'schedule' | 'user';
     target: Schedule | User;
+
+    // account is the plugin's PagerDuty account the incident is created in, which is the
+    // account of the schedule paged.
+    account?: string;
     onClose: () => void;
     onSuccess: (incident: CreateIncidentResponse) => void;
 }
 
-export const PagingDialog: React.FC<PagingDialogProps> = ({theme, targetType, target, onClose, onSuccess}) => {
+export const PagingDialog: React.FC<PagingDialogProps> = ({theme, targetType, target, account, onClose, onSuccess}) => {
     const [title, setTitle] = useState('');
     const [description, setDescription] = useState('');
     const [selectedServiceId, setSelectedServiceId] = useState('');
@@ -23,13 +27,21 @@ export const PagingDialog: React.FC<PagingDialogProps> = ({theme, targetType, ta
     const [loading, setLoading] = useState(false);
     const [error, setError] = useState<string | null>(null);
     const [loadingServices, setLoadingServices] = useState(true);
+    const [policy, setPolicy] = useState<PagingPolicy | null>(null);
+    const [urgency, setUrgency] = useState<'' | 'high' | 'low'>('');
+    const [justification, setJustification] = useState('');
+    const [confirmed, setConfirmed] = useState(false);
+
+    // Identifies this page, so that a double-click or a retry after a network error does not
+    // create a second incident
+    const [idempotencyKey] = useState(() => `${Date.now().toString(36)}-${Math.random().toString(36).slice(2)}`);
 
     // Load services on mount
     useEffect(() => {
         const fetchServices = async () => {
             try {
                 setLoadingServices(true);
-                const response: ServicesResponse = await client.getServices();
+                const response: ServicesResponse = await client.getServices(account);
                 setServices(response.services);
                 if (response.services.length > 0) {
                     setSelectedServiceId(response.services[0].id);
@@ -42,7 +54,31 @@ export const PagingDialog: React.FC<PagingDialogProps> = ({theme, targetType, ta
         };
 
         fetchServices();
-    }, []);
+    }, [account]);
+
+    // Load the paging policy of the selected service, so that the fields it requires are shown
+    useEffect(() => {
+        if (!selectedServiceId) {
+            setPolicy(null);
+            return;
+        }
+
+        let cancelled = false;
+        setConfirmed(false);
+        client.getPagingPolicy(selectedServiceId, account).then((result) => {
+            if (!cancelled) {
+                setPolicy(result);
+            }
+        }).catch(() => {
+            if (!cancelled) {
+                setPolicy(null);
+            }
+        });
+
+        return () => {
+            cancelled = true;
+        };
+    }, [selectedServiceId, account]);
 
     // Set default title based on target
     useEffect(() => {
@@ -66,7 +102,12 @@ export const PagingDialog: React.FC<PagingDialogProps> = ({theme, targetType, ta
 
         try {
             const assigneeIds = targetType === 'user' ? [target.id] : [];
-            const incident = await client.createIncident(title, description, selectedServiceId, assigneeIds);
+            const incident = await client.createIncident(title, description, selectedServiceId, assigneeIds, {
+                urgency: urgency || undefined,
+                justification: justification.trim() || undefined,
+                confirmed,
+                idempotency_key: idempotencyKey,
+            }, account);
             onSuccess(incident);
             onClose();
         } catch (err) {
@@ -170,6 +211,13 @@ export const PagingDialog: React.FC<PagingDialogProps> = ({theme, targetType, ta
         borderRadius: '4px',
     };
 
+    const requiredFields = policy?.required_fields || [];
+    const needsJustification = Boolean(policy?.require_high_urgency_justification) && urgency !== 'low';
+    const needsConfirmation = Boolean(policy?.require_confirmation);
+
+    // During quiet hours the server may also ask for confirmation, which is left to it to decide.
+    const showConfirmation = needsConfirmation || policy?.quiet_hours?.rule === 'confirm';
+
     const targetDisplayName = targetType === 'schedule' ? target.name : (target.name || target.summary);
     const actionText = targetType === 'schedule' ? 'Page Schedule' : 'Page Current On-Call';
 
@@ -232,8 +280,63 @@ export const PagingDialog: React.FC<PagingDialogProps> = ({theme, targetType, ta
                                 onChange={(e) => setDescription(e.target.value)}
                                 style={textareaStyle}
                                 placeholder='Additional details about the incident'
+                                required={requiredFields.includes('description')}
                             />
                         </div>
+
+                        <div>
+                            <label style={labelStyle} htmlFor='incident-urgency'>
+                                {requiredFields.includes('urgency') ? 'Urgency *' : 'Urgency'}
+                            </label>
+                            <select
+                                id='incident-urgency'
+                                value={urgency}
+                                onChange={(e) => setUrgency(e.target.value as '' | 'high' | 'low')}
+                                style={selectStyle}
+                                required={requiredFields.includes('urgency')}
+                            >
+                                <option value=''>Service default</option>
+                                <option value='high'>High</option>
+                                <option value='low'>Low</option>
+                            </select>
+                        </div>
+
+                        {needsJustification && (
+                            <div>
+                                <label style={labelStyle} htmlFor='incident-justification'>
+                                    High Urgency Justification *
+                                </label>
+                                <textarea
+                                    id='incident-justification'
+                                    value={justification}
+                                    onChange={(e) => setJustification(e.target.value)}
+                                    style={textareaStyle}
+                                    placeholder='Why this needs someone right now'
+                                    required={true}
+                                />
+                            </div>
+                        )}
+
+                        {policy?.quiet_hours && (
+                            <div style={{fontSize: '13px', marginBottom: '16px', opacity: 0.8}}>
+                                Quiet hours from {policy.quiet_hours.start} to {policy.quiet_hours.end} {policy.quiet_hours.time_zone || 'UTC'}
+                            </div>
+                        )}
+
+                        {showConfirmation && (
+                            <div style={{marginBottom: '16px'}}>
+                                <label htmlFor='incident-confirmed'>
+                                    <input
+                                        id='incident-confirmed'
+                                        type='checkbox'
+                                        checked={confirmed}
+                                        onChange={(e) => setConfirmed(e.target.checked)}
+                                        style={{marginRight: '8px'}}
+                                    />
+                                    I confirm that I want to page {policy?.service_name || 'this service'}
+                                </label>
+                            </div>
+                        )}
                         
                         <div style={{display: 'flex', justifyContent: 'flex-end', marginTop: '20px'}}>
                             <button
@@ -247,7 +350,7 @@ export const PagingDialog: React.FC<PagingDialogProps> = ({theme, targetType, ta
                             <button
                                 type='submit'
                                 style={primaryButtonStyle}
-                                disabled={loading || !title.trim() || !selectedServiceId}
+                                disabled={loading || !title.trim() || !selectedServiceId || (needsConfirmation && !confirmed)}
                             >
                                 {loading ? 'Creating...' : 'Create Incident'}
                             </button>
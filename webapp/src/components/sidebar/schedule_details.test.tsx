@@ -5,7 +5,11 @@ import React from 'react';
 
 import ScheduleDetails from './schedule_details';
 
-import {render, screen, fireEvent, mockTheme} from '@/test-utils';
+import client from '@/client/client';
+import {render, screen, waitFor, fireEvent, mockTheme} from '@/test-utils';
+
+jest.mock('@/client/client');
+const mockClient = client as jest.Mocked<typeof client>;
 
 describe('ScheduleDetails', () => {
     const mockSchedule = {
@@ -186,4 +190,46 @@ describe('ScheduleDetails', () => {
         expect(avatars[0]).toHaveAttribute('src', 'https://example.com/avatar1.png');
         expect(avatars[1]).toHaveAttribute('src', 'https://example.com/avatar2.png');
     });
+
+    it('should page the current on-call in the account of the schedule', async () => {
+        const now = Date.now();
+        const schedule = {
+            ...mockSchedule,
+            account: 'eu',
+            final_schedule: {
+                name: 'Final Schedule',
+                rendered_schedule_entries: [{
+                    ...mockSchedule.final_schedule.rendered_schedule_entries[0],
+                    start: new Date(now - 3600000).toISOString(),
+                    end: new Date(now + 3600000).toISOString(),
+                }],
+            },
+        };
+        mockClient.getServices.mockResolvedValueOnce({services: [{id: 'PSVC1', name: 'Database'}]});
+        mockClient.getPagingPolicy.mockResolvedValueOnce({service_id: 'PSVC1'});
+        mockClient.createIncident.mockResolvedValueOnce({incident: {id: 'PINC1', title: 'Paging current on-call: John Doe'}});
+
+        render(
+            <ScheduleDetails
+                schedule={schedule}
+                onBack={mockOnBack}
+                theme={mockTheme}
+                loading={false}
+            />,
+        );
+
+        fireEvent.click(screen.getByText('📟 Page Now'));
+        await waitFor(() => {
+            expect(screen.getByText('Create Incident')).toBeInTheDocument();
+        });
+        expect(mockClient.getServices).toHaveBeenCalledWith('eu');
+        await waitFor(() => {
+            expect(mockClient.getPagingPolicy).toHaveBeenCalledWith('PSVC1', 'eu');
+        });
+
+        fireEvent.click(screen.getByText('Create Incident'));
+        await waitFor(() => {
+            expect(mockClient.createIncident).toHaveBeenCalledWith('Paging current on-call: John Doe', '', 'PSVC1', ['USER1'], expect.any(Object), 'eu');
+        });
+    });
 });
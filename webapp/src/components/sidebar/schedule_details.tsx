@@ -284,6 +284,7 @@ const ScheduleDetails: React.FC<Props> = ({schedule, onBack, theme, loading}) =>
                         theme={theme}
                         targetType={pagingTarget.type}
                         target={pagingTarget.target}
+                        account={schedule?.account}
                         onClose={handleClosePagingDialog}
                         onSuccess={handlePagingSuccess}
                     />
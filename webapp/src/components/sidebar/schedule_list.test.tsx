@@ -104,7 +104,7 @@ describe('ScheduleList', () => {
         expect(firstSchedule).toBeInTheDocument();
 
         fireEvent.click(firstSchedule);
-        expect(mockOnScheduleClick).toHaveBeenCalledWith('SCHED1');
+        expect(mockOnScheduleClick).toHaveBeenCalledWith(mockSchedules[0]);
     });
 
     it('should handle keyboard navigation', () => {
@@ -126,7 +126,7 @@ describe('ScheduleList', () => {
 
         // Test Enter key
         fireEvent.keyDown(container!, {key: 'Enter', code: 'Enter'});
-        expect(mockOnScheduleClick).toHaveBeenCalledWith('SCHED1');
+        expect(mockOnScheduleClick).toHaveBeenCalledWith(mockSchedules[0]);
     });
 
     it('should show schedule count', () => {
@@ -142,4 +142,20 @@ describe('ScheduleList', () => {
 
         expect(screen.getByText('2 schedules')).toBeInTheDocument();
     });
+
+    it('should show the accounts whose schedules failed to load', () => {
+        render(
+            <ScheduleList
+                schedules={mockSchedules}
+                accountErrors={[{account: 'eu', message: 'Failed to retrieve schedules'}]}
+                onScheduleClick={mockOnScheduleClick}
+                theme={mockTheme}
+                loading={false}
+                error={null}
+            />,
+        );
+
+        expect(screen.getByTestId('account-error-eu')).toHaveTextContent('Failed to retrieve schedules from the eu account');
+        expect(screen.getByText('2 schedules')).toBeInTheDocument();
+    });
 });
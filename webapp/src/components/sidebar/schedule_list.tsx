@@ -3,18 +3,21 @@
 
 import React, {useState, useRef, useEffect} from 'react';
 
-import type {Schedule} from '@/types/pagerduty';
+import type {AccountError, Schedule} from '@/types/pagerduty';
 import type {Theme} from '@/types/theme';
 
 interface Props {
     schedules: Schedule[];
-    onScheduleClick: (scheduleId: string) => void;
+
+    // accountErrors are the accounts whose schedules could not be loaded.
+    accountErrors?: AccountError[];
+    onScheduleClick: (schedule: Schedule) => void;
     theme: Theme;
     loading: boolean;
     error: string | null;
 }
 
-const ScheduleList: React.FC<Props> = ({schedules, onScheduleClick, theme, loading, error}) => {
+const ScheduleList: React.FC<Props> = ({schedules, accountErrors = [], onScheduleClick, theme, loading, error}) => {
     const [focusedIndex, setFocusedIndex] = useState(-1);
     const scheduleRefs = useRef<Array<HTMLDivElement | null>>([]);
 
@@ -36,7 +39,7 @@ const ScheduleList: React.FC<Props> = ({schedules, onScheduleClick, theme, loadi
             scheduleRefs.current[prevIndex]?.focus();
         } else if (e.key === 'Enter' && focusedIndex >= 0) {
             e.preventDefault();
-            onScheduleClick(schedules[focusedIndex].id);
+            onScheduleClick(schedules[focusedIndex]);
         }
     };
 
@@ -56,10 +59,23 @@ const ScheduleList: React.FC<Props> = ({schedules, onScheduleClick, theme, loadi
         );
     }
 
+    const accountErrorNotices = accountErrors.map((accountError) => (
+        <div
+            key={accountError.account}
+            data-testid={`account-error-${accountError.account}`}
+            style={{marginBottom: '12px', color: theme.errorTextColor, fontSize: '13px'}}
+        >
+            {`${accountError.message} from the ${accountError.account} account`}
+        </div>
+    ));
+
     if (schedules.length === 0) {
         return (
-            <div style={{color: theme.centerChannelColor, opacity: 0.7, fontSize: '14px'}}>
-                {'No schedules found'}
+            <div>
+                {accountErrorNotices}
+                <div style={{color: theme.centerChannelColor, opacity: 0.7, fontSize: '14px'}}>
+                    {'No schedules found'}
+                </div>
             </div>
         );
     }
@@ -69,18 +85,19 @@ const ScheduleList: React.FC<Props> = ({schedules, onScheduleClick, theme, loadi
             className='schedule-list'
             onKeyDown={handleKeyDown}
         >
+            {accountErrorNotices}
             <div style={{marginBottom: '12px', color: theme.centerChannelColor, fontSize: '14px', opacity: 0.7}}>
                 {`${schedules.length} schedule${schedules.length === 1 ? '' : 's'}`}
             </div>
             {schedules.map((schedule, index) => (
                 <div
-                    key={schedule.id}
+                    key={`${schedule.account || ''}/${schedule.id}`}
                     ref={(el) => {
                         scheduleRefs.current[index] = el;
                     }}
                     data-testid={`schedule-${schedule.id}`}
                     tabIndex={0}
-                    onClick={() => onScheduleClick(schedule.id)}
+                    onClick={() => onScheduleClick(schedule)}
                     onFocus={() => setFocusedIndex(index)}
                     style={{
                         padding: '12px',
@@ -111,7 +128,7 @@ const ScheduleList: React.FC<Props> = ({schedules, onScheduleClick, theme, loadi
                         </div>
                     )}
                     <div style={{fontSize: '12px', color: theme.centerChannelColor, opacity: 0.5, marginTop: '4px'}}>
-                        {schedule.time_zone}
+                        {schedule.account && schedule.account !== 'default' ? `${schedule.time_zone} · ${schedule.account}` : schedule.time_zone}
                     </div>
                 </div>
             ))}
@@ -23,7 +23,7 @@ jest.mock('./schedule_list', () => ({
                 <button
                     key={schedule.id}
                     data-testid={`schedule-${schedule.id}`}
-                    onClick={() => onScheduleClick(schedule.id)}
+                    onClick={() => onScheduleClick(schedule)}
                 >
                     {schedule.name}
                 </button>
@@ -89,7 +89,7 @@ describe('PagerDutySidebar', () => {
     it('should show schedule details when a schedule is clicked', async () => {
         const mockSchedules = {
             schedules: [
-                {id: 'SCHED1', name: 'Primary On-Call'},
+                {id: 'SCHED1', name: 'Primary On-Call', account: 'eu'},
             ],
         };
 
@@ -124,7 +124,7 @@ describe('PagerDutySidebar', () => {
             expect(header).toHaveTextContent('Primary On-Call');
         });
 
-        expect(mockClient.getScheduleDetails).toHaveBeenCalledWith('SCHED1');
+        expect(mockClient.getScheduleDetails).toHaveBeenCalledWith('SCHED1', 'eu');
     });
 
     it('should go back to list view when back is clicked', async () => {
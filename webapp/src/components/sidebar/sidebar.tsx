@@ -7,7 +7,7 @@ import ScheduleDetails from './schedule_details';
 import ScheduleList from './schedule_list';
 
 import client from '@/client/client';
-import type {Schedule} from '@/types/pagerduty';
+import type {AccountError, Schedule} from '@/types/pagerduty';
 import type {Theme} from '@/types/theme';
 
 interface Props {
@@ -16,6 +16,7 @@ interface Props {
 
 const PagerDutySidebar: React.FC<Props> = ({theme}) => {
     const [schedules, setSchedules] = useState<Schedule[]>([]);
+    const [accountErrors, setAccountErrors] = useState<AccountError[]>([]);
     const [selectedSchedule, setSelectedSchedule] = useState<Schedule | null>(null);
     const [loading, setLoading] = useState(true);
     const [error, setError] = useState<string | null>(null);
@@ -32,6 +33,7 @@ const PagerDutySidebar: React.FC<Props> = ({theme}) => {
 
             const schedulesData = await client.getSchedules();
             setSchedules(schedulesData.schedules || []);
+            setAccountErrors(schedulesData.account_errors || []);
         } catch (err) {
             setError(err instanceof Error ? err.message : 'Failed to load schedules');
         } finally {
@@ -39,17 +41,22 @@ const PagerDutySidebar: React.FC<Props> = ({theme}) => {
         }
     };
 
-    const handleScheduleClick = async (scheduleId: string) => {
+    const handleScheduleClick = (schedule: Schedule) => {
         // If clicking the same schedule, go back to list view
-        if (selectedSchedule?.id === scheduleId) {
+        if (selectedSchedule?.id === schedule.id && selectedSchedule?.account === schedule.account) {
             setSelectedSchedule(null);
             return;
         }
 
+        fetchScheduleDetails(schedule);
+    };
+
+    const fetchScheduleDetails = async (schedule: Schedule) => {
         setLoadingDetails(true);
         try {
-            const scheduleDetails = await client.getScheduleDetails(scheduleId);
-            setSelectedSchedule(scheduleDetails.schedule);
+            // The details do not name the account, which is kept for refreshing them.
+            const scheduleDetails = await client.getScheduleDetails(schedule.id, schedule.account);
+            setSelectedSchedule({...scheduleDetails.schedule, account: schedule.account});
         } catch (err) {
             setError(err instanceof Error ? err.message : 'Failed to load schedule details');
         } finally {
@@ -63,7 +70,7 @@ const PagerDutySidebar: React.FC<Props> = ({theme}) => {
 
     const handleRefresh = () => {
         if (selectedSchedule) {
-            handleScheduleClick(selectedSchedule.id);
+            fetchScheduleDetails(selectedSchedule);
         } else {
             fetchSchedules();
         }
@@ -131,6 +138,7 @@ const PagerDutySidebar: React.FC<Props> = ({theme}) => {
                 ) : (
                     <ScheduleList
                         schedules={schedules}
+                        accountErrors={accountErrors}
                         onScheduleClick={handleScheduleClick}
                         theme={theme}
                         loading={loading}
@@ -6,6 +6,7 @@ import type {Store, Action} from 'redux';
 
 import type {GlobalState} from '@mattermost/types/store';
 
+import ConnectionStatusSetting from './components/admin_settings/connection_status';
 import PagerDutySidebar from './components/sidebar/sidebar';
 
 import manifest from '@/manifest';
@@ -47,6 +48,9 @@ export default class Plugin {
             () => store.dispatch(toggleRHSPlugin),
             'View PagerDuty on-call schedules',
         );
+
+        // Show the outcome of the last connection check in the System Console
+        registry.registerAdminConsoleCustomSetting('ConnectionStatus', ConnectionStatusSetting, {showTitle: true});
     }
 }
 
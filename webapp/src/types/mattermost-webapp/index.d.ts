@@ -18,5 +18,11 @@ export interface PluginRegistry {
         tooltipText?: string
     ): void;
 
+    registerAdminConsoleCustomSetting(
+        key: string,
+        component: React.ComponentType<any>,
+        options?: {showTitle?: boolean}
+    ): void;
+
     // Add more if needed from https://developers.mattermost.com/extend/plugins/webapp/reference
 }
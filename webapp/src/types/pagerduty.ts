@@ -10,6 +10,10 @@ export interface Schedule {
     schedule_layers?: ScheduleLayer[];
     override_subcycle?: OverrideSubcycle;
     final_schedule?: FinalSchedule;
+
+    // account is the plugin's PagerDuty account the schedule belongs to, when schedules of
+    // several accounts are merged.
+    account?: string;
 }
 
 export interface ScheduleLayer {
@@ -73,6 +77,7 @@ export interface OnCall {
     escalation_level: number;
     start?: string;
     end?: string;
+    account?: string;
 }
 
 export interface EscalationPolicy {
@@ -89,12 +94,20 @@ export interface ListResponse {
     total: number;
 }
 
+// AccountError is an account that failed in a view merged across accounts.
+export interface AccountError {
+    account: string;
+    message: string;
+}
+
 export interface SchedulesResponse extends ListResponse {
     schedules: Schedule[];
+    account_errors?: AccountError[];
 }
 
 export interface OnCallsResponse extends ListResponse {
     oncalls: OnCall[];
+    account_errors?: AccountError[];
 }
 
 export interface Service {
@@ -142,8 +155,53 @@ export interface CreateIncidentRequest {
     description?: string;
     service_id: string;
     assignee_ids?: string[];
+    urgency?: 'high' | 'low';
+    justification?: string;
+    confirmed?: boolean;
+    idempotency_key?: string;
+}
+
+export interface CreateIncidentOptions {
+    urgency?: 'high' | 'low';
+    justification?: string;
+    confirmed?: boolean;
+    idempotency_key?: string;
+}
+
+export interface QuietHours {
+    start: string;
+    end: string;
+    time_zone?: string;
+    rule: 'block' | 'confirm' | 'low_urgency';
+}
+
+export interface PagingPolicy {
+    service_id: string;
+    service_name?: string;
+    allowlist?: string;
+    required_fields?: Array<'description' | 'assignees' | 'urgency'>;
+    require_confirmation?: boolean;
+    require_high_urgency_justification?: boolean;
+    quiet_hours?: QuietHours;
 }
 
 export interface CreateIncidentResponse {
     incident: Incident;
 }
+
+export interface ConnectionStatus {
+    account: string;
+    api_base_url: string;
+    connected: boolean;
+    access?: 'read_only' | 'full' | 'unknown';
+    abilities?: string[];
+    error?: string;
+    checked_at: string;
+}
+
+export interface PluginStatus {
+    configuration_valid: boolean;
+    configuration_error?: string;
+    accounts_error?: string;
+    accounts: ConnectionStatus[];
+}